@@ -40,6 +40,28 @@ func setupCli(ctx context.Context) *config.OperatorSettings {
 	pflag.StringVar(&config.LeaseNamespace.FlagValue, "lease-namespace", "", "The namespace where the operator lease lives.")
 	pflag.BoolVar(&config.Debug.FlagValue, "debug", false, "Enable debug logging.")
 	pflag.BoolVar(&config.Trace.FlagValue, "trace", false, "Enable trace logging.")
+	pflag.BoolVar(&config.DryRun.FlagValue, "dry-run", false, fmt.Sprintf("Record the requests that would be sent to SCC in the %s ConfigMap and the logs instead of sending them.", consts.DryRunConfigMapName))
+	pflag.BoolVar(&config.OfflineRequestConfigMap.FlagValue, "offline-request-configmap", false, "Mirror each offline registration request into a ConfigMap, annotated with its checksum, next to its Secret.")
+	pflag.BoolVar(&config.OfflineRequestEndpoint.FlagValue, "offline-request-endpoint-enabled", false, "Serve offline registration requests for download. The operator then needs RBAC to create tokenreviews and subjectaccessreviews.")
+	pflag.IntVar(&config.OfflineRequestPort.FlagValue, "offline-request-port", 0, fmt.Sprintf("TLS port offline registration requests are served on, using the webhook serving certificate. Defaults to %d when unset.", consts.DefaultOfflineRequestPort))
+	pflag.BoolVar(&config.WebhookEnabled.FlagValue, "webhook-enabled", false, "Serve and register the validating admission webhook.")
+	pflag.IntVar(&config.WebhookPort.FlagValue, "webhook-port", 0, fmt.Sprintf("Port the validating webhook listens on. Defaults to %d when unset.", consts.DefaultWebhookPort))
+	pflag.StringVar(&config.WebhookServiceName.FlagValue, "webhook-service-name", "", fmt.Sprintf("Name of the Service that routes to the validating webhook. Defaults to %s when unset.", consts.DefaultWebhookServiceName))
+	pflag.StringVar(&config.WebhookCertDir.FlagValue, "webhook-cert-dir", "", "Directory holding tls.crt and tls.key for the webhook. A self-signed certificate is generated when unset.")
+	pflag.StringVar(&config.PayAsYouGoRegistrationURL.FlagValue, "payg-registration-url", "", "SCC URL used by pay-as-you-go registrations. Defaults to the SCC URL for the current environment when unset.")
+	pflag.DurationVar(&config.RetryBaseDelay.FlagValue, "retry-base-delay", 0, fmt.Sprintf("Delay before the first retry of a recoverable SCC error, doubled on every further failure. Defaults to %s when unset.", consts.DefaultRetryBaseDelay))
+	pflag.DurationVar(&config.RetryMaxDelay.FlagValue, "retry-max-delay", 0, fmt.Sprintf("Upper bound for the delay between retries of recoverable SCC errors. Defaults to %s when unset.", consts.DefaultRetryMaxDelay))
+	pflag.Float64Var(&config.RetryJitter.FlagValue, "retry-jitter", 0, fmt.Sprintf("Fraction (0-1) of each retry delay that is randomized away. Defaults to %.2f when unset.", consts.DefaultRetryJitter))
+	pflag.Float64Var(&config.SCCRequestRate.FlagValue, "scc-request-rate", 0, fmt.Sprintf("SCC API requests per second allowed across all registrations. Defaults to %.2f when unset.", consts.DefaultSCCRequestRate))
+	pflag.IntVar(&config.SCCRequestBurst.FlagValue, "scc-request-burst", 0, fmt.Sprintf("SCC API requests allowed at once before the request rate applies. Defaults to %d when unset.", consts.DefaultSCCRequestBurst))
+	pflag.StringVar(&config.ExpiryWarningThresholds.FlagValue, "expiry-warning-thresholds", "", fmt.Sprintf("Comma separated lead times before a subscription expires at which the ExpiringSoon condition and Events are raised, e.g. 30d,14d,3d. Defaults to %s when unset.", consts.DefaultExpiryWarningThresholds))
+	pflag.StringVar(&config.RegCodeRotationMode.FlagValue, "regcode-rotation", "", "How a changed regCode in an entrypoint Secret is applied: in-place keeps the SCC system and activates the new code, replace announces a new system. Defaults to in-place when unset.")
+	pflag.StringVar(&config.DeregistrationPolicy.FlagValue, "deregistration-policy", "", "What to do with the SCC system of a deleted Registration that sets no policy: best-effort, required-with-timeout or skip. Defaults to best-effort when unset.")
+	pflag.DurationVar(&config.DeregistrationTimeout.FlagValue, "deregistration-timeout", 0, fmt.Sprintf("How long the required-with-timeout deregistration policy keeps retrying. Defaults to %s when unset.", consts.DefaultDeregistrationTimeout))
+	pflag.DurationVar(&config.OrphanGCInterval.FlagValue, "orphan-gc-interval", 0, fmt.Sprintf("How often orphaned SCC Secrets and Registrations are removed; 0 disables it. Defaults to %s when unset.", consts.DefaultOrphanGCInterval))
+	pflag.BoolVar(&config.OrphanGCDryRun.FlagValue, "orphan-gc-dry-run", false, "Only report the orphaned SCC Secrets and Registrations that would be removed.")
+	pflag.StringVar(&config.OfflineTrustRootsSecret.FlagValue, "offline-trust-roots-secret", "", "Secret in the operator namespace whose values are PEM encoded RSA public keys trusted to sign offline certificates besides SUSE's.")
+	pflag.StringVar(&config.DevOfflineTrustRoots.FlagValue, "dev-offline-trust-roots", "", "PEM encoded RSA public keys trusted to sign offline certificates, e.g. from offlinetest.Signer. Only honored in dev mode.")
 	pflag.Parse()
 
 	flagSet := pflag.CommandLine
@@ -80,6 +102,10 @@ func main() {
 		initializer.RancherDevMode.Get(),
 	)
 
+	if operatorSettings.DryRun {
+		logger.Warnf("dry-run mode is enabled; no requests will be sent to SCC, see the `%s` ConfigMap for what would have been sent", consts.DryRunConfigMapName)
+	}
+
 	if operatorSettings.DevMode {
 		logger.Warn("with DevMode enabled log level will be forced to at least debug.")
 		currentLevel := logging.GetLogLevel()
@@ -119,6 +145,20 @@ func run(ctx context.Context, restKubeConfig *rest.Config, runOptions types.RunO
 	}
 
 	go sccOperatorStarter.StartMetricsAndHealthEndpoint()
+	if runOptions.OperatorSettings.OfflineRequestEndpoint {
+		go func() {
+			if offlineRequestErr := sccOperatorStarter.StartOfflineRequestEndpoint(); offlineRequestErr != nil {
+				logger.Errorf("Error serving offline registration requests: %v", offlineRequestErr)
+			}
+		}()
+	}
+	if runOptions.OperatorSettings.Webhook.Enabled {
+		go func() {
+			if webhookErr := sccOperatorStarter.StartValidationWebhook(); webhookErr != nil {
+				logger.Errorf("Error running validation webhook: %v", webhookErr)
+			}
+		}()
+	}
 	if runErr := sccOperatorStarter.Run(); runErr != nil {
 		logger.Errorf("Error running operator: %v", runErr)
 		return runErr
@@ -1,10 +1,14 @@
 package config
 
 import (
+	"cmp"
 	"context"
 	"fmt"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/rancher/wrangler/v3/pkg/kubeconfig"
 	"github.com/sirupsen/logrus"
@@ -14,6 +18,7 @@ import (
 
 	"github.com/rancher/scc-operator/internal/consts"
 	"github.com/rancher/scc-operator/internal/logging"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
 )
 
 var logger = logging.NewComponentLogger("int/config")
@@ -33,6 +38,92 @@ type OperatorSettings struct {
 	// DevMode tracks the operators "dev mode" status, when enabled many features will be configured for better dev feedback
 	DevMode               bool
 	DefaultSCCEnvironment consts.SCCEnvironment
+	// DryRun drives Registrations through their lifecycle while only recording the SCC requests that would have been sent
+	DryRun bool
+	// OfflineRequestConfigMap mirrors each offline registration request into a ConfigMap next to its Secret
+	OfflineRequestConfigMap bool
+	// OfflineRequestEndpoint serves offline registration requests for download; it needs extra RBAC, so it is opt-in
+	OfflineRequestEndpoint bool
+	// OfflineRequestPort is the TLS port offline registration requests are downloaded from
+	OfflineRequestPort int
+	// PayAsYouGoRegistrationURL overrides the SCC URL used by pay-as-you-go registrations without their own URL
+	PayAsYouGoRegistrationURL string
+
+	// Webhook configures the optional validating admission webhook
+	Webhook WebhookSettings
+	// Retry configures the backoff used after recoverable SCC errors
+	Retry RetrySettings
+	// RateLimit caps the SCC API requests made by the whole operator
+	RateLimit RateLimitSettings
+	// ExpiryWarningThresholds are the lead times before a subscription expires at which ExpiringSoon is raised, longest first
+	ExpiryWarningThresholds []time.Duration
+	// RegCodeRotation decides if changing only the reg code of an entrypoint keeps its Registration or replaces it
+	RegCodeRotation RegCodeRotation
+	// Deregistration is the default policy for Registrations that don't set their own
+	Deregistration DeregistrationSettings
+	// OrphanGC configures the periodic removal of Secrets and Registrations left behind by interrupted cleanups
+	OrphanGC OrphanGCSettings
+	// OfflineTrustRoots are the public keys trusted to sign offline certificates besides SUSE's
+	OfflineTrustRoots OfflineTrustRootsSettings
+}
+
+// WebhookSettings holds the values used to serve and register the validating admission webhook
+type WebhookSettings struct {
+	Enabled     bool
+	Port        int
+	ServiceName string
+	// CertDir is an optional directory holding tls.crt/tls.key; when empty a self-signed cert is generated
+	CertDir string
+}
+
+// RetrySettings configures the capped exponential backoff between retries of recoverable SCC errors
+type RetrySettings struct {
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Jitter is the fraction (0-1) of each delay that is randomly taken off to spread out retries
+	Jitter float64
+}
+
+// RateLimitSettings configures the token bucket shared by all SCC API requests
+type RateLimitSettings struct {
+	RequestsPerSecond float64
+	Burst             int
+}
+
+// RegCodeRotation is how a changed registration code in an entrypoint Secret is applied
+type RegCodeRotation string
+
+const (
+	// RegCodeRotationInPlace keeps the Registration, its SCC system and credentials, and activates the new code
+	RegCodeRotationInPlace RegCodeRotation = "in-place"
+	// RegCodeRotationReplace removes the Registration and announces a new SCC system with the new code
+	RegCodeRotationReplace RegCodeRotation = "replace"
+)
+
+func (r RegCodeRotation) IsValid() bool {
+	return r == RegCodeRotationInPlace || r == RegCodeRotationReplace
+}
+
+// DeregistrationSettings is the deregistration policy applied to Registrations without one in their spec
+type DeregistrationSettings struct {
+	Mode    v1.DeregistrationMode
+	Timeout time.Duration
+}
+
+// OrphanGCSettings configures the garbage collection of orphaned SCC Secrets and Registrations
+type OrphanGCSettings struct {
+	// Interval between collections; zero disables the collector
+	Interval time.Duration
+	// DryRun only reports the orphans that would be removed
+	DryRun bool
+}
+
+// OfflineTrustRootsSettings lists where extra offline certificate signing keys are read from
+type OfflineTrustRootsSettings struct {
+	// SecretName is a Secret in the system namespace whose values are each PEM encoded RSA public keys
+	SecretName string
+	// DevKeys are PEM encoded RSA public keys given inline; only honored in dev mode
+	DevKeys string
 }
 
 // Validate simply validates the configured settings are potentially valid but not if objects exist
@@ -85,16 +176,55 @@ func LoadInitialConfig(ctx context.Context, flags *pflag.FlagSet) (*OperatorSett
 	trace, _ := strconv.ParseBool(valueResolver.Get(Trace))
 	debug, _ := strconv.ParseBool(valueResolver.Get(Debug))
 	devMode, _ := strconv.ParseBool(valueResolver.Get(DevMode))
+	dryRun, _ := strconv.ParseBool(valueResolver.Get(DryRun))
+	offlineRequestConfigMap, _ := strconv.ParseBool(valueResolver.Get(OfflineRequestConfigMap))
+	offlineRequestEndpoint, _ := strconv.ParseBool(valueResolver.Get(OfflineRequestEndpoint))
+	offlineRequestPort, offlinePortErr := strconv.Atoi(valueResolver.Get(OfflineRequestPort))
+	if offlinePortErr != nil {
+		logger.Warnf("Invalid offline request port provided. Defaulting to '%d'.", consts.DefaultOfflineRequestPort)
+		offlineRequestPort = consts.DefaultOfflineRequestPort
+	}
+	webhookEnabled, _ := strconv.ParseBool(valueResolver.Get(WebhookEnabled))
+	webhookPort, portErr := strconv.Atoi(valueResolver.Get(WebhookPort))
+	if portErr != nil {
+		logger.Warnf("Invalid webhook port provided. Defaulting to '%d'.", consts.DefaultWebhookPort)
+		webhookPort = consts.DefaultWebhookPort
+	}
+	retrySettings := decideRetrySettings(valueResolver.Get(RetryBaseDelay), valueResolver.Get(RetryMaxDelay), valueResolver.Get(RetryJitter))
+	rateLimitSettings := decideRateLimitSettings(valueResolver.Get(SCCRequestRate), valueResolver.Get(SCCRequestBurst))
+	expiryWarningThresholds := decideExpiryWarningThresholds(valueResolver.Get(ExpiryWarningThresholds))
+	regCodeRotation := decideRegCodeRotation(valueResolver.Get(RegCodeRotationMode))
+	deregistrationSettings := decideDeregistrationSettings(valueResolver.Get(DeregistrationPolicy), valueResolver.Get(DeregistrationTimeout))
+	orphanGCSettings := decideOrphanGCSettings(valueResolver.Get(OrphanGCInterval), valueResolver.Get(OrphanGCDryRun))
+	offlineTrustRoots := decideOfflineTrustRoots(valueResolver.Get(OfflineTrustRootsSecret), valueResolver.Get(DevOfflineTrustRoots), devMode)
 
 	loadedConfig := &OperatorSettings{
-		Kubeconfig:      kubeconfigPath,
-		OperatorName:    valueResolver.Get(OperatorName),
-		SystemNamespace: operatorNamespace,
-		LeaseNamespace:  valueResolver.Get(LeaseNamespace),
-		LogFormat:       decideLogFormat(valueResolver.Get(LogFormat)),
-		LogLevel:        decideLogLevel(loggingLevel, trace, debug),
-		CattleDevMode:   valueResolver.Get(RancherDevMode) != "",
-		DevMode:         devMode,
+		Kubeconfig:                kubeconfigPath,
+		OperatorName:              valueResolver.Get(OperatorName),
+		SystemNamespace:           operatorNamespace,
+		LeaseNamespace:            valueResolver.Get(LeaseNamespace),
+		LogFormat:                 decideLogFormat(valueResolver.Get(LogFormat)),
+		LogLevel:                  decideLogLevel(loggingLevel, trace, debug),
+		CattleDevMode:             valueResolver.Get(RancherDevMode) != "",
+		DevMode:                   devMode,
+		DryRun:                    dryRun,
+		OfflineRequestConfigMap:   offlineRequestConfigMap,
+		OfflineRequestEndpoint:    offlineRequestEndpoint,
+		OfflineRequestPort:        offlineRequestPort,
+		PayAsYouGoRegistrationURL: valueResolver.Get(PayAsYouGoRegistrationURL),
+		Webhook: WebhookSettings{
+			Enabled:     webhookEnabled,
+			Port:        webhookPort,
+			ServiceName: valueResolver.Get(WebhookServiceName),
+			CertDir:     valueResolver.Get(WebhookCertDir),
+		},
+		Retry:                   retrySettings,
+		RateLimit:               rateLimitSettings,
+		ExpiryWarningThresholds: expiryWarningThresholds,
+		RegCodeRotation:         regCodeRotation,
+		Deregistration:          deregistrationSettings,
+		OrphanGC:                orphanGCSettings,
+		OfflineTrustRoots:       offlineTrustRoots,
 	}
 
 	// Set the global config and start the watcher.
@@ -119,6 +249,145 @@ func decideLogFormat(formatStr string) logging.Format {
 	return logFormat
 }
 
+func decideRetrySettings(baseDelayStr, maxDelayStr, jitterStr string) RetrySettings {
+	retrySettings := RetrySettings{
+		BaseDelay: consts.DefaultRetryBaseDelay,
+		MaxDelay:  consts.DefaultRetryMaxDelay,
+		Jitter:    consts.DefaultRetryJitter,
+	}
+
+	if baseDelay, err := time.ParseDuration(baseDelayStr); err == nil && baseDelay > 0 {
+		retrySettings.BaseDelay = baseDelay
+	} else {
+		logger.Warnf("Invalid retry base delay '%s' provided. Defaulting to '%s'.", baseDelayStr, consts.DefaultRetryBaseDelay)
+	}
+	if maxDelay, err := time.ParseDuration(maxDelayStr); err == nil && maxDelay >= retrySettings.BaseDelay {
+		retrySettings.MaxDelay = maxDelay
+	} else {
+		logger.Warnf("Invalid retry max delay '%s' provided, it must be at least the base delay. Defaulting to '%s'.", maxDelayStr, consts.DefaultRetryMaxDelay)
+	}
+	if jitter, err := strconv.ParseFloat(jitterStr, 64); err == nil && jitter >= 0 && jitter <= 1 {
+		retrySettings.Jitter = jitter
+	} else {
+		logger.Warnf("Invalid retry jitter '%s' provided, it must be between 0 and 1. Defaulting to '%.2f'.", jitterStr, consts.DefaultRetryJitter)
+	}
+
+	return retrySettings
+}
+
+func decideRateLimitSettings(requestRateStr, burstStr string) RateLimitSettings {
+	rateLimitSettings := RateLimitSettings{
+		RequestsPerSecond: consts.DefaultSCCRequestRate,
+		Burst:             consts.DefaultSCCRequestBurst,
+	}
+
+	if requestRate, err := strconv.ParseFloat(requestRateStr, 64); err == nil && requestRate > 0 {
+		rateLimitSettings.RequestsPerSecond = requestRate
+	} else {
+		logger.Warnf("Invalid SCC request rate '%s' provided, it must be above 0. Defaulting to '%.2f'.", requestRateStr, consts.DefaultSCCRequestRate)
+	}
+	if burst, err := strconv.Atoi(burstStr); err == nil && burst > 0 {
+		rateLimitSettings.Burst = burst
+	} else {
+		logger.Warnf("Invalid SCC request burst '%s' provided, it must be above 0. Defaulting to '%d'.", burstStr, consts.DefaultSCCRequestBurst)
+	}
+
+	return rateLimitSettings
+}
+
+// decideExpiryWarningThresholds parses a comma separated list of durations, which may also be given in days (e.g. `30d`)
+func decideExpiryWarningThresholds(thresholdsStr string) []time.Duration {
+	var thresholds []time.Duration
+	for _, entry := range strings.Split(thresholdsStr, ",") {
+		entry = strings.TrimSpace(entry)
+		threshold, err := parseDays(entry)
+		if err != nil || threshold <= 0 {
+			defaults := decideExpiryWarningThresholds(consts.DefaultExpiryWarningThresholds)
+			logger.Warnf("Invalid expiry warning thresholds '%s' provided, each must be a positive duration. Defaulting to '%s'.", thresholdsStr, consts.DefaultExpiryWarningThresholds)
+			return defaults
+		}
+		if !slices.Contains(thresholds, threshold) {
+			thresholds = append(thresholds, threshold)
+		}
+	}
+	slices.SortFunc(thresholds, func(a, b time.Duration) int { return cmp.Compare(b, a) })
+
+	return thresholds
+}
+
+// parseDays parses a Go duration, also accepting a whole number of days such as `14d`
+func parseDays(durationStr string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(durationStr, "d"); ok {
+		count, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(count) * 24 * time.Hour, nil
+	}
+
+	return time.ParseDuration(durationStr)
+}
+
+func decideRegCodeRotation(rotationStr string) RegCodeRotation {
+	rotation := RegCodeRotation(rotationStr)
+	if !rotation.IsValid() {
+		logger.Warnf("Invalid reg code rotation '%s' provided. Defaulting to '%s'.", rotationStr, RegCodeRotationInPlace)
+		return RegCodeRotationInPlace
+	}
+
+	return rotation
+}
+
+func decideDeregistrationSettings(modeStr, timeoutStr string) DeregistrationSettings {
+	deregistrationSettings := DeregistrationSettings{
+		Mode:    v1.DeregistrationBestEffort,
+		Timeout: consts.DefaultDeregistrationTimeout,
+	}
+
+	if mode := v1.DeregistrationMode(modeStr); mode.Valid() {
+		deregistrationSettings.Mode = mode
+	} else {
+		logger.Warnf("Invalid deregistration policy '%s' provided. Defaulting to '%s'.", modeStr, v1.DeregistrationBestEffort)
+	}
+	if timeout, err := time.ParseDuration(timeoutStr); err == nil && timeout > 0 {
+		deregistrationSettings.Timeout = timeout
+	} else {
+		logger.Warnf("Invalid deregistration timeout '%s' provided. Defaulting to '%s'.", timeoutStr, consts.DefaultDeregistrationTimeout)
+	}
+
+	return deregistrationSettings
+}
+
+func decideOrphanGCSettings(intervalStr, dryRunStr string) OrphanGCSettings {
+	dryRun, _ := strconv.ParseBool(dryRunStr)
+	orphanGCSettings := OrphanGCSettings{
+		Interval: consts.DefaultOrphanGCInterval,
+		DryRun:   dryRun,
+	}
+
+	if interval, err := time.ParseDuration(intervalStr); err == nil && interval >= 0 {
+		orphanGCSettings.Interval = interval
+	} else {
+		logger.Warnf("Invalid orphan GC interval '%s' provided, use 0 to disable it. Defaulting to '%s'.", intervalStr, consts.DefaultOrphanGCInterval)
+	}
+
+	return orphanGCSettings
+}
+
+func decideOfflineTrustRoots(secretName, devKeys string, devMode bool) OfflineTrustRootsSettings {
+	offlineTrustRoots := OfflineTrustRootsSettings{SecretName: secretName}
+
+	if devKeys != "" {
+		if devMode {
+			offlineTrustRoots.DevKeys = devKeys
+		} else {
+			logger.Warnf("Ignoring '%s' as it is only honored in dev mode.", DevOfflineTrustRoots.Name)
+		}
+	}
+
+	return offlineTrustRoots
+}
+
 func decideLogLevel(logLevel string, trace, debug bool) logrus.Level {
 	if trace {
 		return logrus.TraceLevel
@@ -1,9 +1,12 @@
 package config
 
 import (
+	"slices"
 	"testing"
+	"time"
 
 	rootLog "github.com/rancher/scc-operator/internal/logging"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
 	"github.com/sirupsen/logrus"
 )
 
@@ -36,6 +39,41 @@ func TestDecideLogLevel(t *testing.T) {
 	}
 }
 
+func TestDecideRetrySettings(t *testing.T) {
+	t.Parallel()
+	// Defaults resolve to the option defaults as strings
+	defaults := decideRetrySettings(RetryBaseDelay.GetDefaultAsString(), RetryMaxDelay.GetDefaultAsString(), RetryJitter.GetDefaultAsString())
+	if defaults.BaseDelay != 30*time.Second || defaults.MaxDelay != time.Hour || defaults.Jitter != 0.2 {
+		t.Fatalf("decideRetrySettings(defaults) = %+v, want 30s, 1h, 0.2", defaults)
+	}
+
+	if got := decideRetrySettings("1m", "2h", "0"); got.BaseDelay != time.Minute || got.MaxDelay != 2*time.Hour || got.Jitter != 0 {
+		t.Fatalf("decideRetrySettings(1m, 2h, 0) = %+v", got)
+	}
+
+	// Invalid values fall back to their defaults, including a max below the base
+	if got := decideRetrySettings("soon", "1s", "1.5"); got != defaults {
+		t.Fatalf("decideRetrySettings(invalid) = %+v, want defaults %+v", got, defaults)
+	}
+}
+
+func TestDecideRateLimitSettings(t *testing.T) {
+	t.Parallel()
+	defaults := decideRateLimitSettings(SCCRequestRate.GetDefaultAsString(), SCCRequestBurst.GetDefaultAsString())
+	if defaults.RequestsPerSecond != 2 || defaults.Burst != 5 {
+		t.Fatalf("decideRateLimitSettings(defaults) = %+v, want 2, 5", defaults)
+	}
+
+	if got := decideRateLimitSettings("0.5", "1"); got.RequestsPerSecond != 0.5 || got.Burst != 1 {
+		t.Fatalf("decideRateLimitSettings(0.5, 1) = %+v", got)
+	}
+
+	// A zero rate or burst would block every SCC call, so they fall back to the defaults
+	if got := decideRateLimitSettings("0", "0"); got != defaults {
+		t.Fatalf("decideRateLimitSettings(0, 0) = %+v, want defaults %+v", got, defaults)
+	}
+}
+
 func TestOperatorSettingsValidate(t *testing.T) {
 	t.Parallel()
 	// Missing operator name
@@ -63,3 +101,94 @@ func TestOperatorSettingsValidate(t *testing.T) {
 		t.Fatalf("Validate() unexpected error: %v", err)
 	}
 }
+
+func TestDecideExpiryWarningThresholds(t *testing.T) {
+	t.Parallel()
+	day := 24 * time.Hour
+	defaults := []time.Duration{30 * day, 14 * day, 3 * day}
+	tests := []struct {
+		input    string
+		expected []time.Duration
+	}{
+		{ExpiryWarningThresholds.GetDefaultAsString(), defaults},
+		{"3d, 30d,14d", defaults},
+		{"7d,12h,7d", []time.Duration{7 * day, 12 * time.Hour}},
+		{"", defaults},
+		{"30d,soon", defaults},
+		{"-1d", defaults},
+	}
+
+	for _, tt := range tests {
+		if got := decideExpiryWarningThresholds(tt.input); !slices.Equal(got, tt.expected) {
+			t.Errorf("decideExpiryWarningThresholds(%q) = %v, want %v", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestDecideRegCodeRotation(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		input    string
+		expected RegCodeRotation
+	}{
+		{"in-place", RegCodeRotationInPlace},
+		{"replace", RegCodeRotationReplace},
+		{"", RegCodeRotationInPlace},
+		{"rebuild", RegCodeRotationInPlace},
+	}
+
+	for _, tt := range tests {
+		if got := decideRegCodeRotation(tt.input); got != tt.expected {
+			t.Errorf("decideRegCodeRotation(%q) = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestDecideDeregistrationSettings(t *testing.T) {
+	t.Parallel()
+	defaults := decideDeregistrationSettings(DeregistrationPolicy.GetDefaultAsString(), DeregistrationTimeout.GetDefaultAsString())
+	if defaults.Mode != v1.DeregistrationBestEffort || defaults.Timeout != 24*time.Hour {
+		t.Fatalf("decideDeregistrationSettings(defaults) = %+v, want best-effort, 24h", defaults)
+	}
+
+	if got := decideDeregistrationSettings("required-with-timeout", "2h"); got.Mode != v1.DeregistrationRequiredWithTimeout || got.Timeout != 2*time.Hour {
+		t.Fatalf("decideDeregistrationSettings(required-with-timeout, 2h) = %+v", got)
+	}
+
+	if got := decideDeregistrationSettings("sometimes", "0s"); got != defaults {
+		t.Fatalf("decideDeregistrationSettings(invalid) = %+v, want defaults %+v", got, defaults)
+	}
+}
+
+func TestDecideOrphanGCSettings(t *testing.T) {
+	t.Parallel()
+	defaults := decideOrphanGCSettings(OrphanGCInterval.GetDefaultAsString(), OrphanGCDryRun.GetDefaultAsString())
+	if defaults.Interval != 30*time.Minute || defaults.DryRun {
+		t.Fatalf("decideOrphanGCSettings(defaults) = %+v, want 30m, false", defaults)
+	}
+
+	// Zero disables the collector
+	if got := decideOrphanGCSettings("0s", "true"); got.Interval != 0 || !got.DryRun {
+		t.Fatalf("decideOrphanGCSettings(0s, true) = %+v", got)
+	}
+
+	if got := decideOrphanGCSettings("-1h", "false"); got != defaults {
+		t.Fatalf("decideOrphanGCSettings(-1h, false) = %+v, want defaults %+v", got, defaults)
+	}
+}
+
+func TestDecideOfflineTrustRoots(t *testing.T) {
+	t.Parallel()
+	if got := decideOfflineTrustRoots("", "", false); got != (OfflineTrustRootsSettings{}) {
+		t.Fatalf("decideOfflineTrustRoots(defaults) = %+v, want none", got)
+	}
+
+	if got := decideOfflineTrustRoots("trust-roots", "PEM", true); got.SecretName != "trust-roots" || got.DevKeys != "PEM" {
+		t.Fatalf("decideOfflineTrustRoots(trust-roots, PEM, dev) = %+v", got)
+	}
+
+	// Inline keys are a dev-only shortcut, the Secret is always honored
+	if got := decideOfflineTrustRoots("trust-roots", "PEM", false); got.SecretName != "trust-roots" || got.DevKeys != "" {
+		t.Fatalf("decideOfflineTrustRoots(trust-roots, PEM, not dev) = %+v", got)
+	}
+}
@@ -16,4 +16,36 @@ var (
 	RancherDevMode    = option.NewOption("rancher-dev-mode", false, option.AllowedFromConfigMap)
 	Debug             = option.NewOption("debug", false, option.AllowedFromConfigMap)
 	Trace             = option.NewOption("trace", false, option.AllowedFromConfigMap)
+	DryRun            = option.NewOption("dry-run", false, option.AllowedFromConfigMap)
+
+	OfflineRequestConfigMap = option.NewOption("offline-request-configmap", false, option.AllowedFromConfigMap)
+	OfflineRequestPort      = option.NewOption("offline-request-port", consts.DefaultOfflineRequestPort)
+	OfflineRequestEndpoint  = option.NewOption("offline-request-endpoint-enabled", false)
+
+	WebhookEnabled     = option.NewOption("webhook-enabled", false)
+	WebhookPort        = option.NewOption("webhook-port", consts.DefaultWebhookPort)
+	WebhookServiceName = option.NewOption("webhook-service-name", consts.DefaultWebhookServiceName)
+	WebhookCertDir     = option.NewOption("webhook-cert-dir", "")
+
+	PayAsYouGoRegistrationURL = option.NewOption("payg-registration-url", "", option.AllowedFromConfigMap)
+
+	RetryBaseDelay = option.NewOption("retry-base-delay", consts.DefaultRetryBaseDelay, option.AllowedFromConfigMap)
+	RetryMaxDelay  = option.NewOption("retry-max-delay", consts.DefaultRetryMaxDelay, option.AllowedFromConfigMap)
+	RetryJitter    = option.NewOption("retry-jitter", consts.DefaultRetryJitter, option.AllowedFromConfigMap)
+
+	SCCRequestRate  = option.NewOption("scc-request-rate", consts.DefaultSCCRequestRate, option.AllowedFromConfigMap)
+	SCCRequestBurst = option.NewOption("scc-request-burst", consts.DefaultSCCRequestBurst, option.AllowedFromConfigMap)
+
+	ExpiryWarningThresholds = option.NewOption("expiry-warning-thresholds", consts.DefaultExpiryWarningThresholds, option.AllowedFromConfigMap)
+
+	RegCodeRotationMode = option.NewOption("regcode-rotation", string(RegCodeRotationInPlace), option.AllowedFromConfigMap)
+
+	DeregistrationPolicy  = option.NewOption("deregistration-policy", "best-effort", option.AllowedFromConfigMap)
+	DeregistrationTimeout = option.NewOption("deregistration-timeout", consts.DefaultDeregistrationTimeout, option.AllowedFromConfigMap)
+
+	OrphanGCInterval = option.NewOption("orphan-gc-interval", consts.DefaultOrphanGCInterval, option.AllowedFromConfigMap)
+	OrphanGCDryRun   = option.NewOption("orphan-gc-dry-run", false, option.AllowedFromConfigMap)
+
+	OfflineTrustRootsSecret = option.NewOption("offline-trust-roots-secret", "", option.AllowedFromConfigMap)
+	DevOfflineTrustRoots    = option.NewOption("dev-offline-trust-roots", "", option.AllowedFromConfigMap)
 )
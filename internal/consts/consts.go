@@ -1,5 +1,7 @@
 package consts
 
+import "time"
+
 const (
 	DefaultOperatorName      = "rancher-scc-operator" // TODO: in the future when this isn't very specific to `rancher` (the product) drop the `rancher-` prefix
 	DefaultSCCNamespace      = "cattle-scc-system"
@@ -7,6 +9,43 @@ const (
 	SCCOperatorConfigMapName = "scc-operator-config"
 )
 
+// Dry-run mode records the SCC requests it would have sent in a ConfigMap, keeping only the latest for each Registration
+const (
+	DryRunConfigMapName           = "scc-operator-dry-run"
+	DryRunRequestsPerRegistration = 20
+)
+
+// Defaults for retrying recoverable SCC errors
+const (
+	DefaultRetryBaseDelay = 30 * time.Second
+	DefaultRetryMaxDelay  = time.Hour
+	DefaultRetryJitter    = 0.2
+)
+
+// Defaults for the process-wide limit on SCC API requests
+const (
+	DefaultSCCRequestRate  = 2.0
+	DefaultSCCRequestBurst = 5
+	// DefaultSCCRateLimitRetryAfter is how long to wait after SCC answers 429 Too Many Requests without a Retry-After header
+	DefaultSCCRateLimitRetryAfter = 5 * time.Minute
+)
+
+// DefaultExpiryWarningThresholds are how long before a subscription expires the ExpiringSoon warnings start, tightening at each one
+const DefaultExpiryWarningThresholds = "30d,14d,3d"
+
+// DefaultDeregistrationTimeout bounds how long the required-with-timeout deregistration policy keeps retrying
+const DefaultDeregistrationTimeout = 24 * time.Hour
+
+// DeregistrationTombstonesConfigMapName holds the SCC systems the operator gave up deregistering, so they can be removed by hand
+const DeregistrationTombstonesConfigMapName = "scc-operator-deregistration-tombstones"
+
+// Defaults for the periodic garbage collection of orphaned Secrets and Registrations
+const (
+	DefaultOrphanGCInterval = 30 * time.Minute
+	// OrphanGCGracePeriod keeps objects younger than this out of garbage collection, so ones still being created are never mistaken for orphans
+	OrphanGCGracePeriod = 10 * time.Minute
+)
+
 const (
 	FinalizerSccMetricsSecretRequest = "scc.cattle.io/scc-metrics-request"
 	FinalizerSccOfflineSecret        = "scc.cattle.io/managed-offline-secret"
@@ -27,6 +66,22 @@ const (
 	// LabelSccManagedBy identifies the name of the SCC operator that manages a specific resource
 	LabelSccManagedBy  = "scc.cattle.io/managed-by"
 	LabelSccSecretRole = "scc.cattle.io/secret-role"
+
+	// LabelSccEntrypoint set to EntrypointLabelValue marks any Secret in the system namespace as an entrypoint Secret.
+	// Each entrypoint produces its own Registration; the `scc-registration` Secret is always an entrypoint, even without it.
+	LabelSccEntrypoint   = "scc.cattle.io/entrypoint"
+	EntrypointLabelValue = "true"
+)
+
+const (
+	// AnnotationSccAction requests a one-off action on a Registration, e.g. `resync`; it is removed once the action ran
+	AnnotationSccAction = "scc.cattle.io/action"
+	// AnnotationSccContentHash records the hash of the data the operator last wrote to a managed Secret, to detect edits made outside of it
+	AnnotationSccContentHash = "scc.cattle.io/content-hash"
+	// AnnotationSccIdentityHash records the hash of an entrypoint's naming data without its reg code, to recognize a rotated reg code
+	AnnotationSccIdentityHash = "scc.cattle.io/identity-hash"
+	// AnnotationSccChecksum records the checksum of the offline request mirrored into a ConfigMap
+	AnnotationSccChecksum = "scc.cattle.io/checksum"
 )
 
 const (
@@ -11,6 +11,10 @@ const (
 	RegistrationCodeSecretNamePrefix     = "registration-code-"
 	OfflineRequestSecretNamePrefix       = "offline-request-"
 	OfflineCertificateSecretNamePrefix   = "offline-certificate-"
+	RegistrationCACertSecretNamePrefix   = "registration-ca-cert-"
+	InstanceDataSecretNamePrefix         = "payg-instance-data-"
+	ProxyCredentialsSecretNamePrefix     = "proxy-credentials-"
+	WebhookTLSSecretName                 = "scc-operator-webhook-tls"
 )
 
 func RegistrationName(namePartIn string) string {
@@ -33,6 +37,18 @@ func OfflineCertificateSecretName(namePartIn string) string {
 	return fmt.Sprintf("%s%s", OfflineCertificateSecretNamePrefix, namePartIn)
 }
 
+func RegistrationCACertSecretName(namePartIn string) string {
+	return fmt.Sprintf("%s%s", RegistrationCACertSecretNamePrefix, namePartIn)
+}
+
+func InstanceDataSecretName(namePartIn string) string {
+	return fmt.Sprintf("%s%s", InstanceDataSecretNamePrefix, namePartIn)
+}
+
+func ProxyCredentialsSecretName(namePartIn string) string {
+	return fmt.Sprintf("%s%s", ProxyCredentialsSecretNamePrefix, namePartIn)
+}
+
 // SccManagedByValue constructs the SCC managed-by label value in the format "<operator>_secret-broker"
 func SccManagedByValue(operatorName string) string {
 	return fmt.Sprintf("%s_%s", operatorName, ManagedByValueSecretBroker)
@@ -33,3 +33,24 @@ func TestOfflineCertificateSecretName(t *testing.T) {
 	asserts.Equal("offline-certificate-", OfflineCertificateSecretName(""))
 	asserts.Equal("offline-certificate-test", OfflineCertificateSecretName("test"))
 }
+
+func TestRegistrationCACertSecretName(t *testing.T) {
+	asserts := assert.New(t)
+
+	asserts.Equal("registration-ca-cert-", RegistrationCACertSecretName(""))
+	asserts.Equal("registration-ca-cert-test", RegistrationCACertSecretName("test"))
+}
+
+func TestInstanceDataSecretName(t *testing.T) {
+	asserts := assert.New(t)
+
+	asserts.Equal("payg-instance-data-", InstanceDataSecretName(""))
+	asserts.Equal("payg-instance-data-test", InstanceDataSecretName("test"))
+}
+
+func TestProxyCredentialsSecretName(t *testing.T) {
+	asserts := assert.New(t)
+
+	asserts.Equal("proxy-credentials-", ProxyCredentialsSecretName(""))
+	asserts.Equal("proxy-credentials-test", ProxyCredentialsSecretName("test"))
+}
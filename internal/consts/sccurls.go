@@ -7,7 +7,7 @@ type SCCEnvironment int
 const (
 	ProductionSCC SCCEnvironment = iota
 	StagingSCC
-	PayAsYouGo //Disabled for now
+	PayAsYouGo // Cloud marketplace clusters billed by usage; served by production SCC
 	RGS        // Shouldn't matter for now until RGS supported
 )
 
@@ -30,11 +30,11 @@ func (s SCCEnvironment) String() string {
 func (s SCCEnvironment) BaseURLForSCC() string {
 	var baseURL string
 	switch s {
-	case ProductionSCC:
+	case ProductionSCC, PayAsYouGo:
 		baseURL = string(ProdSccURL)
 	case StagingSCC:
 		baseURL = string(StagingSccURL)
-	case RGS, PayAsYouGo:
+	case RGS:
 		fallthrough
 	default:
 		// intentionally do nothing and return empty string
@@ -62,6 +62,14 @@ func (s SccURLs) Ptr() *string {
 	return &stringVal
 }
 
+// PayAsYouGoBaseURL returns the SCC URL pay-as-you-go registrations use when none is configured
+func PayAsYouGoBaseURL() string {
+	if GetSCCEnvironment() == StagingSCC {
+		return StagingSCC.BaseURLForSCC()
+	}
+	return PayAsYouGo.BaseURLForSCC()
+}
+
 // BaseURLForSCC returns the SCC URL (or empty string) for the detected environment
 func BaseURLForSCC() string {
 	return GetSCCEnvironment().BaseURLForSCC()
@@ -73,3 +73,20 @@ func TestBaseURLForSCC_Prod(t *testing.T) {
 	initializer.DevMode.SetForTest(false)
 	asserts.Equal(string(ProdSccURL), BaseURLForSCC())
 }
+
+func TestSCCEnvironment_BaseURLForSCC(t *testing.T) {
+	asserts := assert.New(t)
+
+	asserts.Equal(string(ProdSccURL), ProductionSCC.BaseURLForSCC())
+	asserts.Equal(string(StagingSccURL), StagingSCC.BaseURLForSCC())
+	asserts.Equal(string(ProdSccURL), PayAsYouGo.BaseURLForSCC())
+	asserts.Equal("", RGS.BaseURLForSCC())
+}
+
+func TestPayAsYouGoBaseURL(t *testing.T) {
+	asserts := assert.New(t)
+	initializer.DevMode.SetForTest(false)
+	asserts.Equal(string(ProdSccURL), PayAsYouGoBaseURL())
+	initializer.DevMode.SetForTest(true)
+	asserts.Equal(string(StagingSccURL), PayAsYouGoBaseURL())
+}
@@ -2,10 +2,19 @@ package consts
 
 const (
 	SecretKeyMetricsData       = "payload"
+	SecretKeyRegistrationType  = "registrationType"
 	SecretKeyRegistrationCode  = "regCode"
 	SecretKeyOfflineRegRequest = "request"
 	SecretKeyOfflineRegCert    = "certificate"
-	RegistrationURL            = "registrationUrl"
+	// SecretKeyOfflineRegCertReplacement holds a renewed offline certificate staged next to the active one until it is validated
+	SecretKeyOfflineRegCertReplacement = "replacementCertificate"
+	SecretKeyRegistrationCACert        = "registrationCACert"
+	SecretKeyInstanceData              = "instanceData"
+	SecretKeyProxyURL                  = "proxyUrl"
+	SecretKeyProxyUsername             = "proxyUsername"
+	SecretKeyProxyPassword             = "proxyPassword"
+	SecretKeyNoProxy                   = "noProxy"
+	RegistrationURL                    = "registrationUrl"
 )
 
 type SecretRole string
@@ -15,4 +24,7 @@ const (
 	RegistrationCode   SecretRole = "reg-code"
 	OfflineRequestRole SecretRole = "offline-request"
 	OfflineCertificate SecretRole = "offline-certificate"
+	RegistrationCACert SecretRole = "registration-ca-cert"
+	InstanceDataRole   SecretRole = "payg-instance-data"
+	ProxyCredentials   SecretRole = "proxy-credentials"
 )
@@ -0,0 +1,12 @@
+package consts
+
+const (
+	WebhookConfigurationName  = "scc-operator-validation"
+	DefaultWebhookServiceName = "scc-operator-webhook"
+	DefaultWebhookPort        = 9443
+	// DefaultOfflineRequestPort serves offline registration requests over TLS with the webhook serving certificate
+	DefaultOfflineRequestPort = 8443
+
+	WebhookNameSecrets       = "secrets.scc.cattle.io"
+	WebhookNameRegistrations = "registrations.scc.cattle.io"
+)
@@ -0,0 +1,64 @@
+package suseconnect
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	certutil "k8s.io/client-go/util/cert"
+
+	"github.com/rancher/scc-operator/internal/consts"
+	"github.com/rancher/scc-operator/internal/repos/secretrepo"
+)
+
+// ParseCACertificate reads a PEM bundle into the certificate connect-ng will trust alongside the system roots.
+// connect-ng accepts a single certificate, so a CA certificate is preferred over any leaf or intermediate in the bundle.
+func ParseCACertificate(pemData []byte) (*x509.Certificate, error) {
+	certs, err := certutil.ParseCertsPEM(pemData)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse registration CA certificate: %w", err)
+	}
+
+	for _, cert := range certs {
+		if cert.IsCA {
+			return cert, nil
+		}
+	}
+
+	return certs[0], nil
+}
+
+// FetchRegistrationCACertFrom loads the CA certificate from the Secret referenced by RegistrationAPICertificateSecretRef.
+// The `registrationCACert` key is used when present, otherwise the conventional `ca.crt` key is read.
+func FetchRegistrationCACertFrom(secretRepo *secretrepo.SecretRepository, reference *corev1.SecretReference) (*x509.Certificate, error) {
+	sccContextLogger().Debugf("Fetching registration CA certificate from secret %s/%s", reference.Namespace, reference.Name)
+	caSecret, err := secretRepo.Cache.Get(reference.Namespace, reference.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get registration CA certificate secret %s/%s: %w", reference.Namespace, reference.Name, err)
+	}
+
+	caData, ok := caSecret.Data[consts.SecretKeyRegistrationCACert]
+	if !ok || len(caData) == 0 {
+		caData, ok = caSecret.Data[corev1.ServiceAccountRootCAKey]
+	}
+	if !ok || len(caData) == 0 {
+		return nil, fmt.Errorf("registration CA certificate secret %s/%s does not contain `%s` or `%s`", reference.Namespace, reference.Name, consts.SecretKeyRegistrationCACert, corev1.ServiceAccountRootCAKey)
+	}
+
+	return ParseCACertificate(caData)
+}
+
+// IsTLSVerificationError reports if an error was caused by the SCC endpoint's certificate not being trusted
+func IsTLSVerificationError(err error) bool {
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var certInvalidErr x509.CertificateInvalidError
+	var hostnameErr x509.HostnameError
+	var verificationErr *tls.CertificateVerificationError
+
+	return errors.As(err, &unknownAuthorityErr) ||
+		errors.As(err, &certInvalidErr) ||
+		errors.As(err, &hostnameErr) ||
+		errors.As(err, &verificationErr)
+}
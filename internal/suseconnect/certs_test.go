@@ -0,0 +1,107 @@
+package suseconnect
+
+import (
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SUSE/connect-ng/pkg/connection"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/rancher/scc-operator/internal/consts"
+	"github.com/rancher/scc-operator/internal/repos/secretrepo"
+	"github.com/rancher/scc-operator/internal/telemetry"
+)
+
+func newActivationsServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte("[]"))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func serverCAPEM(server *httptest.Server) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+}
+
+func mockCredentials() *connection.MockCredentials {
+	creds := connection.NewMockCredentials()
+	creds.On("HasAuthentication").Return(true)
+	creds.On("Login").Return("login", "password", nil)
+	creds.On("Token").Return("token", nil)
+	creds.On("UpdateToken", mock.Anything).Return(nil)
+	return creds
+}
+
+func TestParseCACertificate(t *testing.T) {
+	server := newActivationsServer(t)
+
+	caCert, err := ParseCACertificate(serverCAPEM(server))
+	require.NoError(t, err)
+	assert.True(t, caCert.Equal(server.Certificate()))
+
+	_, err = ParseCACertificate([]byte("not a certificate"))
+	assert.Error(t, err)
+}
+
+func TestCustomCATrustedByConnection(t *testing.T) {
+	server := newActivationsServer(t)
+	params := OnlineConnectionParams{
+		RegistrationURL: server.URL,
+		Options:         DefaultConnectionOptions("scc-operator-test", "0.0.1"),
+	}
+
+	untrusted := OnlineRancherConnection(params, mockCredentials(), telemetry.MetricsWrapper{})
+	_, err := untrusted.ActivationStatus()
+	require.Error(t, err)
+	assert.True(t, IsTLSVerificationError(err))
+
+	caCert, err := ParseCACertificate(serverCAPEM(server))
+	require.NoError(t, err)
+	params.Options.Certificate = caCert
+	trusted := OnlineRancherConnection(params, mockCredentials(), telemetry.MetricsWrapper{})
+	activations, err := trusted.ActivationStatus()
+	require.NoError(t, err)
+	assert.Empty(t, activations)
+}
+
+func TestFetchRegistrationCACertFrom(t *testing.T) {
+	server := newActivationsServer(t)
+	gomockCtrl := gomock.NewController(t)
+	mockSecretsCache := fake.NewMockCacheInterface[*corev1.Secret](gomockCtrl)
+	secretRepo := &secretrepo.SecretRepository{Cache: mockSecretsCache}
+
+	withKey := func(name, key string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: consts.DefaultSCCNamespace},
+			Data:       map[string][]byte{key: serverCAPEM(server)},
+		}
+	}
+	mockSecretsCache.EXPECT().Get(consts.DefaultSCCNamespace, "operator-ca").Return(withKey("operator-ca", consts.SecretKeyRegistrationCACert), nil)
+	mockSecretsCache.EXPECT().Get(consts.DefaultSCCNamespace, "user-ca").Return(withKey("user-ca", corev1.ServiceAccountRootCAKey), nil)
+	mockSecretsCache.EXPECT().Get(consts.DefaultSCCNamespace, "empty-ca").Return(withKey("empty-ca", "other"), nil)
+	mockSecretsCache.EXPECT().Get(consts.DefaultSCCNamespace, "missing-ca").Return(nil, errors.New("not found"))
+
+	for _, name := range []string{"operator-ca", "user-ca"} {
+		caCert, err := FetchRegistrationCACertFrom(secretRepo, &corev1.SecretReference{Name: name, Namespace: consts.DefaultSCCNamespace})
+		require.NoError(t, err)
+		assert.True(t, caCert.Equal(server.Certificate()))
+	}
+
+	_, err := FetchRegistrationCACertFrom(secretRepo, &corev1.SecretReference{Name: "empty-ca", Namespace: consts.DefaultSCCNamespace})
+	assert.ErrorContains(t, err, "does not contain")
+
+	_, err = FetchRegistrationCACertFrom(secretRepo, &corev1.SecretReference{Name: "missing-ca", Namespace: consts.DefaultSCCNamespace})
+	assert.ErrorContains(t, err, "not found")
+}
@@ -1,8 +1,10 @@
 package credentials
 
 import (
+	"errors"
 	"fmt"
 	"maps"
+	"strings"
 
 	"github.com/SUSE/connect-ng/pkg/connection"
 	corev1 "k8s.io/api/core/v1"
@@ -21,6 +23,24 @@ const (
 	TokenKey    = "systemToken"
 )
 
+// ErrCredentialsDrift is returned instead of loading credentials that were changed outside of the operator
+var ErrCredentialsDrift = errors.New("credentials secret does not match the content last written by the operator")
+
+// ErrCredentialsIncomplete is returned when the credentials Secret lacks the login SCC issued for the system.
+// Only the token is loaded then, which is all a system that is not announced yet has.
+var ErrCredentialsIncomplete = errors.New("credentials secret is missing the system login or password")
+
+// MissingLoginKeys lists the login keys the credentials Secret holds no value for
+func MissingLoginKeys(secret *corev1.Secret) []string {
+	var missing []string
+	for _, key := range []string{UsernameKey, PasswordKey} {
+		if len(secret.Data[key]) == 0 {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
 type CredentialSecretsAdapter struct {
 	secretNamespace string
 	secretName      string
@@ -62,11 +82,14 @@ func (c *CredentialSecretsAdapter) loadCredentials() error {
 		if len(sccCreds.Data) == 0 {
 			return fmt.Errorf("secret %s/%s has no data fields; but should always have them", c.secretNamespace, c.secretName)
 		}
-		username, _ := sccCreds.Data[UsernameKey]
-		password, _ := sccCreds.Data[PasswordKey]
-		token, _ := sccCreds.Data[TokenKey]
-		_ = c.credentials.SetLogin(string(username), string(password))
-		_ = c.credentials.UpdateToken(string(token))
+		if lifecycle.SecretHasDrifted(sccCreds) {
+			return fmt.Errorf("secret %s/%s: %w", c.secretNamespace, c.secretName, ErrCredentialsDrift)
+		}
+		_ = c.credentials.UpdateToken(string(sccCreds.Data[TokenKey]))
+		if missing := MissingLoginKeys(sccCreds); len(missing) > 0 {
+			return fmt.Errorf("secret %s/%s has no %s: %w", c.secretNamespace, c.secretName, strings.Join(missing, " or "), ErrCredentialsIncomplete)
+		}
+		_ = c.credentials.SetLogin(string(sccCreds.Data[UsernameKey]), string(sccCreds.Data[PasswordKey]))
 	}
 
 	return err
@@ -107,6 +130,7 @@ func (c *CredentialSecretsAdapter) saveCredentials() error {
 	}
 
 	sccCreds = lifecycle.SecretAddCredentialsFinalizer(sccCreds)
+	sccCreds = lifecycle.SecretStampContentHash(sccCreds)
 
 	if sccCreds.Labels == nil {
 		sccCreds.Labels = c.labels
@@ -160,7 +184,7 @@ func (c *CredentialSecretsAdapter) HasAuthentication() bool {
 }
 
 func (c *CredentialSecretsAdapter) Token() (string, error) {
-	if err := c.loadCredentials(); err != nil {
+	if err := c.loadCredentials(); err != nil && !errors.Is(err, ErrCredentialsIncomplete) {
 		return "", err
 	}
 	return c.credentials.Token()
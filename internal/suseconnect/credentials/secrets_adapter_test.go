@@ -12,6 +12,7 @@ import (
 	"github.com/rancher/scc-operator/internal/consts"
 	"github.com/rancher/scc-operator/internal/repos/secretrepo"
 	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+	"github.com/rancher/scc-operator/pkg/controllers/lifecycle"
 )
 
 const (
@@ -121,7 +122,7 @@ func testSecretForUpdate(inSecret *corev1.Secret, inReg *v1.Registration) *corev
 		inSecret.Labels = map[string]string{}
 	}
 	inSecret.Labels[consts.LabelSccSecretRole] = string(consts.SCCCredentialsRole)
-	return inSecret
+	return lifecycle.SecretStampContentHash(inSecret)
 }
 
 func preparedSecretsMocks(t *testing.T) (*fake.MockControllerInterface[*corev1.Secret, *corev1.SecretList], *fake.MockCacheInterface[*corev1.Secret]) {
@@ -322,3 +323,43 @@ func TestSecretEmptyTokenUpdate(t *testing.T) {
 func TestSecretLoadErrors(t *testing.T) {
 	t.Skip("TODO: Create a testing scenario that would trigger uncovered lines")
 }
+
+func TestSecretsAdapterCredentials_Drift(t *testing.T) {
+	gomockCtrl := gomock.NewController(t)
+	mockSecretsController := fake.NewMockControllerInterface[*corev1.Secret, *corev1.SecretList](gomockCtrl)
+	mockSecretsCache := fake.NewMockCacheInterface[*corev1.Secret](gomockCtrl)
+	mockBasedSecretRepo := preparedSecretRepoMocks(t, mockSecretsController, mockSecretsCache)
+
+	driftedSecret := testSecret("", "", nil)
+	stamped := lifecycle.SecretStampContentHash(&driftedSecret)
+	stamped.Data[PasswordKey] = []byte("changed-by-hand")
+	mockSecretsCache.EXPECT().Get(Namespace, SecretName).Return(stamped, nil).AnyTimes()
+
+	testReg := mockRegistration()
+	secretsBackedCredentials := mockCredentials(testReg.ToOwnerRef(), &mockBasedSecretRepo)
+	err := secretsBackedCredentials.Refresh()
+	assert.ErrorIs(t, err, ErrCredentialsDrift)
+	assert.False(t, secretsBackedCredentials.HasAuthentication())
+}
+
+func TestSecretsAdapterCredentials_Incomplete(t *testing.T) {
+	gomockCtrl := gomock.NewController(t)
+	mockSecretsController := fake.NewMockControllerInterface[*corev1.Secret, *corev1.SecretList](gomockCtrl)
+	mockSecretsCache := fake.NewMockCacheInterface[*corev1.Secret](gomockCtrl)
+	mockBasedSecretRepo := preparedSecretRepoMocks(t, mockSecretsController, mockSecretsCache)
+
+	incompleteSecret := testSecret("", "", &map[string]string{PasswordKey: ""})
+	mockSecretsCache.EXPECT().Get(Namespace, SecretName).Return(&incompleteSecret, nil).AnyTimes()
+
+	testReg := mockRegistration()
+	secretsBackedCredentials := mockCredentials(testReg.ToOwnerRef(), &mockBasedSecretRepo)
+	err := secretsBackedCredentials.Refresh()
+	assert.ErrorIs(t, err, ErrCredentialsIncomplete)
+	assert.ErrorContains(t, err, PasswordKey)
+	assert.False(t, secretsBackedCredentials.HasAuthentication())
+
+	// The token is still loaded so a system that is not announced yet can announce
+	token, tokenErr := secretsBackedCredentials.Token()
+	assert.NoError(t, tokenErr)
+	assert.Equal(t, "system_testLoginToken", token)
+}
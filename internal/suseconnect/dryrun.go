@@ -0,0 +1,221 @@
+package suseconnect
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/SUSE/connect-ng/pkg/connection"
+	"github.com/SUSE/connect-ng/pkg/registration"
+
+	"github.com/rancher/scc-operator/internal/telemetry"
+)
+
+// redactedValue replaces anything that could identify the system or grant access to SCC in a recorded request
+const redactedValue = "REDACTED"
+
+// DryRunRequest describes an SCC API call the operator would have made if it was not running in dry-run mode
+type DryRunRequest struct {
+	Time         time.Time `json:"time"`
+	Registration string    `json:"registration"`
+	Operation    string    `json:"operation"`
+	URL          string    `json:"url"`
+	Hostname     string    `json:"hostname,omitempty"`
+	// Product is the SCC product triplet, `identifier/version/arch`
+	Product           string `json:"product,omitempty"`
+	RegistrationCode  string `json:"registrationCode,omitempty"`
+	InstanceData      string `json:"instanceData,omitempty"`
+	SystemInformation any    `json:"systemInformation,omitempty"`
+	// OnlineAt holds the usage records a pay-as-you-go keepalive would report
+	OnlineAt []string `json:"onlineAt,omitempty"`
+}
+
+// DryRunRecorder receives every request a DryRunWrapper would have sent
+type DryRunRecorder interface {
+	Record(request DryRunRequest)
+}
+
+// DryRunWrapper takes the place of SccWrapper in dry-run mode: every call is recorded and answered as if SCC accepted it
+type DryRunWrapper struct {
+	registrationName string
+	url              string
+	rancherURL       string
+	credentials      connection.Credentials
+	rancherMetrics   telemetry.MetricsWrapper
+	recorder         DryRunRecorder
+}
+
+func DryRunConnection(
+	registrationName string,
+	params OnlineConnectionParams,
+	credentials connection.Credentials,
+	rancherMetrics telemetry.MetricsWrapper,
+	recorder DryRunRecorder,
+) *DryRunWrapper {
+	url := params.Options.URL
+	if params.RegistrationURL != "" {
+		url = params.RegistrationURL
+	}
+
+	return &DryRunWrapper{
+		registrationName: registrationName,
+		url:              url,
+		rancherURL:       params.RancherURL,
+		credentials:      credentials,
+		rancherMetrics:   rancherMetrics,
+		recorder:         recorder,
+	}
+}
+
+func (dw *DryRunWrapper) record(request DryRunRequest) {
+	request.Time = time.Now().UTC()
+	request.Registration = dw.registrationName
+	request.URL = dw.url
+	dw.recorder.Record(request)
+}
+
+func (dw *DryRunWrapper) productTriplet() string {
+	identifier, version, arch := dw.rancherMetrics.GetProductIdentifier()
+	return fmt.Sprintf("%s/%s/%s", identifier, version, arch)
+}
+
+func (dw *DryRunWrapper) registered() bool {
+	return dw.credentials != nil && dw.credentials.HasAuthentication()
+}
+
+func (dw *DryRunWrapper) SystemRegistration(regCode string) (RegistrationSystemID, error) {
+	dw.record(DryRunRequest{
+		Operation:         "register",
+		Hostname:          dw.rancherURL,
+		RegistrationCode:  redactSecret(regCode),
+		SystemInformation: redactSystemInformation(dw.rancherMetrics.ToSystemInformation()),
+	})
+
+	return DryRunRegistrationSystemID, nil
+}
+
+func (dw *DryRunWrapper) KeepAlive() error {
+	dw.record(DryRunRequest{
+		Operation:         "keepalive",
+		Hostname:          dw.rancherURL,
+		SystemInformation: redactSystemInformation(dw.rancherMetrics.ToSystemInformation()),
+	})
+
+	return nil
+}
+
+func (dw *DryRunWrapper) RegisterOrKeepAlive(regCode string) (RegistrationSystemID, error) {
+	if dw.registered() {
+		return KeepAliveRegistrationSystemID, dw.KeepAlive()
+	}
+
+	return dw.SystemRegistration(regCode)
+}
+
+func (dw *DryRunWrapper) RegisterPayAsYouGo(instanceData string) (RegistrationSystemID, error) {
+	if dw.registered() {
+		return KeepAliveRegistrationSystemID, dw.KeepAlive()
+	}
+
+	dw.record(DryRunRequest{
+		Operation:         "register-payg",
+		Hostname:          dw.rancherURL,
+		InstanceData:      redactSecret(instanceData),
+		SystemInformation: redactSystemInformation(dw.rancherMetrics.ToSystemInformation()),
+	})
+
+	return DryRunRegistrationSystemID, nil
+}
+
+func (dw *DryRunWrapper) ReportUsage(instanceData string, report UsageReport) error {
+	dw.record(DryRunRequest{
+		Operation:         "report-usage",
+		Hostname:          dw.rancherURL,
+		InstanceData:      redactSecret(instanceData),
+		SystemInformation: redactSystemInformation(dw.rancherMetrics.ToSystemInformation()),
+		OnlineAt:          report.OnlineAt,
+	})
+
+	return nil
+}
+
+func (dw *DryRunWrapper) Activate(regCode string) (*registration.Metadata, *registration.Product, error) {
+	dw.record(DryRunRequest{
+		Operation:        "activate",
+		Product:          dw.productTriplet(),
+		RegistrationCode: redactSecret(regCode),
+	})
+
+	product := dw.dryRunProduct()
+	return &registration.Metadata{Name: product.FriendlyName}, product, nil
+}
+
+func (dw *DryRunWrapper) ActivationStatus() ([]*registration.Activation, error) {
+	dw.record(DryRunRequest{Operation: "activations"})
+
+	product := dw.dryRunProduct()
+	return []*registration.Activation{{
+		Name:     product.FriendlyName,
+		Status:   "ACTIVE",
+		Type:     "dry-run",
+		StartsAt: time.Now().UTC(),
+		Metadata: &registration.Metadata{Name: product.FriendlyName},
+		Product:  product,
+	}}, nil
+}
+
+func (dw *DryRunWrapper) ProductInfo() (*registration.Product, error) {
+	dw.record(DryRunRequest{Operation: "product-info", Product: dw.productTriplet()})
+
+	return dw.dryRunProduct(), nil
+}
+
+func (dw *DryRunWrapper) Deregister() error {
+	dw.record(DryRunRequest{Operation: "deregister", Hostname: dw.rancherURL})
+
+	return nil
+}
+
+// dryRunProduct is the product SCC would be expected to answer with; it is named so it cannot be mistaken for a real activation
+func (dw *DryRunWrapper) dryRunProduct() *registration.Product {
+	identifier, version, arch := dw.rancherMetrics.GetProductIdentifier()
+	return &registration.Product{
+		Identifier:   identifier,
+		Version:      version,
+		Arch:         arch,
+		FriendlyName: fmt.Sprintf("%s (dry-run)", dw.productTriplet()),
+		IsBase:       true,
+	}
+}
+
+var _ SCCClient = &DryRunWrapper{}
+
+// redactSecret keeps only whether a secret value would have been sent
+func redactSecret(value string) string {
+	if value == "" {
+		return ""
+	}
+
+	return redactedValue
+}
+
+// redactSystemInformation keeps the shape of the system information and its counts, but none of its text
+func redactSystemInformation(value any) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		redacted := make(map[string]any, len(typed))
+		for key, nested := range typed {
+			redacted[key] = redactSystemInformation(nested)
+		}
+		return redacted
+	case []any:
+		redacted := make([]any, len(typed))
+		for i, nested := range typed {
+			redacted[i] = redactSystemInformation(nested)
+		}
+		return redacted
+	case string:
+		return redactSecret(typed)
+	default:
+		return typed
+	}
+}
@@ -0,0 +1,104 @@
+package suseconnect
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SUSE/connect-ng/pkg/connection"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rancher/scc-operator/internal/telemetry"
+)
+
+type requestCollector struct {
+	requests []DryRunRequest
+}
+
+func (c *requestCollector) Record(request DryRunRequest) {
+	c.requests = append(c.requests, request)
+}
+
+func dryRunMetrics() telemetry.MetricsWrapper {
+	return telemetry.NewMetricsWrapper(map[string]any{
+		"version": "2.12.1",
+		"subscription": map[string]any{
+			"installuuid": "a2f3e1c4-b7d8-4e9f-8a1b-2c3d4e5f6a7b",
+			"product":     "rancher",
+			"version":     "2.12.1",
+			"arch":        "unknown",
+			"git":         "abc1234",
+		},
+		"nodes": map[string]any{"count": 3, "names": []any{"node-1", "node-2", "node-3"}},
+	})
+}
+
+func TestDryRunWrapperRecordsRedactedRequests(t *testing.T) {
+	collector := &requestCollector{}
+	unregistered := connection.NewMockCredentials()
+	unregistered.On("HasAuthentication").Return(false)
+	params := OnlineConnectionParams{
+		RancherURL: "https://rancher.example.com",
+		Options:    DefaultConnectionOptions("scc-operator-test", "0.0.1"),
+	}
+	dryRun := DryRunConnection("scc-registration-abc", params, unregistered, dryRunMetrics(), collector)
+
+	id, err := dryRun.RegisterOrKeepAlive("INTERNAL-USE-ONLY-1234")
+	require.NoError(t, err)
+	assert.Equal(t, DryRunRegistrationSystemID, id)
+
+	_, product, err := dryRun.Activate("INTERNAL-USE-ONLY-1234")
+	require.NoError(t, err)
+	assert.Equal(t, "rancher", product.Identifier)
+
+	activations, err := dryRun.ActivationStatus()
+	require.NoError(t, err)
+	require.Len(t, activations, 1)
+	assert.Contains(t, activations[0].Product.FriendlyName, "dry-run")
+	require.NoError(t, dryRun.Deregister())
+
+	require.Len(t, collector.requests, 4)
+	register := collector.requests[0]
+	assert.Equal(t, "register", register.Operation)
+	assert.Equal(t, "scc-registration-abc", register.Registration)
+	assert.Equal(t, connection.DefaultBaseURL, register.URL)
+	assert.Equal(t, "https://rancher.example.com", register.Hostname)
+	assert.Equal(t, redactedValue, register.RegistrationCode)
+	assert.False(t, register.Time.IsZero())
+
+	// Only counts survive in the system information; identifiers and names are redacted
+	systemInformation := register.SystemInformation.(map[string]any)
+	assert.Equal(t, redactedValue, systemInformation["subscription"].(map[string]any)["installuuid"])
+	nodes := systemInformation["nodes"].(map[string]any)
+	assert.Equal(t, 3, nodes["count"])
+	assert.Equal(t, []any{redactedValue, redactedValue, redactedValue}, nodes["names"])
+
+	activate := collector.requests[1]
+	assert.Equal(t, "activate", activate.Operation)
+	assert.Equal(t, "rancher/2.12.1/unknown", activate.Product)
+	assert.Equal(t, redactedValue, activate.RegistrationCode)
+	assert.Equal(t, "activations", collector.requests[2].Operation)
+	assert.Equal(t, "deregister", collector.requests[3].Operation)
+}
+
+func TestDryRunWrapperKeepsAliveWhenRegistered(t *testing.T) {
+	collector := &requestCollector{}
+	params := OnlineConnectionParams{RegistrationURL: "https://rmt.example.com"}
+	dryRun := DryRunConnection("scc-registration-abc", params, mockCredentials(), dryRunMetrics(), collector)
+
+	id, err := dryRun.RegisterOrKeepAlive("")
+	require.NoError(t, err)
+	assert.Equal(t, KeepAliveRegistrationSystemID, id)
+
+	since := time.Date(2024, 1, 18, 22, 0, 0, 0, time.UTC)
+	require.NoError(t, dryRun.ReportUsage("<instance document>", NewUsageReport(since, since.Add(2*time.Hour))))
+
+	require.Len(t, collector.requests, 2)
+	assert.Equal(t, "keepalive", collector.requests[0].Operation)
+	assert.Equal(t, "https://rmt.example.com", collector.requests[0].URL)
+	assert.Empty(t, collector.requests[0].RegistrationCode)
+	usage := collector.requests[1]
+	assert.Equal(t, "report-usage", usage.Operation)
+	assert.Equal(t, redactedValue, usage.InstanceData)
+	assert.NotEmpty(t, usage.OnlineAt)
+}
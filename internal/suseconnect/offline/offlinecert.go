@@ -139,3 +139,37 @@ func (o *SecretManager) RemoveOfflineCertificate() error {
 	}
 	return delErr
 }
+
+// StagedCertificate returns the replacement certificate staged next to the active one, or nil when none is staged
+func (o *SecretManager) StagedCertificate() ([]byte, error) {
+	offlineCert, err := o.secretRepo.Cache.Get(o.secretNamespace, o.certificateSecretName)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error loading certificate secret: %v", err)
+	}
+
+	return offlineCert.Data[consts.SecretKeyOfflineRegCertReplacement], nil
+}
+
+// PromoteStagedCertificate makes the staged replacement the active certificate in a single update.
+// It refuses to promote when the staged certificate is no longer the one that was validated.
+func (o *SecretManager) PromoteStagedCertificate(validated []byte) error {
+	offlineCert, err := o.secretRepo.Controller.Get(o.secretNamespace, o.certificateSecretName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	staged, ok := offlineCert.Data[consts.SecretKeyOfflineRegCertReplacement]
+	if !ok || !bytes.Equal(staged, validated) {
+		return fmt.Errorf("staged certificate in secret %s/%s changed since it was validated", o.secretNamespace, o.certificateSecretName)
+	}
+
+	promoted := offlineCert.DeepCopy()
+	promoted.Data[consts.SecretKeyOfflineRegCert] = staged
+	delete(promoted.Data, consts.SecretKeyOfflineRegCertReplacement)
+
+	_, updateErr := o.secretRepo.Controller.Update(promoted)
+	return updateErr
+}
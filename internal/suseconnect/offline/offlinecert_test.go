@@ -0,0 +1,84 @@
+package offline
+
+import (
+	"testing"
+
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/rancher/scc-operator/internal/consts"
+	"github.com/rancher/scc-operator/internal/repos/secretrepo"
+)
+
+func stagedCertificateSecret(staged []byte) *corev1.Secret {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "offline-certificate", Namespace: consts.DefaultSCCNamespace, ResourceVersion: "7"},
+		Data:       map[string][]byte{consts.SecretKeyOfflineRegCert: []byte("active")},
+	}
+	if staged != nil {
+		secret.Data[consts.SecretKeyOfflineRegCertReplacement] = staged
+	}
+	return secret
+}
+
+func TestStagedCertificate(t *testing.T) {
+	gomockCtrl := gomock.NewController(t)
+	mockSecretsCache := fake.NewMockCacheInterface[*corev1.Secret](gomockCtrl)
+	manager := New(consts.DefaultSCCNamespace, "offline-request", "offline-certificate", nil, &secretrepo.SecretRepository{Cache: mockSecretsCache}, nil)
+
+	mockSecretsCache.EXPECT().Get(consts.DefaultSCCNamespace, "offline-certificate").Return(stagedCertificateSecret([]byte("renewed")), nil)
+	staged, err := manager.StagedCertificate()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("renewed"), staged)
+
+	mockSecretsCache.EXPECT().Get(consts.DefaultSCCNamespace, "offline-certificate").Return(stagedCertificateSecret(nil), nil)
+	staged, err = manager.StagedCertificate()
+	require.NoError(t, err)
+	assert.Nil(t, staged)
+
+	// Nothing is staged before the certificate Secret exists
+	notFound := apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, "offline-certificate")
+	mockSecretsCache.EXPECT().Get(consts.DefaultSCCNamespace, "offline-certificate").Return(nil, notFound)
+	staged, err = manager.StagedCertificate()
+	require.NoError(t, err)
+	assert.Nil(t, staged)
+
+	mockSecretsCache.EXPECT().Get(consts.DefaultSCCNamespace, "offline-certificate").Return(nil, assert.AnError)
+	_, err = manager.StagedCertificate()
+	assert.ErrorContains(t, err, assert.AnError.Error())
+}
+
+func TestPromoteStagedCertificate(t *testing.T) {
+	gomockCtrl := gomock.NewController(t)
+	mockSecrets := fake.NewMockControllerInterface[*corev1.Secret, *corev1.SecretList](gomockCtrl)
+	manager := New(consts.DefaultSCCNamespace, "offline-request", "offline-certificate", nil, &secretrepo.SecretRepository{Controller: mockSecrets}, nil)
+
+	// The staged certificate becomes the active one in the same update that drops it
+	current := stagedCertificateSecret([]byte("renewed"))
+	mockSecrets.EXPECT().Get(consts.DefaultSCCNamespace, "offline-certificate", gomock.Any()).Return(current, nil)
+	mockSecrets.EXPECT().Update(gomock.Any()).DoAndReturn(func(promoted *corev1.Secret) (*corev1.Secret, error) {
+		assert.Equal(t, "7", promoted.ResourceVersion, "the update must conflict if the Secret changed in between")
+		assert.Equal(t, []byte("renewed"), promoted.Data[consts.SecretKeyOfflineRegCert])
+		assert.NotContains(t, promoted.Data, consts.SecretKeyOfflineRegCertReplacement)
+		return promoted, nil
+	})
+	require.NoError(t, manager.PromoteStagedCertificate([]byte("renewed")))
+	assert.Equal(t, []byte("active"), current.Data[consts.SecretKeyOfflineRegCert], "the cached Secret is left untouched")
+
+	// A certificate staged again after validation is never promoted unchecked
+	mockSecrets.EXPECT().Get(consts.DefaultSCCNamespace, "offline-certificate", gomock.Any()).Return(stagedCertificateSecret([]byte("tampered")), nil)
+	assert.ErrorContains(t, manager.PromoteStagedCertificate([]byte("renewed")), "changed since it was validated")
+
+	mockSecrets.EXPECT().Get(consts.DefaultSCCNamespace, "offline-certificate", gomock.Any()).Return(stagedCertificateSecret(nil), nil)
+	assert.ErrorContains(t, manager.PromoteStagedCertificate([]byte("renewed")), "changed since it was validated")
+
+	mockSecrets.EXPECT().Get(consts.DefaultSCCNamespace, "offline-certificate", gomock.Any()).Return(stagedCertificateSecret([]byte("renewed")), nil)
+	mockSecrets.EXPECT().Update(gomock.Any()).Return(nil, assert.AnError)
+	assert.ErrorIs(t, manager.PromoteStagedCertificate([]byte("renewed")), assert.AnError)
+}
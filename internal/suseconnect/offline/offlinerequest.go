@@ -1,6 +1,8 @@
 package offline
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"maps"
@@ -121,6 +123,12 @@ func (o *SecretManager) UpdateOfflineRequest(inReq *registration.OfflineRequest)
 	return o.saveRequestSecret()
 }
 
+// RequestChecksum identifies the content of an offline request, so a downloaded copy can be checked against the current one
+func RequestChecksum(request []byte) string {
+	sum := sha256.Sum256(request)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
 func (o *SecretManager) RemoveOfflineRequest() error {
 	currentSecret, err := o.secretRepo.Cache.Get(o.secretNamespace, o.requestSecretName)
 	if err != nil {
@@ -0,0 +1,125 @@
+/*
+Package offlinetest issues offline registration certificates signed by a throwaway key, so the whole offline flow can run without SCC.
+
+Certificates from a Signer are only accepted by an operator trusting its public key, see offlinevalidator.TrustRoots:
+
+	signer, _ := offlinetest.NewSigner()
+	certificate, _ := signer.Issue(offlinetest.Certificate{UUID: offlinetest.FakeUUID})
+	// trust signer.PublicKeyPEM(), then put certificate in the entrypoint Secret
+*/
+package offlinetest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"github.com/SUSE/connect-ng/pkg/registration"
+)
+
+// FakeUUID is a Rancher install UUID to issue certificates for when the real one does not matter
+const FakeUUID = "00000000-0000-4000-8000-000000000000"
+
+// Certificate describes the offline certificate to issue; unset fields get usable defaults
+type Certificate struct {
+	// UUID is the Rancher install UUID the certificate is valid for; `0x0` issues a wildcard certificate
+	UUID         string
+	RegCode      string
+	SystemID     int
+	ProductName  string
+	Subscription registration.SubscriptionInfo
+}
+
+// Signer issues offline certificates the same way SCC does, with its own RSA key
+type Signer struct {
+	key          *rsa.PrivateKey
+	publicKeyPEM []byte
+}
+
+// NewSigner generates a new signing key
+func NewSigner() (*Signer, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode signing public key: %w", err)
+	}
+
+	return &Signer{
+		key:          key,
+		publicKeyPEM: pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}),
+	}, nil
+}
+
+// PublicKeyPEM is the public key to trust for the certificates of this Signer
+func (s *Signer) PublicKeyPEM() []byte {
+	return s.publicKeyPEM
+}
+
+// Issue signs an offline certificate, returning it encoded as it is stored in the entrypoint Secret
+func (s *Signer) Issue(certificate Certificate) ([]byte, error) {
+	if certificate.UUID == "" {
+		certificate.UUID = FakeUUID
+	}
+	if certificate.ProductName == "" {
+		certificate.ProductName = "SUSE Rancher Prime"
+	}
+	subscription := certificate.Subscription
+	if subscription.Name == "" {
+		subscription.Name = "Test Subscription"
+	}
+	if subscription.Kind == "" {
+		subscription.Kind = "test"
+	}
+	if subscription.StartsAt.IsZero() {
+		subscription.StartsAt = time.Now().UTC().Truncate(time.Second)
+	}
+	if subscription.ExpiresAt.IsZero() {
+		subscription.ExpiresAt = subscription.StartsAt.AddDate(1, 0, 0)
+	}
+
+	payload, err := json.Marshal(registration.OfflinePayload{
+		SubscriptionInfo: subscription,
+		HashedRegcode:    sha256Hex(certificate.RegCode),
+		HashedUUID:       sha256Hex(certificate.UUID),
+		Information:      map[string]any{},
+	})
+	if err != nil {
+		return nil, err
+	}
+	encodedPayload := base64.StdEncoding.EncodeToString(payload)
+
+	digest := sha256.Sum256([]byte(encodedPayload))
+	signature, err := rsa.SignPSS(rand.Reader, s.key, crypto.SHA256, digest[:], &rsa.PSSOptions{
+		SaltLength: rsa.PSSSaltLengthEqualsHash,
+		Hash:       crypto.SHA256,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign offline certificate: %w", err)
+	}
+
+	return json.Marshal(registration.OfflineCertificate{
+		Version:          "1",
+		Cipher:           "RSA",
+		Hash:             "SHA256",
+		EncodedPayload:   encodedPayload,
+		EncodedSignature: base64.StdEncoding.EncodeToString(signature),
+		SystemID:         certificate.SystemID,
+		ProductName:      certificate.ProductName,
+	})
+}
+
+func sha256Hex(input string) string {
+	sum := sha256.Sum256([]byte(input))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,6 +1,9 @@
 package offlinevalidator
 
 import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
 	"fmt"
 	"time"
 
@@ -45,15 +48,43 @@ func (e *offlineCertError) Unwrap() error {
 type CertificateValidator struct {
 	offlineCert *registration.OfflineCertificate
 	rancherUUID string
+	trustRoots  TrustRoots
 }
 
-func New(offlineCert *registration.OfflineCertificate, rancherUUID string) *CertificateValidator {
+// New prepares a validator for the certificate; it is trusted when signed by SUSE or by one of the extra trust roots
+func New(offlineCert *registration.OfflineCertificate, rancherUUID string, trustRoots TrustRoots) *CertificateValidator {
 	return &CertificateValidator{
 		offlineCert: offlineCert,
 		rancherUUID: rancherUUID,
+		trustRoots:  trustRoots,
 	}
 }
 
+// signedByTrustRoot checks the signature with the same RSA-PSS scheme SCC uses, against each extra trust root
+func (cv *CertificateValidator) signedByTrustRoot() (bool, error) {
+	if len(cv.trustRoots) == 0 {
+		return false, nil
+	}
+
+	signature, sigErr := cv.offlineCert.Signature()
+	if sigErr != nil {
+		return false, sigErr
+	}
+	digest := sha256.Sum256([]byte(cv.offlineCert.EncodedPayload))
+	options := &rsa.PSSOptions{
+		SaltLength: rsa.PSSSaltLengthEqualsHash,
+		Hash:       crypto.SHA256,
+	}
+
+	for _, key := range cv.trustRoots {
+		if rsa.VerifyPSS(key, crypto.SHA256, digest[:], signature, options) == nil {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
 func (cv *CertificateValidator) ValidateCertificate() error {
 	// IsValid call sounds like it could be an overall "this is valid" status,
 	// Ultimately it's a "was this signed by a source I trust" check (validates SHA and verifies PSS sig)
@@ -65,6 +96,16 @@ func (cv *CertificateValidator) ValidateCertificate() error {
 		}
 	}
 
+	if !certIsValid {
+		certIsValid, validateErr = cv.signedByTrustRoot()
+		if validateErr != nil {
+			return &offlineCertError{
+				Operation:  "ValidateSignature",
+				WrappedErr: &validateErr,
+			}
+		}
+	}
+
 	if !certIsValid {
 		return &offlineCertError{
 			Operation: "ValidateSignature",
@@ -0,0 +1,96 @@
+package offlinevalidator
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/SUSE/connect-ng/pkg/registration"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rancher/scc-operator/internal/suseconnect/offlinetest"
+)
+
+func issueCertificate(t *testing.T, signer *offlinetest.Signer, certificate offlinetest.Certificate) *registration.OfflineCertificate {
+	t.Helper()
+	certData, err := signer.Issue(certificate)
+	require.NoError(t, err)
+	offlineCert, err := registration.OfflineCertificateFrom(bytes.NewReader(certData), false)
+	require.NoError(t, err)
+	return offlineCert
+}
+
+func signerTrustRoots(t *testing.T, signer *offlinetest.Signer) TrustRoots {
+	t.Helper()
+	trustRoots, err := ParseTrustRoots(signer.PublicKeyPEM())
+	require.NoError(t, err)
+	require.Len(t, trustRoots, 1)
+	return trustRoots
+}
+
+func TestValidateCertificateTrustRoots(t *testing.T) {
+	signer, err := offlinetest.NewSigner()
+	require.NoError(t, err)
+	offlineCert := issueCertificate(t, signer, offlinetest.Certificate{})
+
+	// Only SUSE is trusted by default
+	assert.ErrorContains(t, New(offlineCert, offlinetest.FakeUUID, nil).ValidateCertificate(), "signature invalid")
+
+	assert.NoError(t, New(offlineCert, offlinetest.FakeUUID, signerTrustRoots(t, signer)).ValidateCertificate())
+
+	otherSigner, err := offlinetest.NewSigner()
+	require.NoError(t, err)
+	assert.ErrorContains(t, New(offlineCert, offlinetest.FakeUUID, signerTrustRoots(t, otherSigner)).ValidateCertificate(), "signature invalid")
+}
+
+func TestValidateCertificateChecksTrustedCertificates(t *testing.T) {
+	signer, err := offlinetest.NewSigner()
+	require.NoError(t, err)
+	trustRoots := signerTrustRoots(t, signer)
+
+	offlineCert := issueCertificate(t, signer, offlinetest.Certificate{})
+	assert.ErrorContains(t, New(offlineCert, "another-rancher", trustRoots).ValidateCertificate(), "does not match Rancher UUID")
+
+	wildcardCert := issueCertificate(t, signer, offlinetest.Certificate{UUID: "0x0"})
+	assert.NoError(t, New(wildcardCert, "another-rancher", trustRoots).ValidateCertificate())
+
+	expiredCert := issueCertificate(t, signer, offlinetest.Certificate{
+		Subscription: registration.SubscriptionInfo{
+			StartsAt:  time.Now().AddDate(-1, 0, 0),
+			ExpiresAt: time.Now().Add(-time.Hour),
+		},
+	})
+	assert.ErrorContains(t, New(expiredCert, offlinetest.FakeUUID, trustRoots).ValidateCertificate(), "already expired")
+}
+
+func TestParseTrustRoots(t *testing.T) {
+	first, err := offlinetest.NewSigner()
+	require.NoError(t, err)
+	second, err := offlinetest.NewSigner()
+	require.NoError(t, err)
+
+	// PKCS#1 keys are accepted next to PKIX ones
+	block, _ := pem.Decode(second.PublicKeyPEM())
+	require.NotNil(t, block)
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	require.NoError(t, err)
+	pkcs1 := pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(key.(*rsa.PublicKey))})
+
+	trustRoots, err := ParseTrustRoots(append(first.PublicKeyPEM(), pkcs1...))
+	require.NoError(t, err)
+	assert.Len(t, trustRoots, 2)
+
+	trustRoots, err = ParseTrustRoots(nil)
+	require.NoError(t, err)
+	assert.Empty(t, trustRoots)
+
+	_, err = ParseTrustRoots(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("secret")}))
+	assert.ErrorContains(t, err, "unexpected PEM block")
+
+	_, err = ParseTrustRoots(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte("garbage")}))
+	assert.Error(t, err)
+}
@@ -0,0 +1,47 @@
+package offlinevalidator
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+)
+
+// TrustRoots are public keys trusted to sign offline certificates next to SUSE's built-in key,
+// e.g. a staging signer or the test signer used by CI
+type TrustRoots []*rsa.PublicKey
+
+// ParseTrustRoots reads every PEM encoded RSA public key in the data, in either PKIX or PKCS#1 form
+func ParseTrustRoots(pemData []byte) (TrustRoots, error) {
+	var roots TrustRoots
+	for rest := pemData; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		switch block.Type {
+		case "PUBLIC KEY":
+			key, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trust root: %w", err)
+			}
+			rsaKey, ok := key.(*rsa.PublicKey)
+			if !ok {
+				return nil, fmt.Errorf("invalid trust root: only RSA public keys are supported, got %T", key)
+			}
+			roots = append(roots, rsaKey)
+		case "RSA PUBLIC KEY":
+			rsaKey, err := x509.ParsePKCS1PublicKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trust root: %w", err)
+			}
+			roots = append(roots, rsaKey)
+		default:
+			return nil, fmt.Errorf("invalid trust root: unexpected PEM block %q", block.Type)
+		}
+	}
+
+	return roots, nil
+}
@@ -0,0 +1,68 @@
+package suseconnect
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/SUSE/connect-ng/pkg/connection"
+	"golang.org/x/net/http/httpproxy"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/rancher/scc-operator/internal/repos/secretrepo"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+)
+
+// ProxyCallback builds the connect-ng proxy callback for a registration's proxy config.
+// Proxy credentials are read from the referenced basic-auth Secret and sent as the proxy URL's userinfo.
+func ProxyCallback(secretRepo *secretrepo.SecretRepository, proxyConfig *v1.ProxyConfig) (connection.ProxyCallbackFunc, error) {
+	proxyURL, err := url.Parse(proxyConfig.URL)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse proxy URL: %w", err)
+	}
+
+	if reference := proxyConfig.CredentialsSecretRef; reference != nil {
+		sccContextLogger().Debugf("Fetching proxy credentials from secret %s/%s", reference.Namespace, reference.Name)
+		credentialsSecret, getErr := secretRepo.Cache.Get(reference.Namespace, reference.Name)
+		if getErr != nil {
+			return nil, fmt.Errorf("failed to get proxy credentials secret %s/%s: %w", reference.Namespace, reference.Name, getErr)
+		}
+
+		username := string(credentialsSecret.Data[corev1.BasicAuthUsernameKey])
+		if username == "" {
+			return nil, fmt.Errorf("proxy credentials secret %s/%s does not contain `%s`", reference.Namespace, reference.Name, corev1.BasicAuthUsernameKey)
+		}
+		proxyURL.User = url.UserPassword(username, string(credentialsSecret.Data[corev1.BasicAuthPasswordKey]))
+	}
+
+	proxyFunc := (&httpproxy.Config{
+		HTTPProxy:  proxyURL.String(),
+		HTTPSProxy: proxyURL.String(),
+		NoProxy:    strings.Join(proxyConfig.NoProxy, ","),
+	}).ProxyFunc()
+
+	return func(request *http.Request) (*url.URL, error) {
+		return proxyFunc(request.URL)
+	}, nil
+}
+
+// proxyAuthRequiredText is the error text net/http returns when a proxy answers CONNECT with a 407
+var proxyAuthRequiredText = http.StatusText(http.StatusProxyAuthRequired)
+
+// IsProxyAuthenticationError reports if an error was caused by the HTTP proxy rejecting (or missing) proxy credentials.
+// Plain HTTP requests surface the proxy's 407 as an API error, while HTTPS tunnels fail while dialing.
+func IsProxyAuthenticationError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var sccAPIError *connection.ApiError
+	if errors.As(err, &sccAPIError) {
+		return sccAPIError.Code == http.StatusProxyAuthRequired
+	}
+
+	var urlErr *url.Error
+	return errors.As(err, &urlErr) && strings.HasSuffix(urlErr.Err.Error(), proxyAuthRequiredText)
+}
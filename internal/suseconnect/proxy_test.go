@@ -0,0 +1,129 @@
+package suseconnect
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/rancher/scc-operator/internal/consts"
+	"github.com/rancher/scc-operator/internal/repos/secretrepo"
+	"github.com/rancher/scc-operator/internal/telemetry"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+)
+
+// newAuthenticatingProxy answers every proxied request itself, so the target host never has to resolve
+func newAuthenticatingProxy(t *testing.T, proxyAuthorization string) *httptest.Server {
+	t.Helper()
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Proxy-Authorization") != proxyAuthorization {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		if r.Method == http.MethodConnect {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte("[]"))
+	}))
+	t.Cleanup(proxy.Close)
+
+	return proxy
+}
+
+func proxiedConnection(t *testing.T, secretRepo *secretrepo.SecretRepository, registrationURL string, proxyConfig *v1.ProxyConfig) SccWrapper {
+	t.Helper()
+	options := DefaultConnectionOptions("scc-operator-test", "0.0.1")
+	proxyCallback, err := ProxyCallback(secretRepo, proxyConfig)
+	require.NoError(t, err)
+	options.Proxy = proxyCallback
+
+	return OnlineRancherConnection(OnlineConnectionParams{RegistrationURL: registrationURL, Options: options}, mockCredentials(), telemetry.MetricsWrapper{})
+}
+
+func TestProxyCallbackAuthenticates(t *testing.T) {
+	// "proxy-user:proxy-password" in base64
+	proxy := newAuthenticatingProxy(t, "Basic cHJveHktdXNlcjpwcm94eS1wYXNzd29yZA==")
+	gomockCtrl := gomock.NewController(t)
+	mockSecretsCache := fake.NewMockCacheInterface[*corev1.Secret](gomockCtrl)
+	secretRepo := &secretrepo.SecretRepository{Cache: mockSecretsCache}
+	mockSecretsCache.EXPECT().Get(consts.DefaultSCCNamespace, "proxy-credentials").Return(&corev1.Secret{
+		Data: map[string][]byte{
+			corev1.BasicAuthUsernameKey: []byte("proxy-user"),
+			corev1.BasicAuthPasswordKey: []byte("proxy-password"),
+		},
+	}, nil)
+
+	proxyConfig := &v1.ProxyConfig{
+		URL:                  proxy.URL,
+		CredentialsSecretRef: &corev1.SecretReference{Name: "proxy-credentials", Namespace: consts.DefaultSCCNamespace},
+	}
+	authenticated := proxiedConnection(t, secretRepo, "http://scc.example.test", proxyConfig)
+	activations, err := authenticated.ActivationStatus()
+	require.NoError(t, err)
+	assert.Empty(t, activations)
+}
+
+func TestProxyAuthenticationErrors(t *testing.T) {
+	proxy := newAuthenticatingProxy(t, "Basic expected")
+
+	for _, registrationURL := range []string{"http://scc.example.test", "https://scc.example.test"} {
+		t.Run(registrationURL, func(t *testing.T) {
+			unauthenticated := proxiedConnection(t, nil, registrationURL, &v1.ProxyConfig{URL: proxy.URL})
+			_, err := unauthenticated.ActivationStatus()
+			require.Error(t, err)
+			assert.True(t, IsProxyAuthenticationError(err))
+		})
+	}
+
+	// Other proxy failures are not mistaken for authentication errors
+	openProxy := newAuthenticatingProxy(t, "")
+	tunnelFailure := proxiedConnection(t, nil, "https://scc.example.test", &v1.ProxyConfig{URL: openProxy.URL})
+	_, err := tunnelFailure.ActivationStatus()
+	require.Error(t, err)
+	assert.False(t, IsProxyAuthenticationError(err))
+	assert.False(t, IsProxyAuthenticationError(nil))
+}
+
+func TestProxyCallbackNoProxy(t *testing.T) {
+	proxyCallback, err := ProxyCallback(nil, &v1.ProxyConfig{
+		URL:     "http://proxy.example.com:3128",
+		NoProxy: []string{".internal.example.com", "10.0.0.0/8"},
+	})
+	require.NoError(t, err)
+
+	for target, wantProxy := range map[string]bool{
+		"https://scc.suse.com/connect/systems":             true,
+		"https://rmt.internal.example.com/connect/systems": false,
+		"https://10.1.2.3/connect/systems":                 false,
+	} {
+		request, _ := http.NewRequest(http.MethodGet, target, nil)
+		proxyURL, proxyErr := proxyCallback(request)
+		require.NoError(t, proxyErr)
+		if wantProxy {
+			require.NotNil(t, proxyURL, target)
+			assert.Equal(t, "proxy.example.com:3128", proxyURL.Host)
+		} else {
+			assert.Nil(t, proxyURL, target)
+		}
+	}
+}
+
+func TestProxyCallbackMissingUsername(t *testing.T) {
+	gomockCtrl := gomock.NewController(t)
+	mockSecretsCache := fake.NewMockCacheInterface[*corev1.Secret](gomockCtrl)
+	secretRepo := &secretrepo.SecretRepository{Cache: mockSecretsCache}
+	mockSecretsCache.EXPECT().Get(consts.DefaultSCCNamespace, "proxy-credentials").Return(&corev1.Secret{}, nil)
+
+	_, err := ProxyCallback(secretRepo, &v1.ProxyConfig{
+		URL:                  "http://proxy.example.com:3128",
+		CredentialsSecretRef: &corev1.SecretReference{Name: "proxy-credentials", Namespace: consts.DefaultSCCNamespace},
+	})
+	assert.ErrorContains(t, err, "does not contain `username`")
+}
@@ -0,0 +1,150 @@
+package suseconnect
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SUSE/connect-ng/pkg/connection"
+	"golang.org/x/time/rate"
+
+	"github.com/rancher/scc-operator/internal/consts"
+)
+
+// sccRateLimiter is shared by every SCC connection in the process, so a restart with many registrations cannot stampede the API
+var sccRateLimiter = rate.NewLimiter(rate.Limit(consts.DefaultSCCRequestRate), consts.DefaultSCCRequestBurst)
+
+// SetRateLimit changes how many SCC requests per second the operator may make, and how many may be sent at once
+func SetRateLimit(requestsPerSecond float64, burst int) {
+	sccRateLimiter.SetLimit(rate.Limit(requestsPerSecond))
+	sccRateLimiter.SetBurst(burst)
+}
+
+// RateLimitError is returned when SCC answers 429 Too Many Requests; it unwraps to the original connection.ApiError
+type RateLimitError struct {
+	*connection.ApiError
+	// RetryAfter is how long SCC asked us to wait, or consts.DefaultSCCRateLimitRetryAfter when it did not say
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.ApiError
+}
+
+// IsRateLimitError reports if SCC rejected a request with 429 Too Many Requests
+func IsRateLimitError(err error) bool {
+	var sccAPIError *connection.ApiError
+	return errors.As(err, &sccAPIError) && sccAPIError.Code == http.StatusTooManyRequests
+}
+
+// RetryAfterFrom returns the wait SCC asked for with its Retry-After header, if any
+func RetryAfterFrom(err error) time.Duration {
+	var rateLimitErr *RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return rateLimitErr.RetryAfter
+	}
+
+	return 0
+}
+
+// parseRetryAfter reads a Retry-After header, which is either a number of seconds or an HTTP date.
+// A missing or unreadable header falls back to consts.DefaultSCCRateLimitRetryAfter.
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	header = strings.TrimSpace(header)
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if retryAt, err := http.ParseTime(header); err == nil {
+		return max(retryAt.Sub(now), 0)
+	}
+
+	return consts.DefaultSCCRateLimitRetryAfter
+}
+
+// rateLimitedConnection waits on the process-wide rate limiter before every SCC request.
+// connect-ng drops the response headers of failed requests, so Do is reimplemented here to keep Retry-After;
+// errors are still parsed by connection.ErrorFromResponse and everything else matches connection.ApiConnection.
+type rateLimitedConnection struct {
+	*connection.ApiConnection
+}
+
+func newRateLimitedConnection(opts connection.Options, creds connection.Credentials) rateLimitedConnection {
+	return rateLimitedConnection{ApiConnection: connection.New(opts, creds)}
+}
+
+func (conn rateLimitedConnection) Do(request *http.Request) ([]byte, error) {
+	if err := sccRateLimiter.Wait(request.Context()); err != nil {
+		return nil, fmt.Errorf("cannot wait for SCC request rate limit: %w", err)
+	}
+
+	rotateToken := !conn.Options.DisableTokenHandling
+	if rotateToken {
+		token, tokenErr := conn.Credentials.Token()
+		if tokenErr != nil {
+			return nil, tokenErr
+		}
+		request.Header.Set("System-Token", token)
+	}
+
+	response, doErr := conn.httpClient().Do(request)
+	if doErr != nil {
+		return nil, doErr
+	}
+	defer response.Body.Close()
+
+	if rotateToken {
+		if err := conn.Credentials.UpdateToken(response.Header.Get("System-Token")); err != nil {
+			return nil, err
+		}
+	}
+
+	if apiError := connection.ErrorFromResponse(response); apiError != nil {
+		if apiError.Code == http.StatusTooManyRequests {
+			return nil, &RateLimitError{
+				ApiError:   apiError,
+				RetryAfter: parseRetryAfter(response.Header.Get("Retry-After"), time.Now()),
+			}
+		}
+		return nil, apiError
+	}
+
+	data, readErr := io.ReadAll(response.Body)
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	if conn.ProfileCache != nil {
+		if response.Header.Get("X-System-Profiles-Action") == "clear-cache" {
+			conn.ProfileCache.Clear()
+		} else {
+			conn.ProfileCache.ResetClearCount()
+		}
+	}
+
+	return data, nil
+}
+
+func (conn rateLimitedConnection) httpClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: !conn.Options.Secure}
+	if conn.Options.Proxy != nil {
+		transport.Proxy = conn.Options.Proxy
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if conn.Options.Certificate != nil {
+		pool.AddCert(conn.Options.Certificate)
+	}
+	transport.TLSClientConfig.RootCAs = pool
+
+	return &http.Client{Transport: transport, Timeout: conn.Options.Timeout}
+}
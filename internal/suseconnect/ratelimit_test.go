@@ -0,0 +1,70 @@
+package suseconnect
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SUSE/connect-ng/pkg/connection"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rancher/scc-operator/internal/consts"
+	"github.com/rancher/scc-operator/internal/telemetry"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 18, 22, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, 120*time.Second, parseRetryAfter("120", now))
+	assert.Equal(t, 15*time.Minute, parseRetryAfter(" 900 ", now))
+	assert.Equal(t, 90*time.Second, parseRetryAfter(now.Add(90*time.Second).Format(http.TimeFormat), now))
+	assert.Zero(t, parseRetryAfter(now.Add(-time.Minute).Format(http.TimeFormat), now))
+	assert.Zero(t, parseRetryAfter("-5", now))
+	// Without a usable header the default wait applies
+	assert.Equal(t, consts.DefaultSCCRateLimitRetryAfter, parseRetryAfter("", now))
+	assert.Equal(t, consts.DefaultSCCRateLimitRetryAfter, parseRetryAfter("soon", now))
+}
+
+func TestRateLimitedResponseKeepsRetryAfter(t *testing.T) {
+	retryAfter := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if retryAfter != "" {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"error": "slow down"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	params := OnlineConnectionParams{
+		RegistrationURL: server.URL,
+		Options:         DefaultConnectionOptions("scc-operator-test", "0.0.1"),
+	}
+	sccConnection := OnlineRancherConnection(params, mockCredentials(), telemetry.MetricsWrapper{})
+
+	retryAfter = "45"
+	_, err := sccConnection.ActivationStatus()
+	require.Error(t, err)
+	assert.True(t, IsRateLimitError(err))
+	assert.Equal(t, 45*time.Second, RetryAfterFrom(err))
+	// Callers matching on the connect-ng error type keep working
+	var sccAPIError *connection.ApiError
+	require.True(t, errors.As(err, &sccAPIError))
+	assert.Equal(t, http.StatusTooManyRequests, sccAPIError.Code)
+	assert.Equal(t, "slow down", sccAPIError.Message)
+
+	retryAfter = time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	_, err = sccConnection.ActivationStatus()
+	assert.InDelta(t, time.Hour, RetryAfterFrom(err), float64(2*time.Second))
+
+	retryAfter = ""
+	_, err = sccConnection.ActivationStatus()
+	assert.Equal(t, consts.DefaultSCCRateLimitRetryAfter, RetryAfterFrom(err))
+
+	assert.False(t, IsRateLimitError(&connection.ApiError{Code: http.StatusServiceUnavailable}))
+	assert.Zero(t, RetryAfterFrom(&connection.ApiError{Code: http.StatusTooManyRequests}))
+}
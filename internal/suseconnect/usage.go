@@ -0,0 +1,54 @@
+package suseconnect
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	// maxUsageReportWindow matches how long SCC keeps online_at records before purging them
+	maxUsageReportWindow = 90 * 24 * time.Hour
+	onlineAtDateLayout   = "2006-01-02"
+)
+
+// UsageReport is the pay-as-you-go usage (hours the cluster was online) sent to SCC with a status update
+type UsageReport struct {
+	// OnlineAt holds one `YYYY-MM-DD:<24 hour bitstring>` record per UTC day with reported hours
+	OnlineAt []string
+	// Hours is the number of hours marked as online in OnlineAt
+	Hours int64
+	// Until is the (exclusive) end of the reported period; the next report should start here
+	Until time.Time
+}
+
+// NewUsageReport marks every completed UTC hour between since and now as online.
+// The hour now falls in is left for the next report so that no hour is ever reported twice.
+func NewUsageReport(since, now time.Time) UsageReport {
+	until := now.UTC().Truncate(time.Hour)
+	from := since.UTC().Truncate(time.Hour)
+	if oldest := until.Add(-maxUsageReportWindow); from.Before(oldest) {
+		from = oldest
+	}
+
+	report := UsageReport{Until: until}
+	var day string
+	var hours []byte
+	flush := func() {
+		if day != "" {
+			report.OnlineAt = append(report.OnlineAt, day+":"+string(hours))
+		}
+	}
+
+	for hour := from; hour.Before(until); hour = hour.Add(time.Hour) {
+		if hourDay := hour.Format(onlineAtDateLayout); hourDay != day {
+			flush()
+			day = hourDay
+			hours = []byte(strings.Repeat("0", 24))
+		}
+		hours[hour.Hour()] = '1'
+		report.Hours++
+	}
+	flush()
+
+	return report
+}
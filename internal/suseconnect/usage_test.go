@@ -0,0 +1,51 @@
+package suseconnect
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewUsageReport(t *testing.T) {
+	since := time.Date(2024, 1, 18, 21, 30, 0, 0, time.UTC)
+	now := time.Date(2024, 1, 19, 2, 10, 0, 0, time.UTC)
+
+	report := NewUsageReport(since, now)
+	assert.Equal(t, []string{
+		"2024-01-18:000000000000000000000111",
+		"2024-01-19:110000000000000000000000",
+	}, report.OnlineAt)
+	assert.Equal(t, int64(5), report.Hours)
+	assert.Equal(t, time.Date(2024, 1, 19, 2, 0, 0, 0, time.UTC), report.Until)
+
+	// The next report picks up where the previous one ended without reporting an hour twice
+	next := NewUsageReport(report.Until, now.Add(time.Hour))
+	assert.Equal(t, []string{"2024-01-19:001000000000000000000000"}, next.OnlineAt)
+	assert.Equal(t, int64(1), next.Hours)
+}
+
+func TestNewUsageReportWithinHour(t *testing.T) {
+	now := time.Date(2024, 1, 19, 2, 10, 0, 0, time.UTC)
+
+	report := NewUsageReport(now.Add(-5*time.Minute), now)
+	assert.Empty(t, report.OnlineAt)
+	assert.Zero(t, report.Hours)
+}
+
+func TestNewUsageReportUsesUTC(t *testing.T) {
+	zone := time.FixedZone("UTC+5", 5*60*60)
+	since := time.Date(2024, 1, 19, 3, 0, 0, 0, zone)
+
+	report := NewUsageReport(since, since.Add(time.Hour))
+	assert.Equal(t, []string{"2024-01-18:000000000000000000000010"}, report.OnlineAt)
+}
+
+func TestNewUsageReportCapsWindow(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	report := NewUsageReport(now.Add(-365*24*time.Hour), now)
+	assert.Equal(t, int64(maxUsageReportWindow/time.Hour), report.Hours)
+	assert.Len(t, report.OnlineAt, 90)
+	assert.Equal(t, "2024-03-03:111111111111111111111111", report.OnlineAt[0])
+}
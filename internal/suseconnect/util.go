@@ -1,6 +1,8 @@
 package suseconnect
 
 import (
+	"fmt"
+
 	corev1 "k8s.io/api/core/v1"
 
 	"github.com/rancher/scc-operator/internal/consts"
@@ -24,3 +26,18 @@ func FetchSccRegistrationCodeFrom(secretRepo *secretrepo.SecretRepository, refer
 
 	return string(regCode)
 }
+
+// FetchInstanceDataFrom reads the cloud instance data used by pay-as-you-go registrations; a missing reference is not an error
+func FetchInstanceDataFrom(secretRepo *secretrepo.SecretRepository, reference *corev1.SecretReference) (string, error) {
+	if reference == nil {
+		return "", nil
+	}
+
+	sccContextLogger().Debugf("Fetching instance data from secret %s/%s", reference.Namespace, reference.Name)
+	instanceDataSecret, err := secretRepo.Cache.Get(reference.Namespace, reference.Name)
+	if err != nil {
+		return "", fmt.Errorf("failed to get instance data secret %s/%s: %w", reference.Namespace, reference.Name, err)
+	}
+
+	return string(instanceDataSecret.Data[consts.SecretKeyInstanceData]), nil
+}
@@ -2,6 +2,7 @@ package suseconnect
 
 import (
 	"fmt"
+	"net/http"
 
 	"github.com/SUSE/connect-ng/pkg/connection"
 	"github.com/SUSE/connect-ng/pkg/registration"
@@ -17,10 +18,24 @@ func sccContextLogger() rootLog.StructuredLogger {
 	return logBuilder.ToLogger()
 }
 
+// SCCClient is the set of SCC API calls made by the connected registration modes.
+// SccWrapper sends them to SCC, while DryRunWrapper only records what would have been sent.
+type SCCClient interface {
+	SystemRegistration(regCode string) (RegistrationSystemID, error)
+	KeepAlive() error
+	RegisterOrKeepAlive(regCode string) (RegistrationSystemID, error)
+	RegisterPayAsYouGo(instanceData string) (RegistrationSystemID, error)
+	ReportUsage(instanceData string, report UsageReport) error
+	Activate(regCode string) (*registration.Metadata, *registration.Product, error)
+	ActivationStatus() ([]*registration.Activation, error)
+	ProductInfo() (*registration.Product, error)
+	Deregister() error
+}
+
 type SccWrapper struct {
 	rancherURL     string
 	credentials    connection.Credentials
-	conn           *connection.ApiConnection
+	conn           connection.Connection
 	registered     *bool // only used by online mode
 	rancherMetrics telemetry.MetricsWrapper
 }
@@ -59,7 +74,7 @@ func OnlineRancherConnection(
 	return SccWrapper{
 		rancherURL:     params.RancherURL,
 		credentials:    credentials,
-		conn:           connection.New(params.Options, credentials),
+		conn:           newRateLimitedConnection(params.Options, credentials),
 		registered:     &registered,
 		rancherMetrics: rancherMetrics,
 	}
@@ -92,6 +107,7 @@ const (
 	ErrorRegistrationSystemID     RegistrationSystemID = -1 // Used when error is related to registration
 	KeepAliveRegistrationSystemID RegistrationSystemID = -2 // Indicates the Registration was handled via keepalive instead
 	OfflineRegistrationSystemID   RegistrationSystemID = -3
+	DryRunRegistrationSystemID    RegistrationSystemID = -4 // Stands in for the ID SCC would assign, as dry-run never announces the system
 )
 
 func (sw *SccWrapper) SystemRegistration(regCode string) (RegistrationSystemID, error) {
@@ -132,6 +148,65 @@ func (sw *SccWrapper) RegisterOrKeepAlive(regCode string) (RegistrationSystemID,
 	return sw.SystemRegistration(regCode)
 }
 
+// RegisterPayAsYouGo announces a pay-as-you-go system; SCC identifies these by instance data rather than a registration code
+func (sw *SccWrapper) RegisterPayAsYouGo(instanceData string) (RegistrationSystemID, error) {
+	if *sw.registered {
+		return KeepAliveRegistrationSystemID, sw.KeepAlive()
+	}
+
+	id, regErr := registration.Register(sw.conn, "", sw.rancherURL, sw.rancherMetrics.ToSystemInformation(), payAsYouGoExtraData(instanceData, nil))
+	if regErr != nil {
+		return ErrorRegistrationSystemID, errors.Wrap(regErr, "Cannot register pay-as-you-go system to SCC")
+	}
+
+	return RegistrationSystemID(id), nil
+}
+
+// ReportUsage sends a keepalive carrying the online hours of a pay-as-you-go system.
+// The error SCC answered with is kept, so callers can tell a rejected report from one that may have been counted.
+func (sw *SccWrapper) ReportUsage(instanceData string, report UsageReport) error {
+	conn := &doErrorRecorder{Connection: sw.conn}
+	status, statusErr := registration.Status(
+		conn,
+		sw.rancherURL,
+		sw.rancherMetrics.ToSystemInformation(),
+		registration.NoExtraData,
+		payAsYouGoExtraData(instanceData, report.OnlineAt),
+	)
+	if statusErr != nil {
+		return statusErr
+	}
+	if status != registration.Registered {
+		return fmt.Errorf("trying to report usage on a system that is not yet registered. register this system first: %w", conn.doErr)
+	}
+
+	return nil
+}
+
+// doErrorRecorder keeps the error of the last request, which registration.Status turns into a bare Unregistered status
+type doErrorRecorder struct {
+	connection.Connection
+	doErr error
+}
+
+func (conn *doErrorRecorder) Do(request *http.Request) ([]byte, error) {
+	data, err := conn.Connection.Do(request)
+	conn.doErr = err
+	return data, err
+}
+
+func payAsYouGoExtraData(instanceData string, onlineAt []string) registration.ExtraData {
+	extraData := registration.ExtraData{}
+	if instanceData != "" {
+		extraData["instance_data"] = instanceData
+	}
+	if len(onlineAt) > 0 {
+		extraData["online_at"] = onlineAt
+	}
+
+	return extraData
+}
+
 func (sw *SccWrapper) Activate(regCode string) (*registration.Metadata, *registration.Product, error) {
 	identifier, version, arch := sw.rancherMetrics.GetProductIdentifier()
 	metaData, product, err := registration.Activate(sw.conn, identifier, version, arch, regCode)
@@ -159,6 +234,8 @@ func (sw *SccWrapper) Deregister() error {
 	return registration.Deregister(sw.conn)
 }
 
+var _ SCCClient = &SccWrapper{}
+
 func PrepareSccURL(regIn *v1.Registration) string {
 	if regIn != nil && regIn.Spec.RegistrationRequest != nil && regIn.Spec.RegistrationRequest.RegistrationAPIUrl != nil {
 		return *regIn.Spec.RegistrationRequest.RegistrationAPIUrl
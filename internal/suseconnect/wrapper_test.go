@@ -1,10 +1,18 @@
 package suseconnect
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/SUSE/connect-ng/pkg/connection"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rancher/scc-operator/internal/telemetry"
 )
 
 func TestDefaultConnectionOptionsBasic(t *testing.T) {
@@ -33,3 +41,102 @@ func TestDefaultRancherConnection(t *testing.T) {
 
 	//assert.Equal(t, expected, DefaultRancherConnection(connection.NoCredentials{}))
 }
+
+// newPayAsYouGoServer stubs the SCC endpoints used by pay-as-you-go registrations and records the decoded request bodies
+func newPayAsYouGoServer(t *testing.T) (*httptest.Server, map[string]map[string]any) {
+	t.Helper()
+	requests := map[string]map[string]any{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]any{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		requests[r.Method+" "+r.URL.Path] = body
+		body["authorization"] = r.Header.Get("Authorization")
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method + " " + r.URL.Path {
+		case "POST /connect/subscriptions/systems":
+			_, _ = w.Write([]byte(`{"id": 1234, "login": "SCC_login", "password": "sccpassword"}`))
+		case "PUT /connect/systems":
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, requests
+}
+
+func TestPayAsYouGoRegistrationAndUsage(t *testing.T) {
+	server, requests := newPayAsYouGoServer(t)
+	params := OnlineConnectionParams{
+		RancherURL:      "https://rancher.example.com",
+		RegistrationURL: server.URL,
+		Options:         DefaultConnectionOptions("scc-operator-test", "0.0.1"),
+	}
+
+	unregistered := connection.NewMockCredentials()
+	unregistered.On("HasAuthentication").Return(false)
+	unregistered.On("SetLogin", "SCC_login", "sccpassword").Return(nil)
+	unregistered.On("Token").Return("", nil)
+	unregistered.On("UpdateToken", mock.Anything).Return(nil)
+	announceConnection := OnlineRancherConnection(params, unregistered, telemetry.MetricsWrapper{})
+	id, err := announceConnection.RegisterPayAsYouGo("<instance document>")
+	require.NoError(t, err)
+	assert.Equal(t, RegistrationSystemID(1234), id)
+	unregistered.AssertExpectations(t)
+
+	announce := requests["POST /connect/subscriptions/systems"]
+	require.NotNil(t, announce)
+	assert.Equal(t, "<instance document>", announce["instance_data"])
+	assert.Equal(t, "Token token=", announce["authorization"])
+
+	since := time.Date(2024, 1, 18, 22, 0, 0, 0, time.UTC)
+	report := NewUsageReport(since, since.Add(2*time.Hour))
+	registered := connection.NewMockCredentials()
+	registered.On("HasAuthentication").Return(true)
+	registered.On("Login").Return("SCC_login", "sccpassword", nil)
+	registered.On("Token").Return("", nil)
+	registered.On("UpdateToken", mock.Anything).Return(nil)
+	usageConnection := OnlineRancherConnection(params, registered, telemetry.MetricsWrapper{})
+	require.NoError(t, usageConnection.ReportUsage("<instance document>", report))
+
+	status := requests["PUT /connect/systems"]
+	require.NotNil(t, status)
+	assert.Equal(t, []any{"2024-01-18:000000000000000000000011"}, status["online_at"])
+	assert.Equal(t, "<instance document>", status["instance_data"])
+}
+
+func TestPayAsYouGoUsageRequiresRegistration(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	t.Cleanup(server.Close)
+	params := OnlineConnectionParams{
+		RegistrationURL: server.URL,
+		Options:         DefaultConnectionOptions("scc-operator-test", "0.0.1"),
+	}
+
+	usageConnection := OnlineRancherConnection(params, mockCredentials(), telemetry.MetricsWrapper{})
+	err := usageConnection.ReportUsage("", UsageReport{})
+	assert.ErrorContains(t, err, "not yet registered")
+}
+
+func TestPayAsYouGoUsageKeepsSCCError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"error": "maintenance"}`))
+	}))
+	t.Cleanup(server.Close)
+	params := OnlineConnectionParams{
+		RegistrationURL: server.URL,
+		Options:         DefaultConnectionOptions("scc-operator-test", "0.0.1"),
+	}
+
+	usageConnection := OnlineRancherConnection(params, mockCredentials(), telemetry.MetricsWrapper{})
+	err := usageConnection.ReportUsage("", UsageReport{})
+	var sccAPIError *connection.ApiError
+	require.ErrorAs(t, err, &sccAPIError)
+	assert.Equal(t, http.StatusServiceUnavailable, sccAPIError.Code)
+}
@@ -50,8 +50,13 @@ func (a ActivationPhase) GroupName() string {
 }
 
 func (a ActivationPhase) IndividualName() string {
-	return [...]string{"Init", "Main"}[a]
+	return [...]string{"Init", "Main", "PrepForKeepalive"}[a]
 }
 
 var _ Phase = RegistrationPhase(0)
 var _ Phase = ActivationPhase(0)
+
+// PhaseName is the `Group/Individual` name of a phase used in Events and the sync history
+func PhaseName(p Phase) string {
+	return p.GroupName() + "/" + p.IndividualName()
+}
@@ -0,0 +1,210 @@
+/*
+Package validation holds the rules used to decide if SCC entrypoint Secrets and Registrations are valid.
+
+These rules are shared by the controllers (when extracting registration params) and the admission webhook,
+so that a bad object is rejected up front with the same message the controller would have logged later.
+*/
+package validation
+
+import (
+	"fmt"
+	"net/url"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	certutil "k8s.io/client-go/util/cert"
+
+	"github.com/rancher/scc-operator/internal/consts"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+)
+
+var (
+	dataPath = field.NewPath("data")
+	specPath = field.NewPath("spec")
+)
+
+func validModes() []string {
+	return []string{
+		string(v1.RegistrationModeOnline),
+		string(v1.RegistrationModeOffline),
+		string(v1.RegistrationModeRMT),
+		string(v1.RegistrationModePayAsYouGo),
+	}
+}
+
+func validDeregistrationModes() []string {
+	return []string{
+		string(v1.DeregistrationBestEffort),
+		string(v1.DeregistrationRequiredWithTimeout),
+		string(v1.DeregistrationSkip),
+	}
+}
+
+// EntrypointSecretMode returns the registration mode requested by an entrypoint Secret; online is used when unset
+func EntrypointSecretMode(data map[string][]byte) (v1.RegistrationMode, error) {
+	regType, ok := data[consts.SecretKeyRegistrationType]
+	if !ok || len(regType) == 0 {
+		return v1.RegistrationModeOnline, nil
+	}
+
+	regMode := v1.RegistrationMode(regType)
+	if !regMode.Valid() {
+		return regMode, field.NotSupported(dataPath.Key(consts.SecretKeyRegistrationType), string(regMode), validModes())
+	}
+
+	return regMode, nil
+}
+
+// EntrypointSecretForMode validates the entrypoint Secret data against the rules of a specific registration mode
+func EntrypointSecretForMode(data map[string][]byte, regMode v1.RegistrationMode) field.ErrorList {
+	var errs field.ErrorList
+
+	regCode, ok := data[consts.SecretKeyRegistrationCode]
+	if (!ok || len(regCode) == 0) && regMode == v1.RegistrationModeOnline {
+		errs = append(errs, field.Required(dataPath.Key(consts.SecretKeyRegistrationCode), "this is required in online mode"))
+	}
+
+	regURL, ok := data[consts.RegistrationURL]
+	if ok && len(regURL) > 0 {
+		errs = append(errs, validateURL(dataPath.Key(consts.RegistrationURL), string(regURL))...)
+	} else if regMode == v1.RegistrationModeRMT {
+		errs = append(errs, field.Required(dataPath.Key(consts.RegistrationURL), "the RMT server URL is required in rmt mode"))
+	}
+
+	if instanceData, ok := data[consts.SecretKeyInstanceData]; ok && len(instanceData) > 0 && regMode != v1.RegistrationModePayAsYouGo {
+		errs = append(errs, field.Forbidden(dataPath.Key(consts.SecretKeyInstanceData), "instance data is only used in payg mode"))
+	}
+
+	errs = append(errs, validateEntrypointProxy(data)...)
+
+	if caCert, ok := data[consts.SecretKeyRegistrationCACert]; ok && len(caCert) > 0 {
+		if _, err := certutil.ParseCertsPEM(caCert); err != nil {
+			errs = append(errs, field.Invalid(dataPath.Key(consts.SecretKeyRegistrationCACert), "<certificate>", err.Error()))
+		}
+	}
+
+	return errs
+}
+
+// validateEntrypointProxy checks the proxy keys of an entrypoint Secret; credentials are only valid alongside a proxy URL
+func validateEntrypointProxy(data map[string][]byte) field.ErrorList {
+	var errs field.ErrorList
+
+	proxyURL := data[consts.SecretKeyProxyURL]
+	if len(proxyURL) > 0 {
+		errs = append(errs, validateURL(dataPath.Key(consts.SecretKeyProxyURL), string(proxyURL))...)
+	}
+
+	for _, key := range []string{consts.SecretKeyProxyUsername, consts.SecretKeyProxyPassword, consts.SecretKeyNoProxy} {
+		if len(data[key]) > 0 && len(proxyURL) == 0 {
+			errs = append(errs, field.Forbidden(dataPath.Key(key), fmt.Sprintf("requires `%s` to be set", consts.SecretKeyProxyURL)))
+		}
+	}
+	if len(data[consts.SecretKeyProxyPassword]) > 0 && len(data[consts.SecretKeyProxyUsername]) == 0 {
+		errs = append(errs, field.Required(dataPath.Key(consts.SecretKeyProxyUsername), "a proxy password requires a username"))
+	}
+
+	return errs
+}
+
+// EntrypointSecret validates an SCC entrypoint Secret
+func EntrypointSecret(secret *corev1.Secret) error {
+	regMode, err := EntrypointSecretMode(secret.Data)
+	if err != nil {
+		return err
+	}
+
+	return EntrypointSecretForMode(secret.Data, regMode).ToAggregate()
+}
+
+// RegistrationSpec validates the spec of a Registration
+func RegistrationSpec(spec *v1.RegistrationSpec) error {
+	var errs field.ErrorList
+
+	if !spec.Mode.Valid() {
+		errs = append(errs, field.NotSupported(specPath.Child("mode"), string(spec.Mode), validModes()))
+	}
+
+	requestPath := specPath.Child("registrationRequest")
+	if spec.Mode == v1.RegistrationModeOnline {
+		if spec.RegistrationRequest == nil {
+			errs = append(errs, field.Required(requestPath, "this is required in online mode"))
+		} else if spec.RegistrationRequest.RegistrationCodeSecretRef == nil {
+			errs = append(errs, field.Required(requestPath.Child("registrationCodeSecretRef"), "this is required in online mode"))
+		}
+	}
+	if spec.Mode == v1.RegistrationModeRMT {
+		if spec.RegistrationRequest == nil {
+			errs = append(errs, field.Required(requestPath, "this is required in rmt mode"))
+		} else if spec.RegistrationRequest.RegistrationAPIUrl == nil || *spec.RegistrationRequest.RegistrationAPIUrl == "" {
+			errs = append(errs, field.Required(requestPath.Child("registrationAPIUrl"), "the RMT server URL is required in rmt mode"))
+		}
+	}
+
+	if spec.Mode == v1.RegistrationModePayAsYouGo && spec.RegistrationRequest == nil {
+		errs = append(errs, field.Required(requestPath, "this is required in payg mode"))
+	}
+
+	if spec.RegistrationRequest != nil {
+		errs = append(errs, validateSecretRef(requestPath.Child("registrationCodeSecretRef"), spec.RegistrationRequest.RegistrationCodeSecretRef)...)
+		errs = append(errs, validateSecretRef(requestPath.Child("instanceDataSecretRef"), spec.RegistrationRequest.InstanceDataSecretRef)...)
+		if proxy := spec.RegistrationRequest.Proxy; proxy != nil {
+			proxyPath := requestPath.Child("proxy")
+			if proxy.URL == "" {
+				errs = append(errs, field.Required(proxyPath.Child("url"), "a proxy URL is required"))
+			} else {
+				errs = append(errs, validateURL(proxyPath.Child("url"), proxy.URL)...)
+			}
+			errs = append(errs, validateSecretRef(proxyPath.Child("credentialsSecretRef"), proxy.CredentialsSecretRef)...)
+		}
+		errs = append(errs, validateSecretRef(requestPath.Child("registrationAPICertificateSecretRef"), spec.RegistrationRequest.RegistrationAPICertificateSecretRef)...)
+		if spec.RegistrationRequest.RegistrationAPIUrl != nil {
+			errs = append(errs, validateURL(requestPath.Child("registrationAPIUrl"), *spec.RegistrationRequest.RegistrationAPIUrl)...)
+		}
+	}
+
+	errs = append(errs, validateSecretRef(specPath.Child("offlineRegistrationCertificateSecretRef"), spec.OfflineRegistrationCertificateSecretRef)...)
+
+	if deregistration := spec.Deregistration; deregistration != nil {
+		deregistrationPath := specPath.Child("deregistration")
+		if !deregistration.Mode.Valid() {
+			errs = append(errs, field.NotSupported(deregistrationPath.Child("mode"), string(deregistration.Mode), validDeregistrationModes()))
+		}
+		if deregistration.Timeout != nil && deregistration.Timeout.Duration <= 0 {
+			errs = append(errs, field.Invalid(deregistrationPath.Child("timeout"), deregistration.Timeout.Duration.String(), "must be positive"))
+		}
+	}
+
+	return errs.ToAggregate()
+}
+
+func validateSecretRef(path *field.Path, ref *corev1.SecretReference) field.ErrorList {
+	if ref == nil {
+		return nil
+	}
+
+	var errs field.ErrorList
+	if ref.Name == "" {
+		errs = append(errs, field.Required(path.Child("name"), "secret reference must have a name"))
+	}
+	if ref.Namespace == "" {
+		errs = append(errs, field.Required(path.Child("namespace"), "secret reference must have a namespace"))
+	}
+
+	return errs
+}
+
+func validateURL(path *field.Path, rawURL string) field.ErrorList {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return field.ErrorList{field.Invalid(path, rawURL, err.Error())}
+	}
+	if parsedURL.Scheme != "https" && parsedURL.Scheme != "http" {
+		return field.ErrorList{field.Invalid(path, rawURL, "must be an http or https URL")}
+	}
+	if parsedURL.Host == "" {
+		return field.ErrorList{field.Invalid(path, rawURL, "must include a host")}
+	}
+
+	return nil
+}
@@ -0,0 +1,311 @@
+package validation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	certutil "k8s.io/client-go/util/cert"
+
+	"github.com/rancher/scc-operator/internal/consts"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+)
+
+func TestEntrypointSecretMode(t *testing.T) {
+	mode, err := EntrypointSecretMode(map[string][]byte{})
+	assert.NoError(t, err)
+	assert.Equal(t, v1.RegistrationModeOnline, mode)
+
+	mode, err = EntrypointSecretMode(map[string][]byte{consts.SecretKeyRegistrationType: []byte("offline")})
+	assert.NoError(t, err)
+	assert.Equal(t, v1.RegistrationModeOffline, mode)
+
+	_, err = EntrypointSecretMode(map[string][]byte{consts.SecretKeyRegistrationType: []byte("nope")})
+	assert.ErrorContains(t, err, "data[registrationType]")
+}
+
+func TestEntrypointSecret(t *testing.T) {
+	var tests = []struct {
+		name    string
+		data    map[string][]byte
+		wantErr string
+	}{
+		{
+			name: "online with reg code",
+			data: map[string][]byte{consts.SecretKeyRegistrationCode: []byte("code")},
+		},
+		{
+			name:    "online without reg code",
+			data:    map[string][]byte{consts.SecretKeyRegistrationType: []byte("online")},
+			wantErr: "data[regCode]: Required value",
+		},
+		{
+			name: "offline without reg code",
+			data: map[string][]byte{consts.SecretKeyRegistrationType: []byte("offline")},
+		},
+		{
+			name:    "invalid mode",
+			data:    map[string][]byte{consts.SecretKeyRegistrationType: []byte("sideways")},
+			wantErr: "Unsupported value: \"sideways\"",
+		},
+		{
+			name: "invalid registration URL",
+			data: map[string][]byte{
+				consts.SecretKeyRegistrationCode: []byte("code"),
+				consts.RegistrationURL:           []byte("ftp://scc.example.com"),
+			},
+			wantErr: "data[registrationUrl]: Invalid value",
+		},
+		{
+			name:    "rmt without URL",
+			data:    map[string][]byte{consts.SecretKeyRegistrationType: []byte("rmt")},
+			wantErr: "data[registrationUrl]: Required value",
+		},
+		{
+			name: "rmt without reg code",
+			data: map[string][]byte{
+				consts.SecretKeyRegistrationType: []byte("rmt"),
+				consts.RegistrationURL:           []byte("https://rmt.example.com"),
+			},
+		},
+		{
+			name: "payg with instance data",
+			data: map[string][]byte{
+				consts.SecretKeyRegistrationType: []byte("payg"),
+				consts.SecretKeyInstanceData:     []byte("<instance document>"),
+			},
+		},
+		{
+			name: "instance data outside payg",
+			data: map[string][]byte{
+				consts.SecretKeyRegistrationCode: []byte("code"),
+				consts.SecretKeyInstanceData:     []byte("<instance document>"),
+			},
+			wantErr: "data[instanceData]: Forbidden",
+		},
+		{
+			name: "proxy with credentials",
+			data: map[string][]byte{
+				consts.SecretKeyRegistrationCode: []byte("code"),
+				consts.SecretKeyProxyURL:         []byte("http://proxy.example.com:3128"),
+				consts.SecretKeyProxyUsername:    []byte("proxy-user"),
+				consts.SecretKeyProxyPassword:    []byte("proxy-password"),
+				consts.SecretKeyNoProxy:          []byte(".internal.example.com"),
+			},
+		},
+		{
+			name: "proxy credentials without URL",
+			data: map[string][]byte{
+				consts.SecretKeyRegistrationCode: []byte("code"),
+				consts.SecretKeyProxyUsername:    []byte("proxy-user"),
+			},
+			wantErr: "data[proxyUsername]: Forbidden",
+		},
+		{
+			name: "proxy password without username",
+			data: map[string][]byte{
+				consts.SecretKeyRegistrationCode: []byte("code"),
+				consts.SecretKeyProxyURL:         []byte("http://proxy.example.com:3128"),
+				consts.SecretKeyProxyPassword:    []byte("proxy-password"),
+			},
+			wantErr: "data[proxyUsername]: Required value",
+		},
+		{
+			name: "invalid proxy URL",
+			data: map[string][]byte{
+				consts.SecretKeyRegistrationCode: []byte("code"),
+				consts.SecretKeyProxyURL:         []byte("proxy.example.com"),
+			},
+			wantErr: "data[proxyUrl]: Invalid value",
+		},
+		{
+			name: "valid registration URL",
+			data: map[string][]byte{
+				consts.SecretKeyRegistrationCode: []byte("code"),
+				consts.RegistrationURL:           []byte("https://rmt.example.com"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := EntrypointSecret(&corev1.Secret{Data: tt.data})
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestRegistrationSpec(t *testing.T) {
+	validRef := &corev1.SecretReference{Name: "registration-code-abc", Namespace: consts.DefaultSCCNamespace}
+	badURL := "not a url"
+	rmtURL := "https://rmt.example.com"
+
+	var tests = []struct {
+		name    string
+		spec    v1.RegistrationSpec
+		wantErr string
+	}{
+		{
+			name: "valid online",
+			spec: v1.RegistrationSpec{
+				Mode:                v1.RegistrationModeOnline,
+				RegistrationRequest: &v1.RegistrationRequest{RegistrationCodeSecretRef: validRef},
+			},
+		},
+		{
+			name:    "online without request",
+			spec:    v1.RegistrationSpec{Mode: v1.RegistrationModeOnline},
+			wantErr: "spec.registrationRequest: Required value",
+		},
+		{
+			name: "online without code ref",
+			spec: v1.RegistrationSpec{
+				Mode:                v1.RegistrationModeOnline,
+				RegistrationRequest: &v1.RegistrationRequest{},
+			},
+			wantErr: "spec.registrationRequest.registrationCodeSecretRef: Required value",
+		},
+		{
+			name: "online with bad URL",
+			spec: v1.RegistrationSpec{
+				Mode: v1.RegistrationModeOnline,
+				RegistrationRequest: &v1.RegistrationRequest{
+					RegistrationCodeSecretRef: validRef,
+					RegistrationAPIUrl:        &badURL,
+				},
+			},
+			wantErr: "spec.registrationRequest.registrationAPIUrl: Invalid value",
+		},
+		{
+			name: "valid offline",
+			spec: v1.RegistrationSpec{Mode: v1.RegistrationModeOffline},
+		},
+		{
+			name: "offline with nameless cert ref",
+			spec: v1.RegistrationSpec{
+				Mode:                                    v1.RegistrationModeOffline,
+				OfflineRegistrationCertificateSecretRef: &corev1.SecretReference{Namespace: consts.DefaultSCCNamespace},
+			},
+			wantErr: "spec.offlineRegistrationCertificateSecretRef.name: Required value",
+		},
+		{
+			name: "valid rmt",
+			spec: v1.RegistrationSpec{
+				Mode:                v1.RegistrationModeRMT,
+				RegistrationRequest: &v1.RegistrationRequest{RegistrationAPIUrl: &rmtURL},
+			},
+		},
+		{
+			name: "rmt without URL",
+			spec: v1.RegistrationSpec{
+				Mode:                v1.RegistrationModeRMT,
+				RegistrationRequest: &v1.RegistrationRequest{},
+			},
+			wantErr: "spec.registrationRequest.registrationAPIUrl: Required value",
+		},
+		{
+			name: "valid payg",
+			spec: v1.RegistrationSpec{
+				Mode:                v1.RegistrationModePayAsYouGo,
+				RegistrationRequest: &v1.RegistrationRequest{},
+			},
+		},
+		{
+			name:    "payg without request",
+			spec:    v1.RegistrationSpec{Mode: v1.RegistrationModePayAsYouGo},
+			wantErr: "spec.registrationRequest: Required value",
+		},
+		{
+			name: "payg instance data ref without name",
+			spec: v1.RegistrationSpec{
+				Mode: v1.RegistrationModePayAsYouGo,
+				RegistrationRequest: &v1.RegistrationRequest{
+					InstanceDataSecretRef: &corev1.SecretReference{Namespace: consts.DefaultSCCNamespace},
+				},
+			},
+			wantErr: "spec.registrationRequest.instanceDataSecretRef.name: Required value",
+		},
+		{
+			name: "proxy without URL",
+			spec: v1.RegistrationSpec{
+				Mode: v1.RegistrationModePayAsYouGo,
+				RegistrationRequest: &v1.RegistrationRequest{
+					Proxy: &v1.ProxyConfig{NoProxy: []string{"10.0.0.0/8"}},
+				},
+			},
+			wantErr: "spec.registrationRequest.proxy.url: Required value",
+		},
+		{
+			name: "proxy credentials ref without namespace",
+			spec: v1.RegistrationSpec{
+				Mode: v1.RegistrationModePayAsYouGo,
+				RegistrationRequest: &v1.RegistrationRequest{
+					Proxy: &v1.ProxyConfig{
+						URL:                  "http://proxy.example.com:3128",
+						CredentialsSecretRef: &corev1.SecretReference{Name: "proxy-credentials"},
+					},
+				},
+			},
+			wantErr: "spec.registrationRequest.proxy.credentialsSecretRef.namespace: Required value",
+		},
+		{
+			name:    "invalid mode",
+			spec:    v1.RegistrationSpec{Mode: "sideways"},
+			wantErr: "spec.mode: Unsupported value",
+		},
+		{
+			name: "valid deregistration policy",
+			spec: v1.RegistrationSpec{
+				Mode:           v1.RegistrationModeOffline,
+				Deregistration: &v1.DeregistrationPolicy{Mode: v1.DeregistrationRequiredWithTimeout, Timeout: &metav1.Duration{Duration: time.Hour}},
+			},
+		},
+		{
+			name: "invalid deregistration mode",
+			spec: v1.RegistrationSpec{
+				Mode:           v1.RegistrationModeOffline,
+				Deregistration: &v1.DeregistrationPolicy{Mode: "eventually"},
+			},
+			wantErr: "spec.deregistration.mode: Unsupported value",
+		},
+		{
+			name: "negative deregistration timeout",
+			spec: v1.RegistrationSpec{
+				Mode:           v1.RegistrationModeOffline,
+				Deregistration: &v1.DeregistrationPolicy{Mode: v1.DeregistrationRequiredWithTimeout, Timeout: &metav1.Duration{Duration: -time.Hour}},
+			},
+			wantErr: "spec.deregistration.timeout: Invalid value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := RegistrationSpec(&tt.spec)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestEntrypointSecretRegistrationCACert(t *testing.T) {
+	caPEM, _, err := certutil.GenerateSelfSignedCertKey("rmt.example.com", nil, nil)
+	assert.NoError(t, err)
+
+	data := map[string][]byte{
+		consts.SecretKeyRegistrationCode:   []byte("code"),
+		consts.SecretKeyRegistrationCACert: caPEM,
+	}
+	assert.NoError(t, EntrypointSecret(&corev1.Secret{Data: data}))
+
+	data[consts.SecretKeyRegistrationCACert] = []byte("not a certificate")
+	assert.ErrorContains(t, EntrypointSecret(&corev1.Secret{Data: data}), "data[registrationCACert]: Invalid value")
+}
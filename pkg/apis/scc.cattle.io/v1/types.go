@@ -1,6 +1,8 @@
 package v1
 
 import (
+	"time"
+
 	"github.com/rancher/wrangler/v3/pkg/condition"
 	"github.com/rancher/wrangler/v3/pkg/genericcondition"
 	corev1 "k8s.io/api/core/v1"
@@ -8,33 +10,64 @@ import (
 )
 
 // RegistrationMode enforces the valid registration modes
-// +kubebuilder:validation:Enum=online;offline
+// +kubebuilder:validation:Enum=online;offline;rmt;payg
 type RegistrationMode string
 
 func (rm *RegistrationMode) Valid() bool {
-	return *rm == RegistrationModeOnline || *rm == RegistrationModeOffline
+	return *rm == RegistrationModeOnline ||
+		*rm == RegistrationModeOffline ||
+		*rm == RegistrationModeRMT ||
+		*rm == RegistrationModePayAsYouGo
 }
 
 const (
 	RegistrationModeOnline  RegistrationMode = "online"
 	RegistrationModeOffline RegistrationMode = "offline"
+	// RegistrationModeRMT registers against a user provided RMT server; no registration code is used
+	RegistrationModeRMT RegistrationMode = "rmt"
+	// RegistrationModePayAsYouGo registers cloud marketplace clusters that are billed by usage; no registration code is used
+	RegistrationModePayAsYouGo RegistrationMode = "payg"
 )
 
-// resource conditions ordered by: general-use, offline specific, general registration
+// resource conditions ordered by: general-use, offline specific, rmt specific, payg specific, general registration
 const (
 	ResourceConditionDone        condition.Cond = "Done"
 	ResourceConditionFailure     condition.Cond = "Failure"
 	ResourceConditionProgressing condition.Cond = "Progressing"
 	ResourceConditionReady       condition.Cond = "Ready"
+	// ResourceConditionSuspended is True while spec.suspend stops all SCC traffic for the registration
+	ResourceConditionSuspended condition.Cond = "Suspended"
+	// ResourceConditionDeregistered is True after the deregister action, stopping all SCC traffic until a reannounce action
+	ResourceConditionDeregistered condition.Cond = "Deregistered"
 
 	RegistrationConditionOfflineRequestReady     condition.Cond = "OfflineRequestReady"
 	RegistrationConditionOfflineCertificateReady condition.Cond = "OfflineCertificateReady"
 	ActivationConditionOfflineDone               condition.Cond = "OfflineActivationDone"
+	// RegistrationConditionOfflineExpired is True once the subscription of the offline registration certificate has expired
+	RegistrationConditionOfflineExpired condition.Cond = "OfflineRegistrationExpired"
+	// RegistrationConditionOfflineCertificateRotated is True once a staged replacement certificate was promoted, and False while one is rejected
+	RegistrationConditionOfflineCertificateRotated condition.Cond = "OfflineCertificateRotated"
+
+	RegistrationConditionRMTAnnounced condition.Cond = "RMTAnnounced"
+	RegistrationConditionRMTKeepalive condition.Cond = "RMTKeepalive"
+
+	RegistrationConditionPayAsYouGoAnnounced     condition.Cond = "PayAsYouGoAnnounced"
+	RegistrationConditionPayAsYouGoUsageReported condition.Cond = "PayAsYouGoUsageReported"
 
 	RegistrationConditionAnnounced   condition.Cond = "RegistrationAnnounced"
 	RegistrationConditionSccURLReady condition.Cond = "RegistrationSccUrlReady"
 	RegistrationConditionActivated   condition.Cond = "RegistrationActivated"
 	RegistrationConditionKeepalive   condition.Cond = "RegistrationKeepalive"
+	// RegistrationConditionTLSVerified is False when the SCC/RMT endpoint certificate could not be verified
+	RegistrationConditionTLSVerified condition.Cond = "RegistrationTLSVerified"
+	// RegistrationConditionProxyAuthenticated is False when the configured HTTP proxy rejected the proxy credentials
+	RegistrationConditionProxyAuthenticated condition.Cond = "RegistrationProxyAuthenticated"
+	// RegistrationConditionRateLimited is True while the registration waits out an SCC 429 Too Many Requests
+	RegistrationConditionRateLimited condition.Cond = "RateLimited"
+	// RegistrationConditionCredentialsDrift is True while a managed credentials or registration code Secret holds content the operator did not write
+	RegistrationConditionCredentialsDrift condition.Cond = "CredentialsDrift"
+	// RegistrationConditionExpiringSoon is True once the subscription is within an expiry warning threshold; its reason names the threshold
+	RegistrationConditionExpiringSoon condition.Cond = "ExpiringSoon"
 )
 
 // +genclient
@@ -46,6 +79,7 @@ const (
 // +kubebuilder:printcolumn:name="Registration Active",type=boolean,JSONPath=`.status.activationStatus.activated`
 // +kubebuilder:printcolumn:name="System ID",type=integer,JSONPath=`.status.sccSystemID`
 // +kubebuilder:printcolumn:name="Last Sync",type=date,JSONPath=".status.activationStatus.lastValidatedTS"
+// +kubebuilder:printcolumn:name="Suspended",type=boolean,JSONPath=`.spec.suspend`,priority=1
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 type Registration struct {
@@ -66,6 +100,15 @@ type RegistrationSpec struct {
 	OfflineRegistrationCertificateSecretRef *corev1.SecretReference `json:"offlineRegistrationCertificateSecretRef,omitempty"`
 	// +optional
 	SyncNow *bool `json:"syncNow,omitempty"`
+	// Suspend stops all keepalives, activations and syncs with SCC without deregistering; a pending SyncNow runs once resumed
+	// +optional
+	Suspend *bool `json:"suspend,omitempty"`
+	// Recovery opts a failed registration into being re-validated with SCC, instead of staying failed until it is recreated
+	// +optional
+	Recovery *RecoveryPolicy `json:"recovery,omitempty"`
+	// Deregistration decides what happens to the SCC system when the registration is deleted, the operator default when unset
+	// +optional
+	Deregistration *DeregistrationPolicy `json:"deregistration,omitempty"`
 }
 
 func (rs *RegistrationSpec) WithoutSyncNow() RegistrationSpec {
@@ -73,20 +116,98 @@ func (rs *RegistrationSpec) WithoutSyncNow() RegistrationSpec {
 		Mode:                                    rs.Mode,
 		RegistrationRequest:                     rs.RegistrationRequest,
 		OfflineRegistrationCertificateSecretRef: rs.OfflineRegistrationCertificateSecretRef,
+		Suspend:                                 rs.Suspend,
+		Recovery:                                rs.Recovery,
+		Deregistration:                          rs.Deregistration,
 	}
 }
 
+// DefaultRecoveryInterval is how often a failed registration with a RecoveryPolicy is re-validated when no interval is set
+const DefaultRecoveryInterval = time.Hour
+
+// RecoveryPolicy re-validates a failed registration on a schedule, and right away when a Secret it references changes.
+// Offline registrations ignore it; they recover when their offline certificate is replaced.
+type RecoveryPolicy struct {
+	// Interval between recovery attempts while the registration is failed, one hour when unset
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+}
+
+func (rp *RecoveryPolicy) GetInterval() time.Duration {
+	if rp == nil || rp.Interval == nil || rp.Interval.Duration <= 0 {
+		return DefaultRecoveryInterval
+	}
+
+	return rp.Interval.Duration
+}
+
+// DeregistrationMode is how hard the operator tries to remove the system from SCC when its Registration is deleted
+type DeregistrationMode string
+
+const (
+	// DeregistrationBestEffort tries once and deletes the Registration even when SCC could not deregister the system
+	DeregistrationBestEffort DeregistrationMode = "best-effort"
+	// DeregistrationRequiredWithTimeout keeps retrying with backoff and only gives up once the timeout has passed
+	DeregistrationRequiredWithTimeout DeregistrationMode = "required-with-timeout"
+	// DeregistrationSkip never contacts SCC, leaving the system registered there
+	DeregistrationSkip DeregistrationMode = "skip"
+)
+
+func (m DeregistrationMode) Valid() bool {
+	switch m {
+	case DeregistrationBestEffort, DeregistrationRequiredWithTimeout, DeregistrationSkip:
+		return true
+	}
+	return false
+}
+
+// DeregistrationPolicy decides what happens to the SCC system when the Registration is deleted.
+// Systems the operator gives up on are recorded as tombstones, so they can be removed from SCC by hand.
+type DeregistrationPolicy struct {
+	// +kubebuilder:validation:Enum=best-effort;required-with-timeout;skip
+	Mode DeregistrationMode `json:"mode"`
+	// Timeout after which required-with-timeout gives up, counted from the deletion of the Registration
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
 type RegistrationRequest struct {
 	RegistrationCodeSecretRef *corev1.SecretReference `json:"registrationCodeSecretRef,omitempty"`
 	// +optional
 	RegistrationAPIUrl *string `json:"registrationAPIUrl,omitempty"`
+	// RegistrationAPICertificateSecretRef points to a Secret holding a CA certificate (`registrationCACert` or `ca.crt`)
+	// to trust when connecting to RegistrationAPIUrl, e.g. an RMT or SCC proxy behind an internal CA.
 	// +optional
 	RegistrationAPICertificateSecretRef *corev1.SecretReference `json:"registrationAPICertificateSecretRef,omitempty"`
+	// InstanceDataSecretRef points to a Secret holding the cloud instance identity document (`instanceData`)
+	// sent when announcing a pay-as-you-go registration.
+	// +optional
+	InstanceDataSecretRef *corev1.SecretReference `json:"instanceDataSecretRef,omitempty"`
+	// Proxy routes this registration's traffic through an HTTP proxy instead of the process-wide proxy env vars
+	// +optional
+	Proxy *ProxyConfig `json:"proxy,omitempty"`
+}
+
+// ProxyConfig describes the HTTP proxy used to reach the registration API
+type ProxyConfig struct {
+	// URL of the proxy, e.g. http://proxy.example.com:3128
+	URL string `json:"url"`
+	// CredentialsSecretRef points to a basic-auth Secret (`username` and `password`) used to authenticate with the proxy
+	// +optional
+	CredentialsSecretRef *corev1.SecretReference `json:"credentialsSecretRef,omitempty"`
+	// NoProxy lists hosts, domains, IPs or CIDRs that are reached directly, using the NO_PROXY env var format
+	// +optional
+	// +listType=atomic
+	NoProxy []string `json:"noProxy,omitempty"`
 }
 
 type RegistrationStatus struct {
 	CurrentCondition *genericcondition.GenericCondition `json:"currentCondition,omitempty"`
 
+	// ObservedGeneration is the spec generation the last sync with SCC was made for
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
 	// +optional
 	// +patchMergeKey=type
 	// +patchStrategy=merge
@@ -102,6 +223,9 @@ type RegistrationStatus struct {
 	RegisteredProduct *string `json:"registeredProduct,omitempty"`
 	// +optional
 	RegistrationExpiresAt *metav1.Time `json:"registrationExpiresAt,omitempty"`
+	// +optional
+	// +listType=atomic
+	Activations []ProductActivation `json:"activations,omitempty"`
 
 	// +optional
 	ActivationStatus SystemActivationState `json:"activationStatus,omitempty"`
@@ -109,6 +233,100 @@ type RegistrationStatus struct {
 	SystemCredentialsSecretRef *corev1.SecretReference `json:"systemCredentialsSecretRef,omitempty"`
 	// +optional
 	OfflineRegistrationRequest *corev1.SecretReference `json:"offlineRegistrationRequest,omitempty"`
+	// +optional
+	OfflineCertificate *OfflineCertificateStatus `json:"offlineCertificate,omitempty"`
+	// +optional
+	PayAsYouGo *PayAsYouGoStatus `json:"payAsYouGo,omitempty"`
+	// SyncHistory lists the most recent sync attempts with SCC, oldest first
+	// +optional
+	// +listType=atomic
+	SyncHistory []SyncAttempt `json:"syncHistory,omitempty"`
+	// ConsecutiveFailures counts the recoverable SCC errors since the last successful sync
+	// +optional
+	ConsecutiveFailures int32 `json:"consecutiveFailures,omitempty"`
+	// NextRetryAt is when the operator will retry after a recoverable SCC error
+	// +optional
+	NextRetryAt *metav1.Time `json:"nextRetryAt,omitempty"`
+	// Recovery tracks the automatic recovery of a failed registration with a RecoveryPolicy
+	// +optional
+	Recovery *RecoveryStatus `json:"recovery,omitempty"`
+	// LastAction is the result of the last action requested with the `scc.cattle.io/action` annotation
+	// +optional
+	LastAction *ActionResult `json:"lastAction,omitempty"`
+	// Deregistration tracks the attempts to deregister the system from SCC while the Registration is being deleted
+	// +optional
+	Deregistration *DeregistrationStatus `json:"deregistration,omitempty"`
+}
+
+// RegistrationAction is a one-off operation requested with the `scc.cattle.io/action` annotation
+type RegistrationAction string
+
+const (
+	// RegistrationActionResync syncs with SCC right away, like spec.syncNow
+	RegistrationActionResync RegistrationAction = "resync"
+	// RegistrationActionReannounce clears any failure and announces the system to SCC again
+	RegistrationActionReannounce RegistrationAction = "reannounce"
+	// RegistrationActionResetActivation clears any failure and activates the registered system again
+	RegistrationActionResetActivation RegistrationAction = "reset-activation"
+	// RegistrationActionDeregister removes the system from SCC but keeps the Registration
+	RegistrationActionDeregister RegistrationAction = "deregister"
+)
+
+// ActionResult records the outcome of an action requested with the `scc.cattle.io/action` annotation
+type ActionResult struct {
+	Action    RegistrationAction `json:"action"`
+	Timestamp metav1.Time        `json:"timestamp"`
+	// +kubebuilder:validation:Enum=Succeeded;Failed
+	Outcome SyncOutcome `json:"outcome"`
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// DeregistrationStatus tracks the failed attempts to deregister a system that is required to be deregistered
+type DeregistrationStatus struct {
+	// Attempts counts the failed deregistrations
+	// +optional
+	Attempts int32 `json:"attempts,omitempty"`
+	// LastError is the error SCC returned on the last attempt
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+	// NextAttemptAt is when the deregistration will be retried
+	// +optional
+	NextAttemptAt *metav1.Time `json:"nextAttemptAt,omitempty"`
+}
+
+// RecoveryStatus tracks the recovery attempts made since a registration last synced with SCC successfully
+type RecoveryStatus struct {
+	// +optional
+	Attempts int32 `json:"attempts,omitempty"`
+	// +optional
+	LastAttemptTS *metav1.Time `json:"lastAttemptTS,omitempty"`
+	// RelatedSecretsFingerprint identifies the referenced Secrets as they were when the failure was last reviewed
+	// +optional
+	RelatedSecretsFingerprint string `json:"relatedSecretsFingerprint,omitempty"`
+}
+
+// MaxSyncHistory is the number of sync attempts kept in the status before the oldest are dropped
+const MaxSyncHistory = 10
+
+type SyncOutcome string
+
+const (
+	SyncOutcomeSucceeded SyncOutcome = "Succeeded"
+	SyncOutcomeFailed    SyncOutcome = "Failed"
+)
+
+// SyncAttempt records a single register, activate or keepalive call made to SCC
+type SyncAttempt struct {
+	Timestamp metav1.Time `json:"timestamp"`
+	// Phase is the lifecycle phase of the attempt, e.g. `Registration/Main` or `Keepalive`
+	Phase string `json:"phase"`
+	// +kubebuilder:validation:Enum=Succeeded;Failed
+	Outcome  SyncOutcome     `json:"outcome"`
+	Duration metav1.Duration `json:"duration"`
+	// ErrorSummary is a short description of why a failed attempt failed
+	// +optional
+	ErrorSummary string `json:"errorSummary,omitempty"`
 }
 
 type SystemActivationState struct {
@@ -120,6 +338,62 @@ type SystemActivationState struct {
 	SystemURL *string `json:"systemURL,omitempty"`
 }
 
+// OfflineCertificateStatus is what the operator read from the offline registration certificate when activating it
+type OfflineCertificateStatus struct {
+	// SubscriptionName is the name of the subscription the certificate was issued for
+	// +optional
+	SubscriptionName string `json:"subscriptionName,omitempty"`
+	// SubscriptionKind is the kind of that subscription, e.g. `full` or `evaluation`
+	// +optional
+	SubscriptionKind string `json:"subscriptionKind,omitempty"`
+	// HashedRegcode is the SHA-256 of the registration code the certificate was issued for, as the code itself is never included
+	// +optional
+	HashedRegcode string `json:"hashedRegcode,omitempty"`
+	// ProductClasses lists the product classes the subscription covers
+	// +optional
+	// +listType=atomic
+	ProductClasses []string `json:"productClasses,omitempty"`
+	// +optional
+	StartsAt *metav1.Time `json:"startsAt,omitempty"`
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+}
+
+// PayAsYouGoStatus tracks the usage reported to SCC for a pay-as-you-go registration
+type PayAsYouGoStatus struct {
+	// LastUsageReportTS is the end of the period covered by the last successful usage report
+	// +optional
+	LastUsageReportTS *metav1.Time `json:"lastUsageReportTS,omitempty"`
+	// ReportedUsageHours is the total number of online hours reported to SCC
+	// +optional
+	ReportedUsageHours int64 `json:"reportedUsageHours,omitempty"`
+	// PendingUsageReport is a usage report sent to SCC whose outcome was never recorded.
+	// It is counted as reported by the next keepalive, so a lost status update never bills the same hours twice.
+	// +optional
+	PendingUsageReport *PendingUsageReport `json:"pendingUsageReport,omitempty"`
+}
+
+// PendingUsageReport is the period covered by a usage report recorded right before it is sent to SCC
+type PendingUsageReport struct {
+	// Until is the end of the reported period
+	Until metav1.Time `json:"until"`
+	// Hours is the number of online hours in the report
+	// +optional
+	Hours int64 `json:"hours,omitempty"`
+}
+
+// ProductActivation is a summary of a single product activation known to SCC for the registered system
+type ProductActivation struct {
+	FriendlyName string `json:"friendlyName"`
+	Identifier   string `json:"identifier"`
+	Version      string `json:"version"`
+	Arch         string `json:"arch"`
+	// +optional
+	RegCodeType string `json:"regCodeType,omitempty"`
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+}
+
 func (r *Registration) HasCondition(matchCond condition.Cond) bool {
 	conditions := r.Status.Conditions
 	for _, cond := range conditions {
@@ -143,6 +417,16 @@ func (r *Registration) RemoveCondition(matchCond condition.Cond) {
 	r.Status.Conditions = newConditions
 }
 
+// AddSyncAttempt appends an attempt to the sync history, dropping the oldest entries past MaxSyncHistory
+func (r *Registration) AddSyncAttempt(attempt SyncAttempt) {
+	history := append(r.Status.SyncHistory, attempt)
+	if len(history) > MaxSyncHistory {
+		history = history[len(history)-MaxSyncHistory:]
+	}
+
+	r.Status.SyncHistory = history
+}
+
 func (r *Registration) ToOwnerRef() *metav1.OwnerReference {
 	return &metav1.OwnerReference{
 		APIVersion: r.TypeMeta.APIVersion,
@@ -24,9 +24,226 @@ package v1
 import (
 	genericcondition "github.com/rancher/wrangler/v3/pkg/genericcondition"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActionResult) DeepCopyInto(out *ActionResult) {
+	*out = *in
+	in.Timestamp.DeepCopyInto(&out.Timestamp)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActionResult.
+func (in *ActionResult) DeepCopy() *ActionResult {
+	if in == nil {
+		return nil
+	}
+	out := new(ActionResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeregistrationPolicy) DeepCopyInto(out *DeregistrationPolicy) {
+	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeregistrationPolicy.
+func (in *DeregistrationPolicy) DeepCopy() *DeregistrationPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(DeregistrationPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeregistrationStatus) DeepCopyInto(out *DeregistrationStatus) {
+	*out = *in
+	if in.NextAttemptAt != nil {
+		in, out := &in.NextAttemptAt, &out.NextAttemptAt
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeregistrationStatus.
+func (in *DeregistrationStatus) DeepCopy() *DeregistrationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DeregistrationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OfflineCertificateStatus) DeepCopyInto(out *OfflineCertificateStatus) {
+	*out = *in
+	if in.ProductClasses != nil {
+		in, out := &in.ProductClasses, &out.ProductClasses
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.StartsAt != nil {
+		in, out := &in.StartsAt, &out.StartsAt
+		*out = (*in).DeepCopy()
+	}
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OfflineCertificateStatus.
+func (in *OfflineCertificateStatus) DeepCopy() *OfflineCertificateStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(OfflineCertificateStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PayAsYouGoStatus) DeepCopyInto(out *PayAsYouGoStatus) {
+	*out = *in
+	if in.LastUsageReportTS != nil {
+		in, out := &in.LastUsageReportTS, &out.LastUsageReportTS
+		*out = (*in).DeepCopy()
+	}
+	if in.PendingUsageReport != nil {
+		in, out := &in.PendingUsageReport, &out.PendingUsageReport
+		*out = new(PendingUsageReport)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PayAsYouGoStatus.
+func (in *PayAsYouGoStatus) DeepCopy() *PayAsYouGoStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PayAsYouGoStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PendingUsageReport) DeepCopyInto(out *PendingUsageReport) {
+	*out = *in
+	in.Until.DeepCopyInto(&out.Until)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PendingUsageReport.
+func (in *PendingUsageReport) DeepCopy() *PendingUsageReport {
+	if in == nil {
+		return nil
+	}
+	out := new(PendingUsageReport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProductActivation) DeepCopyInto(out *ProductActivation) {
+	*out = *in
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProductActivation.
+func (in *ProductActivation) DeepCopy() *ProductActivation {
+	if in == nil {
+		return nil
+	}
+	out := new(ProductActivation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxyConfig) DeepCopyInto(out *ProxyConfig) {
+	*out = *in
+	if in.CredentialsSecretRef != nil {
+		in, out := &in.CredentialsSecretRef, &out.CredentialsSecretRef
+		*out = new(corev1.SecretReference)
+		**out = **in
+	}
+	if in.NoProxy != nil {
+		in, out := &in.NoProxy, &out.NoProxy
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxyConfig.
+func (in *ProxyConfig) DeepCopy() *ProxyConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxyConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RecoveryPolicy) DeepCopyInto(out *RecoveryPolicy) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RecoveryPolicy.
+func (in *RecoveryPolicy) DeepCopy() *RecoveryPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RecoveryPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RecoveryStatus) DeepCopyInto(out *RecoveryStatus) {
+	*out = *in
+	if in.LastAttemptTS != nil {
+		in, out := &in.LastAttemptTS, &out.LastAttemptTS
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RecoveryStatus.
+func (in *RecoveryStatus) DeepCopy() *RecoveryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RecoveryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Registration) DeepCopyInto(out *Registration) {
 	*out = *in
@@ -106,6 +323,16 @@ func (in *RegistrationRequest) DeepCopyInto(out *RegistrationRequest) {
 		*out = new(corev1.SecretReference)
 		**out = **in
 	}
+	if in.InstanceDataSecretRef != nil {
+		in, out := &in.InstanceDataSecretRef, &out.InstanceDataSecretRef
+		*out = new(corev1.SecretReference)
+		**out = **in
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(ProxyConfig)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -137,6 +364,21 @@ func (in *RegistrationSpec) DeepCopyInto(out *RegistrationSpec) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.Suspend != nil {
+		in, out := &in.Suspend, &out.Suspend
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Recovery != nil {
+		in, out := &in.Recovery, &out.Recovery
+		*out = new(RecoveryPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Deregistration != nil {
+		in, out := &in.Deregistration, &out.Deregistration
+		*out = new(DeregistrationPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -181,6 +423,13 @@ func (in *RegistrationStatus) DeepCopyInto(out *RegistrationStatus) {
 		in, out := &in.RegistrationExpiresAt, &out.RegistrationExpiresAt
 		*out = (*in).DeepCopy()
 	}
+	if in.Activations != nil {
+		in, out := &in.Activations, &out.Activations
+		*out = make([]ProductActivation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	in.ActivationStatus.DeepCopyInto(&out.ActivationStatus)
 	if in.SystemCredentialsSecretRef != nil {
 		in, out := &in.SystemCredentialsSecretRef, &out.SystemCredentialsSecretRef
@@ -192,6 +441,42 @@ func (in *RegistrationStatus) DeepCopyInto(out *RegistrationStatus) {
 		*out = new(corev1.SecretReference)
 		**out = **in
 	}
+	if in.OfflineCertificate != nil {
+		in, out := &in.OfflineCertificate, &out.OfflineCertificate
+		*out = new(OfflineCertificateStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PayAsYouGo != nil {
+		in, out := &in.PayAsYouGo, &out.PayAsYouGo
+		*out = new(PayAsYouGoStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SyncHistory != nil {
+		in, out := &in.SyncHistory, &out.SyncHistory
+		*out = make([]SyncAttempt, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NextRetryAt != nil {
+		in, out := &in.NextRetryAt, &out.NextRetryAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Recovery != nil {
+		in, out := &in.Recovery, &out.Recovery
+		*out = new(RecoveryStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastAction != nil {
+		in, out := &in.LastAction, &out.LastAction
+		*out = new(ActionResult)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Deregistration != nil {
+		in, out := &in.Deregistration, &out.Deregistration
+		*out = new(DeregistrationStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -205,6 +490,24 @@ func (in *RegistrationStatus) DeepCopy() *RegistrationStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SyncAttempt) DeepCopyInto(out *SyncAttempt) {
+	*out = *in
+	in.Timestamp.DeepCopyInto(&out.Timestamp)
+	out.Duration = in.Duration
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SyncAttempt.
+func (in *SyncAttempt) DeepCopy() *SyncAttempt {
+	if in == nil {
+		return nil
+	}
+	out := new(SyncAttempt)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SystemActivationState) DeepCopyInto(out *SystemActivationState) {
 	*out = *in
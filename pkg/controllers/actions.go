@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/rancher/scc-operator/internal/consts"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+	"github.com/rancher/scc-operator/pkg/controllers/lifecycle"
+)
+
+// requestedAction returns the action set with the `scc.cattle.io/action` annotation, if any
+func requestedAction(registrationObj *v1.Registration) (v1.RegistrationAction, bool) {
+	action, ok := registrationObj.Annotations[consts.AnnotationSccAction]
+	return v1.RegistrationAction(action), ok
+}
+
+// prepareResync refreshes what the mode needs for a fresh sync, then resets the Registration to activate again
+func prepareResync(registrationHandler SCCHandler, registrationObj *v1.Registration) (*v1.Registration, error) {
+	if offlineHandler, ok := registrationHandler.(*sccOfflineMode); ok {
+		if refreshErr := offlineHandler.RefreshOfflineRequestSecret(); refreshErr != nil {
+			return registrationObj, refreshErr
+		}
+	}
+
+	return registrationHandler.ResetToReadyForActivation(registrationObj)
+}
+
+// applyAction runs a single action against a copy of the Registration, returning the status to store when it succeeds
+func applyAction(registrationHandler SCCHandler, registrationObj *v1.Registration, action v1.RegistrationAction) (*v1.Registration, error) {
+	switch action {
+	case v1.RegistrationActionResync:
+		return prepareResync(registrationHandler, registrationObj)
+	case v1.RegistrationActionResetActivation:
+		reset, err := registrationHandler.ResetToReadyForActivation(registrationObj)
+		if err != nil {
+			return registrationObj, err
+		}
+		return lifecycle.PrepareFailureCleared(reset), nil
+	case v1.RegistrationActionReannounce:
+		reset, err := registrationHandler.ResetToReadyForActivation(registrationObj)
+		if err != nil {
+			return registrationObj, err
+		}
+		return lifecycle.PrepareReannounce(reset), nil
+	case v1.RegistrationActionDeregister:
+		if err := registrationHandler.Deregister(); err != nil {
+			return registrationObj, err
+		}
+		if err := registrationHandler.RemoveSecrets(); err != nil {
+			return registrationObj, err
+		}
+		return lifecycle.PrepareDeregistered(registrationObj), nil
+	default:
+		return registrationObj, fmt.Errorf("unknown action %q; expected one of %s, %s, %s or %s", action,
+			v1.RegistrationActionResync, v1.RegistrationActionReannounce, v1.RegistrationActionResetActivation, v1.RegistrationActionDeregister)
+	}
+}
+
+// runAction handles the `scc.cattle.io/action` annotation: the annotation is removed before the action runs and the outcome
+// is stored in the status afterwards, so an action runs at most once and a failed action is reported rather than retried
+func (h *handler) runAction(registrationHandler SCCHandler, registrationObj *v1.Registration, action v1.RegistrationAction) error {
+	var claimed *v1.Registration
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, getErr := h.registrations.Get(registrationObj.Name, metav1.GetOptions{})
+		if getErr != nil {
+			return getErr
+		}
+		if _, ok := current.Annotations[consts.AnnotationSccAction]; !ok {
+			claimed = nil
+			return nil
+		}
+
+		current = current.DeepCopy()
+		delete(current.Annotations, consts.AnnotationSccAction)
+		var updateErr error
+		claimed, updateErr = h.registrations.Update(current)
+		return updateErr
+	})
+	if err != nil {
+		return fmt.Errorf("cannot remove the annotation of action `%s` before running it: %w", action, err)
+	}
+	if claimed == nil {
+		h.log.Debugf("action `%s` on registration `%s` was already handled", action, registrationObj.Name)
+		return nil
+	}
+
+	h.log.Infof("running action `%s` requested on registration `%s`", action, registrationObj.Name)
+	result := v1.ActionResult{
+		Action:    action,
+		Timestamp: metav1.Now(),
+		Outcome:   v1.SyncOutcomeSucceeded,
+	}
+	updated, actionErr := applyAction(registrationHandler, claimed.DeepCopy(), action)
+	if updated == nil || actionErr != nil {
+		updated = claimed.DeepCopy()
+	}
+	if actionErr != nil {
+		result.Outcome = v1.SyncOutcomeFailed
+		result.Message = sccErrorSummary(actionErr)
+	}
+	updated.Status.LastAction = &result
+
+	updated, err = h.registrations.UpdateStatus(updated)
+	if err != nil {
+		return fmt.Errorf("action `%s` ran but its result could not be stored: %w", action, err)
+	}
+
+	if actionErr != nil {
+		h.log.Warnf("action `%s` on registration `%s` failed: %v", action, registrationObj.Name, actionErr)
+		h.recordEvent(updated, corev1.EventTypeWarning, eventReasonActionFailed, "action %s failed: %s", action, result.Message)
+		return nil
+	}
+	h.recordEvent(updated, corev1.EventTypeNormal, eventReasonActionSucceeded, "action %s completed in %s", action, time.Since(result.Timestamp.Time).Round(time.Millisecond))
+	return nil
+}
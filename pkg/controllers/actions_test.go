@@ -0,0 +1,164 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/rancher/scc-operator/internal/consts"
+	"github.com/rancher/scc-operator/internal/logging"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+	"github.com/rancher/scc-operator/pkg/controllers/lifecycle"
+)
+
+type deregisterRecorder struct {
+	SCCHandler
+	err   error
+	calls int
+}
+
+func (d *deregisterRecorder) Deregister() error {
+	d.calls++
+	return d.err
+}
+
+func (d *deregisterRecorder) RemoveSecrets() error {
+	return nil
+}
+
+func registrationWithAction(action v1.RegistrationAction) *v1.Registration {
+	registration := &v1.Registration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "scc-registration-abc",
+			Annotations: map[string]string{consts.AnnotationSccAction: string(action)},
+		},
+		Spec: v1.RegistrationSpec{Mode: v1.RegistrationModeOnline},
+		Status: v1.RegistrationStatus{
+			SCCSystemID:             ptr.To(1234),
+			RegistrationProcessedTS: &metav1.Time{},
+		},
+	}
+	registration.Status.ActivationStatus.Activated = true
+	v1.RegistrationConditionAnnounced.True(registration)
+	v1.RegistrationConditionActivated.True(registration)
+
+	return registration
+}
+
+// expectActionStored captures the annotation removal and the stored status of a single action run
+func expectActionStored(mockRegistrations *fake.MockNonNamespacedControllerInterface[*v1.Registration, *v1.RegistrationList], registration *v1.Registration) (*v1.Registration, *v1.Registration) {
+	stored := &v1.Registration{}
+	cleaned := &v1.Registration{}
+	gomock.InOrder(
+		mockRegistrations.EXPECT().Get(registration.Name, gomock.Any()).Return(registration, nil),
+		mockRegistrations.EXPECT().Update(gomock.Any()).DoAndReturn(func(reg *v1.Registration) (*v1.Registration, error) {
+			*cleaned = *reg
+			return reg, nil
+		}),
+		mockRegistrations.EXPECT().UpdateStatus(gomock.Any()).DoAndReturn(func(reg *v1.Registration) (*v1.Registration, error) {
+			*stored = *reg
+			return reg, nil
+		}),
+	)
+
+	return stored, cleaned
+}
+
+func TestRunActionResetActivation(t *testing.T) {
+	gomockCtrl := gomock.NewController(t)
+	mockRegistrations := fake.NewMockNonNamespacedControllerInterface[*v1.Registration, *v1.RegistrationList](gomockCtrl)
+	h := &handler{log: logging.NewLog(), registrations: mockRegistrations}
+
+	registration := registrationWithAction(v1.RegistrationActionResetActivation)
+	registration = lifecycle.PrepareFailed(registration, assert.AnError)
+	stored, cleaned := expectActionStored(mockRegistrations, registration)
+
+	require.NoError(t, h.runAction(&sccOnlineMode{}, registration, v1.RegistrationActionResetActivation))
+	require.NotNil(t, stored.Status.LastAction)
+	assert.Equal(t, v1.RegistrationActionResetActivation, stored.Status.LastAction.Action)
+	assert.Equal(t, v1.SyncOutcomeSucceeded, stored.Status.LastAction.Outcome)
+	assert.False(t, stored.Status.LastAction.Timestamp.IsZero())
+	assert.False(t, lifecycle.RegistrationIsFailed(stored))
+	assert.False(t, stored.Status.ActivationStatus.Activated)
+	assert.True(t, (&sccOnlineMode{}).NeedsActivation(stored))
+	assert.NotContains(t, cleaned.Annotations, consts.AnnotationSccAction)
+	// The input is left untouched for the caller
+	assert.Contains(t, registration.Annotations, consts.AnnotationSccAction)
+}
+
+func TestRunActionDeregister(t *testing.T) {
+	gomockCtrl := gomock.NewController(t)
+	mockRegistrations := fake.NewMockNonNamespacedControllerInterface[*v1.Registration, *v1.RegistrationList](gomockCtrl)
+	h := &handler{log: logging.NewLog(), registrations: mockRegistrations}
+	registrationHandler := &deregisterRecorder{}
+
+	registration := registrationWithAction(v1.RegistrationActionDeregister)
+	stored, _ := expectActionStored(mockRegistrations, registration)
+	require.NoError(t, h.runAction(registrationHandler, registration, v1.RegistrationActionDeregister))
+	assert.Equal(t, 1, registrationHandler.calls)
+	assert.Equal(t, v1.SyncOutcomeSucceeded, stored.Status.LastAction.Outcome)
+	assert.True(t, lifecycle.RegistrationIsDeregistered(stored))
+	assert.Nil(t, stored.Status.SCCSystemID)
+	assert.False(t, stored.Status.ActivationStatus.Activated)
+
+	// Reannouncing brings the Registration back through registration
+	deregistered := stored.DeepCopy()
+	deregistered.Annotations = map[string]string{consts.AnnotationSccAction: string(v1.RegistrationActionReannounce)}
+	stored, _ = expectActionStored(mockRegistrations, deregistered)
+	require.NoError(t, h.runAction(&sccOnlineMode{}, deregistered, v1.RegistrationActionReannounce))
+	assert.False(t, lifecycle.RegistrationIsDeregistered(stored))
+	assert.True(t, (&sccOnlineMode{}).NeedsRegistration(stored))
+
+	// A failed deregistration is reported and leaves the Registration as it was
+	registrationHandler.err = assert.AnError
+	registration = registrationWithAction(v1.RegistrationActionDeregister)
+	stored, _ = expectActionStored(mockRegistrations, registration)
+	require.NoError(t, h.runAction(registrationHandler, registration, v1.RegistrationActionDeregister))
+	assert.Equal(t, v1.SyncOutcomeFailed, stored.Status.LastAction.Outcome)
+	assert.Equal(t, assert.AnError.Error(), stored.Status.LastAction.Message)
+	assert.False(t, lifecycle.RegistrationIsDeregistered(stored))
+	assert.Equal(t, 1234, *stored.Status.SCCSystemID)
+}
+
+func TestRunActionUnknown(t *testing.T) {
+	gomockCtrl := gomock.NewController(t)
+	mockRegistrations := fake.NewMockNonNamespacedControllerInterface[*v1.Registration, *v1.RegistrationList](gomockCtrl)
+	h := &handler{log: logging.NewLog(), registrations: mockRegistrations}
+
+	registration := registrationWithAction("reboot")
+	stored, cleaned := expectActionStored(mockRegistrations, registration)
+	require.NoError(t, h.runAction(&sccOnlineMode{}, registration, "reboot"))
+	assert.Equal(t, v1.SyncOutcomeFailed, stored.Status.LastAction.Outcome)
+	assert.Contains(t, stored.Status.LastAction.Message, "unknown action")
+	assert.True(t, stored.Status.ActivationStatus.Activated)
+	assert.NotContains(t, cleaned.Annotations, consts.AnnotationSccAction)
+}
+
+func TestRunActionRunsOnce(t *testing.T) {
+	gomockCtrl := gomock.NewController(t)
+	mockRegistrations := fake.NewMockNonNamespacedControllerInterface[*v1.Registration, *v1.RegistrationList](gomockCtrl)
+	h := &handler{log: logging.NewLog(), registrations: mockRegistrations}
+	registrationHandler := &deregisterRecorder{}
+
+	// Storing the result fails after the annotation was removed
+	registration := registrationWithAction(v1.RegistrationActionDeregister)
+	var cleaned *v1.Registration
+	mockRegistrations.EXPECT().Get(registration.Name, gomock.Any()).Return(registration, nil)
+	mockRegistrations.EXPECT().Update(gomock.Any()).DoAndReturn(func(reg *v1.Registration) (*v1.Registration, error) {
+		cleaned = reg
+		return reg, nil
+	})
+	mockRegistrations.EXPECT().UpdateStatus(gomock.Any()).Return(nil, assert.AnError)
+	assert.ErrorIs(t, h.runAction(registrationHandler, registration, v1.RegistrationActionDeregister), assert.AnError)
+	assert.Equal(t, 1, registrationHandler.calls)
+
+	// The retry finds the annotation gone and does not deregister again
+	mockRegistrations.EXPECT().Get(registration.Name, gomock.Any()).Return(cleaned, nil)
+	require.NoError(t, h.runAction(registrationHandler, registration, v1.RegistrationActionDeregister))
+	assert.Equal(t, 1, registrationHandler.calls)
+}
@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"github.com/SUSE/connect-ng/pkg/registration"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+)
+
+// productActivationsFrom converts the SCC activations for a system into their Registration status form
+func productActivationsFrom(activations []*registration.Activation) []v1.ProductActivation {
+	productActivations := make([]v1.ProductActivation, 0, len(activations))
+	for _, activation := range activations {
+		if activation == nil {
+			continue
+		}
+
+		productActivation := v1.ProductActivation{
+			RegCodeType: activation.Type,
+		}
+		if activation.Product != nil {
+			productActivation.FriendlyName = activation.Product.FriendlyName
+			productActivation.Identifier = activation.Product.Identifier
+			productActivation.Version = activation.Product.Version
+			productActivation.Arch = activation.Product.Arch
+		}
+		if !activation.ExpiresAt.IsZero() {
+			productActivation.ExpiresAt = &metav1.Time{Time: activation.ExpiresAt}
+		}
+
+		productActivations = append(productActivations, productActivation)
+	}
+
+	return productActivations
+}
+
+// earliestActivationExpiry finds the soonest expiry of all activations; activations without an expiry are ignored
+func earliestActivationExpiry(activations []v1.ProductActivation) *metav1.Time {
+	var earliest *metav1.Time
+	for _, activation := range activations {
+		if activation.ExpiresAt.IsZero() {
+			continue
+		}
+		if earliest == nil || activation.ExpiresAt.Before(earliest) {
+			earliest = activation.ExpiresAt.DeepCopy()
+		}
+	}
+
+	return earliest
+}
+
+// baseProductName picks the product name to show as the RegisteredProduct; base products are preferred over extensions
+func baseProductName(activations []*registration.Activation) *string {
+	var firstName *string
+	for _, activation := range activations {
+		if activation == nil || activation.Product == nil {
+			continue
+		}
+		friendlyName := activation.Product.FriendlyName
+		if activation.Product.IsBase {
+			return &friendlyName
+		}
+		if firstName == nil {
+			firstName = &friendlyName
+		}
+	}
+
+	return firstName
+}
+
+// applyActivationsToStatus records all known activations on the Registration status
+func applyActivationsToStatus(registrationObj *v1.Registration, activations []*registration.Activation) *v1.Registration {
+	registrationObj.Status.Activations = productActivationsFrom(activations)
+	registrationObj.Status.RegistrationExpiresAt = earliestActivationExpiry(registrationObj.Status.Activations)
+	registrationObj.Status.RegisteredProduct = baseProductName(activations)
+
+	return registrationObj
+}
@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SUSE/connect-ng/pkg/registration"
+	"github.com/stretchr/testify/assert"
+
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+)
+
+func testActivations(now time.Time) []*registration.Activation {
+	return []*registration.Activation{
+		{
+			Type:      "full",
+			ExpiresAt: now.Add(90 * 24 * time.Hour),
+			Product: &registration.Product{
+				FriendlyName: "SUSE Observability Extension",
+				Identifier:   "observability",
+				Version:      "1.0",
+				Arch:         "x86_64",
+			},
+		},
+		{
+			Type:      "full",
+			ExpiresAt: now.Add(30 * 24 * time.Hour),
+			Product: &registration.Product{
+				FriendlyName: "Rancher Prime",
+				Identifier:   "rancher",
+				Version:      "2.12",
+				Arch:         "unknown",
+				IsBase:       true,
+			},
+		},
+		{
+			Type: "free",
+			Product: &registration.Product{
+				FriendlyName: "Free Extension",
+				Identifier:   "free-ext",
+				Version:      "1.0",
+				Arch:         "x86_64",
+			},
+		},
+	}
+}
+
+func TestProductActivationsFrom(t *testing.T) {
+	now := time.Now()
+	productActivations := productActivationsFrom(testActivations(now))
+
+	assert.Len(t, productActivations, 3)
+	assert.Equal(t, "SUSE Observability Extension", productActivations[0].FriendlyName)
+	assert.Equal(t, "observability", productActivations[0].Identifier)
+	assert.Equal(t, "1.0", productActivations[0].Version)
+	assert.Equal(t, "x86_64", productActivations[0].Arch)
+	assert.Equal(t, "full", productActivations[0].RegCodeType)
+	assert.NotNil(t, productActivations[0].ExpiresAt)
+	assert.Nil(t, productActivations[2].ExpiresAt)
+}
+
+func TestProductActivationsFromSkipsNil(t *testing.T) {
+	productActivations := productActivationsFrom([]*registration.Activation{nil, {Type: "full"}})
+
+	assert.Len(t, productActivations, 1)
+	assert.Equal(t, "", productActivations[0].Identifier)
+}
+
+func TestEarliestActivationExpiry(t *testing.T) {
+	now := time.Now()
+	productActivations := productActivationsFrom(testActivations(now))
+
+	earliest := earliestActivationExpiry(productActivations)
+	assert.NotNil(t, earliest)
+	assert.True(t, earliest.Time.Equal(now.Add(30*24*time.Hour)))
+
+	assert.Nil(t, earliestActivationExpiry([]v1.ProductActivation{{Identifier: "no-expiry"}}))
+	assert.Nil(t, earliestActivationExpiry(nil))
+}
+
+func TestApplyActivationsToStatus(t *testing.T) {
+	now := time.Now()
+	reg := applyActivationsToStatus(&v1.Registration{}, testActivations(now))
+
+	assert.Len(t, reg.Status.Activations, 3)
+	assert.NotNil(t, reg.Status.RegisteredProduct)
+	assert.Equal(t, "Rancher Prime", *reg.Status.RegisteredProduct)
+	assert.True(t, reg.Status.RegistrationExpiresAt.Time.Equal(now.Add(30*24*time.Hour)))
+}
@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/rancher/scc-operator/internal/config"
+	"github.com/rancher/scc-operator/internal/consts"
+	"github.com/rancher/scc-operator/internal/suseconnect"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+	"github.com/rancher/scc-operator/pkg/controllers/lifecycle"
+)
+
+// retryPolicy spaces out the retries of recoverable SCC errors for a single Registration;
+// the zero value disables backoff and leaves retries to the wrangler rate limiter
+type retryPolicy struct {
+	baseDelay time.Duration
+	maxDelay  time.Duration
+	jitter    float64
+	// random returns a value in [0, 1) and is only replaced in tests
+	random func() float64
+}
+
+func newRetryPolicy(settings *config.OperatorSettings) retryPolicy {
+	policy := retryPolicy{
+		baseDelay: consts.DefaultRetryBaseDelay,
+		maxDelay:  consts.DefaultRetryMaxDelay,
+		jitter:    consts.DefaultRetryJitter,
+		random:    rand.Float64,
+	}
+	if settings != nil && settings.Retry.BaseDelay > 0 {
+		policy.baseDelay = settings.Retry.BaseDelay
+		policy.maxDelay = max(settings.Retry.MaxDelay, settings.Retry.BaseDelay)
+		policy.jitter = settings.Retry.Jitter
+	}
+
+	return policy
+}
+
+func (p retryPolicy) enabled() bool {
+	return p.baseDelay > 0
+}
+
+// delay doubles the base delay for every consecutive failure up to the max delay, then takes off up to `jitter` of it
+func (p retryPolicy) delay(consecutiveFailures int32) time.Duration {
+	delay := p.baseDelay
+	for i := int32(1); i < consecutiveFailures && delay < p.maxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, p.maxDelay)
+	if p.random == nil {
+		return delay
+	}
+
+	return delay - time.Duration(p.jitter*p.random()*float64(delay))
+}
+
+// prepareRetry counts a failed sync and sets when it will be retried; errors that need user action are not retried.
+// When SCC rate limited the request, the retry is never scheduled before the rate limit wait has passed.
+func (p retryPolicy) prepareRetry(regIn *v1.Registration, syncErr error, now time.Time) (*v1.Registration, time.Duration) {
+	rateLimited := suseconnect.IsRateLimitError(syncErr)
+	if !p.enabled() && !rateLimited {
+		return regIn, 0
+	}
+	if isNonRecoverableHTTPError(syncErr) || lifecycle.RegistrationIsFailed(regIn) {
+		regIn.Status.NextRetryAt = nil
+		return regIn, 0
+	}
+
+	var retryAfter time.Duration
+	if p.enabled() {
+		regIn.Status.ConsecutiveFailures++
+		retryAfter = p.delay(regIn.Status.ConsecutiveFailures)
+	}
+	if rateLimited {
+		retryAfter = max(retryAfter, suseconnect.RetryAfterFrom(syncErr))
+		v1.RegistrationConditionRateLimited.True(regIn)
+		v1.RegistrationConditionRateLimited.Message(regIn, fmt.Sprintf("SCC API returned %s; retrying after %s", http.StatusText(http.StatusTooManyRequests), now.Add(retryAfter).UTC().Format(time.RFC3339)))
+	}
+	if retryAfter <= 0 {
+		return regIn, 0
+	}
+	regIn.Status.NextRetryAt = &metav1.Time{Time: now.Add(retryAfter)}
+
+	return regIn, retryAfter
+}
+
+// markNotRateLimited clears a previously reported SCC rate limit once a sync succeeds
+func markNotRateLimited(regIn *v1.Registration) {
+	if v1.RegistrationConditionRateLimited.IsTrue(regIn) {
+		v1.RegistrationConditionRateLimited.False(regIn)
+		v1.RegistrationConditionRateLimited.Message(regIn, "")
+	}
+}
+
+// scheduleRetry requeues the Registration once its backoff has passed; with nothing scheduled the error goes back to wrangler
+func (h *handler) scheduleRetry(regName string, retryAfter time.Duration, syncErr error) error {
+	if retryAfter <= 0 {
+		return syncErr
+	}
+
+	h.log.Warnf("%v; retrying registration %s in %s", syncErr, regName, retryAfter.Round(time.Second))
+	h.registrations.EnqueueAfter(regName, retryAfter)
+	return nil
+}
+
+// pendingRetry is how long is left before a Registration in backoff may sync again
+func pendingRetry(registrationObj *v1.Registration, now time.Time) time.Duration {
+	if registrationObj.Status.NextRetryAt == nil || lifecycle.RegistrationNeedsSyncNow(registrationObj) {
+		return 0
+	}
+
+	return registrationObj.Status.NextRetryAt.Sub(now)
+}
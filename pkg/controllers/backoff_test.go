@@ -0,0 +1,234 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SUSE/connect-ng/pkg/connection"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/rancher/scc-operator/internal/config"
+	"github.com/rancher/scc-operator/internal/consts"
+	"github.com/rancher/scc-operator/internal/logging"
+	"github.com/rancher/scc-operator/internal/suseconnect"
+	"github.com/rancher/scc-operator/internal/telemetry"
+	"github.com/rancher/scc-operator/internal/types"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+	"github.com/rancher/scc-operator/pkg/controllers/lifecycle"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := retryPolicy{baseDelay: 10 * time.Second, maxDelay: time.Minute}
+
+	assert.Equal(t, 10*time.Second, policy.delay(1))
+	assert.Equal(t, 20*time.Second, policy.delay(2))
+	assert.Equal(t, 40*time.Second, policy.delay(3))
+	assert.Equal(t, time.Minute, policy.delay(4))
+	assert.Equal(t, time.Minute, policy.delay(1000))
+
+	// Jitter only ever shortens the delay, by at most the configured fraction
+	policy.jitter = 0.5
+	policy.random = func() float64 { return 0.5 }
+	assert.Equal(t, 15*time.Second, policy.delay(2))
+	policy.random = func() float64 { return 0 }
+	assert.Equal(t, 20*time.Second, policy.delay(2))
+}
+
+func TestNewRetryPolicy(t *testing.T) {
+	policy := newRetryPolicy(nil)
+	assert.Equal(t, consts.DefaultRetryBaseDelay, policy.baseDelay)
+	assert.Equal(t, consts.DefaultRetryMaxDelay, policy.maxDelay)
+	assert.Equal(t, consts.DefaultRetryJitter, policy.jitter)
+
+	policy = newRetryPolicy(&config.OperatorSettings{Retry: config.RetrySettings{BaseDelay: time.Minute, MaxDelay: time.Second, Jitter: 0.1}})
+	assert.Equal(t, time.Minute, policy.baseDelay)
+	assert.Equal(t, time.Minute, policy.maxDelay)
+	assert.Equal(t, 0.1, policy.jitter)
+}
+
+func TestPrepareRetry(t *testing.T) {
+	now := time.Now()
+	policy := retryPolicy{baseDelay: 10 * time.Second, maxDelay: time.Minute}
+	registration := &v1.Registration{}
+
+	registration, retryAfter := policy.prepareRetry(registration, errors.New("connection refused"), now)
+	assert.Equal(t, 10*time.Second, retryAfter)
+	assert.Equal(t, int32(1), registration.Status.ConsecutiveFailures)
+	require.NotNil(t, registration.Status.NextRetryAt)
+	assert.True(t, registration.Status.NextRetryAt.Time.Equal(now.Add(retryAfter)))
+
+	registration, retryAfter = policy.prepareRetry(registration, fmt.Errorf("keepalive: %w", &connection.ApiError{Code: 503}), now)
+	assert.Equal(t, 20*time.Second, retryAfter)
+	assert.Equal(t, int32(2), registration.Status.ConsecutiveFailures)
+
+	// Errors that need user action are left for the Failure condition instead of retried
+	registration, retryAfter = policy.prepareRetry(registration, &connection.ApiError{Code: 401}, now)
+	assert.Zero(t, retryAfter)
+	assert.Nil(t, registration.Status.NextRetryAt)
+
+	// A successful sync ends the backoff
+	registration = prepareSyncAttempt(registration, 1, syncPhaseKeepalive, now, nil)
+	assert.Zero(t, registration.Status.ConsecutiveFailures)
+	assert.Nil(t, registration.Status.NextRetryAt)
+
+	// Without a policy nothing is scheduled and the error is handed back to wrangler
+	registration, retryAfter = retryPolicy{}.prepareRetry(registration, errors.New("connection refused"), now)
+	assert.Zero(t, retryAfter)
+	assert.Zero(t, registration.Status.ConsecutiveFailures)
+}
+
+func TestPrepareRetryRateLimited(t *testing.T) {
+	now := time.Now()
+	policy := retryPolicy{baseDelay: 10 * time.Second, maxDelay: time.Minute}
+	registration := &v1.Registration{}
+
+	rateLimited := &suseconnect.RateLimitError{ApiError: &connection.ApiError{Code: http.StatusTooManyRequests}, RetryAfter: 5 * time.Minute}
+	registration, retryAfter := policy.prepareRetry(registration, fmt.Errorf("keepalive: %w", rateLimited), now)
+	assert.Equal(t, 5*time.Minute, retryAfter)
+	assert.True(t, v1.RegistrationConditionRateLimited.IsTrue(registration))
+	assert.Contains(t, v1.RegistrationConditionRateLimited.GetMessage(registration), "Too Many Requests")
+
+	// The backoff still applies when it is longer than the rate limit wait
+	rateLimited.RetryAfter = time.Second
+	registration, retryAfter = policy.prepareRetry(registration, rateLimited, now)
+	assert.Equal(t, 20*time.Second, retryAfter)
+
+	// The rate limit wait applies even without a backoff policy
+	rateLimited.RetryAfter = time.Minute
+	registration, retryAfter = retryPolicy{}.prepareRetry(registration, rateLimited, now)
+	assert.Equal(t, time.Minute, retryAfter)
+
+	registration = prepareSyncAttempt(registration, 1, syncPhaseKeepalive, now, nil)
+	assert.True(t, v1.RegistrationConditionRateLimited.IsFalse(registration))
+}
+
+func TestOnRegistrationChangeWaitsForRetry(t *testing.T) {
+	gomockCtrl := gomock.NewController(t)
+	mockRegistrations := fake.NewMockNonNamespacedControllerInterface[*v1.Registration, *v1.RegistrationList](gomockCtrl)
+	h := &handler{log: logging.NewLog(), registrations: mockRegistrations}
+
+	registration := &v1.Registration{
+		ObjectMeta: metav1.ObjectMeta{Name: "scc-registration-abc"},
+		Spec:       v1.RegistrationSpec{Mode: v1.RegistrationModeOnline},
+		Status: v1.RegistrationStatus{
+			ConsecutiveFailures: 2,
+			NextRetryAt:         &metav1.Time{Time: time.Now().Add(time.Hour)},
+		},
+	}
+
+	mockRegistrations.EXPECT().EnqueueAfter(registration.Name, gomock.Any()).Do(func(_ string, after time.Duration) {
+		assert.Greater(t, after, 59*time.Minute)
+		assert.LessOrEqual(t, after, time.Hour)
+	})
+	_, err := h.OnRegistrationChange(registration.Name, registration)
+	require.NoError(t, err)
+
+	// A requested sync skips the wait
+	registration.Spec.SyncNow = ptr.To(true)
+	assert.Zero(t, pendingRetry(registration, time.Now()))
+	registration.Spec.SyncNow = nil
+	registration.Status.NextRetryAt = &metav1.Time{Time: time.Now().Add(-time.Second)}
+	assert.Negative(t, pendingRetry(registration, time.Now()))
+}
+
+func TestReconcileRegistrationBacksOffServerErrors(t *testing.T) {
+	serverErr := fmt.Errorf("register: %w", &connection.ApiError{Code: http.StatusServiceUnavailable, Message: "Service Unavailable"})
+	handlers := map[string]SCCHandler{
+		"online": &sccOnlineMode{},
+		"rmt":    &sccRMTMode{},
+		"payg":   &sccPayAsYouGoMode{},
+	}
+
+	for name, registrationHandler := range handlers {
+		t.Run(name, func(t *testing.T) {
+			gomockCtrl := gomock.NewController(t)
+			mockRegistrations := fake.NewMockNonNamespacedControllerInterface[*v1.Registration, *v1.RegistrationList](gomockCtrl)
+			h := &handler{
+				log:           logging.NewLog(),
+				registrations: mockRegistrations,
+				backoff:       retryPolicy{baseDelay: 10 * time.Second, maxDelay: time.Minute},
+			}
+
+			registration := &v1.Registration{
+				ObjectMeta: metav1.ObjectMeta{Name: "scc-registration-abc"},
+				Status:     v1.RegistrationStatus{ConsecutiveFailures: 1},
+			}
+			var persisted *v1.Registration
+			mockRegistrations.EXPECT().Get(registration.Name, gomock.Any()).Return(registration, nil)
+			mockRegistrations.EXPECT().UpdateStatus(gomock.Any()).DoAndReturn(func(reg *v1.Registration) (*v1.Registration, error) {
+				persisted = reg
+				return reg, nil
+			})
+			mockRegistrations.EXPECT().EnqueueAfter(registration.Name, 20*time.Second)
+
+			before := time.Now()
+			err := h.reconcileRegistration(registrationHandler, registration, serverErr, types.RegistrationMain, before)
+			require.NoError(t, err)
+
+			require.NotNil(t, persisted)
+			assert.False(t, lifecycle.RegistrationIsFailed(persisted))
+			assert.Equal(t, int32(2), persisted.Status.ConsecutiveFailures)
+			require.NotNil(t, persisted.Status.NextRetryAt)
+			assert.WithinRange(t, persisted.Status.NextRetryAt.Time, before.Add(20*time.Second), time.Now().Add(20*time.Second))
+		})
+	}
+}
+
+func TestReconcileRegistrationWaitsForSCCRetryAfter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", "90")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"error": "slow down"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	creds := connection.NewMockCredentials()
+	creds.On("HasAuthentication").Return(true)
+	creds.On("Login").Return("login", "password", nil)
+	creds.On("Token").Return("token", nil)
+	creds.On("UpdateToken", mock.Anything).Return(nil)
+	sccConnection := suseconnect.OnlineRancherConnection(suseconnect.OnlineConnectionParams{
+		RegistrationURL: server.URL,
+		Options:         suseconnect.DefaultConnectionOptions("scc-operator-test", "0.0.1"),
+	}, creds, telemetry.MetricsWrapper{})
+	_, rateLimitedErr := sccConnection.ActivationStatus()
+	require.True(t, suseconnect.IsRateLimitError(rateLimitedErr))
+
+	gomockCtrl := gomock.NewController(t)
+	mockRegistrations := fake.NewMockNonNamespacedControllerInterface[*v1.Registration, *v1.RegistrationList](gomockCtrl)
+	h := &handler{
+		log:           logging.NewLog(),
+		registrations: mockRegistrations,
+		backoff:       retryPolicy{baseDelay: 10 * time.Second, maxDelay: time.Hour},
+	}
+	registration := &v1.Registration{ObjectMeta: metav1.ObjectMeta{Name: "scc-registration-abc"}}
+	var persisted *v1.Registration
+	mockRegistrations.EXPECT().Get(registration.Name, gomock.Any()).Return(registration, nil)
+	mockRegistrations.EXPECT().UpdateStatus(gomock.Any()).DoAndReturn(func(reg *v1.Registration) (*v1.Registration, error) {
+		persisted = reg
+		return reg, nil
+	})
+	// SCC's 90s outlasts the 10s backoff, so the retry waits for SCC
+	mockRegistrations.EXPECT().EnqueueAfter(registration.Name, 90*time.Second)
+
+	before := time.Now()
+	err := h.reconcileRegistration(&sccOnlineMode{}, registration, rateLimitedErr, types.RegistrationMain, before)
+	require.NoError(t, err)
+
+	require.NotNil(t, persisted)
+	assert.False(t, lifecycle.RegistrationIsFailed(persisted))
+	assert.True(t, v1.RegistrationConditionRateLimited.IsTrue(persisted))
+	require.NotNil(t, persisted.Status.NextRetryAt)
+	assert.WithinRange(t, persisted.Status.NextRetryAt.Time, before.Add(90*time.Second), time.Now().Add(90*time.Second))
+}
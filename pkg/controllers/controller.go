@@ -8,16 +8,21 @@ import (
 	"strings"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rancher/scc-operator/internal/logging"
 	"github.com/rancher/scc-operator/internal/rancher"
 	"github.com/rancher/scc-operator/internal/rancher/settings"
 	"github.com/rancher/scc-operator/internal/telemetry"
+	wranglerCore "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/v3/pkg/generic"
 	"github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
 
 	"github.com/rancher/scc-operator/internal/consts"
@@ -32,6 +37,7 @@ import (
 	"github.com/rancher/scc-operator/pkg/controllers/helpers"
 	"github.com/rancher/scc-operator/pkg/controllers/lifecycle"
 	registrationControllers "github.com/rancher/scc-operator/pkg/generated/controllers/scc.cattle.io/v1"
+	"github.com/rancher/scc-operator/pkg/util/keylock"
 )
 
 const (
@@ -74,8 +80,10 @@ type SCCHandler interface {
 	Keepalive(registrationObj *v1.Registration) error
 	// PrepareKeepaliveSucceeded completes any necessary steps after successful keepalive
 	PrepareKeepaliveSucceeded(*v1.Registration) (*v1.Registration, error)
-	// Deregister initiates the system's deregistration from SCC.
+	// Deregister removes the system from SCC, returning any error so the deregistration policy can decide what to do with it.
 	Deregister() error
+	// RemoveSecrets cleans up the local Secrets holding the system's credentials and registration data.
+	RemoveSecrets() error
 
 	// ReconcileRegisterError prepares the Registration object for error reconciliation after RegisterSystem fails.
 	ReconcileRegisterError(*v1.Registration, error, types.RegistrationPhase) *v1.Registration
@@ -92,7 +100,14 @@ type handler struct {
 	registrations     registrationControllers.RegistrationController
 	registrationCache registrationControllers.RegistrationCache
 	secretRepo        *secretrepo.SecretRepository
+	configMaps        wranglerCore.ConfigMapClient
 	settings          *settings.SettingReader
+	recorder          record.EventRecorder
+	// registrationLocks serializes handlers per Registration name, so a slow SCC call only holds up its own Registration
+	registrationLocks keylock.Locker
+	backoff           retryPolicy
+	// dryRun records the SCC requests that would have been sent instead of sending them; nil outside of dry-run mode
+	dryRun suseconnect.DryRunRecorder
 }
 
 // Register will setup the SCC registration CRDs controllers (and related secret controllers)
@@ -102,7 +117,9 @@ func Register(
 	options *types.RunOptions,
 	registrations registrationControllers.RegistrationController,
 	secretsRepo *secretrepo.SecretRepository,
+	configMaps wranglerCore.ConfigMapClient,
 	settings *settings.SettingReader,
+	recorder record.EventRecorder,
 ) {
 	controller := &handler{
 		log:               logging.NewControllerLogger("registration-controller"),
@@ -112,6 +129,10 @@ func Register(
 		registrationCache: registrations.Cache(),
 		secretRepo:        secretsRepo,
 		settings:          settings,
+		recorder:          recorder,
+		backoff:           newRetryPolicy(options.OperatorSettings),
+		configMaps:        configMaps,
+		dryRun:            newDryRunRecorder(options, configMaps),
 	}
 
 	controller.initIndexers()
@@ -144,6 +165,7 @@ func Register(
 
 	cfg := setupCfg()
 	go controller.RunLifecycleManager(cfg, rancher.GetServerURL(ctx, settings))
+	go controller.RunOrphanCollector(ctx, options.OperatorSettings.OrphanGC)
 }
 
 func (h *handler) prepareHandler(registrationObj *v1.Registration, rancherURL string) SCCHandler {
@@ -163,6 +185,7 @@ func (h *handler) prepareHandler(registrationObj *v1.Registration, rancherURL st
 		return &sccOfflineMode{
 			rancherURL:   rancherURL,
 			rancherUUID:  rancher.GetRancherInstallUUID(h.ctx, h.settings),
+			trustRoots:   h.offlineTrustRoots(),
 			log:          h.log.WithField("regHandler", "offline"),
 			options:      h.options,
 			registration: registrationObj,
@@ -178,6 +201,43 @@ func (h *handler) prepareHandler(registrationObj *v1.Registration, rancherURL st
 	}
 
 	credsSecretName := consts.SCCCredentialsSecretName(nameSuffixHash)
+	if registrationObj.Spec.Mode == v1.RegistrationModeRMT {
+		return &sccRMTMode{
+			rancherURL:   rancherURL,
+			log:          h.log.WithField("regHandler", "rmt"),
+			options:      h.options,
+			registration: registrationObj,
+			sccCredentials: credentials.New(
+				h.options.SystemNamespace(),
+				credsSecretName,
+				ref,
+				h.secretRepo,
+				defaultLabels,
+			),
+			secretRepo: h.secretRepo,
+			dryRun:     h.dryRun,
+		}
+	}
+
+	if registrationObj.Spec.Mode == v1.RegistrationModePayAsYouGo {
+		return &sccPayAsYouGoMode{
+			rancherURL:   rancherURL,
+			log:          h.log.WithField("regHandler", "payg"),
+			options:      h.options,
+			registration: registrationObj,
+			sccCredentials: credentials.New(
+				h.options.SystemNamespace(),
+				credsSecretName,
+				ref,
+				h.secretRepo,
+				defaultLabels,
+			),
+			secretRepo:   h.secretRepo,
+			dryRun:       h.dryRun,
+			updateStatus: h.updateRegistrationStatus,
+		}
+	}
+
 	return &sccOnlineMode{
 		rancherURL:   rancherURL,
 		log:          h.log.WithField("regHandler", "online"),
@@ -191,6 +251,7 @@ func (h *handler) prepareHandler(registrationObj *v1.Registration, rancherURL st
 			defaultLabels,
 		),
 		secretRepo: h.secretRepo,
+		dryRun:     h.dryRun,
 	}
 }
 
@@ -220,6 +281,14 @@ func (h *handler) OnSecretChange(_ string, incomingObj *corev1.Secret) (*corev1.
 		return incomingObj, nil
 	}
 
+	if isDriftCheckedSecret(incomingObj) && helpers.ShouldManage(incomingObj, h.options.OperatorName) {
+		return h.reviewManagedSecret(incomingObj)
+	}
+
+	if isOfflineRequestSecret(incomingObj) && h.mirrorsOfflineRequests() && helpers.ShouldManage(incomingObj, h.options.OperatorName) {
+		return incomingObj, h.mirrorOfflineRequest(incomingObj)
+	}
+
 	if !h.isSCCEntrypointSecret(incomingObj) {
 		return incomingObj, nil
 	}
@@ -271,6 +340,7 @@ func (h *handler) OnSecretChange(_ string, incomingObj *corev1.Secret) (*corev1.
 			newSecret.Annotations = map[string]string{}
 		}
 		newSecret.Annotations[consts.LabelSccLastProcessed] = time.Now().Format(time.RFC3339)
+		newSecret.Annotations[consts.AnnotationSccIdentityHash] = params.identityHash
 
 		// Merge params labels while preserving Helm ownership
 		mergeLabelsPreservingHelm(newSecret, params.Labels())
@@ -288,6 +358,13 @@ func (h *handler) OnSecretChange(_ string, incomingObj *corev1.Secret) (*corev1.
 	// are cleaned up
 	// TODO: make it so that changes to the incoming Salt (which changes the nameID) are correctly handled
 	// Note that change would affect both name and content hashes - however something seems to not.
+	rotatedRegCode := false
+	if pinned, ok := h.pinRotatedNameID(incomingObj, params); ok {
+		params = pinned
+		if rotatedRegCode, err = h.regCodeChanged(params); err != nil {
+			return incomingObj, err
+		}
+	}
 	if incomingNameHash != params.nameID {
 		h.log.Info("must cleanup existing registration managed by secret")
 		if cleanUpErr := h.cleanupRegistrationByHash(hashCleanupRequest{
@@ -302,7 +379,16 @@ func (h *handler) OnSecretChange(_ string, incomingObj *corev1.Secret) (*corev1.
 	// TODO: rework stuff around this as this shouldn't be necessary
 	if incomingContentHash != params.contentHash {
 		h.log.Info("must cleanup existing registration managed by secret")
-		if cleanUpErr := h.cleanupRelatedSecretsByHash(incomingContentHash); cleanUpErr != nil {
+		// A Registration kept through a reg code rotation still needs its SCC credentials and reg code Secret
+		var keepRoles []consts.SecretRole
+		if incomingNameHash == params.nameID {
+			keepRoles = []consts.SecretRole{consts.SCCCredentialsRole, consts.RegistrationCode}
+			// An activated offline Registration keeps using its certificate until the new one is validated and promoted
+			if h.rotatesOfflineCertificate(params) {
+				keepRoles = append(keepRoles, consts.OfflineCertificate)
+			}
+		}
+		if cleanUpErr := h.cleanupRelatedSecretsByHash(incomingContentHash, keepRoles...); cleanUpErr != nil {
 			h.log.Errorf("failed to cleanup registrations for hash %s: %v", incomingNameHash, cleanUpErr)
 			return incomingObj, cleanUpErr
 		}
@@ -316,6 +402,7 @@ func (h *handler) OnSecretChange(_ string, incomingObj *corev1.Secret) (*corev1.
 		newSecret.Annotations = map[string]string{}
 	}
 	newSecret.Annotations[consts.LabelSccLastProcessed] = time.Now().Format(time.RFC3339)
+	newSecret.Annotations[consts.AnnotationSccIdentityHash] = params.identityHash
 
 	// Merge params labels while preserving Helm ownership
 	mergeLabelsPreservingHelm(newSecret, params.Labels())
@@ -324,11 +411,13 @@ func (h *handler) OnSecretChange(_ string, incomingObj *corev1.Secret) (*corev1.
 		return incomingObj, err
 	}
 
+	stagedOfflineCert := false
 	if params.regType == v1.RegistrationModeOffline && params.hasOfflineCertData {
 		offlineCertSecret, err := h.offlineCertFromSecretEntrypoint(params)
 		if err != nil {
 			return incomingObj, err
 		}
+		_, stagedOfflineCert = offlineCertSecret.Data[consts.SecretKeyOfflineRegCertReplacement]
 
 		if _, err := h.secretRepo.CreateOrUpdateSecret(offlineCertSecret); err != nil {
 			return incomingObj, err
@@ -346,16 +435,56 @@ func (h *handler) OnSecretChange(_ string, incomingObj *corev1.Secret) (*corev1.
 		}
 	}
 
+	if params.hasInstanceData {
+		instanceDataSecret, err := h.instanceDataFromSecretEntrypoint(params)
+		if err != nil {
+			return incomingObj, err
+		}
+
+		if _, err := h.secretRepo.CreateOrUpdateSecret(instanceDataSecret); err != nil {
+			return incomingObj, err
+		}
+	}
+
+	if params.hasProxyCredentials() {
+		proxyCredentialsSecret, err := h.proxyCredentialsFromSecretEntrypoint(params)
+		if err != nil {
+			return incomingObj, err
+		}
+
+		if _, err := h.secretRepo.CreateOrUpdateSecret(proxyCredentialsSecret); err != nil {
+			return incomingObj, err
+		}
+	}
+
+	if params.hasRegCACert {
+		regCACertSecret, err := h.regCACertFromSecretEntrypoint(params)
+		if err != nil {
+			return incomingObj, err
+		}
+
+		if _, err := h.secretRepo.CreateOrUpdateSecret(regCACertSecret); err != nil {
+			return incomingObj, err
+		}
+	}
+
 	// construct associated registration CRs
 	registration, err := h.registrationFromSecretEntrypoint(params)
 	if err != nil {
 		return incomingObj, fmt.Errorf("failed to create registration from secret %s/%s: %w", incomingObj.Namespace, incomingObj.Name, err)
 	}
+	if rotatedRegCode {
+		registration = h.prepareRegCodeRotation(registration)
+	}
 
 	if createOrUpdateErr := h.createOrUpdateRegistration(registration); createOrUpdateErr != nil {
 		h.log.Errorf("failed to create or update registration %s: %v", registration.Name, createOrUpdateErr)
 		return incomingObj, fmt.Errorf("failed to create or update registration %s: %w", registration.Name, createOrUpdateErr)
 	}
+	if stagedOfflineCert {
+		// The Registration spec is unchanged by a staged certificate, so it must be told to review it
+		h.registrations.Enqueue(registration.Name)
+	}
 
 	return incomingObj, nil
 }
@@ -406,7 +535,7 @@ func (h *handler) cleanupRegistrationByHash(cleanupRequest hashCleanupRequest) e
 	return nil
 }
 
-func (h *handler) cleanupRelatedSecretsByHash(contentHash string) error {
+func (h *handler) cleanupRelatedSecretsByHash(contentHash string, keepRoles ...consts.SecretRole) error {
 	secrets, err := h.secretRepo.GetBySccContentHash(contentHash)
 	h.log.Infof("found %d matching related secrets to clean up; content hash of %s", len(secrets), contentHash)
 	if err != nil {
@@ -416,7 +545,8 @@ func (h *handler) cleanupRelatedSecretsByHash(contentHash string) error {
 	// It should never be in there, but just in case don't act on the entrypoint
 	secrets = slices.Collect(func(yield func(secret *corev1.Secret) bool) {
 		for _, secret := range secrets {
-			if secret.Name != consts.ResourceSCCEntrypointSecretName && !strings.HasPrefix(secret.Name, consts.OfflineRequestSecretNamePrefix) {
+			if !h.isSCCEntrypointSecret(secret) && !strings.HasPrefix(secret.Name, consts.OfflineRequestSecretNamePrefix) &&
+				!slices.Contains(keepRoles, consts.SecretRole(secret.Labels[consts.LabelSccSecretRole])) {
 				if !yield(secret) {
 					return
 				}
@@ -554,13 +684,54 @@ func (h *handler) OnSecretRemove(_ string, incomingObj *corev1.Secret) (*corev1.
 	return incomingObj, nil
 }
 
-func (h *handler) OnRegistrationChange(_ string, registrationObj *v1.Registration) (*v1.Registration, error) {
-	activiateMu.Lock()
-	defer activiateMu.Unlock()
+func (h *handler) OnRegistrationChange(name string, registrationObj *v1.Registration) (*v1.Registration, error) {
+	unlock := h.registrationLocks.Lock(name)
+	defer unlock()
 	if registrationObj == nil || registrationObj.DeletionTimestamp != nil {
 		return nil, nil
 	}
 
+	// Suspended registrations make no SCC calls; SyncNow is left on the spec so it runs once resumed
+	if lifecycle.RegistrationIsSuspended(registrationObj) {
+		suspended := lifecycle.PrepareSuspended(registrationObj.DeepCopy())
+		if equality.Semantic.DeepEqual(suspended.Status, registrationObj.Status) {
+			return registrationObj, nil
+		}
+
+		h.log.Infof("registration `%s` is suspended, skipping all SCC traffic", registrationObj.Name)
+		if _, err := h.registrations.UpdateStatus(suspended); err != nil {
+			return registrationObj, err
+		}
+		h.recordEvent(registrationObj, corev1.EventTypeNormal, eventReasonSuspended, "%s", v1.ResourceConditionSuspended.GetMessage(suspended))
+		return registrationObj, nil
+	}
+
+	if v1.ResourceConditionSuspended.IsTrue(registrationObj) {
+		h.log.Infof("registration `%s` was resumed", registrationObj.Name)
+		if _, err := h.registrations.UpdateStatus(lifecycle.PrepareResumed(registrationObj.DeepCopy())); err != nil {
+			return registrationObj, err
+		}
+		h.recordEvent(registrationObj, corev1.EventTypeNormal, eventReasonResumed, "SCC traffic resumed")
+		return registrationObj, nil
+	}
+
+	// Outcomes of a dry run never reached SCC, so they are discarded once the operator sends real requests again
+	if h.dryRun == nil && registeredInDryRun(registrationObj) {
+		h.log.Infof("registration `%s` was only registered in dry-run mode, registering it with SCC", registrationObj.Name)
+		if _, err := h.registrations.UpdateStatus(lifecycle.PrepareDryRunDiscarded(registrationObj.DeepCopy())); err != nil {
+			return registrationObj, err
+		}
+		h.recordEvent(registrationObj, corev1.EventTypeNormal, eventReasonDryRunDiscarded, "dry-run registration discarded, registering with SCC")
+		return registrationObj, nil
+	}
+
+	// Registrations backing off after a recoverable error wait out their retry schedule, unless a sync or action is requested
+	action, hasAction := requestedAction(registrationObj)
+	if remaining := pendingRetry(registrationObj, time.Now()); remaining > 0 && !hasAction {
+		h.registrations.EnqueueAfter(registrationObj.Name, remaining)
+		return registrationObj, nil
+	}
+
 	rancherURL := rancher.GetServerURL(h.ctx, h.settings)
 	if rancherURL == "" {
 		h.log.Info("Server URL not set")
@@ -571,6 +742,15 @@ func (h *handler) OnRegistrationChange(_ string, registrationObj *v1.Registratio
 
 	registrationHandler := h.prepareHandler(registrationObj, rancherURL)
 
+	if hasAction {
+		return registrationObj, h.runAction(registrationHandler, registrationObj, action)
+	}
+
+	if lifecycle.RegistrationIsDeregistered(registrationObj) {
+		h.log.Debugf("registration `%s` was deregistered, skipping all SCC traffic", registrationObj.Name)
+		return registrationObj, nil
+	}
+
 	if registrationHandler.NeedsPreprocessRegistration(registrationObj) {
 		processed := registrationObj.DeepCopy()
 		processed, _ = registrationHandler.PreprocessRegistration(processed)
@@ -586,9 +766,14 @@ func (h *handler) OnRegistrationChange(_ string, registrationObj *v1.Registratio
 	}
 
 	if lifecycle.RegistrationIsFailed(registrationObj) {
+		if lifecycle.RegistrationCanRecover(registrationObj) {
+			return registrationObj, h.reviewFailedRegistration(registrationHandler, registrationObj)
+		}
+
 		failedCondition := registrationObj.Status.CurrentCondition
 		if failedCondition != nil {
 			h.log.Errorf("registration `%s` has the Failure status condition from: %v", registrationObj.Name, failedCondition)
+			h.recordEvent(registrationObj, corev1.EventTypeWarning, eventReasonFailed, "registration failed at %s: %s", failedCondition.Type, failedCondition.Message)
 		} else {
 			h.log.Errorf("registration `%s` has the Failure status condition active", registrationObj.Name)
 		}
@@ -596,16 +781,35 @@ func (h *handler) OnRegistrationChange(_ string, registrationObj *v1.Registratio
 
 		errorFixHint := fmt.Sprintf("delete this registration `%s` and then create a new one to try again.", registrationObj.Name)
 		if lifecycle.RegistrationHasManagedFinalizer(registrationObj) {
-			errorFixHint = fmt.Sprintf("delete the entrypoint secret `%s/%s`, give it time to clean up, and then create a new one to try again.", h.options.SystemNamespace(), consts.ResourceSCCEntrypointSecretName)
+			entrypointName := consts.ResourceSCCEntrypointSecretName
+			if entrypoint, err := h.entrypointSecretFor(registrationObj); err == nil && entrypoint != nil {
+				entrypointName = entrypoint.Name
+			}
+			errorFixHint = fmt.Sprintf("delete the entrypoint secret `%s/%s`, give it time to clean up, and then create a new one to try again.", h.options.SystemNamespace(), entrypointName)
 		}
 		h.log.Warn("after resolving the issue(s), " + errorFixHint)
 		return registrationObj, nil
 	}
 
-	// Skip keepalive for anything activated within the last 20 hours
+	if updated, err := h.reviewStagedOfflineCertificate(registrationHandler, registrationObj); updated || err != nil {
+		return registrationObj, err
+	}
+	if lifecycle.RegistrationOfflineExpired(registrationObj) {
+		if !v1.RegistrationConditionOfflineExpired.IsTrue(registrationObj) {
+			return registrationObj, h.markOfflineExpired(registrationObj)
+		}
+	} else {
+		h.scheduleOfflineExpiry(registrationObj, time.Now())
+	}
+	if updated, err := h.reviewExpiry(registrationObj, time.Now()); updated || err != nil {
+		return registrationObj, err
+	}
+
+	// Skip keepalive for anything activated within the last 20 hours, unless a failed keepalive is being retried
 	if !registrationHandler.NeedsRegistration(registrationObj) &&
 		!registrationHandler.NeedsActivation(registrationObj) &&
-		registrationObj.Spec.SyncNow == nil {
+		registrationObj.Spec.SyncNow == nil &&
+		registrationObj.Status.ConsecutiveFailures == 0 {
 		if !registrationObj.Status.ActivationStatus.LastValidatedTS.IsZero() &&
 			registrationObj.Status.ActivationStatus.LastValidatedTS.Time.After(minResyncInterval()) {
 			return registrationObj, nil
@@ -639,15 +843,17 @@ func (h *handler) OnRegistrationChange(_ string, registrationObj *v1.Registratio
 			if progressingUpdateErr != nil {
 				return registrationObj, progressingUpdateErr
 			}
+			h.recordPhaseEvent(registrationObj, eventReasonRegistering, types.RegistrationPrepare, "starting %s registration", registrationObj.Spec.Mode)
 
 			return registrationObj, nil
 		}
 
 		// Start of initial registration/announce of cluster
 		regForAnnounce := registrationObj.DeepCopy()
+		registerStarted := time.Now()
 		preparedForRegister, prepareErr := registrationHandler.PrepareForRegister(regForAnnounce)
 		if prepareErr != nil {
-			err := h.reconcileRegistration(registrationHandler, preparedForRegister, prepareErr, types.RegistrationPrepare)
+			err := h.reconcileRegistration(registrationHandler, preparedForRegister, prepareErr, types.RegistrationPrepare, registerStarted)
 			return registrationObj, err
 		}
 
@@ -658,7 +864,7 @@ func (h *handler) OnRegistrationChange(_ string, registrationObj *v1.Registratio
 
 		announcedSystemID, registerErr := registrationHandler.Register(regForAnnounce)
 		if registerErr != nil {
-			err := h.reconcileRegistration(registrationHandler, preparedForRegister, registerErr, types.RegistrationMain)
+			err := h.reconcileRegistration(registrationHandler, preparedForRegister, registerErr, types.RegistrationMain, registerStarted)
 			return registrationObj, err
 		}
 
@@ -681,17 +887,24 @@ func (h *handler) OnRegistrationChange(_ string, registrationObj *v1.Registratio
 		}
 		regForAnnounce, prepareError = registrationHandler.PrepareRegisteredForActivation(regForAnnounce)
 		if prepareError != nil {
-			err := h.reconcileRegistration(registrationHandler, preparedForRegister, prepareError, types.RegistrationForActivation)
+			err := h.reconcileRegistration(registrationHandler, preparedForRegister, prepareError, types.RegistrationForActivation, registerStarted)
 			return registrationObj, err
 		}
 		regForAnnounce.Status.RegistrationProcessedTS = &metav1.Time{
 			Time: time.Now(),
 		}
 
+		regForAnnounce = prepareSyncAttempt(regForAnnounce, registrationObj.Generation, types.PhaseName(types.RegistrationMain), registerStarted, nil)
+
 		_, registerUpdateErr := h.registrations.UpdateStatus(regForAnnounce)
 		if registerUpdateErr != nil {
 			return registrationObj, registerUpdateErr
 		}
+		if setSystemID {
+			h.recordPhaseEvent(regForAnnounce, eventReasonRegistered, types.RegistrationForActivation, "announced to SCC with system ID %d", announcedSystemID)
+		} else {
+			h.recordPhaseEvent(regForAnnounce, eventReasonRegistered, types.RegistrationForActivation, "ready for activation")
+		}
 
 		return registrationObj, nil
 	}
@@ -701,13 +914,16 @@ func (h *handler) OnRegistrationChange(_ string, registrationObj *v1.Registratio
 			h.log.Debugf("registration needs to be activated, but not yet ready; %v", registrationObj)
 			return registrationObj, nil
 		}
+		observedGeneration := registrationObj.Generation
+		activateStarted := time.Now()
 		activationErr := registrationHandler.Activate(registrationObj)
 		// reconcile error state - must be able to handle Auth errors (or other SCC sourced errors)
 		if activationErr != nil {
-			err := h.reconcileActivation(registrationHandler, registrationObj, activationErr, types.ActivationMain)
+			err := h.reconcileActivation(registrationHandler, registrationObj, activationErr, types.ActivationMain, activateStarted)
 			return registrationObj, err
 		}
 
+		prepForKeepaliveFailed := false
 		activatedUpdateErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
 			var retryErr, updateErr error
 			registrationObj, retryErr = h.registrations.Get(registrationObj.Name, metav1.GetOptions{})
@@ -719,35 +935,30 @@ func (h *handler) OnRegistrationChange(_ string, registrationObj *v1.Registratio
 			activated = lifecycle.PrepareSuccessfulActivation(activated)
 			prepared, err := registrationHandler.PrepareActivatedForKeepalive(activated)
 			if err != nil {
-				err := h.reconcileActivation(registrationHandler, registrationObj, activationErr, types.ActivationPrepForKeepalive)
-				return err
+				prepForKeepaliveFailed = true
+				return h.reconcileActivation(registrationHandler, registrationObj, err, types.ActivationPrepForKeepalive, activateStarted)
 			}
+			prepared = prepareSyncAttempt(prepared, observedGeneration, types.PhaseName(types.ActivationMain), activateStarted, nil)
 			_, updateErr = h.registrations.UpdateStatus(prepared)
 			return updateErr
 		})
-		if activatedUpdateErr != nil {
+		if activatedUpdateErr != nil || prepForKeepaliveFailed {
 			return registrationObj, activatedUpdateErr
 		}
+		h.recordPhaseEvent(registrationObj, eventReasonActivated, types.ActivationPrepForKeepalive, "activated with SCC")
 
 		return registrationObj, nil
 	}
 
 	// Handle what to do when CheckNow is used...
 	if lifecycle.RegistrationNeedsSyncNow(registrationObj) {
-		if registrationObj.Spec.Mode == v1.RegistrationModeOffline {
-			// First refresh the offline secret
-			offlineHandler := registrationHandler.(*sccOfflineMode)
-			if refreshErr := offlineHandler.RefreshOfflineRequestSecret(); refreshErr != nil {
-				return registrationObj, refreshErr
-			}
-		}
-
-		// After offline specific action, do the interface based reset
 		updated := registrationObj.DeepCopy()
 		updated.Spec = registrationObj.Spec.WithoutSyncNow()
-		updated, _ = registrationHandler.ResetToReadyForActivation(updated)
+		updated, err := prepareResync(registrationHandler, updated)
+		if err != nil {
+			return registrationObj, err
+		}
 
-		var err error
 		updated, err = h.registrations.UpdateStatus(updated)
 		if err != nil {
 			// TODO handle this error better via ReconcileSyncNow
@@ -755,11 +966,19 @@ func (h *handler) OnRegistrationChange(_ string, registrationObj *v1.Registratio
 		}
 
 		updated, err = h.registrations.Update(updated)
-		return registrationObj, err
+		if err != nil {
+			return registrationObj, err
+		}
+		h.recordEvent(updated, corev1.EventTypeNormal, eventReasonReset, "syncNow requested, reset to %s", types.PhaseName(types.ActivationInit))
+
+		return registrationObj, nil
 	}
 
+	observedGeneration := registrationObj.Generation
+	keepaliveStarted := time.Now()
 	keepaliveErr := registrationHandler.Keepalive(registrationObj)
 	if keepaliveErr != nil {
+		var retryAfter time.Duration
 		reconcileErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
 			curReg, getErr := h.registrations.Get(registrationObj.Name, metav1.GetOptions{})
 			if getErr != nil {
@@ -768,17 +987,24 @@ func (h *handler) OnRegistrationChange(_ string, registrationObj *v1.Registratio
 
 			prepareObj := curReg.DeepCopy()
 			prepareObj = registrationHandler.ReconcileKeepaliveError(prepareObj, keepaliveErr)
+			prepareObj = prepareSyncAttempt(prepareObj, observedGeneration, syncPhaseKeepalive, keepaliveStarted, keepaliveErr)
+			prepareObj, retryAfter = h.backoff.prepareRetry(prepareObj, keepaliveErr, time.Now())
 
-			_, reconcileUpdateErr := h.registrations.Update(prepareObj)
-			return reconcileUpdateErr
+			var reconcileUpdateErr error
+			if !equality.Semantic.DeepEqual(prepareObj.Spec, curReg.Spec) {
+				_, reconcileUpdateErr = h.registrations.Update(prepareObj)
+			}
+			_, reconcileUpdateStatusErr := h.registrations.UpdateStatus(prepareObj)
+			return errors.Join(reconcileUpdateErr, reconcileUpdateStatusErr)
 		})
 
+		h.recordEvent(registrationObj, corev1.EventTypeWarning, eventReasonKeepaliveFailed, "keepalive failed: %s", sccErrorSummary(keepaliveErr))
 		err := fmt.Errorf("keepalive failed: %w", keepaliveErr)
 		if reconcileErr != nil {
-			err = fmt.Errorf("keepalive failed with additional errors: %w, %w", keepaliveErr, reconcileErr)
+			return registrationObj, fmt.Errorf("keepalive failed with additional errors: %w, %w", keepaliveErr, reconcileErr)
 		}
 
-		return registrationObj, err
+		return registrationObj, h.scheduleRetry(registrationObj.Name, retryAfter, err)
 	}
 
 	keepaliveUpdateErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
@@ -795,12 +1021,14 @@ func (h *handler) OnRegistrationChange(_ string, registrationObj *v1.Registratio
 		if err != nil {
 			return err
 		}
+		prepared = prepareSyncAttempt(prepared, observedGeneration, syncPhaseKeepalive, keepaliveStarted, nil)
 		_, updateErr = h.registrations.UpdateStatus(prepared)
 		return updateErr
 	})
 	if keepaliveUpdateErr != nil {
 		return registrationObj, keepaliveUpdateErr
 	}
+	h.recordEvent(registrationObj, corev1.EventTypeNormal, eventReasonKeepalive, "keepalive with SCC succeeded")
 
 	return registrationObj, nil
 }
@@ -810,21 +1038,40 @@ func (h *handler) OnRegistrationRemove(name string, registrationObj *v1.Registra
 		return nil, nil
 	}
 
+	unlock := h.registrationLocks.Lock(name)
+	defer unlock()
+
 	rancherURL := rancher.GetServerURL(h.ctx, h.settings)
 	if rancherURL == "" {
 		h.log.Info("Server URL not set")
 		return registrationObj, errors.New("no server url found in the system info")
 	}
+	now := time.Now()
+	if remaining := pendingDeregistration(registrationObj, now); remaining > 0 {
+		h.registrations.EnqueueAfter(name, remaining)
+		return registrationObj, generic.ErrSkip
+	}
+
 	regHandler := h.prepareHandler(registrationObj, rancherURL)
-	deRegErr := regHandler.Deregister()
-	if deRegErr != nil {
-		h.log.Warn(deRegErr)
+	retryAfter, deregErr := h.deregister(regHandler, registrationObj, now)
+	if deregErr != nil {
+		return registrationObj, deregErr
+	}
+	if retryAfter > 0 {
+		h.log.Warnf("deregistration of registration %s failed; retrying in %s", name, retryAfter.Round(time.Second))
+		h.registrations.EnqueueAfter(name, retryAfter)
+		return registrationObj, generic.ErrSkip
+	}
+
+	if err := regHandler.RemoveSecrets(); err != nil {
+		return registrationObj, err
 	}
 
 	err := h.registrations.Delete(name, &metav1.DeleteOptions{})
 	if err != nil {
 		return registrationObj, err
 	}
+	registrationSecondsToExpiry.DeletePartialMatch(prometheus.Labels{"registration": name})
 
 	return nil, nil
 }
@@ -0,0 +1,224 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/utils/ptr"
+
+	"github.com/rancher/scc-operator/internal/config"
+	"github.com/rancher/scc-operator/internal/consts"
+	"github.com/rancher/scc-operator/internal/initializer"
+	"github.com/rancher/scc-operator/internal/logging"
+	"github.com/rancher/scc-operator/internal/rancher/settings"
+	"github.com/rancher/scc-operator/internal/repos/secretrepo"
+	"github.com/rancher/scc-operator/internal/suseconnect/credentials"
+	"github.com/rancher/scc-operator/internal/types"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+)
+
+func TestOnRegistrationChangeSuspended(t *testing.T) {
+	gomockCtrl := gomock.NewController(t)
+	mockRegistrations := fake.NewMockNonNamespacedControllerInterface[*v1.Registration, *v1.RegistrationList](gomockCtrl)
+	h := &handler{log: logging.NewLog(), registrations: mockRegistrations}
+
+	registration := &v1.Registration{
+		ObjectMeta: metav1.ObjectMeta{Name: "scc-registration-abc"},
+		Spec: v1.RegistrationSpec{
+			Mode:    v1.RegistrationModeOnline,
+			SyncNow: ptr.To(true),
+			Suspend: ptr.To(true),
+		},
+	}
+
+	var suspended *v1.Registration
+	mockRegistrations.EXPECT().UpdateStatus(gomock.Any()).DoAndReturn(func(reg *v1.Registration) (*v1.Registration, error) {
+		suspended = reg
+		return reg, nil
+	})
+	_, err := h.OnRegistrationChange("", registration)
+	require.NoError(t, err)
+	require.NotNil(t, suspended)
+	assert.True(t, v1.ResourceConditionSuspended.IsTrue(suspended))
+	assert.Contains(t, v1.ResourceConditionSuspended.GetMessage(suspended), "syncNow is deferred")
+	assert.True(t, *suspended.Spec.SyncNow)
+
+	// An already suspended registration is left alone, so no further UpdateStatus is expected
+	_, err = h.OnRegistrationChange("", suspended)
+	require.NoError(t, err)
+
+	resumed := suspended.DeepCopy()
+	resumed.Spec.Suspend = nil
+	mockRegistrations.EXPECT().UpdateStatus(gomock.Any()).DoAndReturn(func(reg *v1.Registration) (*v1.Registration, error) {
+		assert.True(t, v1.ResourceConditionSuspended.IsFalse(reg))
+		assert.True(t, *reg.Spec.SyncNow)
+		return reg, nil
+	})
+	_, err = h.OnRegistrationChange("", resumed)
+	require.NoError(t, err)
+}
+
+func TestWithoutSyncNowKeepsSuspend(t *testing.T) {
+	spec := v1.RegistrationSpec{Mode: v1.RegistrationModeOnline, SyncNow: ptr.To(true), Suspend: ptr.To(true), Recovery: &v1.RecoveryPolicy{}}
+
+	withoutSyncNow := spec.WithoutSyncNow()
+	assert.Nil(t, withoutSyncNow.SyncNow)
+	assert.True(t, *withoutSyncNow.Suspend)
+	assert.Same(t, spec.Recovery, withoutSyncNow.Recovery)
+}
+
+// slowSCCBackend answers pay-as-you-go usage reports, holding the ones from slowLogin until release is closed
+type slowSCCBackend struct {
+	server       *httptest.Server
+	slowLogin    string
+	slowArrived  chan struct{}
+	release      chan struct{}
+	slowRequests atomic.Int32
+	slowInFlight atomic.Int32
+	maxInFlight  atomic.Int32
+}
+
+func newSlowSCCBackend(t *testing.T, slowLogin string) *slowSCCBackend {
+	t.Helper()
+	backend := &slowSCCBackend{
+		slowLogin:   slowLogin,
+		slowArrived: make(chan struct{}, 10),
+		release:     make(chan struct{}),
+	}
+	backend.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if login, _, _ := r.BasicAuth(); login == backend.slowLogin {
+			backend.slowRequests.Add(1)
+			inFlight := backend.slowInFlight.Add(1)
+			for current := backend.maxInFlight.Load(); inFlight > current && !backend.maxInFlight.CompareAndSwap(current, inFlight); {
+				current = backend.maxInFlight.Load()
+			}
+			backend.slowArrived <- struct{}{}
+			<-backend.release
+			backend.slowInFlight.Add(-1)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(backend.server.Close)
+
+	return backend
+}
+
+func paygRegistrationForKeepalive(nameSuffix, registrationURL string) *v1.Registration {
+	registration := &v1.Registration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   consts.RegistrationName(nameSuffix),
+			Labels: map[string]string{consts.LabelNameSuffix: nameSuffix},
+		},
+		Spec: v1.RegistrationSpec{
+			Mode: v1.RegistrationModePayAsYouGo,
+			RegistrationRequest: &v1.RegistrationRequest{
+				RegistrationAPIUrl:    ptr.To(registrationURL),
+				InstanceDataSecretRef: &corev1.SecretReference{Name: consts.InstanceDataSecretName(nameSuffix), Namespace: consts.DefaultSCCNamespace},
+			},
+		},
+	}
+	processedTS := metav1.Now()
+	registration.Status.SCCSystemID = ptr.To(42)
+	registration.Status.RegistrationProcessedTS = &processedTS
+	v1.RegistrationConditionPayAsYouGoAnnounced.True(registration)
+
+	return registration
+}
+
+func TestOnRegistrationChangeSerializesPerRegistration(t *testing.T) {
+	initializer.DevMode.Set(true)
+	initializer.OperatorName.Set(consts.DefaultOperatorName)
+	backend := newSlowSCCBackend(t, "slow_login")
+
+	settingsClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "management.cattle.io/v3",
+		"kind":       "Setting",
+		"metadata":   map[string]any{"name": consts.SettingNameServerURL},
+		"value":      "https://rancher.example.com",
+	}})
+
+	gomockCtrl := gomock.NewController(t)
+	mockSecretsCache := fake.NewMockCacheInterface[*corev1.Secret](gomockCtrl)
+	mockSecretsCache.EXPECT().Get(gomock.Any(), gomock.Any()).DoAndReturn(func(_, name string) (*corev1.Secret, error) {
+		switch name {
+		case consts.SCCMetricsOutputSecretName:
+			return &corev1.Secret{Data: map[string][]byte{
+				consts.SecretKeyMetricsData: []byte(`{"version":"2.12.0","subscription":{"installuuid":"uuid","product":"rancher","version":"2.12.0","arch":"amd64","git":"abc"}}`),
+			}}, nil
+		case consts.SCCCredentialsSecretName("slow"):
+			return &corev1.Secret{Data: map[string][]byte{credentials.UsernameKey: []byte("slow_login"), credentials.PasswordKey: []byte("password")}}, nil
+		case consts.SCCCredentialsSecretName("fast"):
+			return &corev1.Secret{Data: map[string][]byte{credentials.UsernameKey: []byte("fast_login"), credentials.PasswordKey: []byte("password")}}, nil
+		}
+		return &corev1.Secret{Data: map[string][]byte{consts.SecretKeyInstanceData: []byte("<instance document>")}}, nil
+	}).AnyTimes()
+
+	registrations := map[string]*v1.Registration{}
+	for _, nameSuffix := range []string{"slow", "fast"} {
+		registration := paygRegistrationForKeepalive(nameSuffix, backend.server.URL)
+		registrations[registration.Name] = registration
+	}
+	mockRegistrations := fake.NewMockNonNamespacedControllerInterface[*v1.Registration, *v1.RegistrationList](gomockCtrl)
+	mockRegistrations.EXPECT().Get(gomock.Any(), gomock.Any()).DoAndReturn(func(name string, _ metav1.GetOptions) (*v1.Registration, error) {
+		return registrations[name].DeepCopy(), nil
+	}).AnyTimes()
+	mockRegistrations.EXPECT().UpdateStatus(gomock.Any()).DoAndReturn(func(reg *v1.Registration) (*v1.Registration, error) {
+		return reg, nil
+	}).AnyTimes()
+
+	h := &handler{
+		ctx:           context.Background(),
+		log:           logging.NewLog().WithField("test", t.Name()),
+		options:       &types.RunOptions{OperatorName: consts.DefaultOperatorName, OperatorSettings: &config.OperatorSettings{SystemNamespace: consts.DefaultSCCNamespace}},
+		registrations: mockRegistrations,
+		secretRepo:    &secretrepo.SecretRepository{Cache: mockSecretsCache},
+		settings:      settings.NewSettingReader(settingsClient),
+	}
+	reconcile := func(nameSuffix string) <-chan error {
+		done := make(chan error, 1)
+		go func() {
+			registration := registrations[consts.RegistrationName(nameSuffix)]
+			_, err := h.OnRegistrationChange(registration.Name, registration.DeepCopy())
+			done <- err
+		}()
+		return done
+	}
+
+	slowDone := reconcile("slow")
+	<-backend.slowArrived
+
+	// A Registration stuck on a slow SCC call must not hold up any other Registration
+	select {
+	case err := <-reconcile("fast"):
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		close(backend.release)
+		t.Fatal("fast registration was blocked by the slow registration's SCC call")
+	}
+
+	// A second reconcile of the same Registration waits for the first one to finish
+	slowAgainDone := reconcile("slow")
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int32(1), backend.slowRequests.Load())
+
+	close(backend.release)
+	require.NoError(t, <-slowDone)
+	require.NoError(t, <-slowAgainDone)
+	assert.Equal(t, int32(2), backend.slowRequests.Load())
+	assert.Equal(t, int32(1), backend.maxInFlight.Load())
+	assert.Equal(t, 0, h.registrationLocks.Len())
+}
@@ -0,0 +1,206 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/rancher/scc-operator/internal/consts"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+	"github.com/rancher/scc-operator/pkg/controllers/lifecycle"
+)
+
+// deregistrationTombstone records an SCC system that was left registered when its Registration was deleted
+type deregistrationTombstone struct {
+	Registration    string                `json:"registration"`
+	SCCSystemID     int                   `json:"sccSystemID"`
+	Mode            v1.RegistrationMode   `json:"mode"`
+	RegistrationURL string                `json:"registrationURL,omitempty"`
+	Policy          v1.DeregistrationMode `json:"policy"`
+	Reason          string                `json:"reason"`
+	Time            metav1.Time           `json:"time"`
+}
+
+// deregistrationPolicy returns the Registration's own policy, falling back to the operator default
+func (h *handler) deregistrationPolicy(registrationObj *v1.Registration) (v1.DeregistrationMode, time.Duration) {
+	mode, timeout := v1.DeregistrationBestEffort, time.Duration(0)
+	if h.options != nil && h.options.OperatorSettings != nil {
+		mode, timeout = h.options.OperatorSettings.Deregistration.Mode, h.options.OperatorSettings.Deregistration.Timeout
+	}
+
+	if policy := registrationObj.Spec.Deregistration; policy != nil {
+		mode = policy.Mode
+		if policy.Timeout != nil {
+			timeout = policy.Timeout.Duration
+		}
+	}
+	if !mode.Valid() {
+		mode = v1.DeregistrationBestEffort
+	}
+	if timeout <= 0 {
+		timeout = consts.DefaultDeregistrationTimeout
+	}
+
+	return mode, timeout
+}
+
+// needsSCCDeregistration reports if a system is still known to SCC; offline systems are only ever removed from SCC by hand
+func needsSCCDeregistration(registrationObj *v1.Registration) bool {
+	return registrationObj.Spec.Mode != v1.RegistrationModeOffline &&
+		registrationObj.Status.SCCSystemID != nil &&
+		!lifecycle.RegistrationIsDeregistered(registrationObj)
+}
+
+// pendingDeregistration is how long is left before a failed deregistration may be retried
+func pendingDeregistration(registrationObj *v1.Registration, now time.Time) time.Duration {
+	status := registrationObj.Status.Deregistration
+	if status == nil || status.NextAttemptAt == nil {
+		return 0
+	}
+
+	return status.NextAttemptAt.Sub(now)
+}
+
+// deregister removes the system from SCC following the Registration's deregistration policy.
+// It returns a retry delay while a required deregistration keeps failing; the Registration must then keep its finalizer.
+func (h *handler) deregister(regHandler SCCHandler, registrationObj *v1.Registration, now time.Time) (time.Duration, error) {
+	if !needsSCCDeregistration(registrationObj) {
+		return 0, nil
+	}
+
+	mode, timeout := h.deregistrationPolicy(registrationObj)
+	if mode == v1.DeregistrationSkip {
+		if err := h.storeDeregistrationTombstone(registrationObj, mode, "deregistration skipped by policy", now); err != nil {
+			return 0, err
+		}
+		h.recordEvent(registrationObj, corev1.EventTypeNormal, eventReasonDeregistrationSkipped,
+			"left system %d registered in SCC as required by the %s policy", *registrationObj.Status.SCCSystemID, mode)
+		return 0, nil
+	}
+
+	deregErr := regHandler.Deregister()
+	if deregErr == nil {
+		h.recordEvent(registrationObj, corev1.EventTypeNormal, eventReasonDeregistered, "deregistered system %d from SCC", *registrationObj.Status.SCCSystemID)
+		return 0, nil
+	}
+
+	var deletedAt time.Time
+	if registrationObj.DeletionTimestamp != nil {
+		deletedAt = registrationObj.DeletionTimestamp.Time
+	}
+	remaining := deletedAt.Add(timeout).Sub(now)
+	if mode == v1.DeregistrationRequiredWithTimeout && remaining > 0 {
+		return h.scheduleDeregistrationRetry(registrationObj, deregErr, remaining, now)
+	}
+
+	reason := fmt.Sprintf("deregistration failed: %s", sccErrorSummary(deregErr))
+	if mode == v1.DeregistrationRequiredWithTimeout {
+		reason = fmt.Sprintf("deregistration still failing after %s: %s", timeout, sccErrorSummary(deregErr))
+	}
+	if err := h.storeDeregistrationTombstone(registrationObj, mode, reason, now); err != nil {
+		return 0, err
+	}
+	h.recordEvent(registrationObj, corev1.EventTypeWarning, eventReasonDeregistrationAbandoned,
+		"gave up deregistering system %d from SCC, it must be removed by hand: %s", *registrationObj.Status.SCCSystemID, reason)
+
+	return 0, nil
+}
+
+// scheduleDeregistrationRetry counts the failed attempt in the status and returns when to retry, never past the policy timeout
+func (h *handler) scheduleDeregistrationRetry(registrationObj *v1.Registration, deregErr error, remaining time.Duration, now time.Time) (time.Duration, error) {
+	h.recordEvent(registrationObj, corev1.EventTypeWarning, eventReasonDeregistrationFailed, "deregistering system %d failed: %s",
+		*registrationObj.Status.SCCSystemID, sccErrorSummary(deregErr))
+	if !h.backoff.enabled() {
+		return 0, deregErr
+	}
+
+	var retryAfter time.Duration
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, getErr := h.registrations.Get(registrationObj.Name, metav1.GetOptions{})
+		if getErr != nil {
+			return getErr
+		}
+
+		current = current.DeepCopy()
+		status := current.Status.Deregistration
+		if status == nil {
+			status = &v1.DeregistrationStatus{}
+		}
+		status.Attempts++
+		status.LastError = deregErr.Error()
+		retryAfter = min(h.backoff.delay(status.Attempts), remaining)
+		status.NextAttemptAt = &metav1.Time{Time: now.Add(retryAfter)}
+		current.Status.Deregistration = status
+
+		_, updateErr := h.registrations.UpdateStatus(current)
+		return updateErr
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to record deregistration attempt: %w", err)
+	}
+
+	return retryAfter, nil
+}
+
+// storeDeregistrationTombstone records the system left in SCC; the Registration keeps its finalizer until it is stored
+func (h *handler) storeDeregistrationTombstone(registrationObj *v1.Registration, policy v1.DeregistrationMode, reason string, now time.Time) error {
+	tombstone := deregistrationTombstone{
+		Registration: registrationObj.Name,
+		SCCSystemID:  *registrationObj.Status.SCCSystemID,
+		Mode:         registrationObj.Spec.Mode,
+		Policy:       policy,
+		Reason:       reason,
+		Time:         metav1.NewTime(now),
+	}
+	if apiURL := registrationObj.Spec.RegistrationRequest; apiURL != nil && apiURL.RegistrationAPIUrl != nil {
+		tombstone.RegistrationURL = *apiURL.RegistrationAPIUrl
+	}
+	encoded, err := json.Marshal(tombstone)
+	if err != nil {
+		return err
+	}
+	key := registrationObj.Name + "." + strconv.Itoa(tombstone.SCCSystemID)
+	namespace := h.options.SystemNamespace()
+
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configMap, getErr := h.configMaps.Get(namespace, consts.DeregistrationTombstonesConfigMapName, metav1.GetOptions{})
+		if apierrors.IsNotFound(getErr) {
+			configMap = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      consts.DeregistrationTombstonesConfigMapName,
+					Namespace: namespace,
+					Labels: map[string]string{
+						consts.LabelSccManagedBy: consts.SccManagedByValue(h.options.OperatorName),
+						consts.LabelK8sManagedBy: h.options.OperatorName,
+					},
+				},
+				Data: map[string]string{key: string(encoded)},
+			}
+			_, createErr := h.configMaps.Create(configMap)
+			return createErr
+		}
+		if getErr != nil {
+			return getErr
+		}
+
+		configMap = configMap.DeepCopy()
+		if configMap.Data == nil {
+			configMap.Data = map[string]string{}
+		}
+		configMap.Data[key] = string(encoded)
+		_, updateErr := h.configMaps.Update(configMap)
+		return updateErr
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store deregistration tombstone in ConfigMap `%s/%s`: %w", namespace, consts.DeregistrationTombstonesConfigMapName, err)
+	}
+
+	h.log.Warnf("system %d of registration %s was left registered in SCC: %s", tombstone.SCCSystemID, registrationObj.Name, reason)
+	return nil
+}
@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/ptr"
+
+	"github.com/rancher/scc-operator/internal/config"
+	"github.com/rancher/scc-operator/internal/consts"
+	"github.com/rancher/scc-operator/internal/logging"
+	"github.com/rancher/scc-operator/internal/types"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+)
+
+func deletedRegistration(deletedAt time.Time) *v1.Registration {
+	return &v1.Registration{
+		ObjectMeta: metav1.ObjectMeta{Name: "scc-registration-abc", DeletionTimestamp: &metav1.Time{Time: deletedAt}},
+		Spec:       v1.RegistrationSpec{Mode: v1.RegistrationModeOnline},
+		Status:     v1.RegistrationStatus{SCCSystemID: ptr.To(1234)},
+	}
+}
+
+// expectTombstone captures the tombstone written to a new ConfigMap
+func expectTombstone(t *testing.T, mockConfigMaps *fake.MockControllerInterface[*corev1.ConfigMap, *corev1.ConfigMapList]) *deregistrationTombstone {
+	tombstone := &deregistrationTombstone{}
+	notFound := apierrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, consts.DeregistrationTombstonesConfigMapName)
+	mockConfigMaps.EXPECT().Get(consts.DefaultSCCNamespace, consts.DeregistrationTombstonesConfigMapName, gomock.Any()).Return(nil, notFound)
+	mockConfigMaps.EXPECT().Create(gomock.Any()).DoAndReturn(func(configMap *corev1.ConfigMap) (*corev1.ConfigMap, error) {
+		require.Contains(t, configMap.Data, "scc-registration-abc.1234")
+		require.NoError(t, json.Unmarshal([]byte(configMap.Data["scc-registration-abc.1234"]), tombstone))
+		return configMap, nil
+	})
+	return tombstone
+}
+
+func TestDeregisterSucceeds(t *testing.T) {
+	h := &handler{
+		log:     logging.NewLog(),
+		backoff: retryPolicy{baseDelay: time.Minute, maxDelay: time.Hour},
+		options: &types.RunOptions{
+			OperatorName: consts.DefaultOperatorName,
+			OperatorSettings: &config.OperatorSettings{
+				SystemNamespace: consts.DefaultSCCNamespace,
+				Deregistration:  config.DeregistrationSettings{Mode: v1.DeregistrationRequiredWithTimeout, Timeout: time.Hour},
+			},
+		},
+	}
+	regHandler := &deregisterRecorder{}
+
+	retryAfter, err := h.deregister(regHandler, deletedRegistration(time.Now()), time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, retryAfter)
+	assert.Equal(t, 1, regHandler.calls)
+}
+
+func TestDeregisterBestEffortStoresTombstone(t *testing.T) {
+	gomockCtrl := gomock.NewController(t)
+	mockConfigMaps := fake.NewMockControllerInterface[*corev1.ConfigMap, *corev1.ConfigMapList](gomockCtrl)
+	h := &handler{
+		log:        logging.NewLog(),
+		configMaps: mockConfigMaps,
+		backoff:    retryPolicy{baseDelay: time.Minute, maxDelay: time.Hour},
+		options: &types.RunOptions{
+			OperatorName: consts.DefaultOperatorName,
+			OperatorSettings: &config.OperatorSettings{
+				SystemNamespace: consts.DefaultSCCNamespace,
+				Deregistration:  config.DeregistrationSettings{Mode: v1.DeregistrationBestEffort, Timeout: time.Hour},
+			},
+		},
+	}
+	regHandler := &deregisterRecorder{err: errors.New("scc is down")}
+	tombstone := expectTombstone(t, mockConfigMaps)
+
+	retryAfter, err := h.deregister(regHandler, deletedRegistration(time.Now()), time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, retryAfter)
+	assert.Equal(t, 1234, tombstone.SCCSystemID)
+	assert.Equal(t, v1.DeregistrationBestEffort, tombstone.Policy)
+	assert.Contains(t, tombstone.Reason, "scc is down")
+}
+
+func TestDeregisterRequiredRetriesUntilTimeout(t *testing.T) {
+	gomockCtrl := gomock.NewController(t)
+	mockRegistrations := fake.NewMockNonNamespacedControllerInterface[*v1.Registration, *v1.RegistrationList](gomockCtrl)
+	mockConfigMaps := fake.NewMockControllerInterface[*corev1.ConfigMap, *corev1.ConfigMapList](gomockCtrl)
+	h := &handler{
+		log:           logging.NewLog(),
+		registrations: mockRegistrations,
+		configMaps:    mockConfigMaps,
+		backoff:       retryPolicy{baseDelay: time.Minute, maxDelay: time.Hour},
+		options: &types.RunOptions{
+			OperatorName: consts.DefaultOperatorName,
+			OperatorSettings: &config.OperatorSettings{
+				SystemNamespace: consts.DefaultSCCNamespace,
+				Deregistration:  config.DeregistrationSettings{Mode: v1.DeregistrationRequiredWithTimeout, Timeout: time.Hour},
+			},
+		},
+	}
+	regHandler := &deregisterRecorder{err: errors.New("scc is down")}
+	now := time.Now()
+	registration := deletedRegistration(now.Add(-50 * time.Minute))
+
+	mockRegistrations.EXPECT().Get(registration.Name, gomock.Any()).Return(registration, nil)
+	mockRegistrations.EXPECT().UpdateStatus(gomock.Any()).DoAndReturn(func(updated *v1.Registration) (*v1.Registration, error) {
+		registration = updated
+		return updated, nil
+	})
+
+	retryAfter, err := h.deregister(regHandler, registration, now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, retryAfter)
+	require.NotNil(t, registration.Status.Deregistration)
+	assert.Equal(t, int32(1), registration.Status.Deregistration.Attempts)
+	assert.Equal(t, "scc is down", registration.Status.Deregistration.LastError)
+	assert.Equal(t, time.Minute, pendingDeregistration(registration, now))
+
+	// Later retries never wait past the timeout
+	registration.Status.Deregistration.Attempts = 5
+	mockRegistrations.EXPECT().Get(registration.Name, gomock.Any()).Return(registration, nil)
+	mockRegistrations.EXPECT().UpdateStatus(gomock.Any()).DoAndReturn(func(updated *v1.Registration) (*v1.Registration, error) {
+		return updated, nil
+	})
+	retryAfter, err = h.deregister(regHandler, registration, now)
+	require.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, retryAfter)
+
+	// Once the timeout has passed the system is given up on
+	tombstone := expectTombstone(t, mockConfigMaps)
+	retryAfter, err = h.deregister(regHandler, registration, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, retryAfter)
+	assert.Equal(t, v1.DeregistrationRequiredWithTimeout, tombstone.Policy)
+}
+
+func TestDeregisterSkipNeverContactsSCC(t *testing.T) {
+	gomockCtrl := gomock.NewController(t)
+	mockConfigMaps := fake.NewMockControllerInterface[*corev1.ConfigMap, *corev1.ConfigMapList](gomockCtrl)
+	h := &handler{
+		log:        logging.NewLog(),
+		configMaps: mockConfigMaps,
+		backoff:    retryPolicy{baseDelay: time.Minute, maxDelay: time.Hour},
+		options: &types.RunOptions{
+			OperatorName: consts.DefaultOperatorName,
+			OperatorSettings: &config.OperatorSettings{
+				SystemNamespace: consts.DefaultSCCNamespace,
+				Deregistration:  config.DeregistrationSettings{Mode: v1.DeregistrationBestEffort, Timeout: time.Hour},
+			},
+		},
+	}
+	regHandler := &deregisterRecorder{}
+	registration := deletedRegistration(time.Now())
+	registration.Spec.Deregistration = &v1.DeregistrationPolicy{Mode: v1.DeregistrationSkip}
+	registration.Spec.RegistrationRequest = &v1.RegistrationRequest{RegistrationAPIUrl: ptr.To("https://rmt.example.com")}
+	tombstone := expectTombstone(t, mockConfigMaps)
+
+	_, err := h.deregister(regHandler, registration, time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, regHandler.calls)
+	assert.Equal(t, v1.DeregistrationSkip, tombstone.Policy)
+	assert.Equal(t, "https://rmt.example.com", tombstone.RegistrationURL)
+}
+
+func TestDeregisterIgnoresUnregisteredSystems(t *testing.T) {
+	h := &handler{
+		log:     logging.NewLog(),
+		backoff: retryPolicy{baseDelay: time.Minute, maxDelay: time.Hour},
+		options: &types.RunOptions{
+			OperatorName: consts.DefaultOperatorName,
+			OperatorSettings: &config.OperatorSettings{
+				SystemNamespace: consts.DefaultSCCNamespace,
+				Deregistration:  config.DeregistrationSettings{Mode: v1.DeregistrationRequiredWithTimeout, Timeout: time.Hour},
+			},
+		},
+	}
+	regHandler := &deregisterRecorder{}
+	registration := deletedRegistration(time.Now())
+	registration.Status.SCCSystemID = nil
+
+	_, err := h.deregister(regHandler, registration, time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, regHandler.calls)
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/rancher/scc-operator/internal/initializer"
 	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+	"github.com/rancher/scc-operator/pkg/controllers/lifecycle"
 	"github.com/rancher/scc-operator/pkg/util/jitterbug"
 )
 
@@ -47,8 +48,10 @@ func (h *handler) RunLifecycleManager(
 			for _, registrationObj := range registrationsCacheList {
 				registrationHandler := h.prepareHandler(registrationObj, rancherURL)
 
-				// Always skip offline mode registrations, or Registrations that haven't progressed to activation
+				// Always skip offline mode, suspended and deregistered registrations, or Registrations that haven't progressed to activation
 				if registrationObj.Spec.Mode == v1.RegistrationModeOffline ||
+					lifecycle.RegistrationIsSuspended(registrationObj) ||
+					lifecycle.RegistrationIsDeregistered(registrationObj) ||
 					registrationHandler.NeedsRegistration(registrationObj) ||
 					registrationObj.Status.ActivationStatus.LastValidatedTS.IsZero() {
 					continue
@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/rancher/scc-operator/internal/consts"
+	"github.com/rancher/scc-operator/internal/suseconnect/credentials"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+	"github.com/rancher/scc-operator/pkg/controllers/lifecycle"
+)
+
+// isDriftCheckedSecret reports if the Secret is one the operator writes and expects nobody else to edit
+func isDriftCheckedSecret(secret *corev1.Secret) bool {
+	role := consts.SecretRole(secret.Labels[consts.LabelSccSecretRole])
+	return role == consts.SCCCredentialsRole || role == consts.RegistrationCode
+}
+
+// registrationsForSecret finds the Registrations sharing the managed Secret's name hash
+func (h *handler) registrationsForSecret(secret *corev1.Secret) ([]*v1.Registration, error) {
+	nameSuffix := secret.Labels[consts.LabelNameSuffix]
+	if nameSuffix == "" {
+		return nil, nil
+	}
+
+	return h.registrationCache.GetByIndex(IndexRegistrationsByNameHash, nameSuffix)
+}
+
+// reviewManagedSecret compares a managed Secret with the content hash the operator stamped on it.
+// A drifted reg code Secret is restored from its entrypoint; anything else is reported on the Registration.
+func (h *handler) reviewManagedSecret(secret *corev1.Secret) (*corev1.Secret, error) {
+	registrations, err := h.registrationsForSecret(secret)
+	if err != nil {
+		return secret, err
+	}
+
+	// Checked before stamping, so a Secret that lost its login is never recorded as the expected content
+	if message := incompleteCredentialsMessage(secret, registrations); message != "" {
+		h.log.Warnf("%s", message)
+		for _, registrationObj := range registrations {
+			h.recordEvent(registrationObj, corev1.EventTypeWarning, eventReasonCredentialsDrift, "%s", message)
+		}
+		return secret, h.updateCredentialsDrift(registrations, secret, message)
+	}
+
+	if _, stamped := secret.Annotations[consts.AnnotationSccContentHash]; !stamped {
+		// Secrets written before drift detection existed, or whose hash was removed to accept their current content
+		h.log.Infof("recording the current content of secret %s/%s as expected", secret.Namespace, secret.Name)
+		if _, err := h.secretRepo.RetryingPatchUpdate(secret, lifecycle.SecretStampContentHash(secret.DeepCopy())); err != nil {
+			return secret, err
+		}
+		return secret, h.updateCredentialsDrift(registrations, secret, "")
+	}
+
+	if !lifecycle.SecretHasDrifted(secret) {
+		return secret, h.updateCredentialsDrift(registrations, secret, "")
+	}
+
+	h.log.Warnf("secret %s/%s was modified outside of the operator", secret.Namespace, secret.Name)
+	if consts.SecretRole(secret.Labels[consts.LabelSccSecretRole]) == consts.RegistrationCode {
+		restoredFor, restoreErr := h.restoreRegCodeSecret(secret, registrations)
+		if restoreErr != nil {
+			h.log.Errorf("cannot restore secret %s/%s: %v", secret.Namespace, secret.Name, restoreErr)
+		}
+		if restoredFor != nil {
+			h.recordEvent(restoredFor, corev1.EventTypeWarning, eventReasonCredentialsRestored, "restored secret %s/%s after it was modified outside of the operator", secret.Namespace, secret.Name)
+			return secret, nil
+		}
+	}
+
+	for _, registrationObj := range registrations {
+		h.recordEvent(registrationObj, corev1.EventTypeWarning, eventReasonCredentialsDrift, "secret %s/%s was modified outside of the operator", secret.Namespace, secret.Name)
+	}
+	return secret, h.updateCredentialsDrift(registrations, secret, fmt.Sprintf(
+		"secret %s/%s was modified outside of the operator; restore it, or remove its %s annotation to accept the current content",
+		secret.Namespace, secret.Name, consts.AnnotationSccContentHash))
+}
+
+// incompleteCredentialsMessage describes a credentials Secret missing the login of a system SCC already announced, if it is
+func incompleteCredentialsMessage(secret *corev1.Secret, registrations []*v1.Registration) string {
+	if consts.SecretRole(secret.Labels[consts.LabelSccSecretRole]) != consts.SCCCredentialsRole {
+		return ""
+	}
+	missing := credentials.MissingLoginKeys(secret)
+	// Until SCC assigns a system ID the Secret only holds a token, so missing keys are expected
+	announced := slices.ContainsFunc(registrations, func(registrationObj *v1.Registration) bool {
+		return registrationObj.Status.SCCSystemID != nil && *registrationObj.Status.SCCSystemID > 0
+	})
+	if len(missing) == 0 || !announced {
+		return ""
+	}
+
+	return fmt.Sprintf("secret %s/%s has no %s for the announced system; restore it, or deregister and register the system again",
+		secret.Namespace, secret.Name, strings.Join(missing, " or "))
+}
+
+// restoreRegCodeSecret rewrites the reg code Secret from the entrypoint of the Registration using it, returning that Registration
+func (h *handler) restoreRegCodeSecret(secret *corev1.Secret, registrations []*v1.Registration) (*v1.Registration, error) {
+	for _, registrationObj := range registrations {
+		entrypoint, err := h.entrypointSecretFor(registrationObj)
+		if err != nil {
+			return nil, err
+		}
+		if entrypoint == nil {
+			continue
+		}
+
+		params, err := extractRegistrationParamsFromSecret(entrypoint, h.options.OperatorName)
+		if err != nil {
+			return nil, err
+		}
+		params, _ = h.pinRotatedNameID(entrypoint, params)
+		if params.regType != v1.RegistrationModeOnline || params.regCodeSecretRef == nil || params.regCodeSecretRef.Name != secret.Name {
+			continue
+		}
+
+		expected, err := h.regCodeFromSecretEntrypoint(params)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := h.secretRepo.RetryingPatchUpdate(secret, expected); err != nil {
+			return nil, err
+		}
+
+		return registrationObj, nil
+	}
+
+	return nil, nil
+}
+
+// updateCredentialsDrift sets the CredentialsDrift condition caused by the given Secret to the message, or clears it when empty
+func (h *handler) updateCredentialsDrift(registrations []*v1.Registration, secret *corev1.Secret, message string) error {
+	drifted := message != ""
+	for _, registrationObj := range registrations {
+		if drifted && v1.RegistrationConditionCredentialsDrift.IsTrue(registrationObj) && v1.RegistrationConditionCredentialsDrift.GetMessage(registrationObj) == message {
+			continue
+		}
+		// Only the Secret that caused the drift may clear it
+		if !drifted && (!v1.RegistrationConditionCredentialsDrift.IsTrue(registrationObj) ||
+			!strings.Contains(v1.RegistrationConditionCredentialsDrift.GetMessage(registrationObj), secret.Namespace+"/"+secret.Name+" ")) {
+			continue
+		}
+
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			current, getErr := h.registrations.Get(registrationObj.Name, metav1.GetOptions{})
+			if getErr != nil {
+				return getErr
+			}
+
+			current = current.DeepCopy()
+			if drifted {
+				v1.RegistrationConditionCredentialsDrift.True(current)
+				v1.RegistrationConditionCredentialsDrift.Message(current, message)
+			} else {
+				v1.RegistrationConditionCredentialsDrift.False(current)
+				v1.RegistrationConditionCredentialsDrift.Message(current, "")
+			}
+
+			_, updateErr := h.registrations.UpdateStatus(current)
+			return updateErr
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,221 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/rancher/scc-operator/internal/config"
+	"github.com/rancher/scc-operator/internal/consts"
+	"github.com/rancher/scc-operator/internal/initializer"
+	"github.com/rancher/scc-operator/internal/logging"
+	"github.com/rancher/scc-operator/internal/repos/secretrepo"
+	"github.com/rancher/scc-operator/internal/suseconnect/credentials"
+	"github.com/rancher/scc-operator/internal/types"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+	"github.com/rancher/scc-operator/pkg/controllers/lifecycle"
+)
+
+func driftCredentialsSecret() *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "scc-system-credentials-abc",
+			Namespace: consts.DefaultSCCNamespace,
+			Labels: map[string]string{
+				consts.LabelSccSecretRole: string(consts.SCCCredentialsRole),
+				consts.LabelNameSuffix:    "abc",
+			},
+		},
+		Data: map[string][]byte{"login": []byte("SCC_login"), "password": []byte("secret")},
+	}
+}
+
+func TestSecretContentHash(t *testing.T) {
+	secret := driftCredentialsSecret()
+	hash := lifecycle.SecretContentHash(secret.Data)
+	assert.Len(t, hash, 64)
+	assert.Equal(t, hash, lifecycle.SecretContentHash(map[string][]byte{"password": []byte("secret"), "login": []byte("SCC_login")}))
+	// Keys and values cannot be shifted into each other to produce the same hash
+	assert.NotEqual(t, lifecycle.SecretContentHash(map[string][]byte{"a": []byte("bc")}), lifecycle.SecretContentHash(map[string][]byte{"ab": []byte("c")}))
+
+	assert.False(t, lifecycle.SecretHasDrifted(secret), "unstamped secrets are never considered drifted")
+	secret = lifecycle.SecretStampContentHash(secret)
+	assert.Equal(t, hash, secret.Annotations[consts.AnnotationSccContentHash])
+	assert.False(t, lifecycle.SecretHasDrifted(secret))
+
+	secret.Data["password"] = []byte("changed")
+	assert.True(t, lifecycle.SecretHasDrifted(secret))
+}
+
+func TestReviewManagedSecretStampsLegacySecret(t *testing.T) {
+	gomockCtrl := gomock.NewController(t)
+	mockRegistrationCache := fake.NewMockNonNamespacedCacheInterface[*v1.Registration](gomockCtrl)
+	mockSecrets := fake.NewMockControllerInterface[*corev1.Secret, *corev1.SecretList](gomockCtrl)
+	h := &handler{
+		log:               logging.NewLog(),
+		registrationCache: mockRegistrationCache,
+		secretRepo:        &secretrepo.SecretRepository{Controller: mockSecrets},
+		options: &types.RunOptions{
+			OperatorName:     consts.DefaultOperatorName,
+			OperatorSettings: &config.OperatorSettings{SystemNamespace: consts.DefaultSCCNamespace},
+		},
+	}
+	secret := driftCredentialsSecret()
+
+	mockRegistrationCache.EXPECT().GetByIndex(IndexRegistrationsByNameHash, "abc").Return(nil, nil)
+	mockSecrets.EXPECT().Patch(consts.DefaultSCCNamespace, secret.Name, k8stypes.MergePatchType, gomock.Any()).
+		DoAndReturn(func(_, _ string, _ k8stypes.PatchType, patch []byte, _ ...string) (*corev1.Secret, error) {
+			assert.Contains(t, string(patch), consts.AnnotationSccContentHash)
+			return lifecycle.SecretStampContentHash(secret.DeepCopy()), nil
+		})
+
+	_, err := h.reviewManagedSecret(secret)
+	require.NoError(t, err)
+}
+
+func TestReviewManagedSecretReportsCredentialsDrift(t *testing.T) {
+	gomockCtrl := gomock.NewController(t)
+	mockRegistrations := fake.NewMockNonNamespacedControllerInterface[*v1.Registration, *v1.RegistrationList](gomockCtrl)
+	mockRegistrationCache := fake.NewMockNonNamespacedCacheInterface[*v1.Registration](gomockCtrl)
+	h := &handler{
+		log:               logging.NewLog(),
+		registrations:     mockRegistrations,
+		registrationCache: mockRegistrationCache,
+		options: &types.RunOptions{
+			OperatorName:     consts.DefaultOperatorName,
+			OperatorSettings: &config.OperatorSettings{SystemNamespace: consts.DefaultSCCNamespace},
+		},
+	}
+	secret := lifecycle.SecretStampContentHash(driftCredentialsSecret())
+	secret.Data["password"] = []byte("edited by hand")
+	registration := &v1.Registration{ObjectMeta: metav1.ObjectMeta{Name: "scc-registration-abc"}}
+
+	mockRegistrationCache.EXPECT().GetByIndex(IndexRegistrationsByNameHash, "abc").Return([]*v1.Registration{registration}, nil)
+	mockRegistrations.EXPECT().Get(registration.Name, gomock.Any()).Return(registration, nil)
+	mockRegistrations.EXPECT().UpdateStatus(gomock.Any()).DoAndReturn(func(updated *v1.Registration) (*v1.Registration, error) {
+		registration = updated
+		return updated, nil
+	})
+
+	_, err := h.reviewManagedSecret(secret)
+	require.NoError(t, err)
+	assert.True(t, v1.RegistrationConditionCredentialsDrift.IsTrue(registration))
+	assert.Contains(t, v1.RegistrationConditionCredentialsDrift.GetMessage(registration), secret.Namespace+"/"+secret.Name)
+
+	// Once the content matches again, the condition is cleared
+	secret.Data["password"] = []byte("secret")
+	mockRegistrationCache.EXPECT().GetByIndex(IndexRegistrationsByNameHash, "abc").Return([]*v1.Registration{registration}, nil)
+	mockRegistrations.EXPECT().Get(registration.Name, gomock.Any()).Return(registration, nil)
+	mockRegistrations.EXPECT().UpdateStatus(gomock.Any()).DoAndReturn(func(updated *v1.Registration) (*v1.Registration, error) {
+		registration = updated
+		return updated, nil
+	})
+
+	_, err = h.reviewManagedSecret(secret)
+	require.NoError(t, err)
+	assert.False(t, v1.RegistrationConditionCredentialsDrift.IsTrue(registration))
+}
+
+func TestReviewManagedSecretReportsIncompleteCredentials(t *testing.T) {
+	gomockCtrl := gomock.NewController(t)
+	mockRegistrations := fake.NewMockNonNamespacedControllerInterface[*v1.Registration, *v1.RegistrationList](gomockCtrl)
+	mockRegistrationCache := fake.NewMockNonNamespacedCacheInterface[*v1.Registration](gomockCtrl)
+	mockSecrets := fake.NewMockControllerInterface[*corev1.Secret, *corev1.SecretList](gomockCtrl)
+	h := &handler{
+		log:               logging.NewLog(),
+		registrations:     mockRegistrations,
+		registrationCache: mockRegistrationCache,
+		secretRepo:        &secretrepo.SecretRepository{Controller: mockSecrets},
+		options: &types.RunOptions{
+			OperatorName:     consts.DefaultOperatorName,
+			OperatorSettings: &config.OperatorSettings{SystemNamespace: consts.DefaultSCCNamespace},
+		},
+	}
+	// The Secret of an announced system that lost its login is not stamped as the expected content
+	secret := driftCredentialsSecret()
+	secret.Data = map[string][]byte{credentials.TokenKey: []byte("token")}
+	systemID := 1234
+	registration := &v1.Registration{ObjectMeta: metav1.ObjectMeta{Name: "scc-registration-abc"}}
+	registration.Status.SCCSystemID = &systemID
+
+	mockRegistrationCache.EXPECT().GetByIndex(IndexRegistrationsByNameHash, "abc").Return([]*v1.Registration{registration}, nil)
+	mockRegistrations.EXPECT().Get(registration.Name, gomock.Any()).Return(registration, nil)
+	mockRegistrations.EXPECT().UpdateStatus(gomock.Any()).DoAndReturn(func(updated *v1.Registration) (*v1.Registration, error) {
+		registration = updated
+		return updated, nil
+	})
+
+	_, err := h.reviewManagedSecret(secret)
+	require.NoError(t, err)
+	assert.True(t, v1.RegistrationConditionCredentialsDrift.IsTrue(registration))
+	assert.Contains(t, v1.RegistrationConditionCredentialsDrift.GetMessage(registration), credentials.UsernameKey+" or "+credentials.PasswordKey)
+}
+
+func TestReviewManagedSecretRestoresRegCode(t *testing.T) {
+	initializer.DevMode.Set(true)
+	initializer.OperatorName.Set(consts.DefaultOperatorName)
+	gomockCtrl := gomock.NewController(t)
+	mockRegistrationCache := fake.NewMockNonNamespacedCacheInterface[*v1.Registration](gomockCtrl)
+	mockSecretsCache := fake.NewMockCacheInterface[*corev1.Secret](gomockCtrl)
+	mockSecrets := fake.NewMockControllerInterface[*corev1.Secret, *corev1.SecretList](gomockCtrl)
+	h := &handler{
+		log:               logging.NewLog(),
+		registrationCache: mockRegistrationCache,
+		secretRepo:        &secretrepo.SecretRepository{Cache: mockSecretsCache, Controller: mockSecrets},
+		options: &types.RunOptions{
+			OperatorName:     consts.DefaultOperatorName,
+			OperatorSettings: &config.OperatorSettings{SystemNamespace: consts.DefaultSCCNamespace},
+		},
+	}
+	entrypoint := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      consts.ResourceSCCEntrypointSecretName,
+			Namespace: consts.DefaultSCCNamespace,
+			Labels:    map[string]string{consts.LabelObjectSalt: "salty"},
+		},
+		Data: map[string][]byte{
+			consts.SecretKeyRegistrationCode: []byte("REGCODE-1234"),
+			dataKeyRegistrationType:          []byte(v1.RegistrationModeOnline),
+		},
+	}
+	params, err := extractRegistrationParamsFromSecret(entrypoint, consts.DefaultOperatorName)
+	require.NoError(t, err)
+	require.NotNil(t, params.regCodeSecretRef)
+
+	regCodeSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      params.regCodeSecretRef.Name,
+			Namespace: consts.DefaultSCCNamespace,
+			Labels: map[string]string{
+				consts.LabelSccSecretRole: string(consts.RegistrationCode),
+				consts.LabelNameSuffix:    params.nameID,
+			},
+		},
+		Data: map[string][]byte{consts.SecretKeyRegistrationCode: []byte("REGCODE-1234")},
+	}
+	regCodeSecret = lifecycle.SecretStampContentHash(regCodeSecret)
+	regCodeSecret.Data[consts.SecretKeyRegistrationCode] = []byte("SOMETHING-ELSE")
+	registration := &v1.Registration{ObjectMeta: metav1.ObjectMeta{
+		Name:   "scc-registration-" + params.nameID,
+		Labels: map[string]string{consts.LabelNameSuffix: params.nameID},
+	}}
+
+	mockRegistrationCache.EXPECT().GetByIndex(IndexRegistrationsByNameHash, params.nameID).Return([]*v1.Registration{registration}, nil)
+	mockSecretsCache.EXPECT().List(consts.DefaultSCCNamespace, gomock.Any()).Return([]*corev1.Secret{regCodeSecret, entrypoint}, nil)
+	mockSecretsCache.EXPECT().Get(consts.DefaultSCCNamespace, regCodeSecret.Name).Return(regCodeSecret, nil)
+	mockSecrets.EXPECT().Patch(consts.DefaultSCCNamespace, regCodeSecret.Name, k8stypes.MergePatchType, gomock.Any()).
+		DoAndReturn(func(_, _ string, _ k8stypes.PatchType, patch []byte, _ ...string) (*corev1.Secret, error) {
+			assert.NotContains(t, string(patch), "SOMETHING-ELSE")
+			return regCodeSecret, nil
+		})
+
+	_, err = h.reviewManagedSecret(regCodeSecret)
+	require.NoError(t, err)
+	assert.False(t, v1.RegistrationConditionCredentialsDrift.IsTrue(registration))
+}
@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"encoding/json"
+	"sync"
+
+	wranglerCore "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/rancher/scc-operator/internal/consts"
+	"github.com/rancher/scc-operator/internal/initializer"
+	"github.com/rancher/scc-operator/internal/logging"
+	"github.com/rancher/scc-operator/internal/suseconnect"
+	"github.com/rancher/scc-operator/internal/types"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+)
+
+// configMapDryRunRecorder logs every would-be SCC request and keeps the latest ones for each Registration in a ConfigMap
+type configMapDryRunRecorder struct {
+	log        *logrus.Entry
+	configMaps wranglerCore.ConfigMapClient
+	namespace  string
+	// mu serializes updates so concurrent Registrations don't keep conflicting on the shared ConfigMap
+	mu sync.Mutex
+}
+
+// registeredInDryRun reports if the Registration holds the system ID a dry run stands in for SCC's
+func registeredInDryRun(registrationObj *v1.Registration) bool {
+	return registrationObj.Status.SCCSystemID != nil &&
+		*registrationObj.Status.SCCSystemID == int(suseconnect.DryRunRegistrationSystemID)
+}
+
+// newDryRunRecorder returns nil unless the operator runs in dry-run mode
+func newDryRunRecorder(options *types.RunOptions, configMaps wranglerCore.ConfigMapClient) suseconnect.DryRunRecorder {
+	if options == nil || options.OperatorSettings == nil || !options.OperatorSettings.DryRun {
+		return nil
+	}
+
+	return &configMapDryRunRecorder{
+		log:        logging.NewControllerLogger("dry-run"),
+		configMaps: configMaps,
+		namespace:  options.SystemNamespace(),
+	}
+}
+
+func (r *configMapDryRunRecorder) Record(request suseconnect.DryRunRequest) {
+	encoded, err := json.Marshal(request)
+	if err != nil {
+		r.log.Errorf("cannot encode dry-run request `%s` for registration `%s`: %v", request.Operation, request.Registration, err)
+		return
+	}
+	r.log.Infof("dry-run: would send SCC request %s", encoded)
+
+	// Recording must never change the outcome of the lifecycle it observes, so ConfigMap errors are only logged
+	if err := r.store(request); err != nil {
+		r.log.Errorf("cannot store dry-run request in ConfigMap `%s/%s`: %v", r.namespace, consts.DryRunConfigMapName, err)
+	}
+}
+
+func (r *configMapDryRunRecorder) store(request suseconnect.DryRunRequest) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configMap, err := r.configMaps.Get(r.namespace, consts.DryRunConfigMapName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			configMap = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      consts.DryRunConfigMapName,
+					Namespace: r.namespace,
+					Labels: map[string]string{
+						consts.LabelSccManagedBy: consts.SccManagedByValue(initializer.OperatorName.Get()),
+						consts.LabelK8sManagedBy: initializer.OperatorName.Get(),
+					},
+				},
+			}
+			if configMap.Data, err = appendDryRunRequest(nil, request); err != nil {
+				return err
+			}
+			_, err = r.configMaps.Create(configMap)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		configMap = configMap.DeepCopy()
+		if configMap.Data, err = appendDryRunRequest(configMap.Data, request); err != nil {
+			return err
+		}
+		_, err = r.configMaps.Update(configMap)
+		return err
+	})
+}
+
+// appendDryRunRequest adds the request to its Registration's entry, dropping the oldest ones beyond the kept amount
+func appendDryRunRequest(data map[string]string, request suseconnect.DryRunRequest) (map[string]string, error) {
+	if data == nil {
+		data = map[string]string{}
+	}
+
+	var requests []suseconnect.DryRunRequest
+	if existing := data[request.Registration]; existing != "" {
+		// An entry that cannot be read is replaced rather than blocking all further recording
+		_ = json.Unmarshal([]byte(existing), &requests)
+	}
+	requests = append(requests, request)
+	if len(requests) > consts.DryRunRequestsPerRegistration {
+		requests = requests[len(requests)-consts.DryRunRequestsPerRegistration:]
+	}
+
+	encoded, err := json.MarshalIndent(requests, "", "  ")
+	if err != nil {
+		return data, err
+	}
+	data[request.Registration] = string(encoded)
+
+	return data, nil
+}
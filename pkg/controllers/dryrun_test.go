@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/SUSE/connect-ng/pkg/connection"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/utils/ptr"
+
+	"github.com/rancher/scc-operator/internal/config"
+	"github.com/rancher/scc-operator/internal/consts"
+	"github.com/rancher/scc-operator/internal/initializer"
+	"github.com/rancher/scc-operator/internal/logging"
+	"github.com/rancher/scc-operator/internal/rancher/settings"
+	"github.com/rancher/scc-operator/internal/repos/secretrepo"
+	"github.com/rancher/scc-operator/internal/suseconnect"
+	"github.com/rancher/scc-operator/internal/suseconnect/credentials"
+	"github.com/rancher/scc-operator/internal/telemetry"
+	"github.com/rancher/scc-operator/internal/types"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+)
+
+func TestAppendDryRunRequest(t *testing.T) {
+	var data map[string]string
+	var err error
+	for i := range consts.DryRunRequestsPerRegistration + 5 {
+		data, err = appendDryRunRequest(data, suseconnect.DryRunRequest{Registration: "scc-registration-abc", Operation: fmt.Sprintf("keepalive-%d", i)})
+		require.NoError(t, err)
+	}
+
+	var requests []suseconnect.DryRunRequest
+	require.NoError(t, json.Unmarshal([]byte(data["scc-registration-abc"]), &requests))
+	require.Len(t, requests, consts.DryRunRequestsPerRegistration)
+	assert.Equal(t, "keepalive-5", requests[0].Operation)
+	assert.Equal(t, fmt.Sprintf("keepalive-%d", consts.DryRunRequestsPerRegistration+4), requests[len(requests)-1].Operation)
+
+	// An unreadable entry is replaced instead of blocking the recording
+	data["scc-registration-abc"] = "not json"
+	data, err = appendDryRunRequest(data, suseconnect.DryRunRequest{Registration: "scc-registration-abc", Operation: "register"})
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal([]byte(data["scc-registration-abc"]), &requests))
+	assert.Len(t, requests, 1)
+}
+
+func TestConfigMapDryRunRecorder(t *testing.T) {
+	assert.Nil(t, newDryRunRecorder(&types.RunOptions{OperatorSettings: &config.OperatorSettings{}}, nil))
+
+	initializer.OperatorName.Set(consts.DefaultOperatorName)
+	gomockCtrl := gomock.NewController(t)
+	mockConfigMaps := fake.NewMockClientInterface[*corev1.ConfigMap, *corev1.ConfigMapList](gomockCtrl)
+	recorder := newDryRunRecorder(&types.RunOptions{OperatorSettings: &config.OperatorSettings{DryRun: true, SystemNamespace: consts.DefaultSCCNamespace}}, mockConfigMaps)
+	require.NotNil(t, recorder)
+	recorder.(*configMapDryRunRecorder).log = logging.NewLog()
+
+	var stored *corev1.ConfigMap
+	notFound := apierrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, consts.DryRunConfigMapName)
+	mockConfigMaps.EXPECT().Get(consts.DefaultSCCNamespace, consts.DryRunConfigMapName, gomock.Any()).Return(nil, notFound)
+	mockConfigMaps.EXPECT().Create(gomock.Any()).DoAndReturn(func(configMap *corev1.ConfigMap) (*corev1.ConfigMap, error) {
+		stored = configMap
+		return configMap, nil
+	})
+	recorder.Record(suseconnect.DryRunRequest{Registration: "scc-registration-abc", Operation: "register"})
+	require.NotNil(t, stored)
+	assert.Equal(t, consts.DefaultSCCNamespace, stored.Namespace)
+	assert.Contains(t, stored.Data["scc-registration-abc"], `"operation": "register"`)
+
+	mockConfigMaps.EXPECT().Get(consts.DefaultSCCNamespace, consts.DryRunConfigMapName, gomock.Any()).Return(stored, nil)
+	mockConfigMaps.EXPECT().Update(gomock.Any()).DoAndReturn(func(configMap *corev1.ConfigMap) (*corev1.ConfigMap, error) {
+		stored = configMap
+		return configMap, nil
+	})
+	recorder.Record(suseconnect.DryRunRequest{Registration: "scc-registration-abc", Operation: "activate"})
+	var requests []suseconnect.DryRunRequest
+	require.NoError(t, json.Unmarshal([]byte(stored.Data["scc-registration-abc"]), &requests))
+	assert.Len(t, requests, 2)
+}
+
+func TestPrepareConnectedModeConnectionDryRun(t *testing.T) {
+	options := &types.RunOptions{OperatorName: "scc-operator-test", OperatorSettings: &config.OperatorSettings{}}
+	registration := &v1.Registration{ObjectMeta: metav1.ObjectMeta{Name: "scc-registration-abc"}}
+
+	sccConnection, err := prepareConnectedModeConnection(options, nil, "https://rancher.example.com", "", registration, connection.NoCredentials{}, telemetry.MetricsWrapper{}, nil)
+	require.NoError(t, err)
+	assert.IsType(t, &suseconnect.SccWrapper{}, sccConnection)
+
+	sccConnection, err = prepareConnectedModeConnection(options, nil, "https://rancher.example.com", "", registration, connection.NoCredentials{}, telemetry.MetricsWrapper{}, &configMapDryRunRecorder{})
+	require.NoError(t, err)
+	assert.IsType(t, &suseconnect.DryRunWrapper{}, sccConnection)
+}
+
+// requestCollector keeps the would-be SCC requests in memory
+type requestCollector struct {
+	requests []suseconnect.DryRunRequest
+}
+
+func (r *requestCollector) Record(request suseconnect.DryRunRequest) {
+	r.requests = append(r.requests, request)
+}
+
+func TestOnRegistrationChangeDryRun(t *testing.T) {
+	initializer.DevMode.Set(true)
+	initializer.OperatorName.Set(consts.DefaultOperatorName)
+
+	var sccRequests atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		sccRequests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(backend.Close)
+
+	settingsClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "management.cattle.io/v3",
+		"kind":       "Setting",
+		"metadata":   map[string]any{"name": consts.SettingNameServerURL},
+		"value":      "https://rancher.example.com",
+	}})
+
+	gomockCtrl := gomock.NewController(t)
+	mockSecretsCache := fake.NewMockCacheInterface[*corev1.Secret](gomockCtrl)
+	mockSecretsCache.EXPECT().Get(gomock.Any(), gomock.Any()).DoAndReturn(func(_, name string) (*corev1.Secret, error) {
+		switch name {
+		case consts.SCCMetricsOutputSecretName:
+			return &corev1.Secret{Data: map[string][]byte{
+				consts.SecretKeyMetricsData: []byte(`{"version":"2.12.0","subscription":{"installuuid":"uuid","product":"rancher","version":"2.12.0","arch":"amd64","git":"abc"}}`),
+			}}, nil
+		case consts.SCCCredentialsSecretName("abc"):
+			return &corev1.Secret{Data: map[string][]byte{credentials.UsernameKey: []byte("login"), credentials.PasswordKey: []byte("password")}}, nil
+		}
+		return &corev1.Secret{Data: map[string][]byte{consts.SecretKeyInstanceData: []byte("<instance document>")}}, nil
+	}).AnyTimes()
+
+	registration := paygRegistrationForKeepalive("abc", backend.URL)
+	registration.Status.SCCSystemID = ptr.To(int(suseconnect.DryRunRegistrationSystemID))
+	var stored *v1.Registration
+	mockRegistrations := fake.NewMockNonNamespacedControllerInterface[*v1.Registration, *v1.RegistrationList](gomockCtrl)
+	mockRegistrations.EXPECT().Get(gomock.Any(), gomock.Any()).DoAndReturn(func(string, metav1.GetOptions) (*v1.Registration, error) {
+		return registration.DeepCopy(), nil
+	}).AnyTimes()
+	mockRegistrations.EXPECT().UpdateStatus(gomock.Any()).DoAndReturn(func(reg *v1.Registration) (*v1.Registration, error) {
+		stored = reg
+		return reg, nil
+	}).AnyTimes()
+
+	recorder := &requestCollector{}
+	h := &handler{
+		ctx:           context.Background(),
+		log:           logging.NewLog(),
+		options:       &types.RunOptions{OperatorName: consts.DefaultOperatorName, OperatorSettings: &config.OperatorSettings{SystemNamespace: consts.DefaultSCCNamespace, DryRun: true}},
+		registrations: mockRegistrations,
+		secretRepo:    &secretrepo.SecretRepository{Cache: mockSecretsCache},
+		settings:      settings.NewSettingReader(settingsClient),
+		dryRun:        recorder,
+	}
+
+	// In dry-run mode the keepalive is only recorded, and the stand-in system ID is kept
+	_, err := h.OnRegistrationChange(registration.Name, registration.DeepCopy())
+	require.NoError(t, err)
+	assert.NotEmpty(t, recorder.requests)
+	assert.Zero(t, sccRequests.Load())
+	require.NotNil(t, stored)
+	assert.Equal(t, int(suseconnect.DryRunRegistrationSystemID), *stored.Status.SCCSystemID)
+
+	// Once dry-run is off the stand-in outcome is discarded and the registration is announced for real
+	h.dryRun = nil
+	h.options.OperatorSettings.DryRun = false
+	stored = nil
+	_, err = h.OnRegistrationChange(registration.Name, registration.DeepCopy())
+	require.NoError(t, err)
+	assert.Zero(t, sccRequests.Load())
+	require.NotNil(t, stored)
+	assert.Nil(t, stored.Status.SCCSystemID)
+	assert.False(t, stored.Status.ActivationStatus.Activated)
+	assert.False(t, stored.HasCondition(v1.RegistrationConditionPayAsYouGoAnnounced))
+	assert.True(t, (&sccPayAsYouGoMode{}).NeedsRegistration(stored))
+	assert.False(t, registeredInDryRun(stored))
+}
@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/SUSE/connect-ng/pkg/connection"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/rancher/scc-operator/internal/consts"
+	"github.com/rancher/scc-operator/internal/types"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+)
+
+// Event reasons emitted for Registration lifecycle transitions
+const (
+	eventReasonRegistering             = "Registering"
+	eventReasonRegistered              = "Registered"
+	eventReasonRegistrationFailed      = "RegistrationFailed"
+	eventReasonActivated               = "Activated"
+	eventReasonActivationFailed        = "ActivationFailed"
+	eventReasonKeepalive               = "Keepalive"
+	eventReasonKeepaliveFailed         = "KeepaliveFailed"
+	eventReasonReset                   = "ResetForActivation"
+	eventReasonFailed                  = "Failed"
+	eventReasonRecovering              = "Recovering"
+	eventReasonActionSucceeded         = "ActionSucceeded"
+	eventReasonActionFailed            = "ActionFailed"
+	eventReasonCredentialsDrift        = "CredentialsDrift"
+	eventReasonCredentialsRestored     = "CredentialsRestored"
+	eventReasonSuspended               = "Suspended"
+	eventReasonResumed                 = "Resumed"
+	eventReasonRegCodeRotated          = "RegCodeRotated"
+	eventReasonOfflineExpired          = "OfflineExpired"
+	eventReasonExpiringSoon            = "ExpiringSoon"
+	eventReasonDeregistered            = "Deregistered"
+	eventReasonDeregistrationFailed    = "DeregistrationFailed"
+	eventReasonDeregistrationAbandoned = "DeregistrationAbandoned"
+	eventReasonDeregistrationSkipped   = "DeregistrationSkipped"
+	eventReasonOfflineCertRotated      = "OfflineCertificateRotated"
+	eventReasonOfflineCertRejected     = "OfflineCertificateRejected"
+	eventReasonDryRunDiscarded         = "DryRunDiscarded"
+)
+
+// recordEvent emits an Event on the Registration and, when it can be found, on the entrypoint Secret that created it
+func (h *handler) recordEvent(registrationObj *v1.Registration, eventType, reason, messageFmt string, args ...any) {
+	if h.recorder == nil || registrationObj == nil {
+		return
+	}
+
+	h.recorder.Eventf(registrationObj, eventType, reason, messageFmt, args...)
+	if entrypoint, err := h.entrypointSecretFor(registrationObj); err == nil && entrypoint != nil {
+		h.recorder.Eventf(entrypoint, eventType, reason, "Registration %s: "+messageFmt, append([]any{registrationObj.Name}, args...)...)
+	}
+}
+
+// recordPhaseEvent emits a Normal Event naming the lifecycle phase that completed
+func (h *handler) recordPhaseEvent(registrationObj *v1.Registration, reason string, phase types.Phase, messageFmt string, args ...any) {
+	h.recordEvent(registrationObj, corev1.EventTypeNormal, reason, "%s: %s", types.PhaseName(phase), fmt.Sprintf(messageFmt, args...))
+}
+
+// recordPhaseError emits a Warning Event naming the lifecycle phase that failed, including the SCC HTTP code when known
+func (h *handler) recordPhaseError(registrationObj *v1.Registration, reason string, phase types.Phase, err error) {
+	h.recordEvent(registrationObj, corev1.EventTypeWarning, reason, "%s failed: %s", types.PhaseName(phase), sccErrorSummary(err))
+}
+
+// sccErrorSummary describes an error, leading with the HTTP status when it came from the SCC API
+func sccErrorSummary(err error) string {
+	var sccAPIError *connection.ApiError
+	if errors.As(err, &sccAPIError) {
+		return fmt.Sprintf("SCC API returned %s (%d): %s", http.StatusText(sccAPIError.Code), sccAPIError.Code, sccAPIError.Message)
+	}
+
+	return err.Error()
+}
+
+// entrypointSecretFor finds the entrypoint Secret sharing the Registration's name hash, or nil when there is none
+func (h *handler) entrypointSecretFor(registrationObj *v1.Registration) (*corev1.Secret, error) {
+	nameSuffix := registrationObj.Labels[consts.LabelNameSuffix]
+	if nameSuffix == "" || h.secretRepo == nil || h.secretRepo.Cache == nil || h.options == nil {
+		return nil, nil
+	}
+
+	secrets, err := h.secretRepo.Cache.List(h.options.SystemNamespace(), labels.SelectorFromSet(labels.Set{consts.LabelNameSuffix: nameSuffix}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list secrets for registration %s: %w", registrationObj.Name, err)
+	}
+	for _, secret := range secrets {
+		if h.isSCCEntrypointSecret(secret) {
+			return secret, nil
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/SUSE/connect-ng/pkg/connection"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/rancher/scc-operator/internal/config"
+	"github.com/rancher/scc-operator/internal/consts"
+	"github.com/rancher/scc-operator/internal/repos/secretrepo"
+	"github.com/rancher/scc-operator/internal/types"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+)
+
+func TestSCCErrorSummary(t *testing.T) {
+	wrapped := fmt.Errorf("announce failed: %w", &connection.ApiError{Code: 401, Message: "invalid regcode"})
+	assert.Equal(t, "SCC API returned Unauthorized (401): invalid regcode", sccErrorSummary(wrapped))
+	assert.Equal(t, "connection refused", sccErrorSummary(errors.New("connection refused")))
+}
+
+func TestRecordPhaseEvents(t *testing.T) {
+	gomockCtrl := gomock.NewController(t)
+	mockSecretsCache := fake.NewMockCacheInterface[*corev1.Secret](gomockCtrl)
+	secrets := []*corev1.Secret{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "registration-code-abc",
+				Namespace: consts.DefaultSCCNamespace,
+				Labels:    map[string]string{consts.LabelNameSuffix: "abc"},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "addon-registration",
+				Namespace: consts.DefaultSCCNamespace,
+				Labels:    map[string]string{consts.LabelNameSuffix: "abc", consts.LabelSccEntrypoint: consts.EntrypointLabelValue},
+			},
+		},
+	}
+	mockSecretsCache.EXPECT().List(consts.DefaultSCCNamespace, gomock.Any()).DoAndReturn(func(_ string, selector labels.Selector) ([]*corev1.Secret, error) {
+		var matching []*corev1.Secret
+		for _, secret := range secrets {
+			if selector.Matches(labels.Set(secret.Labels)) {
+				matching = append(matching, secret)
+			}
+		}
+		return matching, nil
+	}).AnyTimes()
+
+	recorder := record.NewFakeRecorder(10)
+	h := &handler{
+		options:    &types.RunOptions{OperatorSettings: &config.OperatorSettings{SystemNamespace: consts.DefaultSCCNamespace}},
+		secretRepo: &secretrepo.SecretRepository{Cache: mockSecretsCache},
+		recorder:   recorder,
+	}
+	registration := &v1.Registration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "scc-registration-abc",
+			Labels: map[string]string{consts.LabelNameSuffix: "abc"},
+		},
+	}
+
+	h.recordPhaseError(registration, eventReasonRegistrationFailed, types.RegistrationMain, &connection.ApiError{Code: 401, Message: "invalid regcode"})
+	require.Len(t, recorder.Events, 2)
+	assert.Equal(t, "Warning RegistrationFailed Registration/Main failed: SCC API returned Unauthorized (401): invalid regcode", <-recorder.Events)
+	assert.Equal(t, "Warning RegistrationFailed Registration scc-registration-abc: Registration/Main failed: SCC API returned Unauthorized (401): invalid regcode", <-recorder.Events)
+
+	h.recordPhaseEvent(registration, eventReasonActivated, types.ActivationPrepForKeepalive, "activated with SCC")
+	require.Len(t, recorder.Events, 2)
+	assert.Equal(t, "Normal Activated Activation/PrepForKeepalive: activated with SCC", <-recorder.Events)
+
+	// Registrations from another entrypoint only get the event on the Registration itself
+	<-recorder.Events
+	registration.Labels[consts.LabelNameSuffix] = "other"
+	h.recordEvent(registration, corev1.EventTypeNormal, eventReasonKeepalive, "keepalive with SCC succeeded")
+	assert.Len(t, recorder.Events, 1)
+}
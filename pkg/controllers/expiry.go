@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+	"github.com/rancher/scc-operator/pkg/controllers/lifecycle"
+)
+
+// scheduleOfflineExpiry enqueues an activated offline Registration for when its certificate expires,
+// so it is marked expired on time rather than on its next resync
+func (h *handler) scheduleOfflineExpiry(registrationObj *v1.Registration, now time.Time) {
+	if registrationObj.Spec.Mode != v1.RegistrationModeOffline ||
+		!registrationObj.Status.ActivationStatus.Activated ||
+		registrationObj.Status.RegistrationExpiresAt == nil {
+		return
+	}
+
+	if untilExpiry := registrationObj.Status.RegistrationExpiresAt.Sub(now); untilExpiry > 0 {
+		h.registrations.EnqueueAfter(registrationObj.Name, untilExpiry)
+	}
+}
+
+// markOfflineExpired sets the expired condition on an offline Registration whose certificate is past its expiry
+func (h *handler) markOfflineExpired(registrationObj *v1.Registration) error {
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, getErr := h.registrations.Get(registrationObj.Name, metav1.GetOptions{})
+		if getErr != nil {
+			return getErr
+		}
+
+		_, updateErr := h.registrations.UpdateStatus(lifecycle.PrepareOfflineExpired(current.DeepCopy()))
+		return updateErr
+	})
+	if err != nil {
+		return err
+	}
+
+	h.log.Warnf("offline registration %s has expired", registrationObj.Name)
+	h.recordEvent(registrationObj, corev1.EventTypeWarning, eventReasonOfflineExpired, "offline registration certificate expired at %s",
+		registrationObj.Status.RegistrationExpiresAt.UTC().Format(time.RFC3339))
+	return nil
+}
+
+// registrationExpiry is when the subscription of the Registration expires, falling back to the earliest expiry of its activations
+func registrationExpiry(registrationObj *v1.Registration) *metav1.Time {
+	if registrationObj.Status.RegistrationExpiresAt != nil {
+		return registrationObj.Status.RegistrationExpiresAt
+	}
+
+	return earliestActivationExpiry(registrationObj.Status.Activations)
+}
+
+// crossedExpiryThreshold returns the tightest of the thresholds (sorted longest first) the remaining time is within,
+// and how long until the next one is crossed, or until the expiry once all of them are
+func crossedExpiryThreshold(remaining time.Duration, thresholds []time.Duration) (crossed, untilNext time.Duration) {
+	for _, threshold := range thresholds {
+		if remaining > threshold {
+			return crossed, remaining - threshold
+		}
+		crossed = threshold
+	}
+	if remaining > 0 {
+		untilNext = remaining
+	}
+
+	return crossed, untilNext
+}
+
+// formatThreshold shows whole days the way they are configured, e.g. `14d`
+func formatThreshold(threshold time.Duration) string {
+	const day = 24 * time.Hour
+	if threshold%day == 0 {
+		return fmt.Sprintf("%dd", threshold/day)
+	}
+
+	return threshold.String()
+}
+
+func (h *handler) expiryWarningThresholds() []time.Duration {
+	if h.options == nil || h.options.OperatorSettings == nil {
+		return nil
+	}
+
+	return h.options.OperatorSettings.ExpiryWarningThresholds
+}
+
+// reviewExpiry reports the time left before the subscription expires and raises ExpiringSoon as each warning threshold is crossed.
+// It returns true when it updated the Registration status.
+func (h *handler) reviewExpiry(registrationObj *v1.Registration, now time.Time) (bool, error) {
+	expiresAt := registrationExpiry(registrationObj)
+	if expiresAt == nil {
+		registrationSecondsToExpiry.DeletePartialMatch(prometheus.Labels{"registration": registrationObj.Name})
+		if !v1.RegistrationConditionExpiringSoon.IsTrue(registrationObj) {
+			return false, nil
+		}
+		return true, h.updateExpiringSoon(registrationObj, false, "", "")
+	}
+
+	remaining := expiresAt.Sub(now)
+	registrationSecondsToExpiry.WithLabelValues(registrationObj.Name, string(registrationObj.Spec.Mode)).Set(remaining.Seconds())
+
+	crossed, untilNext := crossedExpiryThreshold(remaining, h.expiryWarningThresholds())
+	if untilNext > 0 {
+		h.registrations.EnqueueAfter(registrationObj.Name, untilNext)
+	}
+	if crossed == 0 {
+		if !v1.RegistrationConditionExpiringSoon.IsTrue(registrationObj) {
+			return false, nil
+		}
+		return true, h.updateExpiringSoon(registrationObj, false, "", "")
+	}
+
+	expiry := expiresAt.UTC().Format(time.RFC3339)
+	reason := "Within" + formatThreshold(crossed)
+	message := fmt.Sprintf("subscription expires at %s, in less than %s", expiry, formatThreshold(crossed))
+	if remaining <= 0 {
+		reason = "Expired"
+		message = fmt.Sprintf("subscription expired at %s", expiry)
+	}
+	if v1.RegistrationConditionExpiringSoon.IsTrue(registrationObj) && v1.RegistrationConditionExpiringSoon.GetReason(registrationObj) == reason {
+		return false, nil
+	}
+
+	if err := h.updateExpiringSoon(registrationObj, true, reason, message); err != nil {
+		return true, err
+	}
+	h.recordEvent(registrationObj, corev1.EventTypeWarning, eventReasonExpiringSoon, "%s", message)
+	return true, nil
+}
+
+func (h *handler) updateExpiringSoon(registrationObj *v1.Registration, expiringSoon bool, reason, message string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, getErr := h.registrations.Get(registrationObj.Name, metav1.GetOptions{})
+		if getErr != nil {
+			return getErr
+		}
+
+		current = current.DeepCopy()
+		v1.RegistrationConditionExpiringSoon.SetStatusBool(current, expiringSoon)
+		v1.RegistrationConditionExpiringSoon.Reason(current, reason)
+		v1.RegistrationConditionExpiringSoon.Message(current, message)
+
+		_, updateErr := h.registrations.UpdateStatus(current)
+		return updateErr
+	})
+}
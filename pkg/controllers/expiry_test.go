@@ -0,0 +1,204 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SUSE/connect-ng/pkg/registration"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/rancher/scc-operator/internal/config"
+	"github.com/rancher/scc-operator/internal/logging"
+	"github.com/rancher/scc-operator/internal/types"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+	"github.com/rancher/scc-operator/pkg/controllers/lifecycle"
+)
+
+func testOfflineCertificate(expiresAt time.Time) *registration.OfflineCertificate {
+	return &registration.OfflineCertificate{
+		SystemID:    4321,
+		ProductName: "SUSE Rancher Prime",
+		OfflinePayload: &registration.OfflinePayload{
+			HashedRegcode: "c0ffee",
+			SubscriptionInfo: registration.SubscriptionInfo{
+				Kind:           "full",
+				Name:           "Rancher Prime Subscription",
+				StartsAt:       expiresAt.AddDate(-1, 0, 0),
+				ExpiresAt:      expiresAt,
+				ProductClasses: []registration.ProductClass{{Name: "RANCHER-X86"}, {Name: "RANCHER-ARM64"}},
+			},
+		},
+	}
+}
+
+func activatedOfflineRegistration(expiresAt time.Time) *v1.Registration {
+	return &v1.Registration{
+		ObjectMeta: metav1.ObjectMeta{Name: "scc-registration-offline"},
+		Spec:       v1.RegistrationSpec{Mode: v1.RegistrationModeOffline},
+		Status: v1.RegistrationStatus{
+			ActivationStatus:      v1.SystemActivationState{Activated: true},
+			RegistrationExpiresAt: &metav1.Time{Time: expiresAt},
+		},
+	}
+}
+
+func TestApplyOfflineCertificateToStatus(t *testing.T) {
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	registrationObj := &v1.Registration{Spec: v1.RegistrationSpec{Mode: v1.RegistrationModeOffline}}
+
+	require.NoError(t, applyOfflineCertificateToStatus(registrationObj, testOfflineCertificate(expiresAt)))
+
+	require.NotNil(t, registrationObj.Status.RegistrationExpiresAt)
+	assert.True(t, expiresAt.Equal(registrationObj.Status.RegistrationExpiresAt.Time))
+	assert.Equal(t, "SUSE Rancher Prime", *registrationObj.Status.RegisteredProduct)
+	assert.Equal(t, 4321, *registrationObj.Status.SCCSystemID)
+	require.NotNil(t, registrationObj.Status.OfflineCertificate)
+	assert.Equal(t, "c0ffee", registrationObj.Status.OfflineCertificate.HashedRegcode)
+	assert.Equal(t, "full", registrationObj.Status.OfflineCertificate.SubscriptionKind)
+	assert.Equal(t, []string{"RANCHER-X86", "RANCHER-ARM64"}, registrationObj.Status.OfflineCertificate.ProductClasses)
+	assert.True(t, v1.RegistrationConditionOfflineExpired.IsFalse(registrationObj))
+
+	// Certificates issued before SCC knew the system carry no ID and no product name
+	unknownSystem := testOfflineCertificate(expiresAt)
+	unknownSystem.SystemID = 0
+	unknownSystem.ProductName = ""
+	registrationObj = &v1.Registration{Spec: v1.RegistrationSpec{Mode: v1.RegistrationModeOffline}}
+	require.NoError(t, applyOfflineCertificateToStatus(registrationObj, unknownSystem))
+	assert.Nil(t, registrationObj.Status.SCCSystemID)
+	assert.Equal(t, "Rancher Prime Subscription", *registrationObj.Status.RegisteredProduct)
+}
+
+func TestRegistrationOfflineExpired(t *testing.T) {
+	assert.True(t, lifecycle.RegistrationOfflineExpired(activatedOfflineRegistration(time.Now().Add(-time.Minute))))
+	assert.False(t, lifecycle.RegistrationOfflineExpired(activatedOfflineRegistration(time.Now().Add(time.Hour))))
+
+	notActivated := activatedOfflineRegistration(time.Now().Add(-time.Minute))
+	notActivated.Status.ActivationStatus.Activated = false
+	assert.False(t, lifecycle.RegistrationOfflineExpired(notActivated), "a registration waiting for a renewed certificate is not expired again")
+
+	online := activatedOfflineRegistration(time.Now().Add(-time.Minute))
+	online.Spec.Mode = v1.RegistrationModeOnline
+	assert.False(t, lifecycle.RegistrationOfflineExpired(online))
+}
+
+func TestScheduleOfflineExpiry(t *testing.T) {
+	gomockCtrl := gomock.NewController(t)
+	registrations := fake.NewMockNonNamespacedControllerInterface[*v1.Registration, *v1.RegistrationList](gomockCtrl)
+	h := &handler{log: logging.NewLog(), registrations: registrations}
+	now := time.Now()
+
+	registrations.EXPECT().EnqueueAfter("scc-registration-offline", 2*time.Hour)
+	h.scheduleOfflineExpiry(activatedOfflineRegistration(now.Add(2*time.Hour)), now)
+
+	// Nothing to schedule once expired, nor for registrations without an expiry
+	h.scheduleOfflineExpiry(activatedOfflineRegistration(now.Add(-time.Hour)), now)
+	h.scheduleOfflineExpiry(&v1.Registration{Spec: v1.RegistrationSpec{Mode: v1.RegistrationModeOffline}}, now)
+}
+
+func TestMarkOfflineExpired(t *testing.T) {
+	gomockCtrl := gomock.NewController(t)
+	registrations := fake.NewMockNonNamespacedControllerInterface[*v1.Registration, *v1.RegistrationList](gomockCtrl)
+	h := &handler{log: logging.NewLog(), registrations: registrations}
+	registrationObj := activatedOfflineRegistration(time.Now().Add(-time.Minute))
+
+	registrations.EXPECT().Get(registrationObj.Name, gomock.Any()).Return(registrationObj, nil)
+	registrations.EXPECT().UpdateStatus(gomock.Any()).DoAndReturn(func(updated *v1.Registration) (*v1.Registration, error) {
+		registrationObj = updated
+		return updated, nil
+	})
+
+	require.NoError(t, h.markOfflineExpired(registrationObj))
+	assert.True(t, v1.RegistrationConditionOfflineExpired.IsTrue(registrationObj))
+	assert.True(t, v1.ResourceConditionReady.IsFalse(registrationObj))
+	assert.Contains(t, v1.RegistrationConditionOfflineExpired.GetMessage(registrationObj), "expired at")
+}
+
+func TestCrossedExpiryThreshold(t *testing.T) {
+	const day = 24 * time.Hour
+	thresholds := []time.Duration{30 * day, 14 * day, 3 * day}
+
+	crossed, untilNext := crossedExpiryThreshold(60*day, thresholds)
+	assert.Zero(t, crossed)
+	assert.Equal(t, 30*day, untilNext)
+
+	crossed, untilNext = crossedExpiryThreshold(20*day, thresholds)
+	assert.Equal(t, 30*day, crossed)
+	assert.Equal(t, 6*day, untilNext)
+
+	crossed, untilNext = crossedExpiryThreshold(2*day, thresholds)
+	assert.Equal(t, 3*day, crossed)
+	assert.Equal(t, 2*day, untilNext, "the expiry itself is the last transition")
+
+	crossed, untilNext = crossedExpiryThreshold(-time.Hour, thresholds)
+	assert.Equal(t, 3*day, crossed)
+	assert.Zero(t, untilNext)
+
+	crossed, untilNext = crossedExpiryThreshold(2*day, nil)
+	assert.Zero(t, crossed)
+	assert.Equal(t, 2*day, untilNext)
+}
+
+func TestFormatThreshold(t *testing.T) {
+	assert.Equal(t, "14d", formatThreshold(14*24*time.Hour))
+	assert.Equal(t, "36h0m0s", formatThreshold(36*time.Hour))
+}
+
+func TestReviewExpiry(t *testing.T) {
+	const day = 24 * time.Hour
+	gomockCtrl := gomock.NewController(t)
+	registrations := fake.NewMockNonNamespacedControllerInterface[*v1.Registration, *v1.RegistrationList](gomockCtrl)
+	h := &handler{
+		log:           logging.NewLog(),
+		registrations: registrations,
+		options: &types.RunOptions{OperatorSettings: &config.OperatorSettings{
+			ExpiryWarningThresholds: []time.Duration{30 * day, 14 * day, 3 * day},
+		}},
+	}
+	now := time.Now()
+	registrationObj := activatedOfflineRegistration(now.Add(20 * day))
+	registrations.EXPECT().Get(registrationObj.Name, gomock.Any()).DoAndReturn(func(string, metav1.GetOptions) (*v1.Registration, error) {
+		return registrationObj, nil
+	}).AnyTimes()
+	registrations.EXPECT().UpdateStatus(gomock.Any()).DoAndReturn(func(updated *v1.Registration) (*v1.Registration, error) {
+		registrationObj = updated
+		return updated, nil
+	}).AnyTimes()
+
+	registrations.EXPECT().EnqueueAfter(registrationObj.Name, 6*day)
+	updated, err := h.reviewExpiry(registrationObj, now)
+	require.NoError(t, err)
+	assert.True(t, updated)
+	assert.True(t, v1.RegistrationConditionExpiringSoon.IsTrue(registrationObj))
+	assert.Equal(t, "Within30d", v1.RegistrationConditionExpiringSoon.GetReason(registrationObj))
+	assert.InDelta(t, (20 * day).Seconds(), testutil.ToFloat64(registrationSecondsToExpiry.WithLabelValues(registrationObj.Name, "offline")), 1)
+
+	// The same threshold is only reported once
+	registrations.EXPECT().EnqueueAfter(registrationObj.Name, 6*day)
+	updated, err = h.reviewExpiry(registrationObj, now)
+	require.NoError(t, err)
+	assert.False(t, updated)
+
+	registrations.EXPECT().EnqueueAfter(registrationObj.Name, day)
+	updated, err = h.reviewExpiry(registrationObj, now.Add(16*day))
+	require.NoError(t, err)
+	assert.True(t, updated)
+	assert.Equal(t, "Within14d", v1.RegistrationConditionExpiringSoon.GetReason(registrationObj))
+
+	updated, err = h.reviewExpiry(registrationObj, now.Add(21*day))
+	require.NoError(t, err)
+	assert.True(t, updated)
+	assert.Equal(t, "Expired", v1.RegistrationConditionExpiringSoon.GetReason(registrationObj))
+
+	// A renewed subscription clears the warning
+	registrationObj.Status.RegistrationExpiresAt = &metav1.Time{Time: now.Add(365 * day)}
+	registrations.EXPECT().EnqueueAfter(registrationObj.Name, 335*day)
+	updated, err = h.reviewExpiry(registrationObj, now)
+	require.NoError(t, err)
+	assert.True(t, updated)
+	assert.True(t, v1.RegistrationConditionExpiringSoon.IsFalse(registrationObj))
+}
@@ -0,0 +1,261 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/rancher/scc-operator/internal/config"
+	"github.com/rancher/scc-operator/internal/consts"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+	"github.com/rancher/scc-operator/pkg/controllers/helpers"
+	"github.com/rancher/scc-operator/pkg/controllers/lifecycle"
+)
+
+// Kinds of objects handled by the orphan garbage collector, used as metric labels
+const (
+	orphanKindSecret       = "secret"
+	orphanKindRegistration = "registration"
+)
+
+// orphanReport lists the objects found by one garbage collection pass
+type orphanReport struct {
+	secrets       []*corev1.Secret
+	registrations []*v1.Registration
+}
+
+// RunOrphanCollector periodically removes SCC Secrets and Registrations left behind by interrupted cleanups.
+// Controllers are only registered on the leader, so the collector never runs on more than one replica.
+func (h *handler) RunOrphanCollector(ctx context.Context, settings config.OrphanGCSettings) {
+	if settings.Interval <= 0 {
+		h.log.Info("orphan garbage collection is disabled")
+		return
+	}
+
+	// An unsynced cache would make every Secret look orphaned
+	if !cache.WaitForCacheSync(ctx.Done(), h.registrations.Informer().HasSynced, h.secretRepo.Controller.Informer().HasSynced) {
+		return
+	}
+
+	ticker := time.NewTicker(settings.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.collectOrphans(time.Now(), settings.DryRun)
+		}
+	}
+}
+
+// collectOrphans runs one garbage collection pass, only reporting what it would remove in dry-run mode
+func (h *handler) collectOrphans(now time.Time, dryRun bool) orphanReport {
+	mode := "remove"
+	if dryRun {
+		mode = "dry-run"
+	}
+	orphanGCRuns.WithLabelValues(mode).Inc()
+
+	report := orphanReport{
+		registrations: h.findOrphanedRegistrations(now),
+		secrets:       h.findOrphanedSecrets(now),
+	}
+	orphanGCFound.WithLabelValues(orphanKindRegistration).Add(float64(len(report.registrations)))
+	orphanGCFound.WithLabelValues(orphanKindSecret).Add(float64(len(report.secrets)))
+
+	if dryRun {
+		for _, registrationObj := range report.registrations {
+			h.log.Infof("orphan GC dry-run: would remove registration %s", registrationObj.Name)
+		}
+		for _, secret := range report.secrets {
+			h.log.Infof("orphan GC dry-run: would remove secret %s/%s", secret.Namespace, secret.Name)
+		}
+		h.log.Infof("orphan GC dry-run: found %d orphaned registrations and %d orphaned secrets", len(report.registrations), len(report.secrets))
+		return report
+	}
+
+	for _, registrationObj := range report.registrations {
+		if err := h.removeOrphanedRegistration(registrationObj); err != nil {
+			orphanGCErrors.WithLabelValues(orphanKindRegistration).Inc()
+			h.log.Errorf("orphan GC: failed to remove registration %s: %v", registrationObj.Name, err)
+			continue
+		}
+		orphanGCRemoved.WithLabelValues(orphanKindRegistration).Inc()
+		h.log.Infof("orphan GC: removed registration %s", registrationObj.Name)
+	}
+	for _, secret := range report.secrets {
+		if err := h.removeOrphanedSecret(secret); err != nil {
+			orphanGCErrors.WithLabelValues(orphanKindSecret).Inc()
+			h.log.Errorf("orphan GC: failed to remove secret %s/%s: %v", secret.Namespace, secret.Name, err)
+			continue
+		}
+		orphanGCRemoved.WithLabelValues(orphanKindSecret).Inc()
+		h.log.Infof("orphan GC: removed secret %s/%s", secret.Namespace, secret.Name)
+	}
+
+	return report
+}
+
+// isOrphanCandidate filters out objects the collector must never touch: ones not managed by this operator, or too new to judge
+func (h *handler) isOrphanCandidate(obj metav1.Object, now time.Time) bool {
+	return helpers.ShouldManage(obj, h.options.OperatorName) &&
+		obj.GetLabels()[consts.LabelNameSuffix] != "" &&
+		now.Sub(obj.GetCreationTimestamp().Time) >= consts.OrphanGCGracePeriod
+}
+
+// findOrphanedRegistrations finds the Registrations whose entrypoint Secret is gone.
+// Registrations already being deleted are left to their own finalizer.
+func (h *handler) findOrphanedRegistrations(now time.Time) []*v1.Registration {
+	registrations, err := h.registrationCache.List(labels.Everything())
+	if err != nil {
+		orphanGCErrors.WithLabelValues(orphanKindRegistration).Inc()
+		h.log.Errorf("orphan GC: failed to list registrations: %v", err)
+		return nil
+	}
+
+	var orphans []*v1.Registration
+	for _, registrationObj := range registrations {
+		if registrationObj.DeletionTimestamp != nil || !h.isOrphanCandidate(registrationObj, now) {
+			continue
+		}
+		entrypoint, err := h.entrypointSecretFor(registrationObj)
+		if err != nil {
+			// A registration is only an orphan once its entrypoint is known to be gone
+			orphanGCErrors.WithLabelValues(orphanKindRegistration).Inc()
+			h.log.Errorf("orphan GC: failed to look up the entrypoint of registration %s: %v", registrationObj.Name, err)
+			continue
+		}
+		if entrypoint == nil {
+			orphans = append(orphans, registrationObj)
+		}
+	}
+
+	return orphans
+}
+
+// findOrphanedSecrets finds the related Secrets whose content and name hashes both match no Registration
+func (h *handler) findOrphanedSecrets(now time.Time) []*corev1.Secret {
+	hasRole, err := labels.NewRequirement(consts.LabelSccSecretRole, selection.Exists, nil)
+	if err != nil {
+		orphanGCErrors.WithLabelValues(orphanKindSecret).Inc()
+		h.log.Errorf("orphan GC: failed to prepare secret selector: %v", err)
+		return nil
+	}
+	relatedSecrets, err := h.secretRepo.Cache.List(h.options.SystemNamespace(), labels.NewSelector().Add(*hasRole))
+	if err != nil {
+		orphanGCErrors.WithLabelValues(orphanKindSecret).Inc()
+		h.log.Errorf("orphan GC: failed to list secrets: %v", err)
+		return nil
+	}
+
+	var contentHashes []string
+	for _, secret := range relatedSecrets {
+		if contentHash := secret.Labels[consts.LabelSccHash]; contentHash != "" && !slices.Contains(contentHashes, contentHash) {
+			contentHashes = append(contentHashes, contentHash)
+		}
+	}
+
+	var orphans []*corev1.Secret
+	for _, contentHash := range contentHashes {
+		orphans = append(orphans, h.findOrphanedSecretsByHash(contentHash, now)...)
+	}
+
+	return orphans
+}
+
+// findOrphanedSecretsByHash checks the Secrets written for one entrypoint content hash.
+// A Secret outlives content changes of a live Registration (credentials keep their old hash), so its name hash is checked as well.
+func (h *handler) findOrphanedSecretsByHash(contentHash string, now time.Time) []*corev1.Secret {
+	registrations, err := h.registrationCache.GetByIndex(IndexRegistrationsBySccHash, contentHash)
+	if err != nil {
+		orphanGCErrors.WithLabelValues(orphanKindSecret).Inc()
+		h.log.Errorf("orphan GC: failed to find registrations for content hash %s: %v", contentHash, err)
+		return nil
+	}
+	if len(registrations) > 0 {
+		return nil
+	}
+
+	secrets, err := h.secretRepo.GetBySccContentHash(contentHash)
+	if err != nil {
+		orphanGCErrors.WithLabelValues(orphanKindSecret).Inc()
+		h.log.Errorf("orphan GC: failed to find secrets for content hash %s: %v", contentHash, err)
+		return nil
+	}
+
+	var orphans []*corev1.Secret
+	for _, secret := range secrets {
+		if secret.Namespace != h.options.SystemNamespace() ||
+			secret.Labels[consts.LabelSccSecretRole] == "" ||
+			h.isSCCEntrypointSecret(secret) ||
+			!h.isOrphanCandidate(secret, now) {
+			continue
+		}
+
+		registrations, err := h.registrationCache.GetByIndex(IndexRegistrationsByNameHash, secret.Labels[consts.LabelNameSuffix])
+		if err != nil {
+			orphanGCErrors.WithLabelValues(orphanKindSecret).Inc()
+			h.log.Errorf("orphan GC: failed to find registrations for secret %s/%s: %v", secret.Namespace, secret.Name, err)
+			continue
+		}
+		if len(registrations) == 0 {
+			orphans = append(orphans, secret)
+		}
+	}
+
+	return orphans
+}
+
+// removeOrphanedRegistration drops the operator finalizer so the Registration can be deleted without contacting SCC
+func (h *handler) removeOrphanedRegistration(registrationObj *v1.Registration) error {
+	if lifecycle.RegistrationHasManagedFinalizer(registrationObj) {
+		if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			current, getErr := h.registrations.Get(registrationObj.Name, metav1.GetOptions{})
+			if getErr != nil {
+				return getErr
+			}
+
+			_, updateErr := h.registrations.Update(lifecycle.RegistrationRemoveManagedFinalizer(current.DeepCopy()))
+			return updateErr
+		}); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to remove finalizer: %w", err)
+		}
+	}
+
+	if err := h.registrations.Delete(registrationObj.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+// removeOrphanedSecret drops every operator finalizer from the Secret and deletes it
+func (h *handler) removeOrphanedSecret(secret *corev1.Secret) error {
+	if lifecycle.SecretHasCredentialsFinalizer(secret) ||
+		lifecycle.SecretHasRegCodeFinalizer(secret) ||
+		lifecycle.SecretHasOfflineFinalizer(secret) {
+		secretUpdated := secret.DeepCopy()
+		secretUpdated = lifecycle.SecretRemoveCredentialsFinalizer(secretUpdated)
+		secretUpdated = lifecycle.SecretRemoveRegCodeFinalizer(secretUpdated)
+		secretUpdated = lifecycle.SecretRemoveOfflineFinalizer(secretUpdated)
+		if _, err := h.secretRepo.RetryingPatchUpdate(secret, secretUpdated); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to remove finalizers: %w", err)
+		}
+	}
+
+	if err := h.secretRepo.Controller.Delete(secret.Namespace, secret.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
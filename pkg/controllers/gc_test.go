@@ -0,0 +1,227 @@
+package controllers
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/rancher/scc-operator/internal/config"
+	"github.com/rancher/scc-operator/internal/consts"
+	"github.com/rancher/scc-operator/internal/logging"
+	"github.com/rancher/scc-operator/internal/repos/secretrepo"
+	"github.com/rancher/scc-operator/internal/types"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+)
+
+func gcLabels(nameSuffix, contentHash string, role consts.SecretRole) map[string]string {
+	objLabels := map[string]string{
+		consts.LabelK8sManagedBy: consts.DefaultOperatorName,
+		consts.LabelSccManagedBy: consts.SccManagedByValue(consts.DefaultOperatorName),
+		consts.LabelNameSuffix:   nameSuffix,
+		consts.LabelSccHash:      contentHash,
+	}
+	if role != "" {
+		objLabels[consts.LabelSccSecretRole] = string(role)
+	}
+	return objLabels
+}
+
+// gcCaches serves live, orphaned, in-progress and foreign objects from mocked caches; only the stale Registration
+// and the `gone` reg code and `gone-offline` offline request Secrets are orphans old enough to collect
+func gcCaches(gomockCtrl *gomock.Controller, now time.Time) (*fake.MockNonNamespacedCacheInterface[*v1.Registration], *fake.MockCacheInterface[*corev1.Secret]) {
+	old := metav1.NewTime(now.Add(-time.Hour))
+	fresh := metav1.NewTime(now.Add(-time.Minute))
+	deleting := metav1.NewTime(now)
+
+	live := &v1.Registration{ObjectMeta: metav1.ObjectMeta{Name: "scc-registration-live", CreationTimestamp: old, Labels: gcLabels("live", "h-live", "")}}
+	stale := &v1.Registration{ObjectMeta: metav1.ObjectMeta{
+		Name: "scc-registration-stale", CreationTimestamp: old, Labels: gcLabels("stale", "h-stale", ""),
+		Finalizers: []string{consts.FinalizerSccRegistration},
+	}}
+	notYetLinked := &v1.Registration{ObjectMeta: metav1.ObjectMeta{Name: "scc-registration-new", CreationTimestamp: fresh, Labels: gcLabels("new", "h-new", "")}}
+	beingDeleted := &v1.Registration{ObjectMeta: metav1.ObjectMeta{
+		Name: "scc-registration-deleting", CreationTimestamp: old, DeletionTimestamp: &deleting, Labels: gcLabels("deleting", "h-deleting", ""),
+	}}
+	registrations := []*v1.Registration{live, stale, notYetLinked, beingDeleted}
+
+	entrypoint := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Name: consts.ResourceSCCEntrypointSecretName, Namespace: consts.DefaultSCCNamespace, CreationTimestamp: old, Labels: gcLabels("live", "h-live", ""),
+	}}
+	// Credentials outlive entrypoint content changes, so they may carry an older content hash than their Registration
+	liveCredentials := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Name: consts.SCCCredentialsSecretName("live"), Namespace: consts.DefaultSCCNamespace, CreationTimestamp: old,
+		Labels: gcLabels("live", "h-previous", consts.SCCCredentialsRole), Finalizers: []string{consts.FinalizerSccCredentials},
+	}}
+	orphanRegCode := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Name: "registration-code-gone", Namespace: consts.DefaultSCCNamespace, CreationTimestamp: old,
+		Labels: gcLabels("gone", "h-gone", consts.RegistrationCode), Finalizers: []string{consts.FinalizerSccRegistrationCode},
+	}}
+	orphanRequest := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Name: consts.OfflineRequestSecretName("gone-offline"), Namespace: consts.DefaultSCCNamespace, CreationTimestamp: old,
+		Labels: gcLabels("gone-offline", "h-gone-offline", consts.OfflineRequestRole),
+	}}
+	inProgress := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Name: "registration-code-new", Namespace: consts.DefaultSCCNamespace, CreationTimestamp: fresh,
+		Labels: gcLabels("gone", "h-gone", consts.RegistrationCode),
+	}}
+	foreignLabels := gcLabels("gone", "h-gone", consts.RegistrationCode)
+	foreignLabels[consts.LabelK8sManagedBy] = "another-operator"
+	foreignLabels[consts.LabelSccManagedBy] = consts.SccManagedByValue("another-operator")
+	foreign := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Name: "registration-code-foreign", Namespace: consts.DefaultSCCNamespace, CreationTimestamp: old, Labels: foreignLabels,
+	}}
+	secrets := []*corev1.Secret{entrypoint, liveCredentials, orphanRegCode, orphanRequest, inProgress, foreign}
+
+	registrationCache := fake.NewMockNonNamespacedCacheInterface[*v1.Registration](gomockCtrl)
+	registrationCache.EXPECT().List(gomock.Any()).Return(registrations, nil).AnyTimes()
+	registrationCache.EXPECT().GetByIndex(gomock.Any(), gomock.Any()).DoAndReturn(func(indexName, key string) ([]*v1.Registration, error) {
+		label := consts.LabelSccHash
+		if indexName == IndexRegistrationsByNameHash {
+			label = consts.LabelNameSuffix
+		}
+		var matches []*v1.Registration
+		for _, registrationObj := range registrations {
+			if registrationObj.Labels[label] == key {
+				matches = append(matches, registrationObj)
+			}
+		}
+		return matches, nil
+	}).AnyTimes()
+
+	secretCache := fake.NewMockCacheInterface[*corev1.Secret](gomockCtrl)
+	secretCache.EXPECT().List(consts.DefaultSCCNamespace, gomock.Any()).DoAndReturn(func(_ string, selector labels.Selector) ([]*corev1.Secret, error) {
+		var matches []*corev1.Secret
+		for _, secret := range secrets {
+			if selector.Matches(labels.Set(secret.Labels)) {
+				matches = append(matches, secret)
+			}
+		}
+		return matches, nil
+	}).AnyTimes()
+	secretCache.EXPECT().GetByIndex(secretrepo.IndexSecretsBySccHash, gomock.Any()).DoAndReturn(func(_, contentHash string) ([]*corev1.Secret, error) {
+		var matches []*corev1.Secret
+		for _, secret := range secrets {
+			if secret.Labels[consts.LabelSccHash] == contentHash {
+				matches = append(matches, secret)
+			}
+		}
+		return matches, nil
+	}).AnyTimes()
+
+	return registrationCache, secretCache
+}
+
+func secretNames(secrets []*corev1.Secret) []string {
+	names := make([]string, 0, len(secrets))
+	for _, secret := range secrets {
+		names = append(names, secret.Name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func TestCollectOrphansDryRun(t *testing.T) {
+	now := time.Now()
+	gomockCtrl := gomock.NewController(t)
+	registrationCache, secretCache := gcCaches(gomockCtrl, now)
+	mockRegistrations := fake.NewMockNonNamespacedControllerInterface[*v1.Registration, *v1.RegistrationList](gomockCtrl)
+	mockSecrets := fake.NewMockControllerInterface[*corev1.Secret, *corev1.SecretList](gomockCtrl)
+	h := &handler{
+		log:               logging.NewLog(),
+		registrations:     mockRegistrations,
+		registrationCache: registrationCache,
+		secretRepo:        &secretrepo.SecretRepository{Cache: secretCache, Controller: mockSecrets},
+		options: &types.RunOptions{
+			OperatorName:     consts.DefaultOperatorName,
+			OperatorSettings: &config.OperatorSettings{SystemNamespace: consts.DefaultSCCNamespace},
+		},
+	}
+	foundSecrets := testutil.ToFloat64(orphanGCFound.WithLabelValues(orphanKindSecret))
+	removedSecrets := testutil.ToFloat64(orphanGCRemoved.WithLabelValues(orphanKindSecret))
+
+	// Any write would fail on the mocks, which expect none
+	report := h.collectOrphans(now, true)
+
+	require.Len(t, report.registrations, 1)
+	assert.Equal(t, "scc-registration-stale", report.registrations[0].Name)
+	assert.Equal(t, []string{consts.OfflineRequestSecretName("gone-offline"), "registration-code-gone"}, secretNames(report.secrets))
+	assert.Equal(t, foundSecrets+2, testutil.ToFloat64(orphanGCFound.WithLabelValues(orphanKindSecret)))
+	assert.Equal(t, removedSecrets, testutil.ToFloat64(orphanGCRemoved.WithLabelValues(orphanKindSecret)))
+}
+
+func TestCollectOrphansRemoves(t *testing.T) {
+	now := time.Now()
+	gomockCtrl := gomock.NewController(t)
+	registrationCache, secretCache := gcCaches(gomockCtrl, now)
+	mockRegistrations := fake.NewMockNonNamespacedControllerInterface[*v1.Registration, *v1.RegistrationList](gomockCtrl)
+	mockSecrets := fake.NewMockControllerInterface[*corev1.Secret, *corev1.SecretList](gomockCtrl)
+	h := &handler{
+		log:               logging.NewLog(),
+		registrations:     mockRegistrations,
+		registrationCache: registrationCache,
+		secretRepo:        &secretrepo.SecretRepository{Cache: secretCache, Controller: mockSecrets},
+		options: &types.RunOptions{
+			OperatorName:     consts.DefaultOperatorName,
+			OperatorSettings: &config.OperatorSettings{SystemNamespace: consts.DefaultSCCNamespace},
+		},
+	}
+	removedSecrets := testutil.ToFloat64(orphanGCRemoved.WithLabelValues(orphanKindSecret))
+	removedRegistrations := testutil.ToFloat64(orphanGCRemoved.WithLabelValues(orphanKindRegistration))
+
+	stale := &v1.Registration{ObjectMeta: metav1.ObjectMeta{Name: "scc-registration-stale", Finalizers: []string{consts.FinalizerSccRegistration}}}
+	mockRegistrations.EXPECT().Get(stale.Name, gomock.Any()).Return(stale, nil)
+	mockRegistrations.EXPECT().Update(gomock.Any()).DoAndReturn(func(updated *v1.Registration) (*v1.Registration, error) {
+		assert.Empty(t, updated.Finalizers)
+		return updated, nil
+	})
+	mockRegistrations.EXPECT().Delete(stale.Name, gomock.Any()).Return(nil)
+	mockSecrets.EXPECT().Patch(consts.DefaultSCCNamespace, "registration-code-gone", k8stypes.MergePatchType, gomock.Any()).
+		DoAndReturn(func(_, _ string, _ k8stypes.PatchType, patch []byte, _ ...string) (*corev1.Secret, error) {
+			assert.Contains(t, string(patch), `"finalizers":null`)
+			return &corev1.Secret{}, nil
+		})
+	mockSecrets.EXPECT().Delete(consts.DefaultSCCNamespace, "registration-code-gone", gomock.Any()).Return(nil)
+	mockSecrets.EXPECT().Delete(consts.DefaultSCCNamespace, consts.OfflineRequestSecretName("gone-offline"), gomock.Any()).Return(nil)
+
+	h.collectOrphans(now, false)
+
+	assert.Equal(t, removedSecrets+2, testutil.ToFloat64(orphanGCRemoved.WithLabelValues(orphanKindSecret)))
+	assert.Equal(t, removedRegistrations+1, testutil.ToFloat64(orphanGCRemoved.WithLabelValues(orphanKindRegistration)))
+}
+
+func TestFindOrphanedRegistrationsSkipsLookupErrors(t *testing.T) {
+	now := time.Now()
+	gomockCtrl := gomock.NewController(t)
+	registrationCache := fake.NewMockNonNamespacedCacheInterface[*v1.Registration](gomockCtrl)
+	secretCache := fake.NewMockCacheInterface[*corev1.Secret](gomockCtrl)
+	h := &handler{
+		log:               logging.NewLog(),
+		registrationCache: registrationCache,
+		secretRepo:        &secretrepo.SecretRepository{Cache: secretCache},
+		options: &types.RunOptions{
+			OperatorName:     consts.DefaultOperatorName,
+			OperatorSettings: &config.OperatorSettings{SystemNamespace: consts.DefaultSCCNamespace},
+		},
+	}
+	registrationErrors := testutil.ToFloat64(orphanGCErrors.WithLabelValues(orphanKindRegistration))
+
+	// A failed cache lookup says nothing about the entrypoint, so the Registration is kept
+	registration := &v1.Registration{ObjectMeta: metav1.ObjectMeta{
+		Name: "scc-registration-live", CreationTimestamp: metav1.NewTime(now.Add(-time.Hour)), Labels: gcLabels("live", "h-live", ""),
+	}}
+	registrationCache.EXPECT().List(gomock.Any()).Return([]*v1.Registration{registration}, nil)
+	secretCache.EXPECT().List(consts.DefaultSCCNamespace, gomock.Any()).Return(nil, assert.AnError)
+
+	assert.Empty(t, h.findOrphanedRegistrations(now))
+	assert.Equal(t, registrationErrors+1, testutil.ToFloat64(orphanGCErrors.WithLabelValues(orphanKindRegistration)))
+}
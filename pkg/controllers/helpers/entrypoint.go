@@ -0,0 +1,18 @@
+package helpers
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/rancher/scc-operator/internal/consts"
+)
+
+// IsEntrypointSecret checks if a Secret is an SCC entrypoint; either the default `scc-registration` Secret,
+// or any other Secret in the system namespace labelled with consts.LabelSccEntrypoint.
+func IsEntrypointSecret[T metav1.Object](incomingObj T, systemNamespace string) bool {
+	if incomingObj.GetNamespace() != systemNamespace {
+		return false
+	}
+
+	return incomingObj.GetName() == consts.ResourceSCCEntrypointSecretName ||
+		incomingObj.GetLabels()[consts.LabelSccEntrypoint] == consts.EntrypointLabelValue
+}
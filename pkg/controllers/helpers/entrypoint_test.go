@@ -0,0 +1,65 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/rancher/scc-operator/internal/consts"
+)
+
+func TestIsEntrypointSecret(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name     string
+		meta     metav1.ObjectMeta
+		expected bool
+	}{
+		{
+			name:     "default entrypoint name",
+			meta:     metav1.ObjectMeta{Name: consts.ResourceSCCEntrypointSecretName, Namespace: consts.DefaultSCCNamespace},
+			expected: true,
+		},
+		{
+			name: "labelled entrypoint",
+			meta: metav1.ObjectMeta{
+				Name:      "addon-registration",
+				Namespace: consts.DefaultSCCNamespace,
+				Labels:    map[string]string{consts.LabelSccEntrypoint: consts.EntrypointLabelValue},
+			},
+			expected: true,
+		},
+		{
+			name: "label with another value",
+			meta: metav1.ObjectMeta{
+				Name:      "addon-registration",
+				Namespace: consts.DefaultSCCNamespace,
+				Labels:    map[string]string{consts.LabelSccEntrypoint: "false"},
+			},
+			expected: false,
+		},
+		{
+			name:     "unlabelled secret",
+			meta:     metav1.ObjectMeta{Name: "registration-code-abc", Namespace: consts.DefaultSCCNamespace},
+			expected: false,
+		},
+		{
+			name: "labelled secret outside the system namespace",
+			meta: metav1.ObjectMeta{
+				Name:      "addon-registration",
+				Namespace: "default",
+				Labels:    map[string]string{consts.LabelSccEntrypoint: consts.EntrypointLabelValue},
+			},
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, IsEntrypointSecret(&corev1.Secret{ObjectMeta: tc.meta}, consts.DefaultSCCNamespace))
+		})
+	}
+}
@@ -8,6 +8,8 @@ import (
 const (
 	IndexRegistrationsBySccHash  = "scc.io/reg-refs-by-scc-hash"
 	IndexRegistrationsByNameHash = "scc.io/reg-refs-by-name-hash"
+	// IndexRegistrationsByRelatedSecret indexes Registrations by the `namespace/name` of every Secret they reference
+	IndexRegistrationsByRelatedSecret = "scc.io/reg-refs-by-related-secret"
 )
 
 func (h *handler) initIndexers() {
@@ -19,6 +21,10 @@ func (h *handler) initIndexers() {
 		IndexRegistrationsByNameHash,
 		h.registrationToNameHash,
 	)
+	h.registrationCache.AddIndexer(
+		IndexRegistrationsByRelatedSecret,
+		registrationToRelatedSecrets,
+	)
 }
 
 func (h *handler) registrationToHash(reg *v1.Registration) ([]string, error) {
@@ -44,3 +50,19 @@ func (h *handler) registrationToNameHash(reg *v1.Registration) ([]string, error)
 	}
 	return []string{hash}, nil
 }
+
+func registrationToRelatedSecrets(reg *v1.Registration) ([]string, error) {
+	if reg == nil {
+		return []string{}, nil
+	}
+
+	keys := []string{}
+	for _, ref := range relatedSecretRefs(reg) {
+		keys = append(keys, relatedSecretKey(ref.Namespace, ref.Name))
+	}
+	return keys, nil
+}
+
+func relatedSecretKey(namespace, name string) string {
+	return namespace + "/" + name
+}
@@ -2,6 +2,7 @@ package lifecycle
 
 import (
 	"slices"
+	"time"
 
 	"github.com/rancher/wrangler/v3/pkg/generic"
 	corev1 "k8s.io/api/core/v1"
@@ -15,6 +16,10 @@ var (
 	registrationDeciders = []types.RegistrationDecider{
 		RegistrationIsFailed,
 		RegistrationNeedsSyncNow,
+		RegistrationIsSuspended,
+		RegistrationCanRecover,
+		RegistrationIsDeregistered,
+		RegistrationOfflineExpired,
 		RegistrationHasNotStarted,
 		RegistrationNeedsActivation,
 		RegistrationHasManagedFinalizer,
@@ -23,6 +28,7 @@ var (
 		SecretHasOfflineFinalizer,
 		SecretHasCredentialsFinalizer,
 		SecretHasRegCodeFinalizer,
+		SecretHasDrifted,
 	}
 )
 
@@ -34,6 +40,27 @@ func RegistrationNeedsSyncNow(regIn *v1.Registration) bool {
 	return regIn.Spec.SyncNow != nil && *regIn.Spec.SyncNow
 }
 
+func RegistrationIsSuspended(regIn *v1.Registration) bool {
+	return regIn.Spec.Suspend != nil && *regIn.Spec.Suspend
+}
+
+// RegistrationCanRecover reports if a failed registration may be re-validated; offline registrations recover by replacing their certificate instead
+func RegistrationCanRecover(regIn *v1.Registration) bool {
+	return regIn.Spec.Recovery != nil && regIn.Spec.Mode != v1.RegistrationModeOffline
+}
+
+func RegistrationIsDeregistered(regIn *v1.Registration) bool {
+	return regIn.HasCondition(v1.ResourceConditionDeregistered) && v1.ResourceConditionDeregistered.IsTrue(regIn)
+}
+
+// RegistrationOfflineExpired reports if an activated offline registration is past the expiry of its certificate
+func RegistrationOfflineExpired(regIn *v1.Registration) bool {
+	return regIn.Spec.Mode == v1.RegistrationModeOffline &&
+		regIn.Status.ActivationStatus.Activated &&
+		regIn.Status.RegistrationExpiresAt != nil &&
+		!regIn.Status.RegistrationExpiresAt.After(time.Now())
+}
+
 func RegistrationHasNotStarted(regIn *v1.Registration) bool {
 	return regIn.Status.RegistrationProcessedTS.IsZero()
 }
@@ -67,3 +94,9 @@ func SecretHasCredentialsFinalizer(objIn *corev1.Secret) bool {
 func SecretHasRegCodeFinalizer(objIn *corev1.Secret) bool {
 	return hasFinalizer(objIn, consts.FinalizerSccRegistrationCode)
 }
+
+// SecretHasDrifted is true when the Secret's data no longer matches the content hash the operator stamped on it
+func SecretHasDrifted(objIn *corev1.Secret) bool {
+	expectedHash, ok := objIn.GetAnnotations()[consts.AnnotationSccContentHash]
+	return ok && expectedHash != SecretContentHash(objIn.Data)
+}
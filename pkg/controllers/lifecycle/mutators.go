@@ -1,6 +1,10 @@
 package lifecycle
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
 	"slices"
 
 	"github.com/rancher/wrangler/v3/pkg/generic"
@@ -14,6 +18,7 @@ import (
 var (
 	registrationMutators = []types.Mutator[*v1.Registration]{
 		RegistrationAddManagedFinalizer,
+		RegistrationRemoveManagedFinalizer,
 	}
 	secretMutators = []types.Mutator[*corev1.Secret]{
 		SecretAddCredentialsFinalizer,
@@ -22,6 +27,7 @@ var (
 		SecretRemoveRegCodeFinalizer,
 		SecretAddOfflineFinalizer,
 		SecretRemoveOfflineFinalizer,
+		SecretStampContentHash,
 	}
 )
 
@@ -29,6 +35,10 @@ func RegistrationAddManagedFinalizer(registration *v1.Registration) *v1.Registra
 	return runtimeAddFinalizer(registration, consts.FinalizerSccRegistration)
 }
 
+func RegistrationRemoveManagedFinalizer(registration *v1.Registration) *v1.Registration {
+	return runtimeRemoveFinalizer(registration, consts.FinalizerSccRegistration)
+}
+
 func runtimeAddFinalizer[T generic.RuntimeMetaObject](objIn T, finalizer string) T {
 	finalizers := objIn.GetFinalizers()
 	if finalizers == nil {
@@ -82,3 +92,23 @@ func SecretAddOfflineFinalizer(secret *corev1.Secret) *corev1.Secret {
 func SecretRemoveOfflineFinalizer(secret *corev1.Secret) *corev1.Secret {
 	return runtimeRemoveFinalizer[*corev1.Secret](secret, consts.FinalizerSccOfflineSecret)
 }
+
+// SecretContentHash hashes the data of a Secret independent of key order
+func SecretContentHash(data map[string][]byte) string {
+	hasher := sha256.New()
+	for _, key := range slices.Sorted(maps.Keys(data)) {
+		_, _ = fmt.Fprintf(hasher, "%s=%x;", key, data[key])
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+// SecretStampContentHash records the current data as the content the operator expects the Secret to hold
+func SecretStampContentHash(secret *corev1.Secret) *corev1.Secret {
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Annotations[consts.AnnotationSccContentHash] = SecretContentHash(secret.Data)
+
+	return secret
+}
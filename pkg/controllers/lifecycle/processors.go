@@ -1,6 +1,9 @@
 package lifecycle
 
 import (
+	"fmt"
+	"time"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/rancher/scc-operator/internal/types"
@@ -11,6 +14,12 @@ var (
 	registrationProcessors       []types.RegistrationProcessor
 	registrationStatusProcessors = []types.RegistrationStatusProcessor{
 		PrepareSuccessfulActivation,
+		PrepareSuspended,
+		PrepareResumed,
+		PrepareRecovering,
+		PrepareReannounce,
+		PrepareDeregistered,
+		PrepareOfflineExpired,
 	}
 )
 
@@ -28,3 +37,104 @@ func PrepareSuccessfulActivation(regIn *v1.Registration) *v1.Registration {
 
 	return regIn
 }
+
+// PrepareSuspended reports that SCC traffic is stopped; any requested SyncNow stays on the spec until resumed
+func PrepareSuspended(regIn *v1.Registration) *v1.Registration {
+	v1.ResourceConditionSuspended.True(regIn)
+	message := "all SCC traffic is suspended until spec.suspend is cleared"
+	if RegistrationNeedsSyncNow(regIn) {
+		message = "all SCC traffic is suspended until spec.suspend is cleared; the requested syncNow is deferred until then"
+	}
+	v1.ResourceConditionSuspended.Message(regIn, message)
+	regIn.SetCurrentCondition(v1.ResourceConditionSuspended)
+
+	return regIn
+}
+
+// PrepareResumed clears the Suspended condition of a registration that was previously suspended
+func PrepareResumed(regIn *v1.Registration) *v1.Registration {
+	if !regIn.HasCondition(v1.ResourceConditionSuspended) {
+		return regIn
+	}
+
+	v1.ResourceConditionSuspended.False(regIn)
+	v1.ResourceConditionSuspended.Message(regIn, "")
+
+	return regIn
+}
+
+// PrepareRecovering clears the Failure condition so a registration can be re-validated with SCC, keeping its system ID
+func PrepareRecovering(regIn *v1.Registration) *v1.Registration {
+	regIn = PrepareFailureCleared(regIn)
+
+	now := metav1.Now()
+	if regIn.Status.Recovery == nil {
+		regIn.Status.Recovery = &v1.RecoveryStatus{}
+	}
+	regIn.Status.Recovery.Attempts++
+	regIn.Status.Recovery.LastAttemptTS = &now
+
+	return regIn
+}
+
+// PrepareReannounce clears any failure or deregistration and sends the registration back through registration
+func PrepareReannounce(regIn *v1.Registration) *v1.Registration {
+	regIn = PrepareFailureCleared(regIn)
+	regIn.RemoveCondition(v1.ResourceConditionDeregistered)
+	regIn.Status.RegistrationProcessedTS = nil
+	regIn.Status.ConsecutiveFailures = 0
+
+	return regIn
+}
+
+// PrepareDryRunDiscarded forgets what a dry run pretended SCC answered, so the registration is announced for real
+func PrepareDryRunDiscarded(regIn *v1.Registration) *v1.Registration {
+	regIn = PrepareReannounce(regIn)
+	regIn.Status.SCCSystemID = nil
+	regIn.Status.NextRetryAt = nil
+	regIn.Status.ActivationStatus = v1.SystemActivationState{}
+	regIn.RemoveCondition(v1.RegistrationConditionAnnounced)
+	regIn.RemoveCondition(v1.RegistrationConditionSccURLReady)
+	regIn.RemoveCondition(v1.RegistrationConditionRMTAnnounced)
+	regIn.RemoveCondition(v1.RegistrationConditionPayAsYouGoAnnounced)
+	v1.RegistrationConditionActivated.False(regIn)
+	v1.ResourceConditionReady.False(regIn)
+	v1.ResourceConditionDone.False(regIn)
+	v1.ResourceConditionProgressing.True(regIn)
+	regIn.SetCurrentCondition(v1.ResourceConditionProgressing)
+
+	return regIn
+}
+
+// PrepareDeregistered forgets the SCC system, and stops all SCC traffic for the registration until it is reannounced
+func PrepareDeregistered(regIn *v1.Registration) *v1.Registration {
+	regIn.Status.ActivationStatus.Activated = false
+	regIn.Status.SCCSystemID = nil
+	regIn.Status.SystemCredentialsSecretRef = nil
+	regIn.Status.NextRetryAt = nil
+	regIn.Status.ConsecutiveFailures = 0
+	v1.RegistrationConditionActivated.False(regIn)
+	v1.ResourceConditionReady.False(regIn)
+	v1.ResourceConditionDone.False(regIn)
+	v1.ResourceConditionProgressing.False(regIn)
+	v1.ResourceConditionDeregistered.True(regIn)
+	v1.ResourceConditionDeregistered.Message(regIn, "system was deregistered from SCC; use the reannounce action to register it again")
+	regIn.SetCurrentCondition(v1.ResourceConditionDeregistered)
+
+	return regIn
+}
+
+// PrepareOfflineExpired reports that the offline certificate expired; a renewed certificate activates the registration again
+func PrepareOfflineExpired(regIn *v1.Registration) *v1.Registration {
+	v1.RegistrationConditionOfflineExpired.True(regIn)
+	message := "offline registration certificate has expired; upload a renewed certificate to activate again"
+	if regIn.Status.RegistrationExpiresAt != nil {
+		message = fmt.Sprintf("offline registration certificate expired at %s; upload a renewed certificate to activate again",
+			regIn.Status.RegistrationExpiresAt.UTC().Format(time.RFC3339))
+	}
+	v1.RegistrationConditionOfflineExpired.Message(regIn, message)
+	v1.ResourceConditionReady.False(regIn)
+	regIn.SetCurrentCondition(v1.RegistrationConditionOfflineExpired)
+
+	return regIn
+}
@@ -22,3 +22,13 @@ func PrepareFailed(regIn *v1.Registration, err error) *v1.Registration {
 
 	return regIn
 }
+
+// PrepareFailureCleared removes the Failure state so the registration is processed again
+func PrepareFailureCleared(regIn *v1.Registration) *v1.Registration {
+	v1.ResourceConditionFailure.False(regIn)
+	v1.ResourceConditionFailure.Reason(regIn, "")
+	v1.ResourceConditionFailure.Message(regIn, "")
+	regIn.Status.NextRetryAt = nil
+
+	return regIn
+}
@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricsNamespace = "scc_operator"
+
+var (
+	orphanGCRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "orphan_gc",
+		Name:      "runs_total",
+		Help:      "Orphan garbage collection passes, by mode (remove or dry-run).",
+	}, []string{"mode"})
+	orphanGCFound = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "orphan_gc",
+		Name:      "orphans_found_total",
+		Help:      "Orphaned objects found by the garbage collector, by kind (secret or registration).",
+	}, []string{"kind"})
+	orphanGCRemoved = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "orphan_gc",
+		Name:      "orphans_removed_total",
+		Help:      "Orphaned objects removed by the garbage collector, by kind (secret or registration).",
+	}, []string{"kind"})
+	orphanGCErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "orphan_gc",
+		Name:      "errors_total",
+		Help:      "Errors met by the garbage collector while finding or removing orphans, by kind (secret or registration).",
+	}, []string{"kind"})
+
+	registrationSecondsToExpiry = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "registration",
+		Name:      "seconds_to_expiry",
+		Help:      "Seconds left before the subscription of a Registration expires; negative once expired.",
+	}, []string{"registration", "mode"})
+)
+
+func init() {
+	prometheus.MustRegister(orphanGCRuns, orphanGCFound, orphanGCRemoved, orphanGCErrors)
+	prometheus.MustRegister(registrationSecondsToExpiry)
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"bytes"
 	"fmt"
 
 	"github.com/SUSE/connect-ng/pkg/registration"
@@ -19,6 +20,7 @@ import (
 type sccOfflineMode struct {
 	rancherURL     string
 	rancherUUID    string
+	trustRoots     offlinevalidator.TrustRoots
 	options        *types.RunOptions
 	registration   *v1.Registration
 	log            rootLog.StructuredLogger
@@ -133,6 +135,7 @@ func (s *sccOfflineMode) ResetToReadyForActivation(registrationObj *v1.Registrat
 	registrationObj.Status.ActivationStatus.LastValidatedTS = &metav1.Time{}
 	registrationObj.RemoveCondition(v1.RegistrationConditionActivated)
 	registrationObj.RemoveCondition(v1.RegistrationConditionOfflineCertificateReady)
+	registrationObj.RemoveCondition(v1.RegistrationConditionOfflineExpired)
 	registrationObj.RemoveCondition(v1.ResourceConditionFailure)
 	registrationObj.RemoveCondition(v1.ResourceConditionReady)
 
@@ -161,25 +164,24 @@ func (s *sccOfflineMode) Activate(_ *v1.Registration) error {
 		return fmt.Errorf("activate failed, cannot prepare offline certificate: %w", certErr)
 	}
 
-	offlineCertValidator := offlinevalidator.New(offlineCert, s.rancherUUID)
+	offlineCertValidator := offlinevalidator.New(offlineCert, s.rancherUUID, s.trustRoots)
 
 	return offlineCertValidator.ValidateCertificate()
 }
 
 func (s *sccOfflineMode) PrepareActivatedForKeepalive(registrationObj *v1.Registration) (*v1.Registration, error) {
-	// TODO: can we actually get the SCC systemID in offline mode?
-	// GH issue: https://github.com/SUSE/connect-ng/issues/313
-	/*
-		certReader, err := s.offlineSecrets.OfflineCertificateReader()
-		if err != nil {
-			return registrationObj, fmt.Errorf("activate failed, cannot get offline certificate reader: %w", err)
-		}
+	certReader, err := s.offlineSecrets.OfflineCertificateReader()
+	if err != nil {
+		return registrationObj, fmt.Errorf("activate failed, cannot get offline certificate reader: %w", err)
+	}
 
-		offlineCert, certErr := registration.OfflineCertificateFrom(certReader, false)
-		if certErr != nil {
-			return registrationObj, fmt.Errorf("activate failed, cannot prepare offline certificate: %w", certErr)
-		}
-	*/
+	offlineCert, certErr := registration.OfflineCertificateFrom(certReader, false)
+	if certErr != nil {
+		return registrationObj, fmt.Errorf("activate failed, cannot prepare offline certificate: %w", certErr)
+	}
+	if applyErr := applyOfflineCertificateToStatus(registrationObj, offlineCert); applyErr != nil {
+		return registrationObj, fmt.Errorf("activate failed, cannot read offline certificate payload: %w", applyErr)
+	}
 
 	registrationObj.RemoveCondition(v1.RegistrationConditionOfflineCertificateReady)
 	v1.RegistrationConditionOfflineCertificateReady.True(registrationObj)
@@ -187,6 +189,77 @@ func (s *sccOfflineMode) PrepareActivatedForKeepalive(registrationObj *v1.Regist
 	return registrationObj, nil
 }
 
+// applyOfflineCertificateToStatus records the subscription details of the offline certificate on the Registration.
+// The certificate only carries a system ID once SCC knows the system, so an unknown one is left unset.
+func applyOfflineCertificateToStatus(registrationObj *v1.Registration, offlineCert *registration.OfflineCertificate) error {
+	payload, err := offlineCert.ExtractPayload()
+	if err != nil {
+		return err
+	}
+	subscription := payload.SubscriptionInfo
+
+	certStatus := &v1.OfflineCertificateStatus{
+		SubscriptionName: subscription.Name,
+		SubscriptionKind: subscription.Kind,
+		HashedRegcode:    payload.HashedRegcode,
+	}
+	for _, productClass := range subscription.ProductClasses {
+		certStatus.ProductClasses = append(certStatus.ProductClasses, productClass.Name)
+	}
+	if !subscription.StartsAt.IsZero() {
+		certStatus.StartsAt = &metav1.Time{Time: subscription.StartsAt}
+	}
+	if !subscription.ExpiresAt.IsZero() {
+		certStatus.ExpiresAt = &metav1.Time{Time: subscription.ExpiresAt}
+	}
+	registrationObj.Status.OfflineCertificate = certStatus
+	registrationObj.Status.RegistrationExpiresAt = certStatus.ExpiresAt
+
+	productName := offlineCert.ProductName
+	if productName == "" {
+		productName = subscription.Name
+	}
+	if productName != "" {
+		registrationObj.Status.RegisteredProduct = &productName
+	}
+	if systemID := offlineCert.SystemID; systemID > 0 {
+		registrationObj.Status.SCCSystemID = &systemID
+	}
+	v1.RegistrationConditionOfflineExpired.False(registrationObj)
+	v1.RegistrationConditionOfflineExpired.Message(registrationObj, "")
+
+	return nil
+}
+
+// StagedCertificate returns the replacement certificate waiting to be validated, or nil when none is staged
+func (s *sccOfflineMode) StagedCertificate() ([]byte, error) {
+	return s.offlineSecrets.StagedCertificate()
+}
+
+// ValidateStagedCertificate runs the same checks as activation on a staged certificate, leaving the active one untouched
+func (s *sccOfflineMode) ValidateStagedCertificate(staged []byte) (*registration.OfflineCertificate, error) {
+	offlineCert, certErr := registration.OfflineCertificateFrom(bytes.NewReader(staged), false)
+	if certErr != nil {
+		return nil, fmt.Errorf("cannot prepare replacement offline certificate: %w", certErr)
+	}
+
+	offlineCertValidator := offlinevalidator.New(offlineCert, s.rancherUUID, s.trustRoots)
+	if validateErr := offlineCertValidator.ValidateCertificate(); validateErr != nil {
+		return nil, fmt.Errorf("cannot validate replacement offline certificate: %w", validateErr)
+	}
+
+	return offlineCert, nil
+}
+
+// PromoteStagedCertificate replaces the active certificate with the validated staged one
+func (s *sccOfflineMode) PromoteStagedCertificate(validated []byte) error {
+	if err := s.offlineSecrets.PromoteStagedCertificate(validated); err != nil {
+		return fmt.Errorf("failed promoting replacement offline certificate: %w", err)
+	}
+
+	return nil
+}
+
 func (s *sccOfflineMode) RemoveOfflineCertificate() error {
 	certErr := s.offlineSecrets.RemoveOfflineCertificate()
 
@@ -230,7 +303,7 @@ func (s *sccOfflineMode) Keepalive(registrationObj *v1.Registration) error {
 		return fmt.Errorf("activate failed, cannot prepare offline certificate: %w", certErr)
 	}
 
-	offlineCertValidator := offlinevalidator.New(offlineCert, s.rancherUUID)
+	offlineCertValidator := offlinevalidator.New(offlineCert, s.rancherUUID, s.trustRoots)
 	validateErr := offlineCertValidator.ValidateCertificate()
 	if validateErr != nil {
 		return fmt.Errorf("activate failed, cannot validate offline certificate: %w", validateErr)
@@ -259,7 +332,12 @@ func (s *sccOfflineMode) ReconcileKeepaliveError(registration *v1.Registration,
 	return registration
 }
 
+// Deregister has nothing to do for offline systems, which must be removed from SCC by hand
 func (s *sccOfflineMode) Deregister() error {
+	return nil
+}
+
+func (s *sccOfflineMode) RemoveSecrets() error {
 	delErr := s.offlineSecrets.Remove()
 	if delErr != nil {
 		return fmt.Errorf("deregister failed: %w", delErr)
@@ -268,4 +346,7 @@ func (s *sccOfflineMode) Deregister() error {
 	return nil
 }
 
-var _ SCCHandler = &sccOfflineMode{}
+var (
+	_ SCCHandler                = &sccOfflineMode{}
+	_ offlineCertificateRotator = &sccOfflineMode{}
+)
@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"fmt"
+	"maps"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/rancher/scc-operator/internal/consts"
+	"github.com/rancher/scc-operator/internal/suseconnect/offline"
+)
+
+// isOfflineRequestSecret reports if the Secret holds an offline registration request written by the operator
+func isOfflineRequestSecret(secret *corev1.Secret) bool {
+	return consts.SecretRole(secret.Labels[consts.LabelSccSecretRole]) == consts.OfflineRequestRole
+}
+
+func (h *handler) mirrorsOfflineRequests() bool {
+	return h.options != nil && h.options.OperatorSettings != nil && h.options.OperatorSettings.OfflineRequestConfigMap
+}
+
+// mirrorOfflineRequest copies the offline request into a ConfigMap of the same name, so UIs and scripts can read it without access to Secrets.
+// The ConfigMap is owned by the Secret, so it is garbage collected along with it.
+func (h *handler) mirrorOfflineRequest(secret *corev1.Secret) error {
+	request := secret.Data[consts.SecretKeyOfflineRegRequest]
+	if len(request) == 0 {
+		return nil
+	}
+	checksum := offline.RequestChecksum(request)
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configMap, getErr := h.configMaps.Get(secret.Namespace, secret.Name, metav1.GetOptions{})
+		switch {
+		case apierrors.IsNotFound(getErr):
+			configMap = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      secret.Name,
+					Namespace: secret.Namespace,
+				},
+			}
+		case getErr != nil:
+			return getErr
+		case configMap.Annotations[consts.AnnotationSccChecksum] == checksum:
+			return nil
+		default:
+			configMap = configMap.DeepCopy()
+		}
+
+		if configMap.Labels == nil {
+			configMap.Labels = map[string]string{}
+		}
+		maps.Copy(configMap.Labels, secret.Labels)
+		if configMap.Annotations == nil {
+			configMap.Annotations = map[string]string{}
+		}
+		configMap.Annotations[consts.AnnotationSccChecksum] = checksum
+		configMap.OwnerReferences = []metav1.OwnerReference{{
+			APIVersion: "v1",
+			Kind:       "Secret",
+			Name:       secret.Name,
+			UID:        secret.UID,
+		}}
+		configMap.Data = map[string]string{consts.SecretKeyOfflineRegRequest: string(request)}
+
+		var writeErr error
+		if configMap.ResourceVersion == "" {
+			_, writeErr = h.configMaps.Create(configMap)
+		} else {
+			_, writeErr = h.configMaps.Update(configMap)
+		}
+		return writeErr
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mirror offline request into ConfigMap `%s/%s`: %w", secret.Namespace, secret.Name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/rancher/scc-operator/internal/consts"
+	"github.com/rancher/scc-operator/internal/logging"
+	"github.com/rancher/scc-operator/internal/suseconnect/offline"
+)
+
+func offlineRequestSecret(request string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      consts.OfflineRequestSecretName("abc"),
+			Namespace: consts.DefaultSCCNamespace,
+			UID:       k8stypes.UID("secret-uid"),
+			Labels:    map[string]string{consts.LabelSccSecretRole: string(consts.OfflineRequestRole), consts.LabelNameSuffix: "abc"},
+		},
+		Data: map[string][]byte{consts.SecretKeyOfflineRegRequest: []byte(request)},
+	}
+}
+
+func TestMirrorOfflineRequest(t *testing.T) {
+	configMaps := fake.NewMockControllerInterface[*corev1.ConfigMap, *corev1.ConfigMapList](gomock.NewController(t))
+	h := &handler{log: logging.NewLog(), configMaps: configMaps}
+	secret := offlineRequestSecret(`{"first":true}`)
+	require.True(t, isOfflineRequestSecret(secret))
+
+	var mirrored *corev1.ConfigMap
+	notFound := apierrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, secret.Name)
+	configMaps.EXPECT().Get(secret.Namespace, secret.Name, gomock.Any()).Return(nil, notFound)
+	configMaps.EXPECT().Create(gomock.Any()).DoAndReturn(func(configMap *corev1.ConfigMap) (*corev1.ConfigMap, error) {
+		mirrored = configMap.DeepCopy()
+		mirrored.ResourceVersion = "1"
+		return mirrored, nil
+	})
+	require.NoError(t, h.mirrorOfflineRequest(secret))
+	assert.Equal(t, `{"first":true}`, mirrored.Data[consts.SecretKeyOfflineRegRequest])
+	assert.Equal(t, offline.RequestChecksum([]byte(`{"first":true}`)), mirrored.Annotations[consts.AnnotationSccChecksum])
+	assert.Equal(t, "abc", mirrored.Labels[consts.LabelNameSuffix])
+	require.Len(t, mirrored.OwnerReferences, 1)
+	assert.Equal(t, secret.UID, mirrored.OwnerReferences[0].UID)
+
+	// An unchanged request is not written again
+	configMaps.EXPECT().Get(secret.Namespace, secret.Name, gomock.Any()).Return(mirrored, nil)
+	require.NoError(t, h.mirrorOfflineRequest(secret))
+
+	secret = offlineRequestSecret(`{"second":true}`)
+	configMaps.EXPECT().Get(secret.Namespace, secret.Name, gomock.Any()).Return(mirrored, nil)
+	configMaps.EXPECT().Update(gomock.Any()).DoAndReturn(func(configMap *corev1.ConfigMap) (*corev1.ConfigMap, error) {
+		mirrored = configMap
+		return configMap, nil
+	})
+	require.NoError(t, h.mirrorOfflineRequest(secret))
+	assert.Equal(t, `{"second":true}`, mirrored.Data[consts.SecretKeyOfflineRegRequest])
+	assert.Equal(t, offline.RequestChecksum([]byte(`{"second":true}`)), mirrored.Annotations[consts.AnnotationSccChecksum])
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/SUSE/connect-ng/pkg/registration"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/rancher/scc-operator/internal/consts"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+	"github.com/rancher/scc-operator/pkg/controllers/lifecycle"
+)
+
+// offlineCertificateRotator is implemented by registration handlers that can replace an active offline certificate in place
+type offlineCertificateRotator interface {
+	StagedCertificate() ([]byte, error)
+	ValidateStagedCertificate(staged []byte) (*registration.OfflineCertificate, error)
+	PromoteStagedCertificate(validated []byte) error
+}
+
+// rotatesOfflineCertificate reports if a changed certificate must be staged next to the active one rather than replace it.
+// Only activated Registrations have a certificate worth keeping; any other is reset with the new certificate as before.
+func (h *handler) rotatesOfflineCertificate(params RegistrationParams) bool {
+	if params.regType != v1.RegistrationModeOffline || !params.hasOfflineCertData {
+		return false
+	}
+
+	registrationObj, err := h.registrationCache.Get(consts.RegistrationName(params.nameID))
+	return err == nil && registrationObj.Status.ActivationStatus.Activated
+}
+
+// stageOfflineCertificate stages the entrypoint certificate as a replacement when it differs from the active one.
+// Going back to the active certificate drops any staged replacement.
+func stageOfflineCertificate(offlineCertSecret *corev1.Secret, certData []byte) {
+	if bytes.Equal(offlineCertSecret.Data[consts.SecretKeyOfflineRegCert], certData) {
+		delete(offlineCertSecret.Data, consts.SecretKeyOfflineRegCertReplacement)
+		return
+	}
+
+	if offlineCertSecret.Data == nil {
+		offlineCertSecret.Data = map[string][]byte{}
+	}
+	offlineCertSecret.Data[consts.SecretKeyOfflineRegCertReplacement] = certData
+}
+
+// reviewStagedOfflineCertificate promotes a staged replacement certificate once it validates; the Registration stays Activated throughout.
+// A rejected replacement is only reported, leaving the active certificate in use. It returns true when it updated the Registration.
+func (h *handler) reviewStagedOfflineCertificate(regHandler SCCHandler, registrationObj *v1.Registration) (bool, error) {
+	rotator, ok := regHandler.(offlineCertificateRotator)
+	if !ok || !registrationObj.Status.ActivationStatus.Activated {
+		return false, nil
+	}
+
+	staged, err := rotator.StagedCertificate()
+	if err != nil || len(staged) == 0 {
+		return false, err
+	}
+
+	offlineCert, rejectErr := rotator.ValidateStagedCertificate(staged)
+	if rejectErr != nil {
+		message := fmt.Sprintf("replacement certificate rejected, the active certificate stays in use: %v", rejectErr)
+		if v1.RegistrationConditionOfflineCertificateRotated.IsFalse(registrationObj) &&
+			v1.RegistrationConditionOfflineCertificateRotated.GetMessage(registrationObj) == message {
+			return false, nil
+		}
+
+		if updateErr := h.updateRegistrationStatus(registrationObj.Name, func(current *v1.Registration) error {
+			v1.RegistrationConditionOfflineCertificateRotated.False(current)
+			v1.RegistrationConditionOfflineCertificateRotated.Message(current, message)
+			return nil
+		}); updateErr != nil {
+			return true, updateErr
+		}
+		h.recordEvent(registrationObj, corev1.EventTypeWarning, eventReasonOfflineCertRejected, "%s", message)
+		return true, nil
+	}
+
+	if promoteErr := rotator.PromoteStagedCertificate(staged); promoteErr != nil {
+		return false, promoteErr
+	}
+
+	if updateErr := h.updateRegistrationStatus(registrationObj.Name, func(current *v1.Registration) error {
+		if applyErr := applyOfflineCertificateToStatus(current, offlineCert); applyErr != nil {
+			return applyErr
+		}
+		lifecycle.PrepareSuccessfulActivation(current)
+		v1.RegistrationConditionOfflineCertificateReady.True(current)
+		v1.RegistrationConditionOfflineCertificateRotated.True(current)
+		v1.RegistrationConditionOfflineCertificateRotated.Message(current, "")
+		return nil
+	}); updateErr != nil {
+		return true, fmt.Errorf("promoted replacement offline certificate but failed to update registration status: %w", updateErr)
+	}
+
+	message := "replacement offline certificate validated and promoted"
+	if offlineCert.ProductName != "" {
+		message = fmt.Sprintf("replacement offline certificate for %s validated and promoted", offlineCert.ProductName)
+	}
+	h.recordEvent(registrationObj, corev1.EventTypeNormal, eventReasonOfflineCertRotated, "%s", message)
+	return true, nil
+}
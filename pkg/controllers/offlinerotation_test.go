@@ -0,0 +1,264 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SUSE/connect-ng/pkg/registration"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/rancher/scc-operator/internal/config"
+	"github.com/rancher/scc-operator/internal/consts"
+	"github.com/rancher/scc-operator/internal/logging"
+	"github.com/rancher/scc-operator/internal/repos/secretrepo"
+	"github.com/rancher/scc-operator/internal/types"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+	"github.com/rancher/scc-operator/pkg/controllers/helpers"
+	"github.com/rancher/scc-operator/pkg/controllers/lifecycle"
+)
+
+// stagedCertRotator is an offline handler holding one staged certificate
+type stagedCertRotator struct {
+	*sccOfflineMode
+	staged    []byte
+	cert      *registration.OfflineCertificate
+	rejectErr error
+	promoted  []byte
+}
+
+func (r *stagedCertRotator) StagedCertificate() ([]byte, error) {
+	return r.staged, nil
+}
+
+func (r *stagedCertRotator) ValidateStagedCertificate([]byte) (*registration.OfflineCertificate, error) {
+	return r.cert, r.rejectErr
+}
+
+func (r *stagedCertRotator) PromoteStagedCertificate(validated []byte) error {
+	r.promoted = validated
+	r.staged = nil
+	return nil
+}
+
+func TestStageOfflineCertificate(t *testing.T) {
+	offlineCertSecret := &corev1.Secret{Data: map[string][]byte{consts.SecretKeyOfflineRegCert: []byte("active")}}
+
+	stageOfflineCertificate(offlineCertSecret, []byte("renewed"))
+	assert.Equal(t, []byte("active"), offlineCertSecret.Data[consts.SecretKeyOfflineRegCert])
+	assert.Equal(t, []byte("renewed"), offlineCertSecret.Data[consts.SecretKeyOfflineRegCertReplacement])
+
+	// Going back to the active certificate drops the staged one
+	stageOfflineCertificate(offlineCertSecret, []byte("active"))
+	assert.Equal(t, []byte("active"), offlineCertSecret.Data[consts.SecretKeyOfflineRegCert])
+	assert.NotContains(t, offlineCertSecret.Data, consts.SecretKeyOfflineRegCertReplacement)
+}
+
+func TestOfflineCertFromSecretEntrypoint(t *testing.T) {
+	gomockCtrl := gomock.NewController(t)
+	mockRegistrationCache := fake.NewMockNonNamespacedCacheInterface[*v1.Registration](gomockCtrl)
+	mockSecretsCache := fake.NewMockCacheInterface[*corev1.Secret](gomockCtrl)
+	h := &handler{
+		log:               logging.NewLog(),
+		registrationCache: mockRegistrationCache,
+		secretRepo:        &secretrepo.SecretRepository{Cache: mockSecretsCache},
+		options: &types.RunOptions{
+			OperatorName:     consts.DefaultOperatorName,
+			OperatorSettings: &config.OperatorSettings{SystemNamespace: consts.DefaultSCCNamespace},
+		},
+	}
+	entrypoint := helpers.TakeOwnership(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      consts.ResourceSCCEntrypointSecretName,
+			Namespace: consts.DefaultSCCNamespace,
+			Labels:    map[string]string{consts.LabelObjectSalt: "salty"},
+		},
+		Data: map[string][]byte{
+			dataKeyRegistrationType:        []byte(v1.RegistrationModeOffline),
+			consts.SecretKeyOfflineRegCert: []byte("renewed"),
+		},
+	}, consts.DefaultOperatorName)
+	params, err := extractRegistrationParamsFromSecret(entrypoint, consts.DefaultOperatorName)
+	require.NoError(t, err)
+	secretName := consts.OfflineCertificateSecretName(params.nameID)
+	currentCertSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: secretName, Namespace: consts.DefaultSCCNamespace},
+		Data: map[string][]byte{
+			consts.SecretKeyOfflineRegCert:            []byte("rejected"),
+			consts.SecretKeyOfflineRegCertReplacement: []byte("stale"),
+		},
+	}
+
+	// A certificate that never activated the Registration is replaced outright
+	registrationObj := activatedOfflineRegistration(time.Now().Add(24 * time.Hour))
+	registrationObj.Name = consts.RegistrationName(params.nameID)
+	registrationObj.Status.ActivationStatus.Activated = false
+	mockRegistrationCache.EXPECT().Get(registrationObj.Name).Return(registrationObj, nil)
+	mockSecretsCache.EXPECT().Get(consts.DefaultSCCNamespace, secretName).Return(currentCertSecret, nil)
+	offlineCertSecret, err := h.offlineCertFromSecretEntrypoint(params)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("renewed"), offlineCertSecret.Data[consts.SecretKeyOfflineRegCert])
+	assert.NotContains(t, offlineCertSecret.Data, consts.SecretKeyOfflineRegCertReplacement)
+	assert.Equal(t, []byte("rejected"), currentCertSecret.Data[consts.SecretKeyOfflineRegCert], "the cached Secret is left untouched")
+
+	// The certificate of an activated Registration stays in use until the new one validates
+	registrationObj.Status.ActivationStatus.Activated = true
+	mockRegistrationCache.EXPECT().Get(registrationObj.Name).Return(registrationObj, nil)
+	mockSecretsCache.EXPECT().Get(consts.DefaultSCCNamespace, secretName).Return(currentCertSecret, nil)
+	offlineCertSecret, err = h.offlineCertFromSecretEntrypoint(params)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("rejected"), offlineCertSecret.Data[consts.SecretKeyOfflineRegCert])
+	assert.Equal(t, []byte("renewed"), offlineCertSecret.Data[consts.SecretKeyOfflineRegCertReplacement])
+
+	mockSecretsCache.EXPECT().Get(consts.DefaultSCCNamespace, secretName).Return(nil, assert.AnError)
+	_, err = h.offlineCertFromSecretEntrypoint(params)
+	assert.ErrorIs(t, err, assert.AnError)
+}
+
+func TestReviewStagedOfflineCertificatePromotes(t *testing.T) {
+	expiresAt := time.Now().Add(365 * 24 * time.Hour).UTC().Truncate(time.Second)
+	registrationObj := activatedOfflineRegistration(time.Now().Add(-time.Hour))
+	v1.RegistrationConditionOfflineExpired.True(registrationObj)
+	gomockCtrl := gomock.NewController(t)
+	mockRegistrations := fake.NewMockNonNamespacedControllerInterface[*v1.Registration, *v1.RegistrationList](gomockCtrl)
+	mockRegistrations.EXPECT().Get(registrationObj.Name, gomock.Any()).Return(registrationObj, nil).AnyTimes()
+	mockRegistrations.EXPECT().UpdateStatus(gomock.Any()).DoAndReturn(func(updated *v1.Registration) (*v1.Registration, error) {
+		registrationObj = updated
+		return updated, nil
+	}).AnyTimes()
+	h := &handler{log: logging.NewLog(), registrations: mockRegistrations}
+	rotator := &stagedCertRotator{staged: []byte("renewed"), cert: testOfflineCertificate(expiresAt)}
+
+	updated, err := h.reviewStagedOfflineCertificate(rotator, registrationObj)
+	require.NoError(t, err)
+	assert.True(t, updated)
+	assert.Equal(t, []byte("renewed"), rotator.promoted)
+	assert.True(t, registrationObj.Status.ActivationStatus.Activated)
+	assert.True(t, v1.RegistrationConditionOfflineCertificateRotated.IsTrue(registrationObj))
+	assert.True(t, v1.RegistrationConditionOfflineExpired.IsFalse(registrationObj))
+	assert.True(t, v1.ResourceConditionReady.IsTrue(registrationObj))
+	assert.True(t, expiresAt.Equal(registrationObj.Status.RegistrationExpiresAt.Time))
+
+	// Nothing is left to review once promoted
+	updated, err = h.reviewStagedOfflineCertificate(rotator, registrationObj)
+	require.NoError(t, err)
+	assert.False(t, updated)
+}
+
+func TestReviewStagedOfflineCertificateRejects(t *testing.T) {
+	activeExpiry := time.Now().Add(24 * time.Hour)
+	registrationObj := activatedOfflineRegistration(activeExpiry)
+	gomockCtrl := gomock.NewController(t)
+	mockRegistrations := fake.NewMockNonNamespacedControllerInterface[*v1.Registration, *v1.RegistrationList](gomockCtrl)
+	mockRegistrations.EXPECT().Get(registrationObj.Name, gomock.Any()).Return(registrationObj, nil).AnyTimes()
+	mockRegistrations.EXPECT().UpdateStatus(gomock.Any()).DoAndReturn(func(updated *v1.Registration) (*v1.Registration, error) {
+		registrationObj = updated
+		return updated, nil
+	}).AnyTimes()
+	h := &handler{log: logging.NewLog(), registrations: mockRegistrations}
+	rotator := &stagedCertRotator{staged: []byte("tampered"), rejectErr: errors.New("signature invalid")}
+
+	updated, err := h.reviewStagedOfflineCertificate(rotator, registrationObj)
+	require.NoError(t, err)
+	assert.True(t, updated)
+	assert.Nil(t, rotator.promoted, "a rejected certificate is never promoted")
+	assert.True(t, registrationObj.Status.ActivationStatus.Activated)
+	assert.True(t, activeExpiry.Equal(registrationObj.Status.RegistrationExpiresAt.Time))
+	assert.True(t, v1.RegistrationConditionOfflineCertificateRotated.IsFalse(registrationObj))
+	assert.Contains(t, v1.RegistrationConditionOfflineCertificateRotated.GetMessage(registrationObj), "signature invalid")
+
+	// The same rejection is only reported once
+	updated, err = h.reviewStagedOfflineCertificate(rotator, registrationObj)
+	require.NoError(t, err)
+	assert.False(t, updated)
+}
+
+func TestReviewStagedOfflineCertificateSkipsInactive(t *testing.T) {
+	registrationObj := activatedOfflineRegistration(time.Now().Add(time.Hour))
+	registrationObj.Status.ActivationStatus.Activated = false
+	h := &handler{log: logging.NewLog()}
+
+	updated, err := h.reviewStagedOfflineCertificate(&stagedCertRotator{staged: []byte("renewed")}, registrationObj)
+	require.NoError(t, err)
+	assert.False(t, updated)
+}
+
+func TestOnSecretChangeStagesOfflineCertificate(t *testing.T) {
+	gomockCtrl := gomock.NewController(t)
+	mockRegistrationCache := fake.NewMockNonNamespacedCacheInterface[*v1.Registration](gomockCtrl)
+	mockRegistrations := fake.NewMockNonNamespacedControllerInterface[*v1.Registration, *v1.RegistrationList](gomockCtrl)
+	mockSecretsCache := fake.NewMockCacheInterface[*corev1.Secret](gomockCtrl)
+	mockSecrets := fake.NewMockControllerInterface[*corev1.Secret, *corev1.SecretList](gomockCtrl)
+	h := &handler{
+		log:               logging.NewLog(),
+		registrations:     mockRegistrations,
+		registrationCache: mockRegistrationCache,
+		secretRepo:        &secretrepo.SecretRepository{Cache: mockSecretsCache, Controller: mockSecrets},
+		options: &types.RunOptions{
+			OperatorName:     consts.DefaultOperatorName,
+			OperatorSettings: &config.OperatorSettings{SystemNamespace: consts.DefaultSCCNamespace},
+		},
+	}
+
+	// The entrypoint was processed with the active certificate and now holds a renewed one
+	entrypoint := helpers.TakeOwnership(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      consts.ResourceSCCEntrypointSecretName,
+			Namespace: consts.DefaultSCCNamespace,
+			Labels:    map[string]string{consts.LabelObjectSalt: "salty"},
+		},
+		Data: map[string][]byte{
+			dataKeyRegistrationType:        []byte(v1.RegistrationModeOffline),
+			consts.SecretKeyOfflineRegCert: []byte("renewed"),
+		},
+	}, consts.DefaultOperatorName)
+	params, err := extractRegistrationParamsFromSecret(entrypoint, consts.DefaultOperatorName)
+	require.NoError(t, err)
+	entrypoint.Labels[consts.LabelNameSuffix] = params.nameID
+	entrypoint.Labels[consts.LabelSccHash] = "active-hash"
+
+	registrationObj := activatedOfflineRegistration(time.Now().Add(24 * time.Hour))
+	registrationObj.Name = consts.RegistrationName(params.nameID)
+	activeCertSecret := lifecycle.SecretAddOfflineFinalizer(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      consts.OfflineCertificateSecretName(params.nameID),
+			Namespace: consts.DefaultSCCNamespace,
+			Labels: map[string]string{
+				consts.LabelSccSecretRole: string(consts.OfflineCertificate),
+				consts.LabelNameSuffix:    params.nameID,
+				consts.LabelSccHash:       "active-hash",
+			},
+		},
+		Data: map[string][]byte{consts.SecretKeyOfflineRegCert: []byte("active")},
+	})
+
+	mockRegistrationCache.EXPECT().Get(registrationObj.Name).Return(registrationObj, nil).AnyTimes()
+	// The active certificate Secret survives the cleanup of the previous content hash
+	mockSecretsCache.EXPECT().GetByIndex(secretrepo.IndexSecretsBySccHash, "active-hash").Return([]*corev1.Secret{activeCertSecret}, nil)
+	mockSecretsCache.EXPECT().Get(consts.DefaultSCCNamespace, activeCertSecret.Name).Return(activeCertSecret, nil).AnyTimes()
+	mockSecrets.EXPECT().Patch(consts.DefaultSCCNamespace, entrypoint.Name, k8stypes.MergePatchType, gomock.Any()).Return(entrypoint, nil)
+	mockSecrets.EXPECT().Patch(consts.DefaultSCCNamespace, activeCertSecret.Name, k8stypes.MergePatchType, gomock.Any()).
+		DoAndReturn(func(_, _ string, _ k8stypes.PatchType, patch []byte, _ ...string) (*corev1.Secret, error) {
+			var patched corev1.Secret
+			require.NoError(t, json.Unmarshal(patch, &patched))
+			assert.Equal(t, []byte("renewed"), patched.Data[consts.SecretKeyOfflineRegCertReplacement])
+			assert.NotContains(t, patched.Data, consts.SecretKeyOfflineRegCert, "the active certificate stays in use until validated")
+			return activeCertSecret, nil
+		})
+	mockRegistrations.EXPECT().Get(registrationObj.Name, gomock.Any()).Return(registrationObj, nil).AnyTimes()
+	mockRegistrations.EXPECT().Patch(registrationObj.Name, k8stypes.MergePatchType, gomock.Any()).Return(registrationObj, nil).AnyTimes()
+	// The Registration spec is unchanged, so it has to be enqueued to review the staged certificate
+	mockRegistrations.EXPECT().Enqueue(registrationObj.Name)
+
+	_, err = h.OnSecretChange(entrypoint.Name, entrypoint)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("active"), activeCertSecret.Data[consts.SecretKeyOfflineRegCert])
+	assert.True(t, registrationObj.Status.ActivationStatus.Activated)
+}
@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/rancher/scc-operator/internal/suseconnect/offlinevalidator"
+)
+
+// offlineTrustRoots gathers the extra keys trusted to sign offline certificates, from the configured Secret and dev-mode keys.
+// Keys that cannot be read are skipped with a warning; certificates they signed are then rejected like any unknown signer.
+func (h *handler) offlineTrustRoots() offlinevalidator.TrustRoots {
+	if h.options == nil || h.options.OperatorSettings == nil {
+		return nil
+	}
+	settings := h.options.OperatorSettings.OfflineTrustRoots
+
+	var trustRoots offlinevalidator.TrustRoots
+	if settings.SecretName != "" {
+		trustRootsSecret, err := h.secretRepo.Cache.Get(h.options.SystemNamespace(), settings.SecretName)
+		if err != nil {
+			h.log.Warnf("failed to get offline trust roots secret %s/%s: %v", h.options.SystemNamespace(), settings.SecretName, err)
+		} else {
+			for _, key := range slices.Sorted(maps.Keys(trustRootsSecret.Data)) {
+				roots, parseErr := offlinevalidator.ParseTrustRoots(trustRootsSecret.Data[key])
+				if parseErr != nil {
+					h.log.Warnf("skipping key %s of offline trust roots secret %s: %v", key, settings.SecretName, parseErr)
+					continue
+				}
+				trustRoots = append(trustRoots, roots...)
+			}
+		}
+	}
+
+	if settings.DevKeys != "" {
+		roots, parseErr := offlinevalidator.ParseTrustRoots([]byte(settings.DevKeys))
+		if parseErr != nil {
+			h.log.Warnf("skipping dev offline trust roots: %v", parseErr)
+		} else {
+			trustRoots = append(trustRoots, roots...)
+		}
+	}
+
+	if len(trustRoots) > 0 {
+		h.log.Debugf("offline certificates signed by %d extra trust roots are accepted", len(trustRoots))
+	}
+
+	return trustRoots
+}
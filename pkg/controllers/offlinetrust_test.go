@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/SUSE/connect-ng/pkg/registration"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/rancher/scc-operator/internal/config"
+	"github.com/rancher/scc-operator/internal/consts"
+	"github.com/rancher/scc-operator/internal/logging"
+	"github.com/rancher/scc-operator/internal/repos/secretrepo"
+	"github.com/rancher/scc-operator/internal/suseconnect/offlinetest"
+	"github.com/rancher/scc-operator/internal/suseconnect/offlinevalidator"
+	"github.com/rancher/scc-operator/internal/types"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+)
+
+// signedStagedCertificate stages a certificate from the test signer, validating it with the real offline handler
+type signedStagedCertificate struct {
+	*sccOfflineMode
+	staged   []byte
+	promoted []byte
+}
+
+func (s *signedStagedCertificate) StagedCertificate() ([]byte, error) {
+	return s.staged, nil
+}
+
+func (s *signedStagedCertificate) PromoteStagedCertificate(validated []byte) error {
+	s.promoted = validated
+	s.staged = nil
+	return nil
+}
+
+func TestOfflineTrustRoots(t *testing.T) {
+	secretSigner, err := offlinetest.NewSigner()
+	require.NoError(t, err)
+	devSigner, err := offlinetest.NewSigner()
+	require.NoError(t, err)
+
+	h := &handler{
+		log: logging.NewLog(),
+		options: &types.RunOptions{
+			OperatorSettings: &config.OperatorSettings{SystemNamespace: consts.DefaultSCCNamespace},
+		},
+	}
+	assert.Empty(t, h.offlineTrustRoots())
+
+	gomockCtrl := gomock.NewController(t)
+	mockSecretsCache := fake.NewMockCacheInterface[*corev1.Secret](gomockCtrl)
+	mockSecretsCache.EXPECT().Get(consts.DefaultSCCNamespace, "offline-trust-roots").Return(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "offline-trust-roots"},
+		Data: map[string][]byte{
+			"staging.pem": secretSigner.PublicKeyPEM(),
+			"broken.pem":  []byte("-----BEGIN CERTIFICATE-----\nAAAA\n-----END CERTIFICATE-----\n"),
+		},
+	}, nil)
+	h.secretRepo = &secretrepo.SecretRepository{Cache: mockSecretsCache}
+	h.options.OperatorSettings.OfflineTrustRoots = config.OfflineTrustRootsSettings{
+		SecretName: "offline-trust-roots",
+		DevKeys:    string(devSigner.PublicKeyPEM()),
+	}
+	trustRoots := h.offlineTrustRoots()
+
+	// The unreadable key is skipped, the others are all trusted
+	require.Len(t, trustRoots, 2)
+	assert.True(t, trustRoots[0].Equal(signerKey(t, secretSigner)))
+	assert.True(t, trustRoots[1].Equal(signerKey(t, devSigner)))
+}
+
+func signerKey(t *testing.T, signer *offlinetest.Signer) *rsa.PublicKey {
+	t.Helper()
+	trustRoots, err := offlinevalidator.ParseTrustRoots(signer.PublicKeyPEM())
+	require.NoError(t, err)
+	require.Len(t, trustRoots, 1)
+	return trustRoots[0]
+}
+
+func TestSignedOfflineCertificateRotation(t *testing.T) {
+	signer, err := offlinetest.NewSigner()
+	require.NoError(t, err)
+	registrationObj := activatedOfflineRegistration(time.Now().Add(time.Hour))
+	gomockCtrl := gomock.NewController(t)
+	mockRegistrations := fake.NewMockNonNamespacedControllerInterface[*v1.Registration, *v1.RegistrationList](gomockCtrl)
+	mockRegistrations.EXPECT().Get(registrationObj.Name, gomock.Any()).Return(registrationObj, nil).AnyTimes()
+	mockRegistrations.EXPECT().UpdateStatus(gomock.Any()).DoAndReturn(func(updated *v1.Registration) (*v1.Registration, error) {
+		registrationObj = updated
+		return updated, nil
+	}).AnyTimes()
+	h := &handler{
+		log:           logging.NewLog(),
+		registrations: mockRegistrations,
+		options: &types.RunOptions{
+			OperatorSettings: &config.OperatorSettings{
+				SystemNamespace:   consts.DefaultSCCNamespace,
+				OfflineTrustRoots: config.OfflineTrustRootsSettings{DevKeys: string(signer.PublicKeyPEM())},
+			},
+		},
+	}
+
+	expiresAt := time.Now().AddDate(1, 0, 0).UTC().Truncate(time.Second)
+	certData, err := signer.Issue(offlinetest.Certificate{SystemID: 4321})
+	require.NoError(t, err)
+	renewed, err := signer.Issue(offlinetest.Certificate{SystemID: 4321, Subscription: registration.SubscriptionInfo{
+		StartsAt:  expiresAt.AddDate(-1, 0, 0),
+		ExpiresAt: expiresAt,
+	}})
+	require.NoError(t, err)
+	require.NotEqual(t, certData, renewed)
+
+	offlineMode := &sccOfflineMode{rancherUUID: offlinetest.FakeUUID, trustRoots: h.offlineTrustRoots()}
+	regHandler := &signedStagedCertificate{sccOfflineMode: offlineMode, staged: renewed}
+
+	updated, err := h.reviewStagedOfflineCertificate(regHandler, registrationObj)
+	require.NoError(t, err)
+	assert.True(t, updated)
+	assert.Equal(t, renewed, regHandler.promoted)
+	assert.True(t, v1.RegistrationConditionOfflineCertificateRotated.IsTrue(registrationObj))
+	assert.True(t, expiresAt.Equal(registrationObj.Status.RegistrationExpiresAt.Time))
+	assert.Equal(t, 4321, *registrationObj.Status.SCCSystemID)
+
+	// Without the signer trusted its certificates are rejected and the active one stays
+	regHandler = &signedStagedCertificate{sccOfflineMode: &sccOfflineMode{rancherUUID: offlinetest.FakeUUID}, staged: certData}
+	_, err = h.reviewStagedOfflineCertificate(regHandler, registrationObj)
+	require.NoError(t, err)
+	assert.Nil(t, regHandler.promoted)
+	assert.True(t, v1.RegistrationConditionOfflineCertificateRotated.IsFalse(registrationObj))
+	assert.Contains(t, v1.RegistrationConditionOfflineCertificateRotated.GetMessage(registrationObj), "signature invalid")
+}
@@ -4,10 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"sync"
 
 	"github.com/SUSE/connect-ng/pkg/connection"
 	"github.com/rancher/scc-operator/internal/telemetry"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
@@ -21,10 +21,6 @@ import (
 	"github.com/rancher/scc-operator/pkg/controllers/lifecycle"
 )
 
-var (
-	activiateMu sync.Mutex
-)
-
 type sccOnlineMode struct {
 	rancherURL     string
 	options        *types.RunOptions
@@ -33,27 +29,122 @@ type sccOnlineMode struct {
 	sccCredentials *credentials.CredentialSecretsAdapter
 	secretRepo     *secretrepo.SecretRepository
 	rancherMetrics telemetry.MetricsWrapper
+	dryRun         suseconnect.DryRunRecorder
 }
 
 func (s *sccOnlineMode) SetRancherMetrics(rancherMetrics telemetry.MetricsWrapper) {
 	s.rancherMetrics = rancherMetrics
 }
 
-func (s *sccOnlineMode) prepareSCCOnlineConnection(
-	rancherMetrics telemetry.MetricsWrapper,
-	registrationURL string,
-) suseconnect.SccWrapper {
-	return suseconnect.OnlineRancherConnection(
-		suseconnect.OnlineConnectionParams{
-			RancherURL:      s.rancherURL,
-			RegistrationURL: registrationURL,
-			Options:         suseconnect.DefaultConnectionOptions(s.options.OperatorName, s.options.OperatorMetadata.Version),
-		},
+func (s *sccOnlineMode) prepareSCCOnlineConnection(registrationObj *v1.Registration) (suseconnect.SCCClient, error) {
+	return prepareConnectedModeConnection(
+		s.options,
+		s.secretRepo,
+		s.rancherURL,
+		suseconnect.PrepareSccURL(registrationObj),
+		registrationObj,
 		s.sccCredentials.SccCredentials(),
-		rancherMetrics,
+		s.rancherMetrics,
+		s.dryRun,
 	)
 }
 
+// prepareConnectedModeConnection builds the API connection shared by the modes talking to SCC or RMT over the network
+func prepareConnectedModeConnection(
+	options *types.RunOptions,
+	secretRepo *secretrepo.SecretRepository,
+	rancherURL string,
+	registrationURL string,
+	registrationObj *v1.Registration,
+	sccCredentials connection.Credentials,
+	rancherMetrics telemetry.MetricsWrapper,
+	dryRun suseconnect.DryRunRecorder,
+) (suseconnect.SCCClient, error) {
+	connectionOptions := suseconnect.DefaultConnectionOptions(options.OperatorName, options.OperatorMetadata.Version)
+	if caCertRef := registrationCACertRef(registrationObj); caCertRef != nil {
+		caCert, err := suseconnect.FetchRegistrationCACertFrom(secretRepo, caCertRef)
+		if err != nil {
+			return nil, err
+		}
+		connectionOptions.Certificate = caCert
+	}
+	if proxyConfig := registrationProxyConfig(registrationObj); proxyConfig != nil {
+		proxyCallback, err := suseconnect.ProxyCallback(secretRepo, proxyConfig)
+		if err != nil {
+			return nil, err
+		}
+		connectionOptions.Proxy = proxyCallback
+	}
+
+	params := suseconnect.OnlineConnectionParams{
+		RancherURL:      rancherURL,
+		RegistrationURL: registrationURL,
+		Options:         connectionOptions,
+	}
+	if dryRun != nil {
+		return suseconnect.DryRunConnection(registrationObj.Name, params, sccCredentials, rancherMetrics, dryRun), nil
+	}
+
+	sccConnection := suseconnect.OnlineRancherConnection(params, sccCredentials, rancherMetrics)
+	return &sccConnection, nil
+}
+
+func registrationCACertRef(registrationObj *v1.Registration) *corev1.SecretReference {
+	if registrationObj == nil || registrationObj.Spec.RegistrationRequest == nil {
+		return nil
+	}
+
+	return registrationObj.Spec.RegistrationRequest.RegistrationAPICertificateSecretRef
+}
+
+func registrationProxyConfig(registrationObj *v1.Registration) *v1.ProxyConfig {
+	if registrationObj == nil || registrationObj.Spec.RegistrationRequest == nil {
+		return nil
+	}
+
+	return registrationObj.Spec.RegistrationRequest.Proxy
+}
+
+// reconcileProxyAuthenticationError marks the registration failed on its own condition when the proxy rejects our credentials.
+// Retrying cannot help until the proxy credentials are fixed, so this is treated like other non-recoverable errors.
+func reconcileProxyAuthenticationError(registrationObj *v1.Registration, err error) *v1.Registration {
+	nowTime := metav1.Now()
+	registrationObj.Status.RegistrationProcessedTS = &nowTime
+	registrationObj.Status.ActivationStatus.LastValidatedTS = &nowTime
+	registrationObj.Status.ActivationStatus.Activated = false
+
+	wrappedErr := fmt.Errorf("proxy authentication failed; to reregister Rancher, fix the proxy credentials then try again. Original error: %w", err)
+	registrationObj = lifecycle.PrepareFailed(registrationObj, wrappedErr)
+	v1.RegistrationConditionActivated.False(registrationObj)
+	v1.RegistrationConditionProxyAuthenticated.SetError(registrationObj, "Error: proxy authentication required", err)
+	registrationObj.SetCurrentCondition(v1.RegistrationConditionProxyAuthenticated)
+
+	return registrationObj
+}
+
+// markProxyAuthenticated clears a previously reported proxy authentication failure once a call succeeds
+func markProxyAuthenticated(registrationObj *v1.Registration) {
+	if registrationObj.HasCondition(v1.RegistrationConditionProxyAuthenticated) {
+		v1.RegistrationConditionProxyAuthenticated.SetError(registrationObj, "", nil)
+	}
+}
+
+// reconcileTLSVerificationError records a failure to trust the SCC endpoint certificate on its own condition
+func reconcileTLSVerificationError(registrationObj *v1.Registration, err error) {
+	if !suseconnect.IsTLSVerificationError(err) {
+		return
+	}
+
+	v1.RegistrationConditionTLSVerified.SetError(registrationObj, "Error: cannot verify registration API certificate", err)
+}
+
+// markTLSVerified clears a previously reported TLS verification failure once a call succeeds
+func markTLSVerified(registrationObj *v1.Registration) {
+	if registrationObj.HasCondition(v1.RegistrationConditionTLSVerified) {
+		v1.RegistrationConditionTLSVerified.SetError(registrationObj, "", nil)
+	}
+}
+
 func (s *sccOnlineMode) NeedsRegistration(registrationObj *v1.Registration) bool {
 	return lifecycle.RegistrationHasNotStarted(registrationObj) ||
 		!registrationObj.HasCondition(v1.RegistrationConditionSccURLReady) ||
@@ -76,7 +167,8 @@ func (s *sccOnlineMode) PrepareForRegister(registration *v1.Registration) (*v1.R
 func (s *sccOnlineMode) Register(registrationObj *v1.Registration) (suseconnect.RegistrationSystemID, error) {
 	// We must always refresh the sccCredentials - this ensures they are current from the secrets
 	credentialsErr := s.sccCredentials.Refresh()
-	if credentialsErr != nil {
+	// A system that is not announced yet has no login to load
+	if credentialsErr != nil && !errors.Is(credentialsErr, credentials.ErrCredentialsIncomplete) {
 		return suseconnect.EmptyRegistrationSystemID, credentialsErr
 	}
 
@@ -87,7 +179,10 @@ func (s *sccOnlineMode) Register(registrationObj *v1.Registration) (suseconnect.
 	registrationCode := suseconnect.FetchSccRegistrationCodeFrom(s.secretRepo, registrationObj.Spec.RegistrationRequest.RegistrationCodeSecretRef)
 
 	// Initiate connection to SCC & verify reg code is for Rancher
-	sccConnection := s.prepareSCCOnlineConnection(s.rancherMetrics, suseconnect.PrepareSccURL(registrationObj))
+	sccConnection, connErr := s.prepareSCCOnlineConnection(registrationObj)
+	if connErr != nil {
+		return suseconnect.EmptyRegistrationSystemID, connErr
+	}
 
 	// Register this Rancher cluster to SCC
 	id, regErr := sccConnection.RegisterOrKeepAlive(registrationCode)
@@ -114,6 +209,8 @@ func (s *sccOnlineMode) PrepareRegisteredForActivation(registration *v1.Registra
 	}
 
 	v1.RegistrationConditionAnnounced.SetStatusBool(registration, true)
+	markTLSVerified(registration)
+	markProxyAuthenticated(registration)
 	v1.ResourceConditionFailure.SetStatusBool(registration, false)
 	v1.ResourceConditionReady.SetStatusBool(registration, true)
 
@@ -150,7 +247,7 @@ func getHTTPErrorCode(err error) *int {
 type registrationReconcilerApplier func(regApplierIn *v1.Registration, httpCode *int) *v1.Registration
 
 // reconcileNonRecoverableHTTPError can help reconcile the registration state for any API/HTTP error related reasons
-func (s *sccOnlineMode) reconcileNonRecoverableHTTPError(registrationIn *v1.Registration, registerErr error, additionalApplier registrationReconcilerApplier) *v1.Registration {
+func reconcileNonRecoverableHTTPError(registrationIn *v1.Registration, registerErr error, additionalApplier registrationReconcilerApplier) *v1.Registration {
 	httpCode := *getHTTPErrorCode(registerErr)
 	nowTime := metav1.Now()
 	registrationIn.Status.RegistrationProcessedTS = &nowTime
@@ -167,10 +264,17 @@ func (s *sccOnlineMode) reconcileNonRecoverableHTTPError(registrationIn *v1.Regi
 }
 
 func (s *sccOnlineMode) ReconcileRegisterError(registrationObj *v1.Registration, registerErr error, phase types.RegistrationPhase) *v1.Registration {
-	registrationObj = lifecycle.PrepareFailed(registrationObj, registerErr)
+	if suseconnect.IsProxyAuthenticationError(registerErr) {
+		return reconcileProxyAuthenticationError(registrationObj, registerErr)
+	}
+
+	if suseconnect.IsTLSVerificationError(registerErr) {
+		registrationObj = lifecycle.PrepareFailed(registrationObj, registerErr)
+		reconcileTLSVerificationError(registrationObj, registerErr)
+	}
 
 	if isNonRecoverableHTTPError(registerErr) {
-		return s.reconcileNonRecoverableHTTPError(
+		return reconcileNonRecoverableHTTPError(
 			registrationObj,
 			registerErr,
 			func(regApplierIn *v1.Registration, httpCode *int) *v1.Registration {
@@ -188,6 +292,7 @@ func (s *sccOnlineMode) ReconcileRegisterError(registrationObj *v1.Registration,
 		)
 	}
 
+	// Anything else, e.g. an SCC outage, is only reported here; the Registration is not failed and backs off before retrying
 	v1.RegistrationConditionActivated.False(registrationObj)
 	if phase <= types.RegistrationForActivation {
 		v1.RegistrationConditionAnnounced.False(registrationObj)
@@ -242,7 +347,10 @@ func (s *sccOnlineMode) Activate(registrationObj *v1.Registration) error {
 	}
 
 	registrationCode := suseconnect.FetchSccRegistrationCodeFrom(s.secretRepo, registrationObj.Spec.RegistrationRequest.RegistrationCodeSecretRef)
-	sccConnection := s.prepareSCCOnlineConnection(s.rancherMetrics, suseconnect.PrepareSccURL(registrationObj))
+	sccConnection, connErr := s.prepareSCCOnlineConnection(registrationObj)
+	if connErr != nil {
+		return connErr
+	}
 
 	metaData, product, err := sccConnection.Activate(registrationCode)
 	if err != nil {
@@ -258,12 +366,22 @@ func (s *sccOnlineMode) Activate(registrationObj *v1.Registration) error {
 
 func (s *sccOnlineMode) PrepareActivatedForKeepalive(registrationObj *v1.Registration) (*v1.Registration, error) {
 	v1.RegistrationConditionSccURLReady.True(registrationObj)
+	markTLSVerified(registrationObj)
+	markProxyAuthenticated(registrationObj)
+
+	return s.refreshActivations(registrationObj)
+}
 
+// refreshActivations fetches every product activation for the system and records them on the Registration status
+func (s *sccOnlineMode) refreshActivations(registrationObj *v1.Registration) (*v1.Registration, error) {
 	credentialsErr := s.sccCredentials.Refresh()
 	if credentialsErr != nil {
 		return nil, fmt.Errorf("cannot load scc credentials: %w", credentialsErr)
 	}
-	sccConnection := s.prepareSCCOnlineConnection(s.rancherMetrics, suseconnect.PrepareSccURL(registrationObj))
+	sccConnection, connErr := s.prepareSCCOnlineConnection(registrationObj)
+	if connErr != nil {
+		return nil, connErr
+	}
 
 	activations, err := sccConnection.ActivationStatus()
 	if err != nil {
@@ -272,18 +390,20 @@ func (s *sccOnlineMode) PrepareActivatedForKeepalive(registrationObj *v1.Registr
 	if len(activations) == 0 {
 		return nil, fmt.Errorf("no activations found for registration %q", registrationObj.Name)
 	}
-	// TODO: what if there are more than 1?
-	firstActivation := activations[0]
+	s.log.Debugf("found %d activations for registration %q", len(activations), registrationObj.Name)
 
-	registrationObj.Status.RegistrationExpiresAt = &metav1.Time{Time: firstActivation.ExpiresAt}
-	registrationObj.Status.RegisteredProduct = &firstActivation.Product.FriendlyName
-	return registrationObj, nil
+	return applyActivationsToStatus(registrationObj, activations), nil
 }
 
 // ReconcileActivateError will first verify if an error is recoverable and then reconcile the error as needed
 func (s *sccOnlineMode) ReconcileActivateError(registration *v1.Registration, activationErr error, _ types.ActivationPhase) *v1.Registration {
+	if suseconnect.IsProxyAuthenticationError(activationErr) {
+		return reconcileProxyAuthenticationError(registration, activationErr)
+	}
+
+	reconcileTLSVerificationError(registration, activationErr)
 	if isNonRecoverableHTTPError(activationErr) {
-		return s.reconcileNonRecoverableHTTPError(
+		return reconcileNonRecoverableHTTPError(
 			registration,
 			activationErr,
 			func(regApplierIn *v1.Registration, httpCode *int) *v1.Registration {
@@ -312,7 +432,10 @@ func (s *sccOnlineMode) Keepalive(registrationObj *v1.Registration) error {
 	}
 
 	regCode := suseconnect.FetchSccRegistrationCodeFrom(s.secretRepo, registrationObj.Spec.RegistrationRequest.RegistrationCodeSecretRef)
-	sccConnection := s.prepareSCCOnlineConnection(s.rancherMetrics, suseconnect.PrepareSccURL(registrationObj))
+	sccConnection, connErr := s.prepareSCCOnlineConnection(registrationObj)
+	if connErr != nil {
+		return connErr
+	}
 
 	metaData, product, err := sccConnection.Activate(regCode)
 	if err != nil {
@@ -334,15 +457,21 @@ func (s *sccOnlineMode) Keepalive(registrationObj *v1.Registration) error {
 
 func (s *sccOnlineMode) PrepareKeepaliveSucceeded(registration *v1.Registration) (*v1.Registration, error) {
 	v1.RegistrationConditionSccURLReady.True(registration)
+	markTLSVerified(registration)
+	markProxyAuthenticated(registration)
 
-	// TODO take any post keepalive success steps
 	s.log.Debug("preparing keepalive succeeded")
-	return registration, nil
+	return s.refreshActivations(registration)
 }
 
 func (s *sccOnlineMode) ReconcileKeepaliveError(registration *v1.Registration, keepaliveErr error) *v1.Registration {
+	if suseconnect.IsProxyAuthenticationError(keepaliveErr) {
+		return reconcileProxyAuthenticationError(registration, keepaliveErr)
+	}
+
+	reconcileTLSVerificationError(registration, keepaliveErr)
 	if isNonRecoverableHTTPError(keepaliveErr) {
-		return s.reconcileNonRecoverableHTTPError(
+		return reconcileNonRecoverableHTTPError(
 			registration,
 			keepaliveErr,
 			func(regApplierIn *v1.Registration, httpCode *int) *v1.Registration {
@@ -365,18 +494,18 @@ func (s *sccOnlineMode) ReconcileKeepaliveError(registration *v1.Registration, k
 
 func (s *sccOnlineMode) Deregister() error {
 	_ = s.sccCredentials.Refresh()
-	sccConnection := s.prepareSCCOnlineConnection(s.rancherMetrics, suseconnect.PrepareSccURL(s.registration))
-	// TODO : this causes deletion to fail if the credentials are invalid. I think we
-	// need to do a best effort check to see if it was ever registered before
-	// we want to fail to delete if deregister fails, but the system is registered in SCC
-
-	// Finalizers on the credential secret have helped this case, but it's still invalid if users edit the secret manually for some reason.
+	sccConnection, connErr := s.prepareSCCOnlineConnection(s.registration)
+	if connErr != nil {
+		return fmt.Errorf("failed to prepare SCC connection to deregister: %w", connErr)
+	}
 	if err := sccConnection.Deregister(); err != nil {
-		s.log.Warn("Deregister failure will be logged but not prevent cleanup")
-		s.log.Errorf("Failed to deregister SCC registration: %v", err)
+		return fmt.Errorf("failed to deregister SCC registration: %w", err)
 	}
 
-	// Delete SCC credentials after successful Deregister
+	return nil
+}
+
+func (s *sccOnlineMode) RemoveSecrets() error {
 	credErr := s.sccCredentials.Remove()
 	if credErr != nil {
 		return credErr
@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/SUSE/connect-ng/pkg/connection"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rancher/scc-operator/internal/types"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+	"github.com/rancher/scc-operator/pkg/controllers/lifecycle"
+)
+
+func TestReconcileTLSVerificationError(t *testing.T) {
+	registration := &v1.Registration{}
+
+	reconcileTLSVerificationError(registration, errors.New("connection refused"))
+	assert.False(t, registration.HasCondition(v1.RegistrationConditionTLSVerified))
+
+	markTLSVerified(registration)
+	assert.False(t, registration.HasCondition(v1.RegistrationConditionTLSVerified))
+
+	tlsErr := fmt.Errorf("Cannot register system to SCC: %w", x509.UnknownAuthorityError{})
+	reconcileTLSVerificationError(registration, tlsErr)
+	assert.True(t, v1.RegistrationConditionTLSVerified.IsFalse(registration))
+	assert.Contains(t, v1.RegistrationConditionTLSVerified.GetMessage(registration), "unknown authority")
+
+	markTLSVerified(registration)
+	assert.True(t, v1.RegistrationConditionTLSVerified.IsTrue(registration))
+	assert.Empty(t, v1.RegistrationConditionTLSVerified.GetMessage(registration))
+}
+
+func TestReconcileProxyAuthenticationError(t *testing.T) {
+	proxyErr := &connection.ApiError{Code: http.StatusProxyAuthRequired, Message: "Proxy Authentication Required"}
+	handlers := map[string]SCCHandler{
+		"online": &sccOnlineMode{},
+		"rmt":    &sccRMTMode{},
+		"payg":   &sccPayAsYouGoMode{},
+	}
+
+	for name, handler := range handlers {
+		t.Run(name, func(t *testing.T) {
+			for _, registration := range []*v1.Registration{
+				handler.ReconcileRegisterError(&v1.Registration{}, proxyErr, types.RegistrationMain),
+				handler.ReconcileActivateError(&v1.Registration{}, proxyErr, types.ActivationMain),
+				handler.ReconcileKeepaliveError(&v1.Registration{}, proxyErr),
+			} {
+				assert.True(t, lifecycle.RegistrationIsFailed(registration))
+				assert.True(t, v1.RegistrationConditionProxyAuthenticated.IsFalse(registration))
+				assert.Equal(t, string(v1.RegistrationConditionProxyAuthenticated), registration.Status.CurrentCondition.Type)
+				assert.False(t, registration.HasCondition(v1.RegistrationConditionAnnounced))
+			}
+		})
+	}
+
+	registration := reconcileProxyAuthenticationError(&v1.Registration{}, proxyErr)
+	markProxyAuthenticated(registration)
+	assert.True(t, v1.RegistrationConditionProxyAuthenticated.IsTrue(registration))
+}
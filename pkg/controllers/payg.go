@@ -0,0 +1,379 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rancher/scc-operator/internal/telemetry"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/rancher/scc-operator/internal/consts"
+	"github.com/rancher/scc-operator/internal/logging"
+	"github.com/rancher/scc-operator/internal/repos/secretrepo"
+	"github.com/rancher/scc-operator/internal/suseconnect"
+	"github.com/rancher/scc-operator/internal/suseconnect/credentials"
+	"github.com/rancher/scc-operator/internal/types"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+	"github.com/rancher/scc-operator/pkg/controllers/lifecycle"
+)
+
+// sccPayAsYouGoMode registers cloud marketplace clusters that are billed by usage.
+// These are announced without a registration code (the cloud instance data identifies them instead),
+// and every keepalive reports the hours the cluster has been online since the previous report.
+type sccPayAsYouGoMode struct {
+	rancherURL     string
+	options        *types.RunOptions
+	registration   *v1.Registration
+	log            logging.StructuredLogger
+	sccCredentials *credentials.CredentialSecretsAdapter
+	secretRepo     *secretrepo.SecretRepository
+	rancherMetrics telemetry.MetricsWrapper
+	dryRun         suseconnect.DryRunRecorder
+	// usageReport is the report sent by Keepalive, kept so the Prepare* methods can record it in status
+	usageReport *suseconnect.UsageReport
+	// usageReportRejected is set when SCC answered the usage report with an error, so it was not counted
+	usageReportRejected bool
+	// updateStatus persists a status change right away, used to record a usage report before it is sent
+	updateStatus func(name string, change func(*v1.Registration) error) error
+	now          func() time.Time
+}
+
+func (s *sccPayAsYouGoMode) SetRancherMetrics(rancherMetrics telemetry.MetricsWrapper) {
+	s.rancherMetrics = rancherMetrics
+}
+
+// payAsYouGoURL prefers the URL on the registration, then the operator wide PAYG URL, then the environment default
+func (s *sccPayAsYouGoMode) payAsYouGoURL(registrationObj *v1.Registration) string {
+	if regURL := suseconnect.PrepareSccURL(registrationObj); regURL != "" {
+		return regURL
+	}
+	if s.options != nil && s.options.OperatorSettings != nil && s.options.OperatorSettings.PayAsYouGoRegistrationURL != "" {
+		return s.options.OperatorSettings.PayAsYouGoRegistrationURL
+	}
+
+	return consts.PayAsYouGoBaseURL()
+}
+
+func (s *sccPayAsYouGoMode) preparePayAsYouGoConnection(registrationObj *v1.Registration) (suseconnect.SCCClient, error) {
+	return prepareConnectedModeConnection(
+		s.options,
+		s.secretRepo,
+		s.rancherURL,
+		s.payAsYouGoURL(registrationObj),
+		registrationObj,
+		s.sccCredentials.SccCredentials(),
+		s.rancherMetrics,
+		s.dryRun,
+	)
+}
+
+func (s *sccPayAsYouGoMode) instanceData(registrationObj *v1.Registration) (string, error) {
+	if registrationObj.Spec.RegistrationRequest == nil {
+		return "", nil
+	}
+
+	return suseconnect.FetchInstanceDataFrom(s.secretRepo, registrationObj.Spec.RegistrationRequest.InstanceDataSecretRef)
+}
+
+func (s *sccPayAsYouGoMode) currentTime() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+func (s *sccPayAsYouGoMode) NeedsRegistration(registrationObj *v1.Registration) bool {
+	return lifecycle.RegistrationHasNotStarted(registrationObj) ||
+		!registrationObj.HasCondition(v1.RegistrationConditionPayAsYouGoAnnounced)
+}
+
+func (s *sccPayAsYouGoMode) NeedsActivation(registrationObj *v1.Registration) bool {
+	return lifecycle.RegistrationNeedsActivation(registrationObj)
+}
+
+func (s *sccPayAsYouGoMode) ReadyForActivation(registrationObj *v1.Registration) bool {
+	return v1.RegistrationConditionPayAsYouGoAnnounced.IsTrue(registrationObj)
+}
+
+func (s *sccPayAsYouGoMode) NeedsPreprocessRegistration(_ *v1.Registration) bool {
+	return false
+}
+
+func (s *sccPayAsYouGoMode) PreprocessRegistration(registrationObj *v1.Registration) (*v1.Registration, error) {
+	return registrationObj, nil
+}
+
+func (s *sccPayAsYouGoMode) ResetToReadyForActivation(registrationObj *v1.Registration) (*v1.Registration, error) {
+	registrationObj.Status.ActivationStatus.Activated = false
+	registrationObj.Status.ActivationStatus.LastValidatedTS = &metav1.Time{}
+	v1.ResourceConditionProgressing.True(registrationObj)
+	v1.ResourceConditionReady.False(registrationObj)
+	v1.ResourceConditionDone.False(registrationObj)
+	v1.RegistrationConditionActivated.False(registrationObj)
+	registrationObj.SetCurrentCondition(v1.ResourceConditionProgressing)
+
+	return registrationObj, nil
+}
+
+// PrepareForRegister creates the SCC creds secret used to store the system credentials
+func (s *sccPayAsYouGoMode) PrepareForRegister(registration *v1.Registration) (*v1.Registration, error) {
+	if registration.Status.SystemCredentialsSecretRef == nil {
+		err := s.sccCredentials.InitSecret()
+		if err != nil {
+			return registration, err
+		}
+		s.sccCredentials.SetRegistrationCredentialsSecretRef(registration)
+	}
+
+	return registration, nil
+}
+
+func (s *sccPayAsYouGoMode) Register(registrationObj *v1.Registration) (suseconnect.RegistrationSystemID, error) {
+	credentialsErr := s.sccCredentials.Refresh()
+	// The login arrives with the pay-as-you-go announcement, so an unannounced system has none
+	if credentialsErr != nil && !errors.Is(credentialsErr, credentials.ErrCredentialsIncomplete) {
+		return suseconnect.EmptyRegistrationSystemID, credentialsErr
+	}
+
+	instanceData, instanceDataErr := s.instanceData(registrationObj)
+	if instanceDataErr != nil {
+		return suseconnect.EmptyRegistrationSystemID, instanceDataErr
+	}
+
+	sccConnection, connErr := s.preparePayAsYouGoConnection(registrationObj)
+	if connErr != nil {
+		return suseconnect.EmptyRegistrationSystemID, connErr
+	}
+
+	return sccConnection.RegisterPayAsYouGo(instanceData)
+}
+
+func (s *sccPayAsYouGoMode) PrepareRegisteredForActivation(registration *v1.Registration) (*v1.Registration, error) {
+	if registration.Status.SCCSystemID == nil {
+		return registration, errors.New("SCC system ID cannot be empty when preparing registered pay-as-you-go system")
+	}
+
+	// Usage is counted from the hour the system was first announced
+	if registration.Status.PayAsYouGo == nil {
+		announcedAt := metav1.NewTime(s.currentTime().UTC().Truncate(time.Hour))
+		registration.Status.PayAsYouGo = &v1.PayAsYouGoStatus{
+			LastUsageReportTS: &announcedAt,
+		}
+	}
+
+	v1.RegistrationConditionPayAsYouGoAnnounced.SetStatusBool(registration, true)
+	markTLSVerified(registration)
+	markProxyAuthenticated(registration)
+	v1.ResourceConditionFailure.SetStatusBool(registration, false)
+	v1.ResourceConditionReady.SetStatusBool(registration, true)
+
+	return registration, nil
+}
+
+// Activate sends the first usage report; PAYG systems are entitled by their instance data, so there is no regcode to activate
+func (s *sccPayAsYouGoMode) Activate(registrationObj *v1.Registration) error {
+	s.log.Debugf("received pay-as-you-go registration ready for activation %q", registrationObj.Name)
+	return s.Keepalive(registrationObj)
+}
+
+func (s *sccPayAsYouGoMode) PrepareActivatedForKeepalive(registrationObj *v1.Registration) (*v1.Registration, error) {
+	return s.recordUsageReport(registrationObj), nil
+}
+
+func (s *sccPayAsYouGoMode) Keepalive(registrationObj *v1.Registration) error {
+	credRefreshErr := s.sccCredentials.Refresh()
+	if credRefreshErr != nil {
+		return fmt.Errorf("cannot refresh credentials: %w", credRefreshErr)
+	}
+
+	instanceData, instanceDataErr := s.instanceData(registrationObj)
+	if instanceDataErr != nil {
+		return instanceDataErr
+	}
+
+	sccConnection, connErr := s.preparePayAsYouGoConnection(registrationObj)
+	if connErr != nil {
+		return connErr
+	}
+
+	since := s.currentTime()
+	if paygStatus := registrationObj.Status.PayAsYouGo; paygStatus != nil {
+		if paygStatus.PendingUsageReport != nil {
+			since = paygStatus.PendingUsageReport.Until.Time
+		} else if paygStatus.LastUsageReportTS != nil {
+			since = paygStatus.LastUsageReportTS.Time
+		}
+	}
+	report := suseconnect.NewUsageReport(since, s.currentTime())
+	s.usageReportRejected = false
+	if pendingErr := s.recordPendingUsageReport(registrationObj.Name, report); pendingErr != nil {
+		return fmt.Errorf("cannot record usage report before sending it: %w", pendingErr)
+	}
+	if reportErr := sccConnection.ReportUsage(instanceData, report); reportErr != nil {
+		s.usageReportRejected = getHTTPErrorCode(reportErr) != nil
+		return reportErr
+	}
+	s.usageReport = &report
+
+	s.log.Infof("Successfully reported %d pay-as-you-go usage hours to SCC", report.Hours)
+
+	return nil
+}
+
+func (s *sccPayAsYouGoMode) PrepareKeepaliveSucceeded(registration *v1.Registration) (*v1.Registration, error) {
+	return s.recordUsageReport(registration), nil
+}
+
+// recordPendingUsageReport stores the report as pending before it is sent; a pending report left by an earlier keepalive
+// counts as reported, as SCC may have received it even though its outcome was never recorded.
+func (s *sccPayAsYouGoMode) recordPendingUsageReport(name string, report suseconnect.UsageReport) error {
+	if s.updateStatus == nil {
+		return nil
+	}
+
+	return s.updateStatus(name, func(current *v1.Registration) error {
+		settlePendingUsageReport(current)
+		current.Status.PayAsYouGo.PendingUsageReport = &v1.PendingUsageReport{
+			Until: metav1.NewTime(report.Until),
+			Hours: report.Hours,
+		}
+		return nil
+	})
+}
+
+// settlePendingUsageReport counts a pending usage report as reported
+func settlePendingUsageReport(registration *v1.Registration) {
+	if registration.Status.PayAsYouGo == nil {
+		registration.Status.PayAsYouGo = &v1.PayAsYouGoStatus{}
+	}
+	paygStatus := registration.Status.PayAsYouGo
+	if pending := paygStatus.PendingUsageReport; pending != nil {
+		reportedUntil := pending.Until
+		paygStatus.LastUsageReportTS = &reportedUntil
+		paygStatus.ReportedUsageHours += pending.Hours
+		paygStatus.PendingUsageReport = nil
+	}
+}
+
+// recordUsageReport moves the reported period forward so the next keepalive starts where this one ended
+func (s *sccPayAsYouGoMode) recordUsageReport(registration *v1.Registration) *v1.Registration {
+	if s.usageReport != nil {
+		if registration.Status.PayAsYouGo == nil {
+			registration.Status.PayAsYouGo = &v1.PayAsYouGoStatus{}
+		}
+		reportedUntil := metav1.NewTime(s.usageReport.Until)
+		registration.Status.PayAsYouGo.LastUsageReportTS = &reportedUntil
+		registration.Status.PayAsYouGo.ReportedUsageHours += s.usageReport.Hours
+		registration.Status.PayAsYouGo.PendingUsageReport = nil
+	}
+
+	v1.RegistrationConditionPayAsYouGoUsageReported.True(registration)
+	markTLSVerified(registration)
+	markProxyAuthenticated(registration)
+
+	return registration
+}
+
+func (s *sccPayAsYouGoMode) Deregister() error {
+	_ = s.sccCredentials.Refresh()
+	sccConnection, connErr := s.preparePayAsYouGoConnection(s.registration)
+	if connErr != nil {
+		return fmt.Errorf("failed to prepare SCC connection to deregister: %w", connErr)
+	}
+	if err := sccConnection.Deregister(); err != nil {
+		return fmt.Errorf("failed to deregister pay-as-you-go registration: %w", err)
+	}
+
+	return nil
+}
+
+func (s *sccPayAsYouGoMode) RemoveSecrets() error {
+	return s.sccCredentials.Remove()
+}
+
+func (s *sccPayAsYouGoMode) ReconcileRegisterError(registrationObj *v1.Registration, registerErr error, phase types.RegistrationPhase) *v1.Registration {
+	if suseconnect.IsProxyAuthenticationError(registerErr) {
+		return reconcileProxyAuthenticationError(registrationObj, registerErr)
+	}
+
+	if suseconnect.IsTLSVerificationError(registerErr) {
+		registrationObj = lifecycle.PrepareFailed(registrationObj, registerErr)
+		reconcileTLSVerificationError(registrationObj, registerErr)
+	}
+
+	if isNonRecoverableHTTPError(registerErr) {
+		return reconcileNonRecoverableHTTPError(
+			registrationObj,
+			registerErr,
+			func(regApplierIn *v1.Registration, httpCode *int) *v1.Registration {
+				preparedErrorReasonCondition := fmt.Sprintf("Error: SCC pay-as-you-go api call returned %s (%d) status", http.StatusText(*httpCode), *httpCode)
+				v1.RegistrationConditionPayAsYouGoAnnounced.SetError(regApplierIn, preparedErrorReasonCondition, registerErr)
+				v1.RegistrationConditionActivated.False(regApplierIn)
+				regApplierIn.SetCurrentCondition(v1.RegistrationConditionPayAsYouGoAnnounced)
+				regApplierIn.Status.ActivationStatus.Activated = false
+
+				return regApplierIn
+			},
+		)
+	}
+
+	// Other errors keep the pay-as-you-go Registration out of Failed, so it is retried with backoff
+	v1.RegistrationConditionActivated.False(registrationObj)
+	if phase <= types.RegistrationForActivation {
+		v1.RegistrationConditionPayAsYouGoAnnounced.False(registrationObj)
+	}
+
+	if phase == types.RegistrationPrepare {
+		v1.ResourceConditionFailure.SetError(registrationObj, "failed during secret initialization", registerErr)
+	}
+
+	return registrationObj
+}
+
+func (s *sccPayAsYouGoMode) ReconcileActivateError(registration *v1.Registration, activationErr error, _ types.ActivationPhase) *v1.Registration {
+	if suseconnect.IsProxyAuthenticationError(activationErr) {
+		return reconcileProxyAuthenticationError(registration, activationErr)
+	}
+
+	reconcileTLSVerificationError(registration, activationErr)
+	return s.reconcileUsageReportHTTPError(registration, activationErr)
+}
+
+func (s *sccPayAsYouGoMode) ReconcileKeepaliveError(registration *v1.Registration, keepaliveErr error) *v1.Registration {
+	if suseconnect.IsProxyAuthenticationError(keepaliveErr) {
+		return reconcileProxyAuthenticationError(registration, keepaliveErr)
+	}
+
+	reconcileTLSVerificationError(registration, keepaliveErr)
+	return s.reconcileUsageReportHTTPError(registration, keepaliveErr)
+}
+
+// reconcileUsageReportHTTPError handles errors from the usage report sent by both activation and keepalive
+func (s *sccPayAsYouGoMode) reconcileUsageReportHTTPError(registration *v1.Registration, reportErr error) *v1.Registration {
+	// SCC answered with an error, so the pending report was not counted and its hours go into the next one
+	if s.usageReportRejected && registration.Status.PayAsYouGo != nil {
+		registration.Status.PayAsYouGo.PendingUsageReport = nil
+	}
+
+	if !isNonRecoverableHTTPError(reportErr) {
+		v1.RegistrationConditionPayAsYouGoUsageReported.False(registration)
+		return registration
+	}
+
+	return reconcileNonRecoverableHTTPError(
+		registration,
+		reportErr,
+		func(regApplierIn *v1.Registration, httpCode *int) *v1.Registration {
+			preparedErrorReasonCondition := fmt.Sprintf("Error: SCC usage report returned %s (%d) status", http.StatusText(*httpCode), *httpCode)
+			v1.RegistrationConditionPayAsYouGoUsageReported.SetError(regApplierIn, preparedErrorReasonCondition, reportErr)
+			regApplierIn.SetCurrentCondition(v1.RegistrationConditionPayAsYouGoUsageReported)
+			regApplierIn.Status.ActivationStatus.Activated = false
+
+			return regApplierIn
+		},
+	)
+}
+
+var _ SCCHandler = &sccPayAsYouGoMode{}
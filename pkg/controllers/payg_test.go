@@ -0,0 +1,246 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SUSE/connect-ng/pkg/connection"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/rancher/scc-operator/internal/config"
+	"github.com/rancher/scc-operator/internal/consts"
+	"github.com/rancher/scc-operator/internal/initializer"
+	"github.com/rancher/scc-operator/internal/logging"
+	"github.com/rancher/scc-operator/internal/repos/secretrepo"
+	"github.com/rancher/scc-operator/internal/suseconnect/credentials"
+	"github.com/rancher/scc-operator/internal/types"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+)
+
+func TestPayAsYouGoModeLifecycleDeciders(t *testing.T) {
+	now := time.Date(2024, 1, 18, 21, 30, 0, 0, time.UTC)
+	paygHandler := &sccPayAsYouGoMode{now: func() time.Time { return now }}
+	registration := &v1.Registration{Spec: v1.RegistrationSpec{Mode: v1.RegistrationModePayAsYouGo}}
+
+	assert.True(t, paygHandler.NeedsRegistration(registration))
+	assert.False(t, paygHandler.ReadyForActivation(registration))
+
+	systemID := 42
+	processedTS := metav1.Now()
+	registration.Status.SCCSystemID = &systemID
+	registration.Status.RegistrationProcessedTS = &processedTS
+	registration, err := paygHandler.PrepareRegisteredForActivation(registration)
+	require.NoError(t, err)
+
+	assert.False(t, paygHandler.NeedsRegistration(registration))
+	assert.True(t, paygHandler.ReadyForActivation(registration))
+	assert.True(t, paygHandler.NeedsActivation(registration))
+	require.NotNil(t, registration.Status.PayAsYouGo)
+	assert.Equal(t, time.Date(2024, 1, 18, 21, 0, 0, 0, time.UTC), registration.Status.PayAsYouGo.LastUsageReportTS.Time)
+}
+
+func TestPayAsYouGoModeURL(t *testing.T) {
+	initializer.DevMode.Set(true)
+	paygHandler := &sccPayAsYouGoMode{options: &types.RunOptions{OperatorSettings: &config.OperatorSettings{}}}
+	registration := &v1.Registration{
+		Spec: v1.RegistrationSpec{
+			Mode:                v1.RegistrationModePayAsYouGo,
+			RegistrationRequest: &v1.RegistrationRequest{},
+		},
+	}
+
+	assert.Equal(t, string(consts.StagingSccURL), paygHandler.payAsYouGoURL(registration))
+
+	paygHandler.options.OperatorSettings.PayAsYouGoRegistrationURL = "https://payg.example.com"
+	assert.Equal(t, "https://payg.example.com", paygHandler.payAsYouGoURL(registration))
+
+	registration.Spec.RegistrationRequest.RegistrationAPIUrl = ptr.To("https://scc.example.com")
+	assert.Equal(t, "https://scc.example.com", paygHandler.payAsYouGoURL(registration))
+}
+
+func TestPayAsYouGoModeKeepaliveReportsUsage(t *testing.T) {
+	var reportedOnlineAt []string
+	var reportedInstanceData string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Path != "/connect/systems" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body := struct {
+			InstanceData string   `json:"instance_data"`
+			OnlineAt     []string `json:"online_at"`
+		}{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		reportedOnlineAt = body.OnlineAt
+		reportedInstanceData = body.InstanceData
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(server.Close)
+
+	gomockCtrl := gomock.NewController(t)
+	mockSecretsCache := fake.NewMockCacheInterface[*corev1.Secret](gomockCtrl)
+	secretRepo := &secretrepo.SecretRepository{Cache: mockSecretsCache}
+	mockSecretsCache.EXPECT().Get(consts.DefaultSCCNamespace, "scc-system-credentials-abc").Return(&corev1.Secret{
+		Data: map[string][]byte{
+			credentials.UsernameKey: []byte("SCC_login"),
+			credentials.PasswordKey: []byte("sccpassword"),
+		},
+	}, nil).AnyTimes()
+	mockSecretsCache.EXPECT().Get(consts.DefaultSCCNamespace, "payg-instance-data-abc").Return(&corev1.Secret{
+		Data: map[string][]byte{consts.SecretKeyInstanceData: []byte("<instance document>")},
+	}, nil)
+
+	lastReport := metav1.NewTime(time.Date(2024, 1, 18, 22, 0, 0, 0, time.UTC))
+	registration := &v1.Registration{
+		Spec: v1.RegistrationSpec{
+			Mode: v1.RegistrationModePayAsYouGo,
+			RegistrationRequest: &v1.RegistrationRequest{
+				RegistrationAPIUrl:    ptr.To(server.URL),
+				InstanceDataSecretRef: &corev1.SecretReference{Name: "payg-instance-data-abc", Namespace: consts.DefaultSCCNamespace},
+			},
+		},
+		Status: v1.RegistrationStatus{
+			PayAsYouGo: &v1.PayAsYouGoStatus{LastUsageReportTS: &lastReport, ReportedUsageHours: 10},
+		},
+	}
+
+	paygHandler := &sccPayAsYouGoMode{
+		options:        &types.RunOptions{OperatorName: "scc-operator", OperatorSettings: &config.OperatorSettings{}},
+		log:            logging.NewLog(),
+		registration:   registration,
+		sccCredentials: credentials.New(consts.DefaultSCCNamespace, "scc-system-credentials-abc", nil, secretRepo, map[string]string{}),
+		secretRepo:     secretRepo,
+		now:            func() time.Time { return time.Date(2024, 1, 19, 1, 15, 0, 0, time.UTC) },
+	}
+
+	require.NoError(t, paygHandler.Keepalive(registration))
+	assert.Equal(t, "<instance document>", reportedInstanceData)
+	assert.Equal(t, []string{
+		"2024-01-18:000000000000000000000011",
+		"2024-01-19:100000000000000000000000",
+	}, reportedOnlineAt)
+
+	registration, err := paygHandler.PrepareKeepaliveSucceeded(registration)
+	require.NoError(t, err)
+	assert.True(t, v1.RegistrationConditionPayAsYouGoUsageReported.IsTrue(registration))
+	assert.Equal(t, int64(13), registration.Status.PayAsYouGo.ReportedUsageHours)
+	assert.Equal(t, time.Date(2024, 1, 19, 1, 0, 0, 0, time.UTC), registration.Status.PayAsYouGo.LastUsageReportTS.Time)
+}
+
+func TestPayAsYouGoModeKeepaliveRecordsPendingUsageReport(t *testing.T) {
+	var reportedOnlineAt []string
+	sccStatus := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := struct {
+			OnlineAt []string `json:"online_at"`
+		}{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		reportedOnlineAt = body.OnlineAt
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(sccStatus)
+		if sccStatus != http.StatusOK {
+			_, _ = w.Write([]byte(`{"error": "scc is down"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(server.Close)
+
+	gomockCtrl := gomock.NewController(t)
+	mockSecretsCache := fake.NewMockCacheInterface[*corev1.Secret](gomockCtrl)
+	secretRepo := &secretrepo.SecretRepository{Cache: mockSecretsCache}
+	mockSecretsCache.EXPECT().Get(consts.DefaultSCCNamespace, "scc-system-credentials-abc").Return(&corev1.Secret{
+		Data: map[string][]byte{
+			credentials.UsernameKey: []byte("SCC_login"),
+			credentials.PasswordKey: []byte("sccpassword"),
+		},
+	}, nil).AnyTimes()
+
+	// The report up to midnight reached SCC, but the status update recording it was lost
+	lastReport := metav1.NewTime(time.Date(2024, 1, 18, 22, 0, 0, 0, time.UTC))
+	registration := &v1.Registration{
+		ObjectMeta: metav1.ObjectMeta{Name: "scc-registration-payg"},
+		Spec: v1.RegistrationSpec{
+			Mode:                v1.RegistrationModePayAsYouGo,
+			RegistrationRequest: &v1.RegistrationRequest{RegistrationAPIUrl: ptr.To(server.URL)},
+		},
+		Status: v1.RegistrationStatus{
+			PayAsYouGo: &v1.PayAsYouGoStatus{
+				LastUsageReportTS:  &lastReport,
+				ReportedUsageHours: 10,
+				PendingUsageReport: &v1.PendingUsageReport{Until: metav1.NewTime(time.Date(2024, 1, 19, 0, 0, 0, 0, time.UTC)), Hours: 2},
+			},
+		},
+	}
+	persisted := registration.DeepCopy()
+	now := time.Date(2024, 1, 19, 1, 15, 0, 0, time.UTC)
+
+	paygHandler := &sccPayAsYouGoMode{
+		options:        &types.RunOptions{OperatorName: "scc-operator", OperatorSettings: &config.OperatorSettings{}},
+		log:            logging.NewLog(),
+		registration:   registration,
+		sccCredentials: credentials.New(consts.DefaultSCCNamespace, "scc-system-credentials-abc", nil, secretRepo, map[string]string{}),
+		secretRepo:     secretRepo,
+		updateStatus: func(name string, change func(*v1.Registration) error) error {
+			assert.Equal(t, registration.Name, name)
+			return change(persisted)
+		},
+		now: func() time.Time { return now },
+	}
+
+	require.NoError(t, paygHandler.Keepalive(registration))
+	assert.Equal(t, []string{"2024-01-19:100000000000000000000000"}, reportedOnlineAt)
+	// The report was recorded as pending before it was sent, with the earlier one counted
+	paygStatus := persisted.Status.PayAsYouGo
+	assert.Equal(t, int64(12), paygStatus.ReportedUsageHours)
+	assert.Equal(t, time.Date(2024, 1, 19, 0, 0, 0, 0, time.UTC), paygStatus.LastUsageReportTS.Time)
+	require.NotNil(t, paygStatus.PendingUsageReport)
+	assert.Equal(t, time.Date(2024, 1, 19, 1, 0, 0, 0, time.UTC), paygStatus.PendingUsageReport.Until.Time)
+
+	succeeded, err := paygHandler.PrepareKeepaliveSucceeded(persisted.DeepCopy())
+	require.NoError(t, err)
+	assert.Equal(t, int64(13), succeeded.Status.PayAsYouGo.ReportedUsageHours)
+	assert.Equal(t, time.Date(2024, 1, 19, 1, 0, 0, 0, time.UTC), succeeded.Status.PayAsYouGo.LastUsageReportTS.Time)
+	assert.Nil(t, succeeded.Status.PayAsYouGo.PendingUsageReport)
+
+	// A report SCC answers with an error is not counted, its hours go into the next report
+	persisted = succeeded
+	sccStatus = http.StatusServiceUnavailable
+	now = now.Add(2 * time.Hour)
+	paygHandler.usageReport = nil
+	keepaliveErr := paygHandler.Keepalive(persisted.DeepCopy())
+	require.Error(t, keepaliveErr)
+	assert.Equal(t, []string{"2024-01-19:011000000000000000000000"}, reportedOnlineAt)
+	require.NotNil(t, persisted.Status.PayAsYouGo.PendingUsageReport)
+	failed := paygHandler.ReconcileKeepaliveError(persisted.DeepCopy(), keepaliveErr)
+	assert.Nil(t, failed.Status.PayAsYouGo.PendingUsageReport)
+	assert.Equal(t, int64(13), failed.Status.PayAsYouGo.ReportedUsageHours)
+	assert.Equal(t, time.Date(2024, 1, 19, 1, 0, 0, 0, time.UTC), failed.Status.PayAsYouGo.LastUsageReportTS.Time)
+}
+
+func TestPayAsYouGoModeReconcileErrors(t *testing.T) {
+	paygHandler := &sccPayAsYouGoMode{}
+
+	registration := paygHandler.ReconcileRegisterError(&v1.Registration{}, &connection.ApiError{Code: 403, Message: "forbidden"}, types.RegistrationMain)
+	assert.True(t, v1.RegistrationConditionPayAsYouGoAnnounced.IsFalse(registration))
+	assert.Contains(t, v1.RegistrationConditionPayAsYouGoAnnounced.GetReason(registration), "pay-as-you-go api call returned Forbidden (403)")
+	assert.Equal(t, string(v1.RegistrationConditionPayAsYouGoAnnounced), registration.Status.CurrentCondition.Type)
+
+	registration = paygHandler.ReconcileKeepaliveError(&v1.Registration{}, &connection.ApiError{Code: 404, Message: "missing"})
+	assert.True(t, v1.RegistrationConditionPayAsYouGoUsageReported.IsFalse(registration))
+	assert.False(t, registration.Status.ActivationStatus.Activated)
+
+	registration = paygHandler.ReconcileKeepaliveError(&v1.Registration{}, &connection.ApiError{Code: 503, Message: "unavailable"})
+	assert.True(t, v1.RegistrationConditionPayAsYouGoUsageReported.IsFalse(registration))
+	assert.False(t, registration.HasCondition(v1.ResourceConditionFailure))
+}
@@ -3,59 +3,115 @@ package controllers
 import (
 	"errors"
 	"fmt"
+	"time"
+	"unicode/utf8"
 
+	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/util/retry"
 
+	"github.com/rancher/scc-operator/internal/suseconnect"
 	"github.com/rancher/scc-operator/internal/types"
 	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
-	registrationControllers "github.com/rancher/scc-operator/pkg/generated/controllers/scc.cattle.io/v1"
 )
 
-func phaseBasedReconcilerApplier(
-	registrations registrationControllers.RegistrationController,
+// applyPhaseReconciler stores the reconciled error state of a Registration and schedules its retry when the error is recoverable
+func (h *handler) applyPhaseReconciler(
 	regName string,
 	reconciler types.HandlerReconcileErrorProcessor,
 	regErr error,
 	phase types.Phase,
 ) error {
+	var retryAfter time.Duration
 	reconcileErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		curReg, getErr := registrations.Get(regName, metav1.GetOptions{})
+		curReg, getErr := h.registrations.Get(regName, metav1.GetOptions{})
 		if getErr != nil {
 			return getErr
 		}
 
 		prepareObj := curReg.DeepCopy()
 		prepareObj = reconciler(prepareObj, regErr, phase)
+		prepareObj, retryAfter = h.backoff.prepareRetry(prepareObj, regErr, time.Now())
 
 		var reconcileUpdateErr error
-		if prepareObj.Spec != curReg.Spec {
-			_, reconcileUpdateErr = registrations.Update(prepareObj)
+		if !equality.Semantic.DeepEqual(prepareObj.Spec, curReg.Spec) {
+			_, reconcileUpdateErr = h.registrations.Update(prepareObj)
 		}
-		_, reconcileUpdateStatusErr := registrations.UpdateStatus(prepareObj)
+		_, reconcileUpdateStatusErr := h.registrations.UpdateStatus(prepareObj)
 		return errors.Join(reconcileUpdateErr, reconcileUpdateStatusErr)
 	})
 
 	err := fmt.Errorf("%s failed: %w", phase.GroupName(), regErr)
 	if reconcileErr != nil {
-		err = fmt.Errorf("%s failed with additional errors: %w, %w", phase.GroupName(), err, reconcileErr)
+		return fmt.Errorf("%s failed with additional errors: %w, %w", phase.GroupName(), err, reconcileErr)
 	}
 
-	return err
+	return h.scheduleRetry(regName, retryAfter, err)
 }
 
-func (h *handler) reconcileRegistration(registrationHandler SCCHandler, registrationObj *v1.Registration, regErr error, phase types.RegistrationPhase) error {
+func (h *handler) reconcileRegistration(registrationHandler SCCHandler, registrationObj *v1.Registration, regErr error, phase types.RegistrationPhase, started time.Time) error {
 	phaseAdapter := func(reg *v1.Registration, err error, p types.Phase) *v1.Registration {
 		specificPhase, _ := p.(types.RegistrationPhase)
-		return registrationHandler.ReconcileRegisterError(reg, err, specificPhase)
+		// Being rate limited says nothing about the registration itself, so it must not be failed for it
+		if !suseconnect.IsRateLimitError(err) {
+			reg = registrationHandler.ReconcileRegisterError(reg, err, specificPhase)
+		}
+		return prepareSyncAttempt(reg, registrationObj.Generation, types.PhaseName(p), started, err)
 	}
-	return phaseBasedReconcilerApplier(h.registrations, registrationObj.Name, phaseAdapter, regErr, phase)
+	h.recordPhaseError(registrationObj, eventReasonRegistrationFailed, phase, regErr)
+	return h.applyPhaseReconciler(registrationObj.Name, phaseAdapter, regErr, phase)
 }
 
-func (h *handler) reconcileActivation(registrationHandler SCCHandler, registrationObj *v1.Registration, regErr error, phase types.ActivationPhase) error {
+func (h *handler) reconcileActivation(registrationHandler SCCHandler, registrationObj *v1.Registration, regErr error, phase types.ActivationPhase, started time.Time) error {
 	phaseAdapter := func(reg *v1.Registration, err error, p types.Phase) *v1.Registration {
 		specificPhase, _ := p.(types.ActivationPhase)
-		return registrationHandler.ReconcileActivateError(reg, err, specificPhase)
+		reg = registrationHandler.ReconcileActivateError(reg, err, specificPhase)
+		return prepareSyncAttempt(reg, registrationObj.Generation, types.PhaseName(p), started, err)
+	}
+	h.recordPhaseError(registrationObj, eventReasonActivationFailed, phase, regErr)
+	return h.applyPhaseReconciler(registrationObj.Name, phaseAdapter, regErr, phase)
+}
+
+// syncPhaseKeepalive names keepalive attempts in the sync history, as keepalive has no phases of its own
+const syncPhaseKeepalive = "Keepalive"
+
+// maxSyncErrorSummaryLength keeps a failed attempt's error summary from bloating the status
+const maxSyncErrorSummaryLength = 256
+
+// truncateOnRune shortens the string to at most maxBytes without splitting a multi-byte character
+func truncateOnRune(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
 	}
-	return phaseBasedReconcilerApplier(h.registrations, registrationObj.Name, phaseAdapter, regErr, phase)
+	for maxBytes > 0 && !utf8.RuneStart(s[maxBytes]) {
+		maxBytes--
+	}
+
+	return s[:maxBytes]
+}
+
+// prepareSyncAttempt adds a finished sync with SCC to the history and marks the generation it was made for as observed;
+// a successful sync also ends any backoff or recovery
+func prepareSyncAttempt(regIn *v1.Registration, generation int64, phase string, started time.Time, syncErr error) *v1.Registration {
+	attempt := v1.SyncAttempt{
+		Timestamp: metav1.NewTime(started),
+		Phase:     phase,
+		Outcome:   v1.SyncOutcomeSucceeded,
+		Duration:  metav1.Duration{Duration: time.Since(started).Round(time.Millisecond)},
+	}
+	if syncErr != nil {
+		attempt.Outcome = v1.SyncOutcomeFailed
+		attempt.ErrorSummary = truncateOnRune(sccErrorSummary(syncErr), maxSyncErrorSummaryLength)
+	}
+
+	regIn.AddSyncAttempt(attempt)
+	regIn.Status.ObservedGeneration = generation
+	if syncErr == nil {
+		regIn.Status.ConsecutiveFailures = 0
+		regIn.Status.NextRetryAt = nil
+		regIn.Status.Recovery = nil
+		markNotRateLimited(regIn)
+	}
+
+	return regIn
 }
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/SUSE/connect-ng/pkg/connection"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/rancher/scc-operator/internal/logging"
+	"github.com/rancher/scc-operator/internal/types"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+)
+
+func TestPrepareSyncAttempt(t *testing.T) {
+	registration := &v1.Registration{}
+	started := time.Now().Add(-2 * time.Second)
+
+	registration = prepareSyncAttempt(registration, 3, syncPhaseKeepalive, started, nil)
+	assert.Equal(t, int64(3), registration.Status.ObservedGeneration)
+	require.Len(t, registration.Status.SyncHistory, 1)
+	attempt := registration.Status.SyncHistory[0]
+	assert.Equal(t, syncPhaseKeepalive, attempt.Phase)
+	assert.Equal(t, v1.SyncOutcomeSucceeded, attempt.Outcome)
+	assert.GreaterOrEqual(t, attempt.Duration.Duration, 2*time.Second)
+	assert.Empty(t, attempt.ErrorSummary)
+
+	registration = prepareSyncAttempt(registration, 4, "Registration/Main", started, fmt.Errorf("register: %w", &connection.ApiError{Code: 401, Message: strings.Repeat("x", 500)}))
+	assert.Equal(t, int64(4), registration.Status.ObservedGeneration)
+	attempt = registration.Status.SyncHistory[1]
+	assert.Equal(t, v1.SyncOutcomeFailed, attempt.Outcome)
+	assert.True(t, strings.HasPrefix(attempt.ErrorSummary, "SCC API returned Unauthorized (401)"))
+	assert.Len(t, attempt.ErrorSummary, maxSyncErrorSummaryLength)
+
+	// Multi-byte characters are never split by the truncation
+	registration = prepareSyncAttempt(registration, 5, "Registration/Main", started, errors.New(strings.Repeat("é", 200)))
+	attempt = registration.Status.SyncHistory[2]
+	assert.True(t, utf8.ValidString(attempt.ErrorSummary))
+	assert.LessOrEqual(t, len(attempt.ErrorSummary), maxSyncErrorSummaryLength)
+	assert.Equal(t, strings.Repeat("é", maxSyncErrorSummaryLength/2), attempt.ErrorSummary)
+}
+
+func TestApplyPhaseReconcilerOnlyUpdatesChangedSpec(t *testing.T) {
+	gomockCtrl := gomock.NewController(t)
+	mockRegistrations := fake.NewMockNonNamespacedControllerInterface[*v1.Registration, *v1.RegistrationList](gomockCtrl)
+	h := &handler{log: logging.NewLog(), registrations: mockRegistrations}
+
+	registration := &v1.Registration{
+		ObjectMeta: metav1.ObjectMeta{Name: "scc-registration-abc"},
+		Spec: v1.RegistrationSpec{
+			Mode:    v1.RegistrationModeOffline,
+			Suspend: ptr.To(false),
+			OfflineRegistrationCertificateSecretRef: &corev1.SecretReference{
+				Name:      "offline-certificate",
+				Namespace: "cattle-scc-system",
+			},
+		},
+	}
+	regErr := errors.New("keepalive failed")
+	unchangedSpec := func(reg *v1.Registration, _ error, _ types.Phase) *v1.Registration {
+		return reg
+	}
+
+	// The spec is deep copied before reconciling, so only a changed value may trigger an Update
+	mockRegistrations.EXPECT().Get(registration.Name, gomock.Any()).Return(registration, nil)
+	mockRegistrations.EXPECT().UpdateStatus(gomock.Any()).Return(registration, nil)
+	err := h.applyPhaseReconciler(registration.Name, unchangedSpec, regErr, types.RegistrationMain)
+	assert.ErrorIs(t, err, regErr)
+
+	changedSpec := func(reg *v1.Registration, _ error, _ types.Phase) *v1.Registration {
+		reg.Spec.Suspend = ptr.To(true)
+		return reg
+	}
+	mockRegistrations.EXPECT().Get(registration.Name, gomock.Any()).Return(registration, nil)
+	mockRegistrations.EXPECT().Update(gomock.Any()).Return(registration, nil)
+	mockRegistrations.EXPECT().UpdateStatus(gomock.Any()).Return(registration, nil)
+	err = h.applyPhaseReconciler(registration.Name, changedSpec, regErr, types.RegistrationMain)
+	assert.ErrorIs(t, err, regErr)
+}
+
+func TestSyncHistoryIsCapped(t *testing.T) {
+	registration := &v1.Registration{}
+	for i := range v1.MaxSyncHistory + 3 {
+		registration = prepareSyncAttempt(registration, int64(i), fmt.Sprintf("Attempt/%d", i), time.Now(), nil)
+	}
+
+	require.Len(t, registration.Status.SyncHistory, v1.MaxSyncHistory)
+	assert.Equal(t, int64(v1.MaxSyncHistory+2), registration.Status.ObservedGeneration)
+	// The oldest attempts are dropped first
+	assert.Equal(t, "Attempt/3", registration.Status.SyncHistory[0].Phase)
+	assert.Equal(t, fmt.Sprintf("Attempt/%d", v1.MaxSyncHistory+2), registration.Status.SyncHistory[v1.MaxSyncHistory-1].Phase)
+}
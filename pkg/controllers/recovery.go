@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+	"github.com/rancher/scc-operator/pkg/controllers/lifecycle"
+)
+
+// relatedSecretRefs lists the Secrets a Registration reads while talking to SCC; fixing any of them may resolve a failure
+func relatedSecretRefs(registrationObj *v1.Registration) []*corev1.SecretReference {
+	request := registrationObj.Spec.RegistrationRequest
+	if request == nil {
+		return nil
+	}
+
+	var refs []*corev1.SecretReference
+	for _, ref := range []*corev1.SecretReference{
+		request.RegistrationCodeSecretRef,
+		request.RegistrationAPICertificateSecretRef,
+		request.InstanceDataSecretRef,
+	} {
+		if ref != nil {
+			refs = append(refs, ref)
+		}
+	}
+	if request.Proxy != nil && request.Proxy.CredentialsSecretRef != nil {
+		refs = append(refs, request.Proxy.CredentialsSecretRef)
+	}
+
+	return refs
+}
+
+// relatedSecretsFingerprint identifies the current version of every related Secret, including ones that are missing
+func (h *handler) relatedSecretsFingerprint(registrationObj *v1.Registration) string {
+	hasher := sha256.New()
+	for _, ref := range relatedSecretRefs(registrationObj) {
+		version := "missing"
+		secret, err := h.secretRepo.Cache.Get(ref.Namespace, ref.Name)
+		if err == nil {
+			version = secret.ResourceVersion
+		} else if !apierrors.IsNotFound(err) {
+			version = "unknown"
+		}
+		_, _ = fmt.Fprintf(hasher, "%s/%s@%s;", ref.Namespace, ref.Name, version)
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil))[:16]
+}
+
+// recoveryDue decides if a failed Registration should be re-validated now, and otherwise how long until it should be.
+// Recovery is due once the interval has passed since the failure (or the last attempt), or as soon as a related Secret changes.
+func recoveryDue(registrationObj *v1.Registration, fingerprint string, now time.Time) (bool, time.Duration) {
+	recovery := registrationObj.Status.Recovery
+	if recovery != nil && recovery.RelatedSecretsFingerprint != "" && recovery.RelatedSecretsFingerprint != fingerprint {
+		return true, 0
+	}
+
+	since := now
+	if failedAt, err := time.Parse(time.RFC3339, v1.ResourceConditionFailure.GetLastUpdated(registrationObj)); err == nil {
+		since = failedAt
+	}
+	if recovery != nil && recovery.LastAttemptTS != nil && recovery.LastAttemptTS.After(since) {
+		since = recovery.LastAttemptTS.Time
+	}
+
+	remaining := since.Add(registrationObj.Spec.Recovery.GetInterval()).Sub(now)
+	return remaining <= 0, remaining
+}
+
+// reviewFailedRegistration re-validates a failed Registration once its recovery is due, and otherwise schedules the next review
+func (h *handler) reviewFailedRegistration(registrationHandler SCCHandler, registrationObj *v1.Registration) error {
+	fingerprint := h.relatedSecretsFingerprint(registrationObj)
+	due, remaining := recoveryDue(registrationObj, fingerprint, time.Now())
+	if !due {
+		if registrationObj.Status.Recovery == nil || registrationObj.Status.Recovery.RelatedSecretsFingerprint != fingerprint {
+			reviewed := registrationObj.DeepCopy()
+			if reviewed.Status.Recovery == nil {
+				reviewed.Status.Recovery = &v1.RecoveryStatus{}
+			}
+			reviewed.Status.Recovery.RelatedSecretsFingerprint = fingerprint
+			if _, err := h.registrations.UpdateStatus(reviewed); err != nil {
+				return err
+			}
+		}
+
+		h.log.Infof("registration `%s` is failed; recovery will be attempted in %s, or sooner if a related secret changes", registrationObj.Name, remaining.Round(time.Second))
+		h.registrations.EnqueueAfter(registrationObj.Name, remaining)
+		return nil
+	}
+
+	recovering, err := registrationHandler.ResetToReadyForActivation(registrationObj.DeepCopy())
+	if err != nil {
+		return fmt.Errorf("cannot reset registration `%s` for recovery: %w", registrationObj.Name, err)
+	}
+	recovering = lifecycle.PrepareRecovering(recovering)
+	recovering.Status.Recovery.RelatedSecretsFingerprint = fingerprint
+	if !registrationHandler.ReadyForActivation(recovering) {
+		// The failure happened before SCC accepted the announce, so it has to be announced again
+		recovering.Status.RegistrationProcessedTS = nil
+	}
+
+	if _, err := h.registrations.UpdateStatus(recovering); err != nil {
+		return err
+	}
+	h.log.Infof("registration `%s` cleared its failure to attempt recovery", registrationObj.Name)
+	h.recordEvent(registrationObj, corev1.EventTypeNormal, eventReasonRecovering, "clearing failure for recovery attempt %d", recovering.Status.Recovery.Attempts)
+	return nil
+}
@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/rancher/scc-operator/internal/consts"
+	"github.com/rancher/scc-operator/internal/logging"
+	"github.com/rancher/scc-operator/internal/repos/secretrepo"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+	"github.com/rancher/scc-operator/pkg/controllers/lifecycle"
+)
+
+func failedRegistrationForRecovery(failedAt time.Time) *v1.Registration {
+	registration := &v1.Registration{
+		ObjectMeta: metav1.ObjectMeta{Name: "scc-registration-abc"},
+		Spec: v1.RegistrationSpec{
+			Mode: v1.RegistrationModeOnline,
+			RegistrationRequest: &v1.RegistrationRequest{
+				RegistrationCodeSecretRef: &corev1.SecretReference{Namespace: consts.DefaultSCCNamespace, Name: "registration-code-abc"},
+				Proxy: &v1.ProxyConfig{
+					URL:                  "http://proxy.example.com:3128",
+					CredentialsSecretRef: &corev1.SecretReference{Namespace: consts.DefaultSCCNamespace, Name: "proxy-credentials"},
+				},
+			},
+			Recovery: &v1.RecoveryPolicy{Interval: &metav1.Duration{Duration: time.Hour}},
+		},
+		Status: v1.RegistrationStatus{
+			RegistrationProcessedTS: &metav1.Time{Time: failedAt},
+			SCCSystemID:             ptr.To(1234),
+		},
+	}
+	v1.RegistrationConditionAnnounced.True(registration)
+	registration = lifecycle.PrepareFailed(registration, assert.AnError)
+	v1.ResourceConditionFailure.LastUpdated(registration, failedAt.UTC().Format(time.RFC3339))
+
+	return registration
+}
+
+func TestRegistrationToRelatedSecrets(t *testing.T) {
+	keys, err := registrationToRelatedSecrets(failedRegistrationForRecovery(time.Now()))
+	require.NoError(t, err)
+	assert.Equal(t, []string{consts.DefaultSCCNamespace + "/registration-code-abc", consts.DefaultSCCNamespace + "/proxy-credentials"}, keys)
+
+	keys, err = registrationToRelatedSecrets(&v1.Registration{Spec: v1.RegistrationSpec{Mode: v1.RegistrationModeOffline}})
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestRecoveryDue(t *testing.T) {
+	now := time.Now()
+	registration := failedRegistrationForRecovery(now.Add(-20 * time.Minute))
+
+	due, remaining := recoveryDue(registration, "fingerprint", now)
+	assert.False(t, due)
+	assert.InDelta(t, (40 * time.Minute).Seconds(), remaining.Seconds(), 1)
+
+	// A changed related Secret makes recovery due right away
+	registration.Status.Recovery = &v1.RecoveryStatus{RelatedSecretsFingerprint: "fingerprint"}
+	due, _ = recoveryDue(registration, "changed", now)
+	assert.True(t, due)
+
+	// The interval counts from the last attempt when that is more recent than the failure
+	registration.Status.Recovery.LastAttemptTS = &metav1.Time{Time: now.Add(-10 * time.Minute)}
+	due, remaining = recoveryDue(registration, "fingerprint", now)
+	assert.False(t, due)
+	assert.InDelta(t, (50 * time.Minute).Seconds(), remaining.Seconds(), 1)
+
+	due, _ = recoveryDue(failedRegistrationForRecovery(now.Add(-2*time.Hour)), "fingerprint", now)
+	assert.True(t, due)
+
+	// Without an interval the default applies
+	registration.Spec.Recovery.Interval = nil
+	assert.Equal(t, v1.DefaultRecoveryInterval, registration.Spec.Recovery.GetInterval())
+}
+
+func TestReviewFailedRegistration(t *testing.T) {
+	gomockCtrl := gomock.NewController(t)
+	mockRegistrations := fake.NewMockNonNamespacedControllerInterface[*v1.Registration, *v1.RegistrationList](gomockCtrl)
+	mockSecretsCache := fake.NewMockCacheInterface[*corev1.Secret](gomockCtrl)
+	regCodeVersion := "1"
+	mockSecretsCache.EXPECT().Get(consts.DefaultSCCNamespace, "registration-code-abc").DoAndReturn(func(_, name string) (*corev1.Secret, error) {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, ResourceVersion: regCodeVersion}}, nil
+	}).AnyTimes()
+	mockSecretsCache.EXPECT().Get(consts.DefaultSCCNamespace, "proxy-credentials").Return(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{ResourceVersion: "7"}}, nil).AnyTimes()
+	h := &handler{
+		log:           logging.NewLog(),
+		registrations: mockRegistrations,
+		secretRepo:    &secretrepo.SecretRepository{Cache: mockSecretsCache},
+	}
+	registrationHandler := &sccOnlineMode{}
+
+	// Before the interval passes the related Secrets are fingerprinted and the review is scheduled
+	registration := failedRegistrationForRecovery(time.Now().Add(-10 * time.Minute))
+	mockRegistrations.EXPECT().UpdateStatus(gomock.Any()).DoAndReturn(func(reg *v1.Registration) (*v1.Registration, error) {
+		registration = reg
+		return reg, nil
+	})
+	mockRegistrations.EXPECT().EnqueueAfter(registration.Name, gomock.Any())
+	require.NoError(t, h.reviewFailedRegistration(registrationHandler, registration))
+	require.NotNil(t, registration.Status.Recovery)
+	assert.NotEmpty(t, registration.Status.Recovery.RelatedSecretsFingerprint)
+	assert.True(t, lifecycle.RegistrationIsFailed(registration))
+
+	// A fixed registration code clears the failure without losing the system ID
+	regCodeVersion = "2"
+	mockRegistrations.EXPECT().UpdateStatus(gomock.Any()).DoAndReturn(func(reg *v1.Registration) (*v1.Registration, error) {
+		registration = reg
+		return reg, nil
+	})
+	require.NoError(t, h.reviewFailedRegistration(registrationHandler, registration))
+	assert.False(t, lifecycle.RegistrationIsFailed(registration))
+	assert.True(t, v1.ResourceConditionProgressing.IsTrue(registration))
+	assert.Equal(t, 1234, *registration.Status.SCCSystemID)
+	assert.Equal(t, int32(1), registration.Status.Recovery.Attempts)
+	assert.False(t, registration.Status.RegistrationProcessedTS.IsZero())
+	assert.True(t, registrationHandler.NeedsActivation(registration))
+
+	// A registration that was never announced goes back through registration
+	unannounced := failedRegistrationForRecovery(time.Now().Add(-2 * time.Hour))
+	v1.RegistrationConditionAnnounced.False(unannounced)
+	mockRegistrations.EXPECT().UpdateStatus(gomock.Any()).DoAndReturn(func(reg *v1.Registration) (*v1.Registration, error) {
+		unannounced = reg
+		return reg, nil
+	})
+	require.NoError(t, h.reviewFailedRegistration(registrationHandler, unannounced))
+	assert.True(t, registrationHandler.NeedsRegistration(unannounced))
+	assert.Equal(t, 1234, *unannounced.Status.SCCSystemID)
+}
@@ -52,3 +52,21 @@ func (h *handler) createOrUpdateRegistration(reg *v1.Registration) error {
 		return updateErr
 	})
 }
+
+// updateRegistrationStatus applies the change to the latest Registration status, retrying on conflicts
+func (h *handler) updateRegistrationStatus(name string, change func(*v1.Registration) error) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, getErr := h.registrations.Get(name, metav1.GetOptions{})
+		if getErr != nil {
+			return getErr
+		}
+
+		current = current.DeepCopy()
+		if changeErr := change(current); changeErr != nil {
+			return changeErr
+		}
+
+		_, updateErr := h.registrations.UpdateStatus(current)
+		return updateErr
+	})
+}
@@ -8,9 +8,10 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 
 	"github.com/rancher/scc-operator/internal/consts"
+	"github.com/rancher/scc-operator/pkg/controllers/lifecycle"
 )
 
-// initResolvers creates a secret watcher to check for SCC entrypoint secrets
+// initResolvers creates secret watchers to check for SCC entrypoint secrets, and secrets failed registrations may recover with
 func (h *handler) initResolvers(ctx context.Context) {
 	relatedresource.Watch(
 		ctx,
@@ -18,20 +19,20 @@ func (h *handler) initResolvers(ctx context.Context) {
 		h.resolveEntrypointSecret,
 		h.secretRepo.Controller,
 	)
+	relatedresource.WatchClusterScoped(
+		ctx,
+		"watch-scc-secret-recovery",
+		h.resolveRecoverySecret,
+		h.registrations,
+		h.secretRepo.Controller,
+	)
 }
 
 func (h *handler) resolveEntrypointSecret(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
 	var relatedKeys []relatedresource.Key
-	if namespace != h.options.SystemNamespace() {
-		return relatedKeys, nil
-	}
-	if name != consts.ResourceSCCEntrypointSecretName {
-		return relatedKeys, nil
-	}
-
 	// Only handle secrets - objects of other types ignored by this watcher.
 	secret, ok := obj.(*corev1.Secret)
-	if !ok {
+	if !ok || !h.isSCCEntrypointSecret(secret) {
 		return relatedKeys, nil
 	}
 
@@ -63,3 +64,25 @@ func (h *handler) resolveEntrypointSecret(namespace, name string, obj runtime.Ob
 	}
 	return relatedKeys, nil
 }
+
+// resolveRecoverySecret enqueues the failed Registrations with a recovery policy that reference a changed Secret
+func (h *handler) resolveRecoverySecret(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+	var relatedKeys []relatedresource.Key
+	if _, ok := obj.(*corev1.Secret); !ok {
+		return relatedKeys, nil
+	}
+
+	regs, err := h.registrationCache.GetByIndex(IndexRegistrationsByRelatedSecret, relatedSecretKey(namespace, name))
+	if err != nil {
+		return relatedKeys, err
+	}
+	for _, reg := range regs {
+		if reg == nil || !lifecycle.RegistrationIsFailed(reg) || !lifecycle.RegistrationCanRecover(reg) {
+			continue
+		}
+		relatedKeys = append(relatedKeys, relatedresource.Key{
+			Name: reg.GetName(),
+		})
+	}
+	return relatedKeys, nil
+}
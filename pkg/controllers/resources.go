@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"maps"
+	"strings"
 
 	"github.com/rancher/scc-operator/internal/logging"
 	utilUrls "github.com/rancher/scc-operator/pkg/util/urls"
@@ -14,7 +15,9 @@ import (
 
 	"github.com/rancher/scc-operator/internal/consts"
 	coreUtil "github.com/rancher/scc-operator/internal/initializer"
+	"github.com/rancher/scc-operator/internal/validation"
 	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+	"github.com/rancher/scc-operator/pkg/controllers/helpers"
 	"github.com/rancher/scc-operator/pkg/controllers/lifecycle"
 	"github.com/rancher/scc-operator/pkg/util/salt"
 )
@@ -40,14 +43,11 @@ type hashCleanupRequest struct {
 }
 
 const (
-	dataKeyRegistrationType = "registrationType"
+	dataKeyRegistrationType = consts.SecretKeyRegistrationType
 )
 
 func (h *handler) isSCCEntrypointSecret(secretObj *corev1.Secret) bool {
-	if secretObj.Name != consts.ResourceSCCEntrypointSecretName || secretObj.Namespace != h.options.SystemNamespace() {
-		return false
-	}
-	return true
+	return helpers.IsEntrypointSecret(secretObj, h.options.SystemNamespace())
 }
 
 // prepareSecretSalt applies an instance salt onto an entrypoint secret used to create randomness in hashes
@@ -95,41 +95,71 @@ func extractRegistrationParamsFromSecret(secret *corev1.Secret, managedByName st
 	incomingSalt := []byte(secret.GetLabels()[consts.LabelObjectSalt])
 	extractParamsLog.Debugf("extracting registration params from secret %s/%s - with salt %s", secret.Namespace, secret.Name, incomingSalt)
 
-	regMode := v1.RegistrationModeOnline
+	regMode, modeErr := validation.EntrypointSecretMode(secret.Data)
+	if modeErr != nil {
+		return RegistrationParams{}, fmt.Errorf("invalid registration mode: %w", modeErr)
+	}
 	regType, ok := secret.Data[dataKeyRegistrationType]
 	if !ok || len(regType) == 0 {
 		extractParamsLog.Warnf("secret does not have the `%s` field, defaulting to %s", dataKeyRegistrationType, regMode)
-	} else {
-		regMode = v1.RegistrationMode(regType)
-		if !regMode.Valid() {
-			return RegistrationParams{}, fmt.Errorf("invalid registration mode %s", string(regMode))
-		}
 	}
 	extractParamsLog.Debugf("incoming %s/%s secret params mode: %s", secret.Namespace, secret.Name, string(regMode))
 
-	regCode, ok := secret.Data[consts.SecretKeyRegistrationCode]
-	if !ok || len(regCode) == 0 {
-		if regMode == v1.RegistrationModeOnline {
-			return RegistrationParams{}, fmt.Errorf("secret does not have data %s; this is required in online mode", consts.SecretKeyRegistrationCode)
-		}
+	if validationErr := validation.EntrypointSecretForMode(secret.Data, regMode).ToAggregate(); validationErr != nil {
+		return RegistrationParams{}, fmt.Errorf("invalid entrypoint secret %s/%s: %w", secret.Namespace, secret.Name, validationErr)
 	}
+	regCode := secret.Data[consts.SecretKeyRegistrationCode]
 
 	offlineRegCertData, certOk := secret.Data[consts.SecretKeyOfflineRegCert]
 	hasOfflineCert := certOk && len(offlineRegCertData) > 0
 
-	// TODO: when RMT needs to be supported eventually we need to accept Reg URL and Reg Server Cert.
+	regCACertData, caOk := secret.Data[consts.SecretKeyRegistrationCACert]
+	hasRegCACert := caOk && len(regCACertData) > 0 && regMode != v1.RegistrationModeOffline
+
+	proxyURL := string(secret.Data[consts.SecretKeyProxyURL])
+	hasProxy := proxyURL != "" && regMode != v1.RegistrationModeOffline
+	proxyUsername := secret.Data[consts.SecretKeyProxyUsername]
+	proxyPassword := secret.Data[consts.SecretKeyProxyPassword]
+	noProxy := secret.Data[consts.SecretKeyNoProxy]
+
+	instanceData, instanceDataOk := secret.Data[consts.SecretKeyInstanceData]
+	hasInstanceData := instanceDataOk && len(instanceData) > 0 && regMode == v1.RegistrationModePayAsYouGo
+
 	var regURLBytes []byte
 	regURLString := ""
-	if regMode == v1.RegistrationModeOnline {
+	switch regMode {
+	case v1.RegistrationModeOnline:
 		regURLBytes = getCurrentRegURL(secret)
 		regURLString = string(regURLBytes)
+	case v1.RegistrationModeRMT, v1.RegistrationModePayAsYouGo:
+		// RMT servers are always user provided, and PAYG falls back to the operator's PAYG URL at connection time
+		regURLBytes = secret.Data[consts.RegistrationURL]
+		regURLString = string(regURLBytes)
 	}
 
 	hasher := md5.New()
-	nameData := append(incomingSalt, regType...)
+	nameData := incomingSalt
+	// Labelled entrypoints also hash their name, so a copy of another entrypoint (salt label included) cannot collide with it.
+	// The default entrypoint is left out to keep the names of its existing related resources.
+	if secret.Name != consts.ResourceSCCEntrypointSecretName {
+		nameData = append([]byte(secret.Name), nameData...)
+	}
+	nameData = append(nameData, regType...)
+	// The identity leaves out the reg code, so a rotated code can be told apart from a different registration
+	identityData := append(append([]byte{}, nameData...), regURLBytes...)
 	nameData = append(nameData, regCode...)
 	nameData = append(nameData, regURLBytes...)
 	data := append(nameData, offlineRegCertData...)
+	// The CA only changes how we connect, so it affects content but not the name of related resources
+	data = append(data, regCACertData...)
+	data = append(data, instanceData...)
+	// Like the CA, proxy settings only change how we connect
+	if hasProxy {
+		data = append(data, proxyURL...)
+		data = append(data, proxyUsername...)
+		data = append(data, proxyPassword...)
+		data = append(data, noProxy...)
+	}
 
 	// Generate a hash for the name data
 	if _, err := hasher.Write(nameData); err != nil {
@@ -142,39 +172,102 @@ func extractRegistrationParamsFromSecret(secret *corev1.Secret, managedByName st
 		return RegistrationParams{}, fmt.Errorf("failed to hash data: %v", err)
 	}
 	contentsID := hex.EncodeToString(hasher.Sum(nil))
+	identitySum := md5.Sum(identityData)
 	extractParamsLog.Debugf("incoming %s/%s secret hashes; name hash: %s, content hash: %s", secret.Namespace, secret.Name, nameID, contentsID)
 
-	return RegistrationParams{
-		managedByName: managedByName,
-		regType:       regMode,
-		nameID:        nameID,
-		contentHash:   contentsID,
-		regCode:       regCode,
-		regCodeSecretRef: &corev1.SecretReference{
-			Name:      consts.RegistrationCodeSecretName(nameID),
-			Namespace: secret.Namespace,
-		},
+	params := RegistrationParams{
+		managedByName:      managedByName,
+		regType:            regMode,
+		contentHash:        contentsID,
+		identityHash:       hex.EncodeToString(identitySum[:]),
+		regCode:            regCode,
 		hasOfflineCertData: hasOfflineCert,
 		offlineCertData:    &offlineRegCertData,
-		offlineCertSecretRef: &corev1.SecretReference{
-			Name:      consts.OfflineCertificateSecretName(nameID),
-			Namespace: secret.Namespace,
-		},
-		regURL: regURLString,
-	}, nil
+		regURL:             regURLString,
+		hasRegCACert:       hasRegCACert,
+		regCACert:          regCACertData,
+		hasInstanceData:    hasInstanceData,
+		instanceData:       instanceData,
+		hasProxy:           hasProxy,
+		proxyURL:           proxyURL,
+		noProxy:            splitNoProxy(string(noProxy)),
+		proxyUsername:      proxyUsername,
+		proxyPassword:      proxyPassword,
+	}
+
+	return params.withNameID(nameID, secret.Namespace), nil
+}
+
+// withNameID names the params, and the related Secrets they reference, after the given name hash
+func (r RegistrationParams) withNameID(nameID, namespace string) RegistrationParams {
+	r.nameID = nameID
+	r.regCodeSecretRef = &corev1.SecretReference{
+		Name:      consts.RegistrationCodeSecretName(nameID),
+		Namespace: namespace,
+	}
+	r.offlineCertSecretRef = &corev1.SecretReference{
+		Name:      consts.OfflineCertificateSecretName(nameID),
+		Namespace: namespace,
+	}
+	r.regCACertSecretRef = &corev1.SecretReference{
+		Name:      consts.RegistrationCACertSecretName(nameID),
+		Namespace: namespace,
+	}
+	r.instanceDataSecretRef = &corev1.SecretReference{
+		Name:      consts.InstanceDataSecretName(nameID),
+		Namespace: namespace,
+	}
+	r.proxyCredentialsSecretRef = &corev1.SecretReference{
+		Name:      consts.ProxyCredentialsSecretName(nameID),
+		Namespace: namespace,
+	}
+
+	return r
 }
 
 type RegistrationParams struct {
-	managedByName        string
-	regType              v1.RegistrationMode
-	nameID               string
-	contentHash          string
+	managedByName string
+	regType       v1.RegistrationMode
+	nameID        string
+	contentHash   string
+	// identityHash covers what names the registration except for its reg code
+	identityHash         string
 	regCode              []byte
 	regCodeSecretRef     *corev1.SecretReference
 	regURL               string
 	hasOfflineCertData   bool
 	offlineCertData      *[]byte
 	offlineCertSecretRef *corev1.SecretReference
+	hasRegCACert         bool
+	regCACert            []byte
+	regCACertSecretRef   *corev1.SecretReference
+	// instanceData is the cloud instance identity document only used in payg mode
+	hasInstanceData       bool
+	instanceData          []byte
+	instanceDataSecretRef *corev1.SecretReference
+	// proxy settings route SCC traffic for this registration through an HTTP proxy
+	hasProxy                  bool
+	proxyURL                  string
+	noProxy                   []string
+	proxyUsername             []byte
+	proxyPassword             []byte
+	proxyCredentialsSecretRef *corev1.SecretReference
+}
+
+func (r RegistrationParams) hasProxyCredentials() bool {
+	return r.hasProxy && len(r.proxyUsername) > 0
+}
+
+// splitNoProxy turns the NO_PROXY style comma separated entrypoint value into a list
+func splitNoProxy(noProxy string) []string {
+	var entries []string
+	for _, entry := range strings.Split(noProxy, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
 }
 
 // Labels produces the labels to apply to related resources.
@@ -194,10 +287,12 @@ func (h *handler) registrationFromSecretEntrypoint(
 ) (*v1.Registration, error) {
 	if !params.regType.Valid() {
 		return nil, fmt.Errorf(
-			"invalid registration type %s, must be one of %s or %s",
+			"invalid registration type %s, must be one of %s, %s, %s or %s",
 			params.regType,
 			v1.RegistrationModeOnline,
 			v1.RegistrationModeOffline,
+			v1.RegistrationModeRMT,
+			v1.RegistrationModePayAsYouGo,
 		)
 	}
 
@@ -223,7 +318,13 @@ func (h *handler) registrationFromSecretEntrypoint(
 	}
 	maps.Copy(reg.Labels, params.Labels())
 
-	reg.Spec = paramsToRegSpec(params)
+	// Suspend, SyncNow, Recovery and Deregistration are set on the Registration itself, so they must survive the entrypoint rebuilding the spec
+	regSpec := paramsToRegSpec(params)
+	regSpec.Suspend = reg.Spec.Suspend
+	regSpec.SyncNow = reg.Spec.SyncNow
+	regSpec.Recovery = reg.Spec.Recovery
+	regSpec.Deregistration = reg.Spec.Deregistration
+	reg.Spec = regSpec
 	if !lifecycle.RegistrationHasManagedFinalizer(reg) {
 		reg = lifecycle.RegistrationAddManagedFinalizer(reg)
 	}
@@ -241,6 +342,13 @@ func paramsToRegSpec(params RegistrationParams) v1.RegistrationSpec {
 		regSpec.RegistrationRequest = &v1.RegistrationRequest{
 			RegistrationCodeSecretRef: params.regCodeSecretRef,
 		}
+	} else if params.regType == v1.RegistrationModeRMT {
+		regSpec.RegistrationRequest = &v1.RegistrationRequest{}
+	} else if params.regType == v1.RegistrationModePayAsYouGo {
+		regSpec.RegistrationRequest = &v1.RegistrationRequest{}
+		if params.hasInstanceData {
+			regSpec.RegistrationRequest.InstanceDataSecretRef = params.instanceDataSecretRef
+		}
 	} else if params.regType == v1.RegistrationModeOffline && params.hasOfflineCertData {
 		regSpec.OfflineRegistrationCertificateSecretRef = params.offlineCertSecretRef
 	}
@@ -249,6 +357,18 @@ func paramsToRegSpec(params RegistrationParams) v1.RegistrationSpec {
 	if params.regURL != "" {
 		regSpec.RegistrationRequest.RegistrationAPIUrl = &params.regURL
 	}
+	if params.hasRegCACert {
+		regSpec.RegistrationRequest.RegistrationAPICertificateSecretRef = params.regCACertSecretRef
+	}
+	if params.hasProxy {
+		regSpec.RegistrationRequest.Proxy = &v1.ProxyConfig{
+			URL:     params.proxyURL,
+			NoProxy: params.noProxy,
+		}
+		if params.hasProxyCredentials() {
+			regSpec.RegistrationRequest.Proxy.CredentialsSecretRef = params.proxyCredentialsSecretRef
+		}
+	}
 
 	return regSpec
 }
@@ -258,17 +378,24 @@ func (h *handler) regCodeFromSecretEntrypoint(params RegistrationParams) (*corev
 	secretName := params.regCodeSecretRef.Name
 
 	regcodeSecret, err := h.secretRepo.Cache.Get(h.options.SystemNamespace(), secretName)
-	if err != nil && apierrors.IsNotFound(err) {
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	if apierrors.IsNotFound(err) {
 		regcodeSecret = &corev1.Secret{
 			ObjectMeta: metav1.ObjectMeta{
 				Namespace: h.options.SystemNamespace(),
 				Name:      secretName,
 			},
-			Data: map[string][]byte{
-				consts.SecretKeyRegistrationCode: params.regCode,
-			},
 		}
+	} else {
+		regcodeSecret = regcodeSecret.DeepCopy()
+	}
+	// The entrypoint is the source of truth, so any edit made directly to the reg code Secret is overwritten
+	regcodeSecret.Data = map[string][]byte{
+		consts.SecretKeyRegistrationCode: params.regCode,
 	}
+	regcodeSecret = lifecycle.SecretStampContentHash(regcodeSecret)
 
 	if regcodeSecret.Labels == nil {
 		regcodeSecret.Labels = map[string]string{}
@@ -290,17 +417,30 @@ func (h *handler) offlineCertFromSecretEntrypoint(params RegistrationParams) (*c
 
 	offlineCertSecret, err := h.secretRepo.Cache.Get(h.options.SystemNamespace(), secretName)
 	if err != nil {
-		if apierrors.IsNotFound(err) {
-			offlineCertSecret = &corev1.Secret{
-				ObjectMeta: metav1.ObjectMeta{
-					Namespace: h.options.SystemNamespace(),
-					Name:      secretName,
-				},
-				Data: map[string][]byte{
-					consts.SecretKeyOfflineRegCert: *params.offlineCertData,
-				},
-			}
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		offlineCertSecret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: h.options.SystemNamespace(),
+				Name:      secretName,
+			},
+			Data: map[string][]byte{
+				consts.SecretKeyOfflineRegCert: *params.offlineCertData,
+			},
+		}
+	} else if h.rotatesOfflineCertificate(params) {
+		// The certificate of an activated Registration is still active, so a different one from the entrypoint waits to be validated
+		offlineCertSecret = offlineCertSecret.DeepCopy()
+		stageOfflineCertificate(offlineCertSecret, *params.offlineCertData)
+	} else {
+		// Nothing was activated with the current certificate yet, so the entrypoint one replaces it outright
+		offlineCertSecret = offlineCertSecret.DeepCopy()
+		if offlineCertSecret.Data == nil {
+			offlineCertSecret.Data = map[string][]byte{}
 		}
+		offlineCertSecret.Data[consts.SecretKeyOfflineRegCert] = *params.offlineCertData
+		delete(offlineCertSecret.Data, consts.SecretKeyOfflineRegCertReplacement)
 	}
 
 	if offlineCertSecret.Labels == nil {
@@ -316,3 +456,101 @@ func (h *handler) offlineCertFromSecretEntrypoint(params RegistrationParams) (*c
 
 	return offlineCertSecret, nil
 }
+
+// regCACertFromSecretEntrypoint prepares the Secret holding the custom registration CA provided by an entrypoint secret
+func (h *handler) regCACertFromSecretEntrypoint(params RegistrationParams) (*corev1.Secret, error) {
+	secretName := params.regCACertSecretRef.Name
+
+	regCACertSecret, err := h.secretRepo.Cache.Get(h.options.SystemNamespace(), secretName)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		regCACertSecret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: h.options.SystemNamespace(),
+				Name:      secretName,
+			},
+		}
+	} else {
+		regCACertSecret = regCACertSecret.DeepCopy()
+	}
+	regCACertSecret.Data = map[string][]byte{
+		consts.SecretKeyRegistrationCACert: params.regCACert,
+	}
+
+	if regCACertSecret.Labels == nil {
+		regCACertSecret.Labels = map[string]string{}
+	}
+	defaultLabels := params.Labels()
+	defaultLabels[consts.LabelSccSecretRole] = string(consts.RegistrationCACert)
+	maps.Copy(regCACertSecret.Labels, defaultLabels)
+
+	return regCACertSecret, nil
+}
+
+// instanceDataFromSecretEntrypoint prepares the Secret holding the PAYG cloud instance data provided by an entrypoint secret
+func (h *handler) instanceDataFromSecretEntrypoint(params RegistrationParams) (*corev1.Secret, error) {
+	secretName := params.instanceDataSecretRef.Name
+
+	instanceDataSecret, err := h.secretRepo.Cache.Get(h.options.SystemNamespace(), secretName)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		instanceDataSecret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: h.options.SystemNamespace(),
+				Name:      secretName,
+			},
+		}
+	} else {
+		instanceDataSecret = instanceDataSecret.DeepCopy()
+	}
+	instanceDataSecret.Data = map[string][]byte{
+		consts.SecretKeyInstanceData: params.instanceData,
+	}
+
+	if instanceDataSecret.Labels == nil {
+		instanceDataSecret.Labels = map[string]string{}
+	}
+	defaultLabels := params.Labels()
+	defaultLabels[consts.LabelSccSecretRole] = string(consts.InstanceDataRole)
+	maps.Copy(instanceDataSecret.Labels, defaultLabels)
+
+	return instanceDataSecret, nil
+}
+
+// proxyCredentialsFromSecretEntrypoint prepares the basic-auth Secret holding the proxy credentials provided by an entrypoint secret
+func (h *handler) proxyCredentialsFromSecretEntrypoint(params RegistrationParams) (*corev1.Secret, error) {
+	secretName := params.proxyCredentialsSecretRef.Name
+
+	proxyCredentialsSecret, err := h.secretRepo.Cache.Get(h.options.SystemNamespace(), secretName)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		proxyCredentialsSecret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: h.options.SystemNamespace(),
+				Name:      secretName,
+			},
+			Type: corev1.SecretTypeBasicAuth,
+		}
+	} else {
+		proxyCredentialsSecret = proxyCredentialsSecret.DeepCopy()
+	}
+	proxyCredentialsSecret.Data = map[string][]byte{
+		corev1.BasicAuthUsernameKey: params.proxyUsername,
+		corev1.BasicAuthPasswordKey: params.proxyPassword,
+	}
+
+	if proxyCredentialsSecret.Labels == nil {
+		proxyCredentialsSecret.Labels = map[string]string{}
+	}
+	defaultLabels := params.Labels()
+	defaultLabels[consts.LabelSccSecretRole] = string(consts.ProxyCredentials)
+	maps.Copy(proxyCredentialsSecret.Labels, defaultLabels)
+
+	return proxyCredentialsSecret, nil
+}
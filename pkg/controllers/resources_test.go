@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	certutil "k8s.io/client-go/util/cert"
 
 	"github.com/rancher/scc-operator/internal/consts"
 	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
@@ -60,3 +61,151 @@ func TestRegistrationFromSecret(t *testing.T) {
 		assert.Less(t, len(label), 63)
 	}
 }
+
+func TestRegistrationCACertFromSecret(t *testing.T) {
+	caPEM, _, err := certutil.GenerateSelfSignedCertKey("rmt.example.com", nil, nil)
+	assert.NoError(t, err)
+
+	sec := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{},
+		Data: map[string][]byte{
+			consts.SecretKeyRegistrationCode: []byte("hello"),
+			consts.RegistrationURL:           []byte("https://rmt.example.com"),
+		},
+	}
+	withoutCA, err := extractRegistrationParamsFromSecret(sec, "testing")
+	assert.NoError(t, err)
+	assert.False(t, withoutCA.hasRegCACert)
+	assert.Nil(t, paramsToRegSpec(withoutCA).RegistrationRequest.RegistrationAPICertificateSecretRef)
+
+	sec.Data[consts.SecretKeyRegistrationCACert] = caPEM
+	withCA, err := extractRegistrationParamsFromSecret(sec, "testing")
+	assert.NoError(t, err)
+	assert.True(t, withCA.hasRegCACert)
+	assert.Equal(t, withoutCA.nameID, withCA.nameID)
+	assert.NotEqual(t, withoutCA.contentHash, withCA.contentHash)
+
+	regSpec := paramsToRegSpec(withCA)
+	assert.Equal(t, consts.RegistrationCACertSecretName(withCA.nameID), regSpec.RegistrationRequest.RegistrationAPICertificateSecretRef.Name)
+
+	sec.Data[consts.SecretKeyRegistrationCACert] = []byte("not a certificate")
+	_, err = extractRegistrationParamsFromSecret(sec, "testing")
+	assert.ErrorContains(t, err, "data[registrationCACert]")
+}
+
+func TestRMTRegistrationFromSecret(t *testing.T) {
+	sec := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{},
+		Data: map[string][]byte{
+			dataKeyRegistrationType: []byte(v1.RegistrationModeRMT),
+			consts.RegistrationURL:  []byte("https://rmt.example.com"),
+		},
+	}
+
+	params, err := extractRegistrationParamsFromSecret(sec, "testing")
+	assert.NoError(t, err)
+	assert.Equal(t, v1.RegistrationModeRMT, params.regType)
+
+	regSpec := paramsToRegSpec(params)
+	assert.Equal(t, v1.RegistrationModeRMT, regSpec.Mode)
+	assert.Nil(t, regSpec.RegistrationRequest.RegistrationCodeSecretRef)
+	assert.Equal(t, "https://rmt.example.com", *regSpec.RegistrationRequest.RegistrationAPIUrl)
+
+	delete(sec.Data, consts.RegistrationURL)
+	_, err = extractRegistrationParamsFromSecret(sec, "testing")
+	assert.ErrorContains(t, err, "data[registrationUrl]: Required value")
+}
+
+func TestPayAsYouGoRegistrationFromSecret(t *testing.T) {
+	sec := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{},
+		Data: map[string][]byte{
+			dataKeyRegistrationType: []byte(v1.RegistrationModePayAsYouGo),
+		},
+	}
+
+	withoutInstanceData, err := extractRegistrationParamsFromSecret(sec, "testing")
+	assert.NoError(t, err)
+	assert.Equal(t, v1.RegistrationModePayAsYouGo, withoutInstanceData.regType)
+
+	regSpec := paramsToRegSpec(withoutInstanceData)
+	assert.Equal(t, v1.RegistrationModePayAsYouGo, regSpec.Mode)
+	assert.Nil(t, regSpec.RegistrationRequest.RegistrationCodeSecretRef)
+	assert.Nil(t, regSpec.RegistrationRequest.RegistrationAPIUrl)
+	assert.Nil(t, regSpec.RegistrationRequest.InstanceDataSecretRef)
+
+	sec.Data[consts.SecretKeyInstanceData] = []byte("<instance document>")
+	withInstanceData, err := extractRegistrationParamsFromSecret(sec, "testing")
+	assert.NoError(t, err)
+	assert.True(t, withInstanceData.hasInstanceData)
+	assert.Equal(t, withoutInstanceData.nameID, withInstanceData.nameID)
+	assert.NotEqual(t, withoutInstanceData.contentHash, withInstanceData.contentHash)
+
+	regSpec = paramsToRegSpec(withInstanceData)
+	assert.Equal(t, consts.InstanceDataSecretName(withInstanceData.nameID), regSpec.RegistrationRequest.InstanceDataSecretRef.Name)
+}
+
+func TestProxyRegistrationFromSecret(t *testing.T) {
+	sec := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{},
+		Data: map[string][]byte{
+			consts.SecretKeyRegistrationCode: []byte("hello"),
+		},
+	}
+	withoutProxy, err := extractRegistrationParamsFromSecret(sec, "testing")
+	assert.NoError(t, err)
+	assert.Nil(t, paramsToRegSpec(withoutProxy).RegistrationRequest.Proxy)
+
+	sec.Data[consts.SecretKeyProxyURL] = []byte("http://proxy.example.com:3128")
+	sec.Data[consts.SecretKeyNoProxy] = []byte(" .internal.example.com, 10.0.0.0/8,,")
+	withProxy, err := extractRegistrationParamsFromSecret(sec, "testing")
+	assert.NoError(t, err)
+	assert.Equal(t, withoutProxy.nameID, withProxy.nameID)
+	assert.NotEqual(t, withoutProxy.contentHash, withProxy.contentHash)
+	assert.False(t, withProxy.hasProxyCredentials())
+
+	proxy := paramsToRegSpec(withProxy).RegistrationRequest.Proxy
+	assert.Equal(t, "http://proxy.example.com:3128", proxy.URL)
+	assert.Equal(t, []string{".internal.example.com", "10.0.0.0/8"}, proxy.NoProxy)
+	assert.Nil(t, proxy.CredentialsSecretRef)
+
+	sec.Data[consts.SecretKeyProxyUsername] = []byte("proxy-user")
+	sec.Data[consts.SecretKeyProxyPassword] = []byte("proxy-password")
+	withCredentials, err := extractRegistrationParamsFromSecret(sec, "testing")
+	assert.NoError(t, err)
+	assert.True(t, withCredentials.hasProxyCredentials())
+	assert.NotEqual(t, withProxy.contentHash, withCredentials.contentHash)
+	proxy = paramsToRegSpec(withCredentials).RegistrationRequest.Proxy
+	assert.Equal(t, consts.ProxyCredentialsSecretName(withCredentials.nameID), proxy.CredentialsSecretRef.Name)
+}
+
+func TestLabelledEntrypointRegistrationFromSecret(t *testing.T) {
+	defaultEntrypoint := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      consts.ResourceSCCEntrypointSecretName,
+			Namespace: consts.DefaultSCCNamespace,
+			Labels:    map[string]string{consts.LabelObjectSalt: "salty"},
+		},
+		Data: map[string][]byte{
+			consts.SecretKeyRegistrationCode: []byte("hello"),
+			consts.RegistrationURL:           []byte("https://scc.example.com"),
+		},
+	}
+	addonEntrypoint := defaultEntrypoint.DeepCopy()
+	addonEntrypoint.Name = "addon-registration"
+	addonEntrypoint.Labels[consts.LabelSccEntrypoint] = consts.EntrypointLabelValue
+	copiedEntrypoint := addonEntrypoint.DeepCopy()
+	copiedEntrypoint.Name = "another-addon-registration"
+
+	defaultParams, err := extractRegistrationParamsFromSecret(defaultEntrypoint, "testing")
+	assert.NoError(t, err)
+	addonParams, err := extractRegistrationParamsFromSecret(addonEntrypoint, "testing")
+	assert.NoError(t, err)
+	copiedParams, err := extractRegistrationParamsFromSecret(copiedEntrypoint, "testing")
+	assert.NoError(t, err)
+
+	// Entrypoints sharing a salt and data still get their own Registration
+	assert.NotEqual(t, defaultParams.nameID, addonParams.nameID)
+	assert.NotEqual(t, addonParams.nameID, copiedParams.nameID)
+	assert.NotEqual(t, defaultParams.contentHash, addonParams.contentHash)
+}
@@ -0,0 +1,268 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/rancher/scc-operator/internal/telemetry"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/rancher/scc-operator/internal/logging"
+	"github.com/rancher/scc-operator/internal/repos/secretrepo"
+	"github.com/rancher/scc-operator/internal/suseconnect"
+	"github.com/rancher/scc-operator/internal/suseconnect/credentials"
+	"github.com/rancher/scc-operator/internal/types"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+	"github.com/rancher/scc-operator/pkg/controllers/lifecycle"
+)
+
+// sccRMTMode registers against a Repository Mirroring Tool (RMT) server.
+// RMT accepts announcements without a registration code and has no product activation endpoint,
+// so a successful announce plus keepalive is what marks these registrations as activated.
+type sccRMTMode struct {
+	rancherURL     string
+	options        *types.RunOptions
+	registration   *v1.Registration
+	log            logging.StructuredLogger
+	sccCredentials *credentials.CredentialSecretsAdapter
+	secretRepo     *secretrepo.SecretRepository
+	rancherMetrics telemetry.MetricsWrapper
+	dryRun         suseconnect.DryRunRecorder
+}
+
+func (s *sccRMTMode) SetRancherMetrics(rancherMetrics telemetry.MetricsWrapper) {
+	s.rancherMetrics = rancherMetrics
+}
+
+func (s *sccRMTMode) prepareRMTConnection(registrationObj *v1.Registration) (suseconnect.SCCClient, error) {
+	rmtURL := suseconnect.PrepareSccURL(registrationObj)
+	if rmtURL == "" {
+		return nil, errors.New("an RMT server URL is required for rmt mode registrations")
+	}
+
+	return prepareConnectedModeConnection(
+		s.options,
+		s.secretRepo,
+		s.rancherURL,
+		rmtURL,
+		registrationObj,
+		s.sccCredentials.SccCredentials(),
+		s.rancherMetrics,
+		s.dryRun,
+	)
+}
+
+func (s *sccRMTMode) NeedsRegistration(registrationObj *v1.Registration) bool {
+	return lifecycle.RegistrationHasNotStarted(registrationObj) ||
+		!registrationObj.HasCondition(v1.RegistrationConditionRMTAnnounced)
+}
+
+func (s *sccRMTMode) NeedsActivation(registrationObj *v1.Registration) bool {
+	return lifecycle.RegistrationNeedsActivation(registrationObj)
+}
+
+func (s *sccRMTMode) ReadyForActivation(registrationObj *v1.Registration) bool {
+	return v1.RegistrationConditionRMTAnnounced.IsTrue(registrationObj)
+}
+
+func (s *sccRMTMode) NeedsPreprocessRegistration(_ *v1.Registration) bool {
+	return false
+}
+
+func (s *sccRMTMode) PreprocessRegistration(registrationObj *v1.Registration) (*v1.Registration, error) {
+	return registrationObj, nil
+}
+
+func (s *sccRMTMode) ResetToReadyForActivation(registrationObj *v1.Registration) (*v1.Registration, error) {
+	registrationObj.Status.ActivationStatus.Activated = false
+	registrationObj.Status.ActivationStatus.LastValidatedTS = &metav1.Time{}
+	v1.ResourceConditionProgressing.True(registrationObj)
+	v1.ResourceConditionReady.False(registrationObj)
+	v1.ResourceConditionDone.False(registrationObj)
+	v1.RegistrationConditionActivated.False(registrationObj)
+	registrationObj.SetCurrentCondition(v1.ResourceConditionProgressing)
+
+	return registrationObj, nil
+}
+
+// PrepareForRegister creates the SCC creds secret used to store the system credentials RMT hands out
+func (s *sccRMTMode) PrepareForRegister(registration *v1.Registration) (*v1.Registration, error) {
+	if registration.Status.SystemCredentialsSecretRef == nil {
+		err := s.sccCredentials.InitSecret()
+		if err != nil {
+			return registration, err
+		}
+		s.sccCredentials.SetRegistrationCredentialsSecretRef(registration)
+	}
+
+	return registration, nil
+}
+
+func (s *sccRMTMode) Register(registrationObj *v1.Registration) (suseconnect.RegistrationSystemID, error) {
+	credentialsErr := s.sccCredentials.Refresh()
+	// RMT only hands out the system login when announcing, so there may be none stored yet
+	if credentialsErr != nil && !errors.Is(credentialsErr, credentials.ErrCredentialsIncomplete) {
+		return suseconnect.EmptyRegistrationSystemID, credentialsErr
+	}
+
+	rmtConnection, connErr := s.prepareRMTConnection(registrationObj)
+	if connErr != nil {
+		return suseconnect.EmptyRegistrationSystemID, connErr
+	}
+
+	// RMT does not use registration codes, the system is announced with an empty one
+	return rmtConnection.RegisterOrKeepAlive("")
+}
+
+func (s *sccRMTMode) PrepareRegisteredForActivation(registration *v1.Registration) (*v1.Registration, error) {
+	if registration.Status.SCCSystemID == nil {
+		return registration, errors.New("RMT system ID cannot be empty when preparing registered system")
+	}
+
+	v1.RegistrationConditionRMTAnnounced.SetStatusBool(registration, true)
+	markTLSVerified(registration)
+	markProxyAuthenticated(registration)
+	v1.ResourceConditionFailure.SetStatusBool(registration, false)
+	v1.ResourceConditionReady.SetStatusBool(registration, true)
+
+	return registration, nil
+}
+
+// Activate only confirms RMT still knows the system; RMT has no activation endpoint to call
+func (s *sccRMTMode) Activate(registrationObj *v1.Registration) error {
+	s.log.Debugf("received RMT registration ready for activation %q", registrationObj.Name)
+	return s.Keepalive(registrationObj)
+}
+
+func (s *sccRMTMode) PrepareActivatedForKeepalive(registrationObj *v1.Registration) (*v1.Registration, error) {
+	v1.RegistrationConditionRMTKeepalive.True(registrationObj)
+	markTLSVerified(registrationObj)
+	markProxyAuthenticated(registrationObj)
+
+	return registrationObj, nil
+}
+
+func (s *sccRMTMode) Keepalive(registrationObj *v1.Registration) error {
+	credRefreshErr := s.sccCredentials.Refresh()
+	if credRefreshErr != nil {
+		return fmt.Errorf("cannot refresh credentials: %w", credRefreshErr)
+	}
+
+	rmtConnection, connErr := s.prepareRMTConnection(registrationObj)
+	if connErr != nil {
+		return connErr
+	}
+
+	if keepAliveErr := rmtConnection.KeepAlive(); keepAliveErr != nil {
+		return keepAliveErr
+	}
+
+	s.log.Info("Successfully checked in with RMT")
+
+	return nil
+}
+
+func (s *sccRMTMode) PrepareKeepaliveSucceeded(registration *v1.Registration) (*v1.Registration, error) {
+	v1.RegistrationConditionRMTKeepalive.True(registration)
+	markTLSVerified(registration)
+	markProxyAuthenticated(registration)
+
+	return registration, nil
+}
+
+func (s *sccRMTMode) Deregister() error {
+	_ = s.sccCredentials.Refresh()
+	rmtConnection, connErr := s.prepareRMTConnection(s.registration)
+	if connErr != nil {
+		return fmt.Errorf("failed to prepare RMT connection to deregister: %w", connErr)
+	}
+	if err := rmtConnection.Deregister(); err != nil {
+		return fmt.Errorf("failed to deregister RMT registration: %w", err)
+	}
+
+	return nil
+}
+
+func (s *sccRMTMode) RemoveSecrets() error {
+	return s.sccCredentials.Remove()
+}
+
+func (s *sccRMTMode) ReconcileRegisterError(registrationObj *v1.Registration, registerErr error, phase types.RegistrationPhase) *v1.Registration {
+	if suseconnect.IsProxyAuthenticationError(registerErr) {
+		return reconcileProxyAuthenticationError(registrationObj, registerErr)
+	}
+
+	if suseconnect.IsTLSVerificationError(registerErr) {
+		registrationObj = lifecycle.PrepareFailed(registrationObj, registerErr)
+		reconcileTLSVerificationError(registrationObj, registerErr)
+	}
+
+	if isNonRecoverableHTTPError(registerErr) {
+		return reconcileNonRecoverableHTTPError(
+			registrationObj,
+			registerErr,
+			func(regApplierIn *v1.Registration, httpCode *int) *v1.Registration {
+				preparedErrorReasonCondition := fmt.Sprintf("Error: RMT api call returned %s (%d) status", http.StatusText(*httpCode), *httpCode)
+				v1.RegistrationConditionRMTAnnounced.SetError(regApplierIn, preparedErrorReasonCondition, registerErr)
+				v1.RegistrationConditionActivated.False(regApplierIn)
+				regApplierIn.SetCurrentCondition(v1.RegistrationConditionRMTAnnounced)
+				regApplierIn.Status.ActivationStatus.Activated = false
+
+				return regApplierIn
+			},
+		)
+	}
+
+	// An unreachable or overloaded RMT server is not a failure; the retry backoff decides when to announce again
+	v1.RegistrationConditionActivated.False(registrationObj)
+	if phase <= types.RegistrationForActivation {
+		v1.RegistrationConditionRMTAnnounced.False(registrationObj)
+	}
+
+	if phase == types.RegistrationPrepare {
+		v1.ResourceConditionFailure.SetError(registrationObj, "failed during secret initialization", registerErr)
+	}
+
+	return registrationObj
+}
+
+func (s *sccRMTMode) ReconcileActivateError(registration *v1.Registration, activationErr error, _ types.ActivationPhase) *v1.Registration {
+	if suseconnect.IsProxyAuthenticationError(activationErr) {
+		return reconcileProxyAuthenticationError(registration, activationErr)
+	}
+
+	reconcileTLSVerificationError(registration, activationErr)
+	return s.reconcileKeepaliveHTTPError(registration, activationErr)
+}
+
+func (s *sccRMTMode) ReconcileKeepaliveError(registration *v1.Registration, keepaliveErr error) *v1.Registration {
+	if suseconnect.IsProxyAuthenticationError(keepaliveErr) {
+		return reconcileProxyAuthenticationError(registration, keepaliveErr)
+	}
+
+	reconcileTLSVerificationError(registration, keepaliveErr)
+	return s.reconcileKeepaliveHTTPError(registration, keepaliveErr)
+}
+
+// reconcileKeepaliveHTTPError handles errors from the RMT status check used by both activation and keepalive
+func (s *sccRMTMode) reconcileKeepaliveHTTPError(registration *v1.Registration, keepaliveErr error) *v1.Registration {
+	if !isNonRecoverableHTTPError(keepaliveErr) {
+		return registration
+	}
+
+	return reconcileNonRecoverableHTTPError(
+		registration,
+		keepaliveErr,
+		func(regApplierIn *v1.Registration, httpCode *int) *v1.Registration {
+			preparedErrorReasonCondition := fmt.Sprintf("Error: RMT sync returned %s (%d) status", http.StatusText(*httpCode), *httpCode)
+			v1.RegistrationConditionRMTKeepalive.SetError(regApplierIn, preparedErrorReasonCondition, keepaliveErr)
+			regApplierIn.SetCurrentCondition(v1.RegistrationConditionRMTKeepalive)
+			regApplierIn.Status.ActivationStatus.Activated = false
+
+			return regApplierIn
+		},
+	)
+}
+
+var _ SCCHandler = &sccRMTMode{}
@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/SUSE/connect-ng/pkg/connection"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/rancher/scc-operator/internal/types"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+)
+
+func TestRMTModeLifecycleDeciders(t *testing.T) {
+	rmtHandler := &sccRMTMode{}
+	registration := &v1.Registration{Spec: v1.RegistrationSpec{Mode: v1.RegistrationModeRMT}}
+
+	assert.True(t, rmtHandler.NeedsRegistration(registration))
+	assert.False(t, rmtHandler.ReadyForActivation(registration))
+
+	systemID := 42
+	now := metav1.Now()
+	registration.Status.SCCSystemID = &systemID
+	registration.Status.RegistrationProcessedTS = &now
+	registration, err := rmtHandler.PrepareRegisteredForActivation(registration)
+	assert.NoError(t, err)
+
+	assert.False(t, rmtHandler.NeedsRegistration(registration))
+	assert.True(t, rmtHandler.ReadyForActivation(registration))
+	assert.True(t, rmtHandler.NeedsActivation(registration))
+	assert.False(t, registration.HasCondition(v1.RegistrationConditionAnnounced))
+}
+
+func TestRMTModeRequiresURL(t *testing.T) {
+	rmtHandler := &sccRMTMode{}
+	registration := &v1.Registration{
+		Spec: v1.RegistrationSpec{
+			Mode:                v1.RegistrationModeRMT,
+			RegistrationRequest: &v1.RegistrationRequest{},
+		},
+	}
+
+	_, err := rmtHandler.prepareRMTConnection(registration)
+	assert.ErrorContains(t, err, "RMT server URL is required")
+}
+
+func TestRMTModeReconcileRegisterError(t *testing.T) {
+	rmtHandler := &sccRMTMode{}
+
+	registration := rmtHandler.ReconcileRegisterError(&v1.Registration{}, &connection.ApiError{Code: 403, Message: "forbidden"}, types.RegistrationMain)
+	assert.True(t, v1.RegistrationConditionRMTAnnounced.IsFalse(registration))
+	assert.Contains(t, v1.RegistrationConditionRMTAnnounced.GetReason(registration), "RMT api call returned Forbidden (403)")
+	assert.Equal(t, string(v1.RegistrationConditionRMTAnnounced), registration.Status.CurrentCondition.Type)
+
+	registration = rmtHandler.ReconcileKeepaliveError(&v1.Registration{}, &connection.ApiError{Code: 404, Message: "missing"})
+	assert.True(t, v1.RegistrationConditionRMTKeepalive.IsFalse(registration))
+	assert.False(t, registration.Status.ActivationStatus.Activated)
+}
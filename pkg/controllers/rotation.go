@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"bytes"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/rancher/scc-operator/internal/config"
+	"github.com/rancher/scc-operator/internal/consts"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+)
+
+// regCodeRotation returns how a changed reg code is applied; in place unless the operator was configured otherwise
+func (h *handler) regCodeRotation() config.RegCodeRotation {
+	if h.options == nil || h.options.OperatorSettings == nil || !h.options.OperatorSettings.RegCodeRotation.IsValid() {
+		return config.RegCodeRotationInPlace
+	}
+
+	return h.options.OperatorSettings.RegCodeRotation
+}
+
+// pinRotatedNameID keeps the entrypoint's current name hash when its reg code is the only naming data that changed,
+// so the existing Registration and its SCC system are kept instead of being replaced by a newly announced one
+func (h *handler) pinRotatedNameID(entrypoint *corev1.Secret, params RegistrationParams) (RegistrationParams, bool) {
+	if h.regCodeRotation() != config.RegCodeRotationInPlace || params.regType != v1.RegistrationModeOnline {
+		return params, false
+	}
+
+	currentNameID := entrypoint.Labels[consts.LabelNameSuffix]
+	if currentNameID == "" || currentNameID == params.nameID || entrypoint.Annotations[consts.AnnotationSccIdentityHash] != params.identityHash {
+		return params, false
+	}
+
+	return params.withNameID(currentNameID, entrypoint.Namespace), true
+}
+
+// regCodeChanged reports if the reg code Secret of the params does not hold their reg code yet
+func (h *handler) regCodeChanged(params RegistrationParams) (bool, error) {
+	regCodeSecret, err := h.secretRepo.Cache.Get(params.regCodeSecretRef.Namespace, params.regCodeSecretRef.Name)
+	if apierrors.IsNotFound(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return !bytes.Equal(regCodeSecret.Data[consts.SecretKeyRegistrationCode], params.regCode), nil
+}
+
+// prepareRegCodeRotation requests the kept Registration to activate again, now with the new reg code
+func (h *handler) prepareRegCodeRotation(registrationObj *v1.Registration) *v1.Registration {
+	if registrationObj.Status.SCCSystemID == nil {
+		// Not announced yet, so the new code is used by the registration still in progress
+		return registrationObj
+	}
+
+	registrationObj = registrationObj.DeepCopy()
+	h.log.Infof("reg code of registration %s changed; activating system %d with the new code", registrationObj.Name, *registrationObj.Status.SCCSystemID)
+	if registrationObj.Annotations == nil {
+		registrationObj.Annotations = map[string]string{}
+	}
+	registrationObj.Annotations[consts.AnnotationSccAction] = string(v1.RegistrationActionResetActivation)
+	h.recordEvent(registrationObj, corev1.EventTypeNormal, eventReasonRegCodeRotated, "reg code changed; activating system %d with the new code", *registrationObj.Status.SCCSystemID)
+
+	return registrationObj
+}
@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/ptr"
+
+	"github.com/rancher/scc-operator/internal/config"
+	"github.com/rancher/scc-operator/internal/consts"
+	"github.com/rancher/scc-operator/internal/initializer"
+	"github.com/rancher/scc-operator/internal/logging"
+	"github.com/rancher/scc-operator/internal/repos/secretrepo"
+	"github.com/rancher/scc-operator/internal/types"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+)
+
+func rotationEntrypoint(regCode string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      consts.ResourceSCCEntrypointSecretName,
+			Namespace: consts.DefaultSCCNamespace,
+			Labels:    map[string]string{consts.LabelObjectSalt: "salty"},
+		},
+		Data: map[string][]byte{
+			consts.SecretKeyRegistrationCode: []byte(regCode),
+			dataKeyRegistrationType:          []byte(v1.RegistrationModeOnline),
+		},
+	}
+}
+
+// rotatedEntrypoint returns an entrypoint processed with the old reg code, then edited to hold the new one
+func rotatedEntrypoint(t *testing.T, oldRegCode, newRegCode string) (*corev1.Secret, RegistrationParams) {
+	entrypoint := rotationEntrypoint(oldRegCode)
+	oldParams, err := extractRegistrationParamsFromSecret(entrypoint, consts.DefaultOperatorName)
+	require.NoError(t, err)
+	entrypoint.Labels[consts.LabelNameSuffix] = oldParams.nameID
+	entrypoint.Annotations = map[string]string{consts.AnnotationSccIdentityHash: oldParams.identityHash}
+	entrypoint.Data[consts.SecretKeyRegistrationCode] = []byte(newRegCode)
+
+	return entrypoint, oldParams
+}
+
+func TestExtractRegistrationParamsIdentityIgnoresRegCode(t *testing.T) {
+	initializer.DevMode.Set(true)
+	oldParams, err := extractRegistrationParamsFromSecret(rotationEntrypoint("REGCODE-OLD"), consts.DefaultOperatorName)
+	require.NoError(t, err)
+	newParams, err := extractRegistrationParamsFromSecret(rotationEntrypoint("REGCODE-NEW"), consts.DefaultOperatorName)
+	require.NoError(t, err)
+
+	assert.NotEqual(t, oldParams.nameID, newParams.nameID)
+	assert.Equal(t, oldParams.identityHash, newParams.identityHash)
+
+	otherURL := rotationEntrypoint("REGCODE-NEW")
+	otherURL.Data[consts.RegistrationURL] = []byte("https://scc.example.com")
+	otherParams, err := extractRegistrationParamsFromSecret(otherURL, consts.DefaultOperatorName)
+	require.NoError(t, err)
+	assert.NotEqual(t, oldParams.identityHash, otherParams.identityHash)
+}
+
+func TestPinRotatedNameID(t *testing.T) {
+	initializer.DevMode.Set(true)
+	h := &handler{
+		log: logging.NewLog(),
+		options: &types.RunOptions{
+			OperatorName:     consts.DefaultOperatorName,
+			OperatorSettings: &config.OperatorSettings{SystemNamespace: consts.DefaultSCCNamespace},
+		},
+	}
+	entrypoint, oldParams := rotatedEntrypoint(t, "REGCODE-OLD", "REGCODE-NEW")
+	params, err := extractRegistrationParamsFromSecret(entrypoint, consts.DefaultOperatorName)
+	require.NoError(t, err)
+
+	pinned, ok := h.pinRotatedNameID(entrypoint, params)
+	require.True(t, ok)
+	assert.Equal(t, oldParams.nameID, pinned.nameID)
+	assert.Equal(t, oldParams.regCodeSecretRef, pinned.regCodeSecretRef)
+	assert.Equal(t, []byte("REGCODE-NEW"), pinned.regCode)
+
+	// The old behaviour replaces the Registration
+	h.options.OperatorSettings.RegCodeRotation = config.RegCodeRotationReplace
+	_, ok = h.pinRotatedNameID(entrypoint, params)
+	assert.False(t, ok)
+
+	// Anything else naming the registration changing still replaces it
+	h.options.OperatorSettings.RegCodeRotation = config.RegCodeRotationInPlace
+	entrypoint.Data[consts.RegistrationURL] = []byte("https://scc.example.com")
+	params, err = extractRegistrationParamsFromSecret(entrypoint, consts.DefaultOperatorName)
+	require.NoError(t, err)
+	_, ok = h.pinRotatedNameID(entrypoint, params)
+	assert.False(t, ok)
+}
+
+func TestRegCodeChanged(t *testing.T) {
+	initializer.DevMode.Set(true)
+	gomockCtrl := gomock.NewController(t)
+	mockSecretsCache := fake.NewMockCacheInterface[*corev1.Secret](gomockCtrl)
+	h := &handler{
+		log:        logging.NewLog(),
+		secretRepo: &secretrepo.SecretRepository{Cache: mockSecretsCache},
+		options: &types.RunOptions{
+			OperatorName:     consts.DefaultOperatorName,
+			OperatorSettings: &config.OperatorSettings{SystemNamespace: consts.DefaultSCCNamespace},
+		},
+	}
+	entrypoint, oldParams := rotatedEntrypoint(t, "REGCODE-OLD", "REGCODE-NEW")
+	params, err := extractRegistrationParamsFromSecret(entrypoint, consts.DefaultOperatorName)
+	require.NoError(t, err)
+	pinned, _ := h.pinRotatedNameID(entrypoint, params)
+
+	regCodeSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: oldParams.regCodeSecretRef.Name, Namespace: consts.DefaultSCCNamespace},
+		Data:       map[string][]byte{consts.SecretKeyRegistrationCode: []byte("REGCODE-OLD")},
+	}
+	mockSecretsCache.EXPECT().Get(consts.DefaultSCCNamespace, regCodeSecret.Name).Return(regCodeSecret, nil)
+	changed, err := h.regCodeChanged(pinned)
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	// Once written, later reconciles of the same entrypoint don't activate again
+	regCodeSecret.Data[consts.SecretKeyRegistrationCode] = []byte("REGCODE-NEW")
+	mockSecretsCache.EXPECT().Get(consts.DefaultSCCNamespace, regCodeSecret.Name).Return(regCodeSecret, nil)
+	changed, err = h.regCodeChanged(pinned)
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	mockSecretsCache.EXPECT().Get(consts.DefaultSCCNamespace, regCodeSecret.Name).Return(nil, apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, regCodeSecret.Name))
+	changed, err = h.regCodeChanged(pinned)
+	require.NoError(t, err)
+	assert.True(t, changed)
+}
+
+func TestPrepareRegCodeRotation(t *testing.T) {
+	gomockCtrl := gomock.NewController(t)
+	mockSecretsCache := fake.NewMockCacheInterface[*corev1.Secret](gomockCtrl)
+	h := &handler{
+		log:        logging.NewLog(),
+		secretRepo: &secretrepo.SecretRepository{Cache: mockSecretsCache},
+		options: &types.RunOptions{
+			OperatorName:     consts.DefaultOperatorName,
+			OperatorSettings: &config.OperatorSettings{SystemNamespace: consts.DefaultSCCNamespace},
+		},
+	}
+	mockSecretsCache.EXPECT().List(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+
+	announced := &v1.Registration{
+		ObjectMeta: metav1.ObjectMeta{Name: "scc-registration-abc"},
+		Status:     v1.RegistrationStatus{SCCSystemID: ptr.To(1234)},
+	}
+	prepared := h.prepareRegCodeRotation(announced)
+	assert.Equal(t, string(v1.RegistrationActionResetActivation), prepared.Annotations[consts.AnnotationSccAction])
+	assert.Empty(t, announced.Annotations, "the cached Registration is left untouched")
+
+	notAnnounced := &v1.Registration{ObjectMeta: metav1.ObjectMeta{Name: "scc-registration-abc"}}
+	assert.Empty(t, h.prepareRegCodeRotation(notAnnounced).Annotations)
+}
@@ -29,61 +29,465 @@ import (
 
 func GetOpenAPIDefinitions(ref common.ReferenceCallback) map[string]common.OpenAPIDefinition {
 	return map[string]common.OpenAPIDefinition{
-		"github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.Registration":          schema_pkg_apis_scccattleio_v1_Registration(ref),
-		"github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.RegistrationList":      schema_pkg_apis_scccattleio_v1_RegistrationList(ref),
-		"github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.RegistrationRequest":   schema_pkg_apis_scccattleio_v1_RegistrationRequest(ref),
-		"github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.RegistrationSpec":      schema_pkg_apis_scccattleio_v1_RegistrationSpec(ref),
-		"github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.RegistrationStatus":    schema_pkg_apis_scccattleio_v1_RegistrationStatus(ref),
-		"github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.SystemActivationState": schema_pkg_apis_scccattleio_v1_SystemActivationState(ref),
-		v1.APIGroup{}.OpenAPIModelName():                                                  schema_pkg_apis_meta_v1_APIGroup(ref),
-		v1.APIGroupList{}.OpenAPIModelName():                                              schema_pkg_apis_meta_v1_APIGroupList(ref),
-		v1.APIResource{}.OpenAPIModelName():                                               schema_pkg_apis_meta_v1_APIResource(ref),
-		v1.APIResourceList{}.OpenAPIModelName():                                           schema_pkg_apis_meta_v1_APIResourceList(ref),
-		v1.APIVersions{}.OpenAPIModelName():                                               schema_pkg_apis_meta_v1_APIVersions(ref),
-		v1.ApplyOptions{}.OpenAPIModelName():                                              schema_pkg_apis_meta_v1_ApplyOptions(ref),
-		v1.Condition{}.OpenAPIModelName():                                                 schema_pkg_apis_meta_v1_Condition(ref),
-		v1.CreateOptions{}.OpenAPIModelName():                                             schema_pkg_apis_meta_v1_CreateOptions(ref),
-		v1.DeleteOptions{}.OpenAPIModelName():                                             schema_pkg_apis_meta_v1_DeleteOptions(ref),
-		v1.Duration{}.OpenAPIModelName():                                                  schema_pkg_apis_meta_v1_Duration(ref),
-		v1.FieldSelectorRequirement{}.OpenAPIModelName():                                  schema_pkg_apis_meta_v1_FieldSelectorRequirement(ref),
-		v1.FieldsV1{}.OpenAPIModelName():                                                  schema_pkg_apis_meta_v1_FieldsV1(ref),
-		v1.GetOptions{}.OpenAPIModelName():                                                schema_pkg_apis_meta_v1_GetOptions(ref),
-		v1.GroupKind{}.OpenAPIModelName():                                                 schema_pkg_apis_meta_v1_GroupKind(ref),
-		v1.GroupResource{}.OpenAPIModelName():                                             schema_pkg_apis_meta_v1_GroupResource(ref),
-		v1.GroupVersion{}.OpenAPIModelName():                                              schema_pkg_apis_meta_v1_GroupVersion(ref),
-		v1.GroupVersionForDiscovery{}.OpenAPIModelName():                                  schema_pkg_apis_meta_v1_GroupVersionForDiscovery(ref),
-		v1.GroupVersionKind{}.OpenAPIModelName():                                          schema_pkg_apis_meta_v1_GroupVersionKind(ref),
-		v1.GroupVersionResource{}.OpenAPIModelName():                                      schema_pkg_apis_meta_v1_GroupVersionResource(ref),
-		v1.InternalEvent{}.OpenAPIModelName():                                             schema_pkg_apis_meta_v1_InternalEvent(ref),
-		v1.LabelSelector{}.OpenAPIModelName():                                             schema_pkg_apis_meta_v1_LabelSelector(ref),
-		v1.LabelSelectorRequirement{}.OpenAPIModelName():                                  schema_pkg_apis_meta_v1_LabelSelectorRequirement(ref),
-		v1.List{}.OpenAPIModelName():                                                      schema_pkg_apis_meta_v1_List(ref),
-		v1.ListMeta{}.OpenAPIModelName():                                                  schema_pkg_apis_meta_v1_ListMeta(ref),
-		v1.ListOptions{}.OpenAPIModelName():                                               schema_pkg_apis_meta_v1_ListOptions(ref),
-		v1.ManagedFieldsEntry{}.OpenAPIModelName():                                        schema_pkg_apis_meta_v1_ManagedFieldsEntry(ref),
-		v1.MicroTime{}.OpenAPIModelName():                                                 schema_pkg_apis_meta_v1_MicroTime(ref),
-		v1.ObjectMeta{}.OpenAPIModelName():                                                schema_pkg_apis_meta_v1_ObjectMeta(ref),
-		v1.OwnerReference{}.OpenAPIModelName():                                            schema_pkg_apis_meta_v1_OwnerReference(ref),
-		v1.PartialObjectMetadata{}.OpenAPIModelName():                                     schema_pkg_apis_meta_v1_PartialObjectMetadata(ref),
-		v1.PartialObjectMetadataList{}.OpenAPIModelName():                                 schema_pkg_apis_meta_v1_PartialObjectMetadataList(ref),
-		v1.Patch{}.OpenAPIModelName():                                                     schema_pkg_apis_meta_v1_Patch(ref),
-		v1.PatchOptions{}.OpenAPIModelName():                                              schema_pkg_apis_meta_v1_PatchOptions(ref),
-		v1.Preconditions{}.OpenAPIModelName():                                             schema_pkg_apis_meta_v1_Preconditions(ref),
-		v1.RootPaths{}.OpenAPIModelName():                                                 schema_pkg_apis_meta_v1_RootPaths(ref),
-		v1.ServerAddressByClientCIDR{}.OpenAPIModelName():                                 schema_pkg_apis_meta_v1_ServerAddressByClientCIDR(ref),
-		v1.Status{}.OpenAPIModelName():                                                    schema_pkg_apis_meta_v1_Status(ref),
-		v1.StatusCause{}.OpenAPIModelName():                                               schema_pkg_apis_meta_v1_StatusCause(ref),
-		v1.StatusDetails{}.OpenAPIModelName():                                             schema_pkg_apis_meta_v1_StatusDetails(ref),
-		v1.Table{}.OpenAPIModelName():                                                     schema_pkg_apis_meta_v1_Table(ref),
-		v1.TableColumnDefinition{}.OpenAPIModelName():                                     schema_pkg_apis_meta_v1_TableColumnDefinition(ref),
-		v1.TableOptions{}.OpenAPIModelName():                                              schema_pkg_apis_meta_v1_TableOptions(ref),
-		v1.TableRow{}.OpenAPIModelName():                                                  schema_pkg_apis_meta_v1_TableRow(ref),
-		v1.TableRowCondition{}.OpenAPIModelName():                                         schema_pkg_apis_meta_v1_TableRowCondition(ref),
-		v1.Time{}.OpenAPIModelName():                                                      schema_pkg_apis_meta_v1_Time(ref),
-		v1.Timestamp{}.OpenAPIModelName():                                                 schema_pkg_apis_meta_v1_Timestamp(ref),
-		v1.TypeMeta{}.OpenAPIModelName():                                                  schema_pkg_apis_meta_v1_TypeMeta(ref),
-		v1.UpdateOptions{}.OpenAPIModelName():                                             schema_pkg_apis_meta_v1_UpdateOptions(ref),
-		v1.WatchEvent{}.OpenAPIModelName():                                                schema_pkg_apis_meta_v1_WatchEvent(ref),
+		"github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.ActionResult":             schema_pkg_apis_scccattleio_v1_ActionResult(ref),
+		"github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.DeregistrationPolicy":     schema_pkg_apis_scccattleio_v1_DeregistrationPolicy(ref),
+		"github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.DeregistrationStatus":     schema_pkg_apis_scccattleio_v1_DeregistrationStatus(ref),
+		"github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.OfflineCertificateStatus": schema_pkg_apis_scccattleio_v1_OfflineCertificateStatus(ref),
+		"github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.PayAsYouGoStatus":         schema_pkg_apis_scccattleio_v1_PayAsYouGoStatus(ref),
+		"github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.PendingUsageReport":       schema_pkg_apis_scccattleio_v1_PendingUsageReport(ref),
+		"github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.ProductActivation":        schema_pkg_apis_scccattleio_v1_ProductActivation(ref),
+		"github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.ProxyConfig":              schema_pkg_apis_scccattleio_v1_ProxyConfig(ref),
+		"github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.RecoveryPolicy":           schema_pkg_apis_scccattleio_v1_RecoveryPolicy(ref),
+		"github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.RecoveryStatus":           schema_pkg_apis_scccattleio_v1_RecoveryStatus(ref),
+		"github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.Registration":             schema_pkg_apis_scccattleio_v1_Registration(ref),
+		"github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.RegistrationList":         schema_pkg_apis_scccattleio_v1_RegistrationList(ref),
+		"github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.RegistrationRequest":      schema_pkg_apis_scccattleio_v1_RegistrationRequest(ref),
+		"github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.RegistrationSpec":         schema_pkg_apis_scccattleio_v1_RegistrationSpec(ref),
+		"github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.RegistrationStatus":       schema_pkg_apis_scccattleio_v1_RegistrationStatus(ref),
+		"github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.SyncAttempt":              schema_pkg_apis_scccattleio_v1_SyncAttempt(ref),
+		"github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.SystemActivationState":    schema_pkg_apis_scccattleio_v1_SystemActivationState(ref),
+		v1.APIGroup{}.OpenAPIModelName():                                                     schema_pkg_apis_meta_v1_APIGroup(ref),
+		v1.APIGroupList{}.OpenAPIModelName():                                                 schema_pkg_apis_meta_v1_APIGroupList(ref),
+		v1.APIResource{}.OpenAPIModelName():                                                  schema_pkg_apis_meta_v1_APIResource(ref),
+		v1.APIResourceList{}.OpenAPIModelName():                                              schema_pkg_apis_meta_v1_APIResourceList(ref),
+		v1.APIVersions{}.OpenAPIModelName():                                                  schema_pkg_apis_meta_v1_APIVersions(ref),
+		v1.ApplyOptions{}.OpenAPIModelName():                                                 schema_pkg_apis_meta_v1_ApplyOptions(ref),
+		v1.Condition{}.OpenAPIModelName():                                                    schema_pkg_apis_meta_v1_Condition(ref),
+		v1.CreateOptions{}.OpenAPIModelName():                                                schema_pkg_apis_meta_v1_CreateOptions(ref),
+		v1.DeleteOptions{}.OpenAPIModelName():                                                schema_pkg_apis_meta_v1_DeleteOptions(ref),
+		v1.Duration{}.OpenAPIModelName():                                                     schema_pkg_apis_meta_v1_Duration(ref),
+		v1.FieldSelectorRequirement{}.OpenAPIModelName():                                     schema_pkg_apis_meta_v1_FieldSelectorRequirement(ref),
+		v1.FieldsV1{}.OpenAPIModelName():                                                     schema_pkg_apis_meta_v1_FieldsV1(ref),
+		v1.GetOptions{}.OpenAPIModelName():                                                   schema_pkg_apis_meta_v1_GetOptions(ref),
+		v1.GroupKind{}.OpenAPIModelName():                                                    schema_pkg_apis_meta_v1_GroupKind(ref),
+		v1.GroupResource{}.OpenAPIModelName():                                                schema_pkg_apis_meta_v1_GroupResource(ref),
+		v1.GroupVersion{}.OpenAPIModelName():                                                 schema_pkg_apis_meta_v1_GroupVersion(ref),
+		v1.GroupVersionForDiscovery{}.OpenAPIModelName():                                     schema_pkg_apis_meta_v1_GroupVersionForDiscovery(ref),
+		v1.GroupVersionKind{}.OpenAPIModelName():                                             schema_pkg_apis_meta_v1_GroupVersionKind(ref),
+		v1.GroupVersionResource{}.OpenAPIModelName():                                         schema_pkg_apis_meta_v1_GroupVersionResource(ref),
+		v1.InternalEvent{}.OpenAPIModelName():                                                schema_pkg_apis_meta_v1_InternalEvent(ref),
+		v1.LabelSelector{}.OpenAPIModelName():                                                schema_pkg_apis_meta_v1_LabelSelector(ref),
+		v1.LabelSelectorRequirement{}.OpenAPIModelName():                                     schema_pkg_apis_meta_v1_LabelSelectorRequirement(ref),
+		v1.List{}.OpenAPIModelName():                                                         schema_pkg_apis_meta_v1_List(ref),
+		v1.ListMeta{}.OpenAPIModelName():                                                     schema_pkg_apis_meta_v1_ListMeta(ref),
+		v1.ListOptions{}.OpenAPIModelName():                                                  schema_pkg_apis_meta_v1_ListOptions(ref),
+		v1.ManagedFieldsEntry{}.OpenAPIModelName():                                           schema_pkg_apis_meta_v1_ManagedFieldsEntry(ref),
+		v1.MicroTime{}.OpenAPIModelName():                                                    schema_pkg_apis_meta_v1_MicroTime(ref),
+		v1.ObjectMeta{}.OpenAPIModelName():                                                   schema_pkg_apis_meta_v1_ObjectMeta(ref),
+		v1.OwnerReference{}.OpenAPIModelName():                                               schema_pkg_apis_meta_v1_OwnerReference(ref),
+		v1.PartialObjectMetadata{}.OpenAPIModelName():                                        schema_pkg_apis_meta_v1_PartialObjectMetadata(ref),
+		v1.PartialObjectMetadataList{}.OpenAPIModelName():                                    schema_pkg_apis_meta_v1_PartialObjectMetadataList(ref),
+		v1.Patch{}.OpenAPIModelName():                                                        schema_pkg_apis_meta_v1_Patch(ref),
+		v1.PatchOptions{}.OpenAPIModelName():                                                 schema_pkg_apis_meta_v1_PatchOptions(ref),
+		v1.Preconditions{}.OpenAPIModelName():                                                schema_pkg_apis_meta_v1_Preconditions(ref),
+		v1.RootPaths{}.OpenAPIModelName():                                                    schema_pkg_apis_meta_v1_RootPaths(ref),
+		v1.ServerAddressByClientCIDR{}.OpenAPIModelName():                                    schema_pkg_apis_meta_v1_ServerAddressByClientCIDR(ref),
+		v1.Status{}.OpenAPIModelName():                                                       schema_pkg_apis_meta_v1_Status(ref),
+		v1.StatusCause{}.OpenAPIModelName():                                                  schema_pkg_apis_meta_v1_StatusCause(ref),
+		v1.StatusDetails{}.OpenAPIModelName():                                                schema_pkg_apis_meta_v1_StatusDetails(ref),
+		v1.Table{}.OpenAPIModelName():                                                        schema_pkg_apis_meta_v1_Table(ref),
+		v1.TableColumnDefinition{}.OpenAPIModelName():                                        schema_pkg_apis_meta_v1_TableColumnDefinition(ref),
+		v1.TableOptions{}.OpenAPIModelName():                                                 schema_pkg_apis_meta_v1_TableOptions(ref),
+		v1.TableRow{}.OpenAPIModelName():                                                     schema_pkg_apis_meta_v1_TableRow(ref),
+		v1.TableRowCondition{}.OpenAPIModelName():                                            schema_pkg_apis_meta_v1_TableRowCondition(ref),
+		v1.Time{}.OpenAPIModelName():                                                         schema_pkg_apis_meta_v1_Time(ref),
+		v1.Timestamp{}.OpenAPIModelName():                                                    schema_pkg_apis_meta_v1_Timestamp(ref),
+		v1.TypeMeta{}.OpenAPIModelName():                                                     schema_pkg_apis_meta_v1_TypeMeta(ref),
+		v1.UpdateOptions{}.OpenAPIModelName():                                                schema_pkg_apis_meta_v1_UpdateOptions(ref),
+		v1.WatchEvent{}.OpenAPIModelName():                                                   schema_pkg_apis_meta_v1_WatchEvent(ref),
+	}
+}
+
+func schema_pkg_apis_scccattleio_v1_ActionResult(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "ActionResult records the outcome of an action requested with the `scc.cattle.io/action` annotation",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"action": {
+						SchemaProps: spec.SchemaProps{
+							Default: "",
+							Type:    []string{"string"},
+							Format:  "",
+						},
+					},
+					"timestamp": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref(v1.Time{}.OpenAPIModelName()),
+						},
+					},
+					"outcome": {
+						SchemaProps: spec.SchemaProps{
+							Default: "",
+							Type:    []string{"string"},
+							Format:  "",
+						},
+					},
+					"message": {
+						SchemaProps: spec.SchemaProps{
+							Type:   []string{"string"},
+							Format: "",
+						},
+					},
+				},
+				Required: []string{"action", "timestamp", "outcome"},
+			},
+		},
+		Dependencies: []string{
+			v1.Time{}.OpenAPIModelName()},
+	}
+}
+
+func schema_pkg_apis_scccattleio_v1_DeregistrationPolicy(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "DeregistrationPolicy decides what happens to the SCC system when the Registration is deleted. Systems the operator gives up on are recorded as tombstones, so they can be removed from SCC by hand.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"mode": {
+						SchemaProps: spec.SchemaProps{
+							Default: "",
+							Type:    []string{"string"},
+							Format:  "",
+						},
+					},
+					"timeout": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Timeout after which required-with-timeout gives up, counted from the deletion of the Registration",
+							Ref:         ref(v1.Duration{}.OpenAPIModelName()),
+						},
+					},
+				},
+				Required: []string{"mode"},
+			},
+		},
+		Dependencies: []string{
+			v1.Duration{}.OpenAPIModelName()},
+	}
+}
+
+func schema_pkg_apis_scccattleio_v1_DeregistrationStatus(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "DeregistrationStatus tracks the failed attempts to deregister a system that is required to be deregistered",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"attempts": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Attempts counts the failed deregistrations",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"lastError": {
+						SchemaProps: spec.SchemaProps{
+							Description: "LastError is the error SCC returned on the last attempt",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"nextAttemptAt": {
+						SchemaProps: spec.SchemaProps{
+							Description: "NextAttemptAt is when the deregistration will be retried",
+							Ref:         ref(v1.Time{}.OpenAPIModelName()),
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			v1.Time{}.OpenAPIModelName()},
+	}
+}
+
+func schema_pkg_apis_scccattleio_v1_OfflineCertificateStatus(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "OfflineCertificateStatus is what the operator read from the offline registration certificate when activating it",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"subscriptionName": {
+						SchemaProps: spec.SchemaProps{
+							Description: "SubscriptionName is the name of the subscription the certificate was issued for",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"subscriptionKind": {
+						SchemaProps: spec.SchemaProps{
+							Description: "SubscriptionKind is the kind of that subscription, e.g. `full` or `evaluation`",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"hashedRegcode": {
+						SchemaProps: spec.SchemaProps{
+							Description: "HashedRegcode is the SHA-256 of the registration code the certificate was issued for, as the code itself is never included",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"productClasses": {
+						VendorExtensible: spec.VendorExtensible{
+							Extensions: spec.Extensions{
+								"x-kubernetes-list-type": "atomic",
+							},
+						},
+						SchemaProps: spec.SchemaProps{
+							Description: "ProductClasses lists the product classes the subscription covers",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: "",
+										Type:    []string{"string"},
+										Format:  "",
+									},
+								},
+							},
+						},
+					},
+					"startsAt": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref(v1.Time{}.OpenAPIModelName()),
+						},
+					},
+					"expiresAt": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref(v1.Time{}.OpenAPIModelName()),
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			v1.Time{}.OpenAPIModelName()},
+	}
+}
+
+func schema_pkg_apis_scccattleio_v1_PayAsYouGoStatus(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "PayAsYouGoStatus tracks the usage reported to SCC for a pay-as-you-go registration",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"lastUsageReportTS": {
+						SchemaProps: spec.SchemaProps{
+							Description: "LastUsageReportTS is the end of the period covered by the last successful usage report",
+							Ref:         ref(v1.Time{}.OpenAPIModelName()),
+						},
+					},
+					"reportedUsageHours": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ReportedUsageHours is the total number of online hours reported to SCC",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
+					"pendingUsageReport": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PendingUsageReport is a usage report sent to SCC whose outcome was never recorded. It is counted as reported by the next keepalive, so a lost status update never bills the same hours twice.",
+							Ref:         ref("github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.PendingUsageReport"),
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.PendingUsageReport", v1.Time{}.OpenAPIModelName()},
+	}
+}
+
+func schema_pkg_apis_scccattleio_v1_PendingUsageReport(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "PendingUsageReport is the period covered by a usage report recorded right before it is sent to SCC",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"until": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Until is the end of the reported period",
+							Ref:         ref(v1.Time{}.OpenAPIModelName()),
+						},
+					},
+					"hours": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Hours is the number of online hours in the report",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
+				},
+				Required: []string{"until"},
+			},
+		},
+		Dependencies: []string{
+			v1.Time{}.OpenAPIModelName()},
+	}
+}
+
+func schema_pkg_apis_scccattleio_v1_ProductActivation(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "ProductActivation is a summary of a single product activation known to SCC for the registered system",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"friendlyName": {
+						SchemaProps: spec.SchemaProps{
+							Default: "",
+							Type:    []string{"string"},
+							Format:  "",
+						},
+					},
+					"identifier": {
+						SchemaProps: spec.SchemaProps{
+							Default: "",
+							Type:    []string{"string"},
+							Format:  "",
+						},
+					},
+					"version": {
+						SchemaProps: spec.SchemaProps{
+							Default: "",
+							Type:    []string{"string"},
+							Format:  "",
+						},
+					},
+					"arch": {
+						SchemaProps: spec.SchemaProps{
+							Default: "",
+							Type:    []string{"string"},
+							Format:  "",
+						},
+					},
+					"regCodeType": {
+						SchemaProps: spec.SchemaProps{
+							Type:   []string{"string"},
+							Format: "",
+						},
+					},
+					"expiresAt": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref(v1.Time{}.OpenAPIModelName()),
+						},
+					},
+				},
+				Required: []string{"friendlyName", "identifier", "version", "arch"},
+			},
+		},
+		Dependencies: []string{
+			v1.Time{}.OpenAPIModelName()},
+	}
+}
+
+func schema_pkg_apis_scccattleio_v1_ProxyConfig(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "ProxyConfig describes the HTTP proxy used to reach the registration API",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"url": {
+						SchemaProps: spec.SchemaProps{
+							Description: "URL of the proxy, e.g. http://proxy.example.com:3128",
+							Default:     "",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"credentialsSecretRef": {
+						SchemaProps: spec.SchemaProps{
+							Description: "CredentialsSecretRef points to a basic-auth Secret (`username` and `password`) used to authenticate with the proxy",
+							Ref:         ref("k8s.io/api/core/v1.SecretReference"),
+						},
+					},
+					"noProxy": {
+						VendorExtensible: spec.VendorExtensible{
+							Extensions: spec.Extensions{
+								"x-kubernetes-list-type": "atomic",
+							},
+						},
+						SchemaProps: spec.SchemaProps{
+							Description: "NoProxy lists hosts, domains, IPs or CIDRs that are reached directly, using the NO_PROXY env var format",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: "",
+										Type:    []string{"string"},
+										Format:  "",
+									},
+								},
+							},
+						},
+					},
+				},
+				Required: []string{"url"},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/api/core/v1.SecretReference"},
+	}
+}
+
+func schema_pkg_apis_scccattleio_v1_RecoveryPolicy(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "RecoveryPolicy re-validates a failed registration on a schedule, and right away when a Secret it references changes. Offline registrations ignore it; they recover when their offline certificate is replaced.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"interval": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Interval between recovery attempts while the registration is failed, one hour when unset",
+							Ref:         ref(v1.Duration{}.OpenAPIModelName()),
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			v1.Duration{}.OpenAPIModelName()},
+	}
+}
+
+func schema_pkg_apis_scccattleio_v1_RecoveryStatus(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "RecoveryStatus tracks the recovery attempts made since a registration last synced with SCC successfully",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"attempts": {
+						SchemaProps: spec.SchemaProps{
+							Type:   []string{"integer"},
+							Format: "int32",
+						},
+					},
+					"lastAttemptTS": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref(v1.Time{}.OpenAPIModelName()),
+						},
+					},
+					"relatedSecretsFingerprint": {
+						SchemaProps: spec.SchemaProps{
+							Description: "RelatedSecretsFingerprint identifies the referenced Secrets as they were when the failure was last reviewed",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			v1.Time{}.OpenAPIModelName()},
 	}
 }
 
@@ -201,14 +605,27 @@ func schema_pkg_apis_scccattleio_v1_RegistrationRequest(ref common.ReferenceCall
 					},
 					"registrationAPICertificateSecretRef": {
 						SchemaProps: spec.SchemaProps{
-							Ref: ref("k8s.io/api/core/v1.SecretReference"),
+							Description: "RegistrationAPICertificateSecretRef points to a Secret holding a CA certificate (`registrationCACert` or `ca.crt`) to trust when connecting to RegistrationAPIUrl, e.g. an RMT or SCC proxy behind an internal CA.",
+							Ref:         ref("k8s.io/api/core/v1.SecretReference"),
+						},
+					},
+					"instanceDataSecretRef": {
+						SchemaProps: spec.SchemaProps{
+							Description: "InstanceDataSecretRef points to a Secret holding the cloud instance identity document (`instanceData`) sent when announcing a pay-as-you-go registration.",
+							Ref:         ref("k8s.io/api/core/v1.SecretReference"),
+						},
+					},
+					"proxy": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Proxy routes this registration's traffic through an HTTP proxy instead of the process-wide proxy env vars",
+							Ref:         ref("github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.ProxyConfig"),
 						},
 					},
 				},
 			},
 		},
 		Dependencies: []string{
-			"k8s.io/api/core/v1.SecretReference"},
+			"github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.ProxyConfig", "k8s.io/api/core/v1.SecretReference"},
 	}
 }
 
@@ -242,12 +659,31 @@ func schema_pkg_apis_scccattleio_v1_RegistrationSpec(ref common.ReferenceCallbac
 							Format: "",
 						},
 					},
+					"suspend": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Suspend stops all keepalives, activations and syncs with SCC without deregistering; a pending SyncNow runs once resumed",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"recovery": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Recovery opts a failed registration into being re-validated with SCC, instead of staying failed until it is recreated",
+							Ref:         ref("github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.RecoveryPolicy"),
+						},
+					},
+					"deregistration": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Deregistration decides what happens to the SCC system when the registration is deleted, the operator default when unset",
+							Ref:         ref("github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.DeregistrationPolicy"),
+						},
+					},
 				},
 				Required: []string{"mode"},
 			},
 		},
 		Dependencies: []string{
-			"github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.RegistrationRequest", "k8s.io/api/core/v1.SecretReference"},
+			"github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.DeregistrationPolicy", "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.RecoveryPolicy", "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.RegistrationRequest", "k8s.io/api/core/v1.SecretReference"},
 	}
 }
 
@@ -262,6 +698,13 @@ func schema_pkg_apis_scccattleio_v1_RegistrationStatus(ref common.ReferenceCallb
 							Ref: ref("github.com/rancher/wrangler/v3/pkg/genericcondition.GenericCondition"),
 						},
 					},
+					"observedGeneration": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ObservedGeneration is the spec generation the last sync with SCC was made for",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
 					"conditions": {
 						VendorExtensible: spec.VendorExtensible{
 							Extensions: spec.Extensions{
@@ -307,6 +750,24 @@ func schema_pkg_apis_scccattleio_v1_RegistrationStatus(ref common.ReferenceCallb
 							Ref: ref(v1.Time{}.OpenAPIModelName()),
 						},
 					},
+					"activations": {
+						VendorExtensible: spec.VendorExtensible{
+							Extensions: spec.Extensions{
+								"x-kubernetes-list-type": "atomic",
+							},
+						},
+						SchemaProps: spec.SchemaProps{
+							Type: []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: map[string]interface{}{},
+										Ref:     ref("github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.ProductActivation"),
+									},
+								},
+							},
+						},
+					},
 					"activationStatus": {
 						SchemaProps: spec.SchemaProps{
 							Default: map[string]interface{}{},
@@ -323,11 +784,119 @@ func schema_pkg_apis_scccattleio_v1_RegistrationStatus(ref common.ReferenceCallb
 							Ref: ref("k8s.io/api/core/v1.SecretReference"),
 						},
 					},
+					"offlineCertificate": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref("github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.OfflineCertificateStatus"),
+						},
+					},
+					"payAsYouGo": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref("github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.PayAsYouGoStatus"),
+						},
+					},
+					"syncHistory": {
+						VendorExtensible: spec.VendorExtensible{
+							Extensions: spec.Extensions{
+								"x-kubernetes-list-type": "atomic",
+							},
+						},
+						SchemaProps: spec.SchemaProps{
+							Description: "SyncHistory lists the most recent sync attempts with SCC, oldest first",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: map[string]interface{}{},
+										Ref:     ref("github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.SyncAttempt"),
+									},
+								},
+							},
+						},
+					},
+					"consecutiveFailures": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ConsecutiveFailures counts the recoverable SCC errors since the last successful sync",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"nextRetryAt": {
+						SchemaProps: spec.SchemaProps{
+							Description: "NextRetryAt is when the operator will retry after a recoverable SCC error",
+							Ref:         ref(v1.Time{}.OpenAPIModelName()),
+						},
+					},
+					"recovery": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Recovery tracks the automatic recovery of a failed registration with a RecoveryPolicy",
+							Ref:         ref("github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.RecoveryStatus"),
+						},
+					},
+					"lastAction": {
+						SchemaProps: spec.SchemaProps{
+							Description: "LastAction is the result of the last action requested with the `scc.cattle.io/action` annotation",
+							Ref:         ref("github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.ActionResult"),
+						},
+					},
+					"deregistration": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Deregistration tracks the attempts to deregister the system from SCC while the Registration is being deleted",
+							Ref:         ref("github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.DeregistrationStatus"),
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.ActionResult", "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.DeregistrationStatus", "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.OfflineCertificateStatus", "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.PayAsYouGoStatus", "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.ProductActivation", "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.RecoveryStatus", "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.SyncAttempt", "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.SystemActivationState", "github.com/rancher/wrangler/v3/pkg/genericcondition.GenericCondition", "k8s.io/api/core/v1.SecretReference", v1.Time{}.OpenAPIModelName()},
+	}
+}
+
+func schema_pkg_apis_scccattleio_v1_SyncAttempt(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "SyncAttempt records a single register, activate or keepalive call made to SCC",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"timestamp": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref(v1.Time{}.OpenAPIModelName()),
+						},
+					},
+					"phase": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Phase is the lifecycle phase of the attempt, e.g. `Registration/Main` or `Keepalive`",
+							Default:     "",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"outcome": {
+						SchemaProps: spec.SchemaProps{
+							Default: "",
+							Type:    []string{"string"},
+							Format:  "",
+						},
+					},
+					"duration": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref(v1.Duration{}.OpenAPIModelName()),
+						},
+					},
+					"errorSummary": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ErrorSummary is a short description of why a failed attempt failed",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
 				},
+				Required: []string{"timestamp", "phase", "outcome", "duration"},
 			},
 		},
 		Dependencies: []string{
-			"github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1.SystemActivationState", "github.com/rancher/wrangler/v3/pkg/genericcondition.GenericCondition", "k8s.io/api/core/v1.SecretReference", v1.Time{}.OpenAPIModelName()},
+			v1.Duration{}.OpenAPIModelName(), v1.Time{}.OpenAPIModelName()},
 	}
 }
 
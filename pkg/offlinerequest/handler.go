@@ -0,0 +1,207 @@
+/*
+Package offlinerequest serves the offline registration request of each Registration over HTTPS.
+
+Air-gapped users download the request from here and upload it to SCC, instead of decoding the Secret holding it.
+Callers authenticate with a Kubernetes bearer token and must be allowed to `get` the `registrations/offlinerequest`
+subresource of the Registration, e.g.:
+
+	curl --cacert ca.crt -H "Authorization: Bearer $TOKEN" https://scc-operator-webhook.cattle-scc-system.svc:8443/v1/offline-requests/<registration>
+
+The endpoint is off unless --offline-request-endpoint-enabled is set.
+It listens on --offline-request-port with the webhook serving certificate: either the one in --webhook-cert-dir,
+or the self-signed one stored in the scc-operator-webhook-tls Secret (its ca.crt), issued for the webhook Service.
+
+To check callers, the operator's ServiceAccount needs a ClusterRole allowing it to create token and access reviews:
+
+	rules:
+	  - apiGroups: ["authentication.k8s.io"]
+	    resources: ["tokenreviews"]
+	    verbs: ["create"]
+	  - apiGroups: ["authorization.k8s.io"]
+	    resources: ["subjectaccessreviews"]
+	    verbs: ["create"]
+
+Without them every request fails with 503 Service Unavailable, and the operator logs which review it may not create.
+*/
+package offlinerequest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/rancher/scc-operator/internal/consts"
+	"github.com/rancher/scc-operator/internal/logging"
+	"github.com/rancher/scc-operator/internal/suseconnect/offline"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+	registrationControllers "github.com/rancher/scc-operator/pkg/generated/controllers/scc.cattle.io/v1"
+)
+
+const (
+	// PathPrefix is followed by the name of the Registration whose offline request is served
+	PathPrefix = "/v1/offline-requests/"
+	// Subresource is the Registration subresource callers must be allowed to `get`
+	Subresource = "offlinerequest"
+
+	missingRBACMessage = "the operator's ServiceAccount may not create %s, so no offline request can be served until a ClusterRole grants it: %v"
+)
+
+type requestHandler struct {
+	log             logging.StructuredLogger
+	k8sClient       kubernetes.Interface
+	registrations   registrationControllers.RegistrationClient
+	systemNamespace string
+}
+
+// NewHandler prepares the http.Handler serving offline requests under PathPrefix
+func NewHandler(k8sClient kubernetes.Interface, registrations registrationControllers.RegistrationClient, systemNamespace string) http.Handler {
+	return &requestHandler{
+		log:             logging.NewComponentLogger("offline-request-server"),
+		k8sClient:       k8sClient,
+		registrations:   registrations,
+		systemNamespace: systemNamespace,
+	}
+}
+
+func (h *requestHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, PathPrefix)
+	if name == "" || strings.Contains(name, "/") {
+		http.Error(w, "path must name a single Registration", http.StatusNotFound)
+		return
+	}
+
+	user, status := h.authenticate(r)
+	if status != http.StatusOK {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	if status = h.authorize(r.Context(), user, name); status != http.StatusOK {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	request, err := h.offlineRequest(r.Context(), name)
+	if err != nil {
+		h.log.Debugf("cannot serve offline request of registration %s to %s: %v", name, user.Username, err)
+		if apierrors.IsNotFound(err) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+"-offline-request.json"))
+	w.Header().Set("ETag", fmt.Sprintf("%q", offline.RequestChecksum(request)))
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err := w.Write(request); err != nil {
+		h.log.Errorf("failed to write offline request of registration %s: %v", name, err)
+	}
+}
+
+// authenticate resolves the bearer token of the request to a user with a TokenReview
+func (h *requestHandler) authenticate(r *http.Request) (authenticationv1.UserInfo, int) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || strings.TrimSpace(token) == "" {
+		return authenticationv1.UserInfo{}, http.StatusUnauthorized
+	}
+
+	review, err := h.k8sClient.AuthenticationV1().TokenReviews().Create(r.Context(), &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: strings.TrimSpace(token)},
+	}, metav1.CreateOptions{})
+	if apierrors.IsForbidden(err) {
+		h.log.Errorf(missingRBACMessage, "tokenreviews.authentication.k8s.io", err)
+		return authenticationv1.UserInfo{}, http.StatusServiceUnavailable
+	}
+	if err != nil {
+		h.log.Errorf("failed to review token: %v", err)
+		return authenticationv1.UserInfo{}, http.StatusInternalServerError
+	}
+	if !review.Status.Authenticated {
+		return authenticationv1.UserInfo{}, http.StatusUnauthorized
+	}
+
+	return review.Status.User, http.StatusOK
+}
+
+// authorize checks with a SubjectAccessReview that the user may get the offline request of the Registration
+func (h *requestHandler) authorize(ctx context.Context, user authenticationv1.UserInfo, name string) int {
+	extra := make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+	for key, values := range user.Extra {
+		extra[key] = authorizationv1.ExtraValue(values)
+	}
+
+	review, err := h.k8sClient.AuthorizationV1().SubjectAccessReviews().Create(ctx, &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   user.Username,
+			UID:    user.UID,
+			Groups: user.Groups,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Verb:        "get",
+				Group:       v1.SchemeGroupVersion.Group,
+				Resource:    "registrations",
+				Subresource: Subresource,
+				Name:        name,
+			},
+		},
+	}, metav1.CreateOptions{})
+	if apierrors.IsForbidden(err) {
+		h.log.Errorf(missingRBACMessage, "subjectaccessreviews.authorization.k8s.io", err)
+		return http.StatusServiceUnavailable
+	}
+	if err != nil {
+		h.log.Errorf("failed to review access of %s: %v", user.Username, err)
+		return http.StatusInternalServerError
+	}
+	if !review.Status.Allowed {
+		return http.StatusForbidden
+	}
+
+	return http.StatusOK
+}
+
+// offlineRequest reads the current offline request JSON referenced by the Registration
+func (h *requestHandler) offlineRequest(ctx context.Context, name string) ([]byte, error) {
+	registration, err := h.registrations.Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	notFound := apierrors.NewNotFound(v1.Resource("registrations/"+Subresource), name)
+	ref := registration.Status.OfflineRegistrationRequest
+	// Only Secrets the operator writes itself are served, whatever the status points to
+	if registration.Spec.Mode != v1.RegistrationModeOffline || ref == nil ||
+		ref.Namespace != h.systemNamespace || !strings.HasPrefix(ref.Name, consts.OfflineRequestSecretNamePrefix) {
+		return nil, notFound
+	}
+
+	secret, err := h.k8sClient.CoreV1().Secrets(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if consts.SecretRole(secret.Labels[consts.LabelSccSecretRole]) != consts.OfflineRequestRole {
+		return nil, notFound
+	}
+	request := secret.Data[consts.SecretKeyOfflineRegRequest]
+	if len(request) == 0 {
+		return nil, notFound
+	}
+
+	return request, nil
+}
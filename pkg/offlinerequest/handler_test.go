@@ -0,0 +1,163 @@
+package offlinerequest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/rancher/scc-operator/internal/consts"
+	"github.com/rancher/scc-operator/internal/suseconnect/offline"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+)
+
+const (
+	testNamespace    = consts.DefaultSCCNamespace
+	testRegistration = "scc-registration-abc"
+	testRequest      = `{"productTriplet":"rancher/2.12/x86_64"}`
+	validToken       = "valid-token"
+	otherToken       = "other-token"
+	allowedUser      = "air-gap-admin"
+)
+
+// newTestServer serves a Registration whose offline request is ready; only allowedUser may read it
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	k8sClient := k8sfake.NewClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      consts.OfflineRequestSecretName("abc"),
+			Namespace: testNamespace,
+			Labels:    map[string]string{consts.LabelSccSecretRole: string(consts.OfflineRequestRole)},
+		},
+		Data: map[string][]byte{consts.SecretKeyOfflineRegRequest: []byte(testRequest)},
+	})
+	k8sClient.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		switch review.Spec.Token {
+		case validToken:
+			review.Status = authenticationv1.TokenReviewStatus{Authenticated: true, User: authenticationv1.UserInfo{Username: allowedUser}}
+		case otherToken:
+			review.Status = authenticationv1.TokenReviewStatus{Authenticated: true, User: authenticationv1.UserInfo{Username: "viewer"}}
+		}
+		return true, review, nil
+	})
+	k8sClient.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		attributes := review.Spec.ResourceAttributes
+		review.Status.Allowed = review.Spec.User == allowedUser &&
+			attributes.Verb == "get" && attributes.Resource == "registrations" && attributes.Subresource == Subresource
+		return true, review, nil
+	})
+
+	registrations := fake.NewMockNonNamespacedControllerInterface[*v1.Registration, *v1.RegistrationList](gomock.NewController(t))
+	registrations.EXPECT().Get(gomock.Any(), gomock.Any()).DoAndReturn(func(name string, _ metav1.GetOptions) (*v1.Registration, error) {
+		if name != testRegistration {
+			return nil, apierrors.NewNotFound(v1.Resource("registrations"), name)
+		}
+		return &v1.Registration{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       v1.RegistrationSpec{Mode: v1.RegistrationModeOffline},
+			Status: v1.RegistrationStatus{
+				OfflineRegistrationRequest: &corev1.SecretReference{Name: consts.OfflineRequestSecretName("abc"), Namespace: testNamespace},
+			},
+		}, nil
+	}).AnyTimes()
+
+	server := httptest.NewServer(NewHandler(k8sClient, registrations, testNamespace))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func get(t *testing.T, server *httptest.Server, registration, token string) (*http.Response, string) {
+	t.Helper()
+
+	request, err := http.NewRequestWithContext(t.Context(), http.MethodGet, server.URL+PathPrefix+registration, nil)
+	require.NoError(t, err)
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	response, err := server.Client().Do(request)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+
+	return response, string(body)
+}
+
+func TestServesOfflineRequest(t *testing.T) {
+	server := newTestServer(t)
+
+	response, body := get(t, server, testRegistration, validToken)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, testRequest, body)
+	assert.Equal(t, "application/json", response.Header.Get("Content-Type"))
+	assert.Equal(t, `"`+offline.RequestChecksum([]byte(testRequest))+`"`, response.Header.Get("ETag"))
+}
+
+func TestRejectsUnauthenticatedCallers(t *testing.T) {
+	server := newTestServer(t)
+
+	response, _ := get(t, server, testRegistration, "")
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+
+	response, _ = get(t, server, testRegistration, "expired-token")
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+}
+
+func TestRejectsUnauthorizedUsers(t *testing.T) {
+	server := newTestServer(t)
+
+	response, body := get(t, server, testRegistration, otherToken)
+	assert.Equal(t, http.StatusForbidden, response.StatusCode)
+	assert.NotContains(t, body, testRequest)
+}
+
+func TestUnknownRegistration(t *testing.T) {
+	server := newTestServer(t)
+
+	response, _ := get(t, server, "scc-registration-unknown", validToken)
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+
+	response, _ = get(t, server, testRegistration+"/extra", validToken)
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+}
+
+func TestReportsMissingReviewRBAC(t *testing.T) {
+	k8sClient := k8sfake.NewClientset()
+	k8sClient.PrependReactor("create", "tokenreviews", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(authenticationv1.Resource("tokenreviews"), "", nil)
+	})
+	server := httptest.NewServer(NewHandler(k8sClient, nil, testNamespace))
+	t.Cleanup(server.Close)
+
+	response, body := get(t, server, testRegistration, validToken)
+	assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+	assert.NotContains(t, body, testRequest)
+
+	k8sClient.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		review.Status = authenticationv1.TokenReviewStatus{Authenticated: true, User: authenticationv1.UserInfo{Username: allowedUser}}
+		return true, review, nil
+	})
+	k8sClient.PrependReactor("create", "subjectaccessreviews", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(authorizationv1.Resource("subjectaccessreviews"), "", nil)
+	})
+
+	response, _ = get(t, server, testRegistration, validToken)
+	assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+}
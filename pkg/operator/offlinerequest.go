@@ -0,0 +1,26 @@
+package operator
+
+import (
+	"net/http"
+
+	"github.com/rancher/scc-operator/pkg/offlinerequest"
+	"github.com/rancher/scc-operator/pkg/webhook"
+)
+
+// StartOfflineRequestEndpoint serves offline registration requests until the operator stops; it is only started when enabled.
+// Callers send bearer tokens, so it is only served over TLS with the webhook serving certificate, on every replica.
+func (s *SccStarter) StartOfflineRequestEndpoint() error {
+	servingCert, err := s.webhookServingCert()
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(offlinerequest.PathPrefix, offlinerequest.NewHandler(s.wrangler.K8sClient, s.wrangler.SCC.Registration(), s.options.SystemNamespace()))
+	server, err := webhook.NewTLSServer("offline request server", s.options.OperatorSettings.OfflineRequestPort, servingCert, mux)
+	if err != nil {
+		return err
+	}
+
+	return server.Start(s.context)
+}
@@ -7,9 +7,11 @@ import (
 	corev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
 	"github.com/rancher/wrangler/v3/pkg/ratelimit"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 
 	"github.com/rancher/scc-operator/internal/initializer"
 	"github.com/rancher/scc-operator/internal/logging"
+	"github.com/rancher/scc-operator/internal/suseconnect"
 	"github.com/rancher/scc-operator/internal/types"
 	"github.com/rancher/scc-operator/internal/wrangler"
 	"github.com/rancher/scc-operator/pkg/crds"
@@ -22,6 +24,7 @@ type SccOperator struct {
 	sccResourceFactory *scc.Factory
 	secrets            corev1.SecretController
 	options            *types.RunOptions
+	eventRecorder      record.EventRecorder
 }
 
 func New(
@@ -37,6 +40,7 @@ func New(
 	}
 
 	initializer.OperatorName.Set(options.OperatorName)
+	suseconnect.SetRateLimit(options.OperatorSettings.RateLimit.RequestsPerSecond, options.OperatorSettings.RateLimit.Burst)
 
 	kubeconfig.RateLimiter = ratelimit.None
 	starterLog.Debugf("Creating WranglerMiniContext with lease namespace: %s", options.OperatorSettings.LeaseNamespace)
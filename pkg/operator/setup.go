@@ -11,6 +11,8 @@ import (
 	k8sv1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	typedv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
 
 	"github.com/rancher/scc-operator/internal/logging"
@@ -67,10 +69,14 @@ func setup(
 		return nil, fmt.Errorf("invalid UUID format: rancherUUID=%s, kubeSystemNS.UID=%s", rancherUUID, string(kubeSystemNS.UID))
 	}
 
+	eventBroadcaster := record.NewBroadcaster(record.WithContext(ctx))
+	eventBroadcaster.StartRecordingToSink(&typedv1.EventSinkImpl{Interface: wContext.K8sClient.CoreV1().Events("")})
+
 	return &SccOperator{
 		log:                logger,
 		options:            options,
 		sccResourceFactory: sccResources,
 		secrets:            wContext.Core.Secret(),
+		eventRecorder:      eventBroadcaster.NewRecorder(wrangler.Scheme, k8sv1.EventSource{Component: options.OperatorName}),
 	}, nil
 }
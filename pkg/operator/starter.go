@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rancher/scc-operator/internal/consts"
 	"github.com/rancher/scc-operator/internal/rancher"
 	"github.com/rancher/scc-operator/internal/telemetry"
@@ -105,7 +106,9 @@ func (s *SccStarter) SetupControllers() error {
 			&s.options,
 			initOperator.sccResourceFactory.Scc().V1().Registration(),
 			s.wrangler.Secrets,
+			s.wrangler.Core.ConfigMap(),
 			s.wrangler.Settings,
+			initOperator.eventRecorder,
 		)
 
 		if startErr := start.All(s.context, consts.OperatorWorkerThreads, initOperator.sccResourceFactory); startErr != nil {
@@ -145,5 +148,7 @@ func (s *SccStarter) StartMetricsAndHealthEndpoint() {
 		}
 	})
 
+	http.Handle("/metrics", promhttp.Handler())
+
 	http.ListenAndServe(":8080", nil)
 }
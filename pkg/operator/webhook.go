@@ -0,0 +1,56 @@
+package operator
+
+import (
+	"github.com/rancher/scc-operator/pkg/webhook"
+)
+
+// StartValidationWebhook registers the validating webhook with the API server and serves it until the operator stops.
+// Unlike the controllers this runs on every replica, so admission requests are answered regardless of leadership.
+func (s *SccStarter) StartValidationWebhook() error {
+	settings := s.options.OperatorSettings
+	systemNamespace := s.options.SystemNamespace()
+
+	servingCert, err := s.webhookServingCert()
+	if err != nil {
+		return err
+	}
+
+	configErr := webhook.EnsureConfiguration(
+		s.context,
+		s.wrangler.K8sClient.AdmissionregistrationV1().ValidatingWebhookConfigurations(),
+		webhook.ConfigurationOptions{
+			ServiceName:     settings.Webhook.ServiceName,
+			Namespace:       systemNamespace,
+			Port:            int32(settings.Webhook.Port),
+			CABundle:        servingCert.CA(),
+			OperatorName:    s.options.OperatorName,
+			SystemNamespace: systemNamespace,
+		},
+	)
+	if configErr != nil {
+		return configErr
+	}
+
+	server, err := webhook.NewServer(settings.Webhook.Port, servingCert, systemNamespace)
+	if err != nil {
+		return err
+	}
+
+	return server.Start(s.context)
+}
+
+// webhookServingCert loads the certificate from the configured directory, or the self-signed one kept in the webhook TLS Secret
+func (s *SccStarter) webhookServingCert() (*webhook.ServingCert, error) {
+	settings := s.options.OperatorSettings
+	if settings.Webhook.CertDir != "" {
+		return webhook.LoadServingCertFromDir(settings.Webhook.CertDir)
+	}
+
+	systemNamespace := s.options.SystemNamespace()
+	return webhook.EnsureServingCert(
+		s.context,
+		s.wrangler.K8sClient.CoreV1().Secrets(systemNamespace),
+		settings.Webhook.ServiceName,
+		systemNamespace,
+	)
+}
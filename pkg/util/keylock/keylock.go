@@ -0,0 +1,50 @@
+// Package keylock serializes work per key, so work on one key never waits on work for another.
+package keylock
+
+import "sync"
+
+// Locker is a set of mutexes keyed by name; the zero value is ready to use.
+type Locker struct {
+	mu    sync.Mutex
+	locks map[string]*refCountedMutex
+}
+
+type refCountedMutex struct {
+	sync.Mutex
+	refs int
+}
+
+// Lock blocks until the key is free and returns the function that releases it.
+// Mutexes are dropped once no caller holds or waits on them, so the Locker does not grow with every key it has seen.
+func (l *Locker) Lock(key string) (unlock func()) {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = map[string]*refCountedMutex{}
+	}
+	keyLock, ok := l.locks[key]
+	if !ok {
+		keyLock = &refCountedMutex{}
+		l.locks[key] = keyLock
+	}
+	keyLock.refs++
+	l.mu.Unlock()
+
+	keyLock.Lock()
+	return func() {
+		keyLock.Unlock()
+
+		l.mu.Lock()
+		keyLock.refs--
+		if keyLock.refs == 0 {
+			delete(l.locks, key)
+		}
+		l.mu.Unlock()
+	}
+}
+
+// Len is the number of keys currently held or waited on.
+func (l *Locker) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.locks)
+}
@@ -0,0 +1,58 @@
+package keylock
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockerSerializesSameKey(t *testing.T) {
+	t.Parallel()
+	var locker Locker
+	var inside, maxInside atomic.Int32
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock := locker.Lock("same")
+			defer unlock()
+
+			current := inside.Add(1)
+			if current > maxInside.Load() {
+				maxInside.Store(current)
+			}
+			time.Sleep(time.Millisecond)
+			inside.Add(-1)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), maxInside.Load())
+	assert.Equal(t, 0, locker.Len())
+}
+
+func TestLockerDoesNotBlockOtherKeys(t *testing.T) {
+	t.Parallel()
+	var locker Locker
+	unlockFirst := locker.Lock("first")
+	defer unlockFirst()
+
+	acquired := make(chan struct{})
+	go func() {
+		unlock := locker.Lock("second")
+		defer unlock()
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("a held key blocked an unrelated key")
+	}
+	assert.Equal(t, 1, locker.Len())
+}
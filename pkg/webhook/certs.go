@@ -0,0 +1,159 @@
+package webhook
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	certutil "k8s.io/client-go/util/cert"
+
+	"github.com/rancher/scc-operator/internal/consts"
+)
+
+const (
+	// certRenewBefore is how long before expiry a stored serving certificate is replaced
+	certRenewBefore = 30 * 24 * time.Hour
+	// storeCertAttempts bounds how often a replica re-reads the Secret after losing a write to another replica
+	storeCertAttempts = 5
+)
+
+// ServingCert holds the PEM encoded serving certificate (and its CA) used by the webhook
+type ServingCert struct {
+	CertPEM []byte
+	KeyPEM  []byte
+	// CABundle is handed to the API server to verify the webhook; it defaults to CertPEM when unset
+	CABundle []byte
+}
+
+// CA returns the PEM bundle the API server should trust for this certificate
+func (s *ServingCert) CA() []byte {
+	if len(s.CABundle) > 0 {
+		return s.CABundle
+	}
+
+	return s.CertPEM
+}
+
+// TLSCertificate converts the PEM pair into a certificate usable by a tls.Config
+func (s *ServingCert) TLSCertificate() (tls.Certificate, error) {
+	return tls.X509KeyPair(s.CertPEM, s.KeyPEM)
+}
+
+// ServiceHostname is the in-cluster DNS name the API server uses to reach the webhook service
+func ServiceHostname(serviceName, namespace string) string {
+	return fmt.Sprintf("%s.%s.svc", serviceName, namespace)
+}
+
+// EnsureServingCert loads the webhook serving certificate from its Secret, creating a new self-signed one when
+// the Secret is missing or the stored certificate is invalid or close to expiring.
+// Every replica runs this, so only one of them gets to write a new certificate; the others adopt the stored one.
+func EnsureServingCert(ctx context.Context, secrets typedcorev1.SecretInterface, serviceName, namespace string) (*ServingCert, error) {
+	hostname := ServiceHostname(serviceName, namespace)
+
+	var err error
+	for attempt := 0; attempt < storeCertAttempts; attempt++ {
+		var existing *corev1.Secret
+		existing, err = secrets.Get(ctx, consts.WebhookTLSSecretName, metav1.GetOptions{})
+		notFound := apierrors.IsNotFound(err)
+		if err != nil && !notFound {
+			return nil, fmt.Errorf("failed to get webhook TLS secret: %w", err)
+		}
+		if !notFound {
+			if stored := servingCertFromSecret(existing); certIsUsable(stored, hostname) {
+				return stored, nil
+			}
+		}
+
+		certPEM, keyPEM, genErr := certutil.GenerateSelfSignedCertKey(
+			hostname,
+			nil,
+			[]string{serviceName, fmt.Sprintf("%s.%s", serviceName, namespace), hostname},
+		)
+		if genErr != nil {
+			return nil, fmt.Errorf("failed to generate webhook serving certificate: %w", genErr)
+		}
+		certData := map[string][]byte{
+			corev1.TLSCertKey:              certPEM,
+			corev1.TLSPrivateKeyKey:        keyPEM,
+			corev1.ServiceAccountRootCAKey: certPEM,
+		}
+
+		var written *corev1.Secret
+		if notFound {
+			written, err = secrets.Create(ctx, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      consts.WebhookTLSSecretName,
+					Namespace: namespace,
+				},
+				Type: corev1.SecretTypeTLS,
+				Data: certData,
+			}, metav1.CreateOptions{})
+		} else {
+			// The resourceVersion of the Secret we read makes this fail with a Conflict when another replica wrote first
+			updated := existing.DeepCopy()
+			updated.Data = certData
+			written, err = secrets.Update(ctx, updated, metav1.UpdateOptions{})
+		}
+		if apierrors.IsAlreadyExists(err) || apierrors.IsConflict(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to store webhook TLS secret: %w", err)
+		}
+
+		return servingCertFromSecret(written), nil
+	}
+
+	return nil, fmt.Errorf("failed to store webhook TLS secret after %d attempts: %w", storeCertAttempts, err)
+}
+
+// servingCertFromSecret reads the pair and CA stored in the webhook TLS Secret
+func servingCertFromSecret(secret *corev1.Secret) *ServingCert {
+	return &ServingCert{
+		CertPEM:  secret.Data[corev1.TLSCertKey],
+		KeyPEM:   secret.Data[corev1.TLSPrivateKeyKey],
+		CABundle: secret.Data[corev1.ServiceAccountRootCAKey],
+	}
+}
+
+// LoadServingCertFromDir reads a user provided tls.crt/tls.key pair, along with ca.crt when it exists
+func LoadServingCertFromDir(certDir string) (*ServingCert, error) {
+	certPEM, err := os.ReadFile(filepath.Join(certDir, corev1.TLSCertKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webhook certificate from %s: %w", certDir, err)
+	}
+	keyPEM, err := os.ReadFile(filepath.Join(certDir, corev1.TLSPrivateKeyKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webhook key from %s: %w", certDir, err)
+	}
+	caPEM, err := os.ReadFile(filepath.Join(certDir, corev1.ServiceAccountRootCAKey))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to load webhook CA from %s: %w", certDir, err)
+	}
+
+	return &ServingCert{CertPEM: certPEM, KeyPEM: keyPEM, CABundle: caPEM}, nil
+}
+
+func certIsUsable(servingCert *ServingCert, hostname string) bool {
+	keyPair, err := servingCert.TLSCertificate()
+	if err != nil || len(keyPair.Certificate) == 0 {
+		return false
+	}
+	leaf, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		return false
+	}
+	if leaf.VerifyHostname(hostname) != nil {
+		return false
+	}
+
+	return time.Now().Add(certRenewBefore).Before(leaf.NotAfter)
+}
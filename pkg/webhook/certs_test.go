@@ -0,0 +1,103 @@
+package webhook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/rancher/scc-operator/internal/consts"
+)
+
+func TestEnsureServingCertCreatesAndReuses(t *testing.T) {
+	secrets := fake.NewClientset().CoreV1().Secrets(testNamespace)
+
+	first, err := EnsureServingCert(t.Context(), secrets, consts.DefaultWebhookServiceName, testNamespace)
+	require.NoError(t, err)
+
+	stored, err := secrets.Get(t.Context(), consts.WebhookTLSSecretName, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, corev1.SecretTypeTLS, stored.Type)
+	assert.Equal(t, first.CertPEM, stored.Data[corev1.TLSCertKey])
+	assert.True(t, certIsUsable(first, ServiceHostname(consts.DefaultWebhookServiceName, testNamespace)))
+
+	second, err := EnsureServingCert(t.Context(), secrets, consts.DefaultWebhookServiceName, testNamespace)
+	require.NoError(t, err)
+	assert.Equal(t, first.CertPEM, second.CertPEM)
+}
+
+func TestEnsureServingCertReplacesMismatchedHost(t *testing.T) {
+	secrets := fake.NewClientset().CoreV1().Secrets(testNamespace)
+
+	original, err := EnsureServingCert(t.Context(), secrets, "old-service", testNamespace)
+	require.NoError(t, err)
+
+	replaced, err := EnsureServingCert(t.Context(), secrets, consts.DefaultWebhookServiceName, testNamespace)
+	require.NoError(t, err)
+	assert.NotEqual(t, original.CertPEM, replaced.CertPEM)
+
+	stored, err := secrets.Get(t.Context(), consts.WebhookTLSSecretName, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, replaced.CertPEM, stored.Data[corev1.TLSCertKey])
+}
+
+// racingReplica stores its own certificate right before the operation under test, as another replica would
+func racingReplica(t *testing.T, client *fake.Clientset, verb string) *corev1.Secret {
+	t.Helper()
+	otherReplicaSecrets := fake.NewClientset().CoreV1().Secrets(testNamespace)
+	_, err := EnsureServingCert(t.Context(), otherReplicaSecrets, consts.DefaultWebhookServiceName, testNamespace)
+	require.NoError(t, err)
+	winner, err := otherReplicaSecrets.Get(t.Context(), consts.WebhookTLSSecretName, metav1.GetOptions{})
+	require.NoError(t, err)
+
+	raced := false
+	client.PrependReactor(verb, "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if raced {
+			return false, nil, nil
+		}
+		raced = true
+		stored := winner.DeepCopy()
+		if verb == "create" {
+			require.NoError(t, client.Tracker().Add(stored))
+			return true, nil, apierrors.NewAlreadyExists(corev1.Resource("secrets"), stored.Name)
+		}
+		require.NoError(t, client.Tracker().Update(corev1.SchemeGroupVersion.WithResource("secrets"), stored, testNamespace))
+		return true, nil, apierrors.NewConflict(corev1.Resource("secrets"), stored.Name, nil)
+	})
+
+	return winner
+}
+
+func TestEnsureServingCertAdoptsCreatedByOtherReplica(t *testing.T) {
+	client := fake.NewClientset()
+	winner := racingReplica(t, client, "create")
+
+	adopted, err := EnsureServingCert(t.Context(), client.CoreV1().Secrets(testNamespace), consts.DefaultWebhookServiceName, testNamespace)
+	require.NoError(t, err)
+	assert.Equal(t, winner.Data[corev1.TLSCertKey], adopted.CertPEM)
+	assert.Equal(t, winner.Data[corev1.TLSPrivateKeyKey], adopted.KeyPEM)
+	assert.Equal(t, winner.Data[corev1.ServiceAccountRootCAKey], adopted.CA())
+}
+
+func TestEnsureServingCertAdoptsRenewedByOtherReplica(t *testing.T) {
+	client := fake.NewClientset()
+	secrets := client.CoreV1().Secrets(testNamespace)
+	_, err := EnsureServingCert(t.Context(), secrets, "old-service", testNamespace)
+	require.NoError(t, err)
+	winner := racingReplica(t, client, "update")
+
+	adopted, err := EnsureServingCert(t.Context(), secrets, consts.DefaultWebhookServiceName, testNamespace)
+	require.NoError(t, err)
+	assert.Equal(t, winner.Data[corev1.TLSCertKey], adopted.CertPEM)
+	assert.Equal(t, winner.Data[corev1.ServiceAccountRootCAKey], adopted.CA())
+
+	stored, err := secrets.Get(t.Context(), consts.WebhookTLSSecretName, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, winner.Data, stored.Data)
+}
@@ -0,0 +1,125 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	typedadmissionv1 "k8s.io/client-go/kubernetes/typed/admissionregistration/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/rancher/scc-operator/internal/consts"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+)
+
+// ConfigurationOptions describe how the API server should reach the webhook service
+type ConfigurationOptions struct {
+	ServiceName     string
+	Namespace       string
+	Port            int32
+	CABundle        []byte
+	OperatorName    string
+	SystemNamespace string
+}
+
+// DesiredConfiguration builds the ValidatingWebhookConfiguration for SCC objects.
+// Failures are ignored by the API server so an unavailable operator never blocks edits; the controllers still validate.
+func DesiredConfiguration(opts ConfigurationOptions) *admissionregistrationv1.ValidatingWebhookConfiguration {
+	failurePolicy := admissionregistrationv1.Ignore
+	sideEffects := admissionregistrationv1.SideEffectClassNone
+	operations := []admissionregistrationv1.OperationType{
+		admissionregistrationv1.Create,
+		admissionregistrationv1.Update,
+	}
+
+	clientConfig := func(path string) admissionregistrationv1.WebhookClientConfig {
+		return admissionregistrationv1.WebhookClientConfig{
+			Service: &admissionregistrationv1.ServiceReference{
+				Name:      opts.ServiceName,
+				Namespace: opts.Namespace,
+				Path:      ptr.To(path),
+				Port:      ptr.To(opts.Port),
+			},
+			CABundle: opts.CABundle,
+		}
+	}
+
+	return &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: consts.WebhookConfigurationName,
+			Labels: map[string]string{
+				consts.LabelK8sManagedBy: opts.OperatorName,
+			},
+		},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{
+			{
+				Name:         consts.WebhookNameSecrets,
+				ClientConfig: clientConfig(SecretsPath),
+				Rules: []admissionregistrationv1.RuleWithOperations{
+					{
+						Operations: operations,
+						Rule: admissionregistrationv1.Rule{
+							APIGroups:   []string{""},
+							APIVersions: []string{"v1"},
+							Resources:   []string{"secrets"},
+							Scope:       ptr.To(admissionregistrationv1.NamespacedScope),
+						},
+					},
+				},
+				NamespaceSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"kubernetes.io/metadata.name": opts.SystemNamespace,
+					},
+				},
+				FailurePolicy:           &failurePolicy,
+				SideEffects:             &sideEffects,
+				AdmissionReviewVersions: []string{"v1"},
+			},
+			{
+				Name:         consts.WebhookNameRegistrations,
+				ClientConfig: clientConfig(RegistrationsPath),
+				Rules: []admissionregistrationv1.RuleWithOperations{
+					{
+						Operations: operations,
+						Rule: admissionregistrationv1.Rule{
+							APIGroups:   []string{v1.SchemeGroupVersion.Group},
+							APIVersions: []string{v1.SchemeGroupVersion.Version},
+							Resources:   []string{"registrations"},
+							Scope:       ptr.To(admissionregistrationv1.ClusterScope),
+						},
+					},
+				},
+				FailurePolicy:           &failurePolicy,
+				SideEffects:             &sideEffects,
+				AdmissionReviewVersions: []string{"v1"},
+			},
+		},
+	}
+}
+
+// EnsureConfiguration creates or updates the ValidatingWebhookConfiguration to match the desired state
+func EnsureConfiguration(ctx context.Context, client typedadmissionv1.ValidatingWebhookConfigurationInterface, opts ConfigurationOptions) error {
+	desired := DesiredConfiguration(opts)
+
+	existing, err := client.Get(ctx, desired.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		if _, err := client.Create(ctx, desired, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create validating webhook configuration: %w", err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get validating webhook configuration: %w", err)
+	}
+
+	updated := existing.DeepCopy()
+	updated.Labels = desired.Labels
+	updated.Webhooks = desired.Webhooks
+	if _, err := client.Update(ctx, updated, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update validating webhook configuration: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package webhook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/rancher/scc-operator/internal/consts"
+)
+
+func TestEnsureConfiguration(t *testing.T) {
+	client := fake.NewClientset().AdmissionregistrationV1().ValidatingWebhookConfigurations()
+	opts := ConfigurationOptions{
+		ServiceName:     consts.DefaultWebhookServiceName,
+		Namespace:       testNamespace,
+		Port:            consts.DefaultWebhookPort,
+		CABundle:        []byte("first-ca"),
+		OperatorName:    consts.DefaultOperatorName,
+		SystemNamespace: testNamespace,
+	}
+
+	require.NoError(t, EnsureConfiguration(t.Context(), client, opts))
+
+	created, err := client.Get(t.Context(), consts.WebhookConfigurationName, metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Len(t, created.Webhooks, 2)
+	secretsHook := created.Webhooks[0]
+	assert.Equal(t, consts.WebhookNameSecrets, secretsHook.Name)
+	assert.Equal(t, SecretsPath, *secretsHook.ClientConfig.Service.Path)
+	assert.Equal(t, admissionregistrationv1.Ignore, *secretsHook.FailurePolicy)
+	assert.Equal(t, testNamespace, secretsHook.NamespaceSelector.MatchLabels["kubernetes.io/metadata.name"])
+	assert.Equal(t, []string{"registrations"}, created.Webhooks[1].Rules[0].Resources)
+
+	opts.CABundle = []byte("rotated-ca")
+	require.NoError(t, EnsureConfiguration(t.Context(), client, opts))
+
+	updated, err := client.Get(t.Context(), consts.WebhookConfigurationName, metav1.GetOptions{})
+	require.NoError(t, err)
+	for _, hook := range updated.Webhooks {
+		assert.Equal(t, []byte("rotated-ca"), hook.ClientConfig.CABundle)
+	}
+}
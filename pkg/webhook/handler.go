@@ -0,0 +1,166 @@
+/*
+Package webhook serves the validating admission webhook for SCC entrypoint Secrets and Registrations.
+
+The webhook runs the same rules as the controllers (see internal/validation) so invalid objects are
+rejected at admission time instead of surfacing later as a failed reconcile.
+*/
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/rancher/scc-operator/internal/logging"
+	"github.com/rancher/scc-operator/internal/validation"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+	"github.com/rancher/scc-operator/pkg/controllers/helpers"
+)
+
+const (
+	SecretsPath       = "/validate/secrets"
+	RegistrationsPath = "/validate/registrations"
+
+	maxRequestBytes = 3 * 1024 * 1024
+)
+
+type validateFunc func(request *admissionv1.AdmissionRequest) error
+
+type admissionHandler struct {
+	log             logging.StructuredLogger
+	systemNamespace string
+}
+
+// NewHandler prepares the http.Handler serving all SCC validation paths
+func NewHandler(systemNamespace string) http.Handler {
+	h := &admissionHandler{
+		log:             logging.NewComponentLogger("webhook"),
+		systemNamespace: systemNamespace,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(SecretsPath, h.serve(h.validateSecret))
+	mux.HandleFunc(RegistrationsPath, h.serve(h.validateRegistration))
+	return mux
+}
+
+func (h *admissionHandler) serve(validate validateFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBytes))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to read request: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		review := admissionv1.AdmissionReview{}
+		if err := json.Unmarshal(body, &review); err != nil || review.Request == nil {
+			http.Error(w, "request body must be an AdmissionReview", http.StatusBadRequest)
+			return
+		}
+
+		response := &admissionv1.AdmissionResponse{
+			UID:     review.Request.UID,
+			Allowed: true,
+		}
+		if validationErr := validate(review.Request); validationErr != nil {
+			h.log.Debugf("denying %s of %s %s/%s: %v", review.Request.Operation, review.Request.Kind.Kind, review.Request.Namespace, review.Request.Name, validationErr)
+			response.Allowed = false
+			response.Result = &metav1.Status{
+				Status:  metav1.StatusFailure,
+				Reason:  metav1.StatusReasonInvalid,
+				Code:    http.StatusUnprocessableEntity,
+				Message: validationErr.Error(),
+			}
+		}
+
+		review.Response = response
+		review.Request = nil
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(review); err != nil {
+			h.log.Errorf("failed to write admission response: %v", err)
+		}
+	}
+}
+
+func (h *admissionHandler) validateSecret(request *admissionv1.AdmissionRequest) error {
+	if request.Namespace != h.systemNamespace {
+		return nil
+	}
+
+	secret := &corev1.Secret{}
+	if err := decodeObject(request.Object, secret); err != nil {
+		return err
+	}
+	// The request namespace is authoritative, decoded objects may omit it on create
+	secret.Namespace = request.Namespace
+	if !helpers.IsEntrypointSecret(secret, h.systemNamespace) || secret.DeletionTimestamp != nil {
+		return nil
+	}
+
+	if request.Operation == admissionv1.Update {
+		oldSecret := &corev1.Secret{}
+		if err := decodeObject(request.OldObject, oldSecret); err != nil {
+			return err
+		}
+		// Metadata only updates (salt, labels, finalizers) are made by the operator itself
+		if reflect.DeepEqual(oldSecret.Data, secret.Data) && reflect.DeepEqual(oldSecret.StringData, secret.StringData) {
+			return nil
+		}
+	}
+
+	// StringData is only merged into Data after admission, so it must be considered here
+	merged := secret.DeepCopy()
+	for key, value := range secret.StringData {
+		if merged.Data == nil {
+			merged.Data = map[string][]byte{}
+		}
+		merged.Data[key] = []byte(value)
+	}
+
+	return validation.EntrypointSecret(merged)
+}
+
+func (h *admissionHandler) validateRegistration(request *admissionv1.AdmissionRequest) error {
+	registration := &v1.Registration{}
+	if err := decodeObject(request.Object, registration); err != nil {
+		return err
+	}
+	if registration.DeletionTimestamp != nil {
+		return nil
+	}
+
+	if request.Operation == admissionv1.Update {
+		oldRegistration := &v1.Registration{}
+		if err := decodeObject(request.OldObject, oldRegistration); err != nil {
+			return err
+		}
+		if reflect.DeepEqual(oldRegistration.Spec, registration.Spec) {
+			return nil
+		}
+	}
+
+	return validation.RegistrationSpec(&registration.Spec)
+}
+
+func decodeObject(raw runtime.RawExtension, into runtime.Object) error {
+	if len(raw.Raw) == 0 {
+		return fmt.Errorf("admission request is missing an object")
+	}
+	if err := json.Unmarshal(raw.Raw, into); err != nil {
+		return fmt.Errorf("failed to decode admission object: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,210 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+
+	"github.com/rancher/scc-operator/internal/consts"
+	v1 "github.com/rancher/scc-operator/pkg/apis/scc.cattle.io/v1"
+)
+
+const testNamespace = consts.DefaultSCCNamespace
+
+// newTestWebhookServer serves the webhook over TLS with a generated cert and returns a client that, like the API
+// server, only trusts the CA bundle that would be written into the webhook configuration.
+func newTestWebhookServer(t *testing.T) (*httptest.Server, *http.Client) {
+	t.Helper()
+
+	servingCert, err := EnsureServingCert(t.Context(), fake.NewClientset().CoreV1().Secrets(testNamespace), "127.0.0.1", testNamespace)
+	require.NoError(t, err)
+	keyPair, err := servingCert.TLSCertificate()
+	require.NoError(t, err)
+
+	server := httptest.NewUnstartedServer(NewHandler(testNamespace))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{keyPair}}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(servingCert.CA()))
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				RootCAs:    roots,
+				ServerName: ServiceHostname("127.0.0.1", testNamespace),
+			},
+		},
+	}
+
+	return server, client
+}
+
+func rawObject(t *testing.T, obj runtime.Object) runtime.RawExtension {
+	t.Helper()
+	raw, err := json.Marshal(obj)
+	require.NoError(t, err)
+	return runtime.RawExtension{Raw: raw}
+}
+
+func review(t *testing.T, server *httptest.Server, client *http.Client, path string, request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	t.Helper()
+	body, err := json.Marshal(admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+		Request:  request,
+	})
+	require.NoError(t, err)
+
+	resp, err := client.Post(server.URL+path, "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	result := admissionv1.AdmissionReview{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	require.NotNil(t, result.Response)
+	assert.Equal(t, request.UID, result.Response.UID)
+	return result.Response
+}
+
+func entrypointSecret(data map[string][]byte) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: consts.ResourceSCCEntrypointSecretName, Namespace: testNamespace},
+		Data:       data,
+	}
+}
+
+func TestValidateSecrets(t *testing.T) {
+	server, client := newTestWebhookServer(t)
+
+	valid := entrypointSecret(map[string][]byte{consts.SecretKeyRegistrationCode: []byte("code")})
+	missingCode := entrypointSecret(map[string][]byte{consts.SecretKeyRegistrationType: []byte("online")})
+	badMode := entrypointSecret(map[string][]byte{consts.SecretKeyRegistrationType: []byte("sideways")})
+	otherSecret := badMode.DeepCopy()
+	otherSecret.Name = "not-the-entrypoint"
+	labelledEntrypoint := otherSecret.DeepCopy()
+	labelledEntrypoint.Labels = map[string]string{consts.LabelSccEntrypoint: consts.EntrypointLabelValue}
+	withStringData := missingCode.DeepCopy()
+	withStringData.StringData = map[string]string{consts.SecretKeyRegistrationCode: "code"}
+
+	var tests = []struct {
+		name      string
+		operation admissionv1.Operation
+		object    *corev1.Secret
+		oldObject *corev1.Secret
+		allowed   bool
+		message   string
+	}{
+		{name: "valid create", operation: admissionv1.Create, object: valid, allowed: true},
+		{name: "missing reg code", operation: admissionv1.Create, object: missingCode, message: "data[regCode]: Required value"},
+		{name: "invalid mode", operation: admissionv1.Create, object: badMode, message: "Unsupported value: \"sideways\""},
+		{name: "other secrets are ignored", operation: admissionv1.Create, object: otherSecret, allowed: true},
+		{name: "labelled entrypoints are validated", operation: admissionv1.Create, object: labelledEntrypoint, message: "Unsupported value: \"sideways\""},
+		{name: "string data is considered", operation: admissionv1.Create, object: withStringData, allowed: true},
+		{name: "invalid update", operation: admissionv1.Update, object: missingCode, oldObject: valid, message: "data[regCode]: Required value"},
+		{name: "metadata only update", operation: admissionv1.Update, object: missingCode, oldObject: missingCode, allowed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := &admissionv1.AdmissionRequest{
+				UID:       types.UID(tt.name),
+				Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Secret"},
+				Name:      tt.object.Name,
+				Namespace: tt.object.Namespace,
+				Operation: tt.operation,
+				Object:    rawObject(t, tt.object),
+			}
+			if tt.oldObject != nil {
+				request.OldObject = rawObject(t, tt.oldObject)
+			}
+
+			response := review(t, server, client, SecretsPath, request)
+			assert.Equal(t, tt.allowed, response.Allowed)
+			if !tt.allowed {
+				assert.Contains(t, response.Result.Message, tt.message)
+			}
+		})
+	}
+}
+
+func TestValidateRegistrations(t *testing.T) {
+	server, client := newTestWebhookServer(t)
+
+	valid := &v1.Registration{
+		ObjectMeta: metav1.ObjectMeta{Name: "scc-registration-abc"},
+		Spec: v1.RegistrationSpec{
+			Mode: v1.RegistrationModeOnline,
+			RegistrationRequest: &v1.RegistrationRequest{
+				RegistrationCodeSecretRef: &corev1.SecretReference{Name: "registration-code-abc", Namespace: testNamespace},
+			},
+		},
+	}
+	invalid := valid.DeepCopy()
+	invalid.Spec.RegistrationRequest = nil
+	deleting := invalid.DeepCopy()
+	deleting.DeletionTimestamp = ptr.To(metav1.Now())
+
+	var tests = []struct {
+		name      string
+		operation admissionv1.Operation
+		object    *v1.Registration
+		oldObject *v1.Registration
+		allowed   bool
+	}{
+		{name: "valid create", operation: admissionv1.Create, object: valid, allowed: true},
+		{name: "invalid create", operation: admissionv1.Create, object: invalid},
+		{name: "invalid update", operation: admissionv1.Update, object: invalid, oldObject: valid},
+		{name: "unchanged spec", operation: admissionv1.Update, object: invalid, oldObject: invalid, allowed: true},
+		{name: "deleting", operation: admissionv1.Update, object: deleting, oldObject: valid, allowed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := &admissionv1.AdmissionRequest{
+				UID:       types.UID(tt.name),
+				Kind:      metav1.GroupVersionKind{Group: "scc.cattle.io", Version: "v1", Kind: "Registration"},
+				Name:      tt.object.Name,
+				Operation: tt.operation,
+				Object:    rawObject(t, tt.object),
+			}
+			if tt.oldObject != nil {
+				request.OldObject = rawObject(t, tt.oldObject)
+			}
+
+			response := review(t, server, client, RegistrationsPath, request)
+			assert.Equal(t, tt.allowed, response.Allowed)
+			if !tt.allowed {
+				assert.Contains(t, response.Result.Message, "spec.registrationRequest: Required value")
+			}
+		})
+	}
+}
+
+func TestHandlerRejectsMalformedRequests(t *testing.T) {
+	server, client := newTestWebhookServer(t)
+
+	resp, err := client.Post(server.URL+SecretsPath, "application/json", bytes.NewReader([]byte("{}")))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = client.Get(server.URL + SecretsPath)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
@@ -0,0 +1,65 @@
+package webhook
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rancher/scc-operator/internal/logging"
+)
+
+// Server serves HTTP handlers over TLS with the webhook serving certificate
+type Server struct {
+	log        logging.StructuredLogger
+	name       string
+	httpServer *http.Server
+}
+
+// NewServer prepares a webhook Server listening on the given port
+func NewServer(port int, servingCert *ServingCert, systemNamespace string) (*Server, error) {
+	return NewTLSServer("validating webhook server", port, servingCert, NewHandler(systemNamespace))
+}
+
+// NewTLSServer prepares a Server serving the handler on the given port, e.g. for other endpoints that must not be plain HTTP
+func NewTLSServer(name string, port int, servingCert *ServingCert, handler http.Handler) (*Server, error) {
+	keyPair, err := servingCert.TLSCertificate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook serving certificate: %w", err)
+	}
+
+	return &Server{
+		log:  logging.NewComponentLogger("webhook-server"),
+		name: name,
+		httpServer: &http.Server{
+			Addr:              fmt.Sprintf(":%d", port),
+			Handler:           handler,
+			ReadHeaderTimeout: 10 * time.Second,
+			TLSConfig: &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{keyPair},
+			},
+		},
+	}, nil
+}
+
+// Start serves requests until the context is cancelled
+func (s *Server) Start(ctx context.Context) error {
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.httpServer.Shutdown(shutdownCtx); err != nil {
+			s.log.Warnf("failed to shut down %s: %v", s.name, err)
+		}
+	}()
+
+	s.log.Infof("Starting %s on %s", s.name, s.httpServer.Addr)
+	if err := s.httpServer.ListenAndServeTLS("", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}